@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"k8s.io/klog"
 )
@@ -27,63 +28,91 @@ import (
 // Client interface
 type Client interface {
 	NewClient() (*ClientService, error)
-	CreateVolume(volume *VolumeParam, storagePoolName string) (*Volume, error)
-	GetStoragePoolIDByName(name string) (id int64, err error)
-	FindStoragePool(id int64, name string) (StoragePool, error)
-	GetStoragePool(poolID int64, storagepool string) ([]StoragePool, error)
-	GetVolumeByName(volumename string) (*Volume, error)
-	GetVolume(volumeid int) (*Volume, error)
-	CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error)
-	GetNetworkSpaceByName(networkSpaceName string) (nspace NetworkSpace, err error)
-	DeleteVolume(volumeID int) (err error)
-	UpdateVolume(volumeID int, volume Volume) (*Volume, error)
-	GetVolumeSnapshotByParentID(volumeID int) (*[]Volume, error)
-
-	GetHostByName(hostName string) (host Host, err error)
-	CreateHost(hostName string) (host Host, err error)
-	AddHostPort(portType, portAddress string, hostID int) (hostPort HostPort, err error)
-	AddHostSecurity(chapCreds map[string]string, hostID int) (host Host, err error)
-	MapVolumeToHost(hostID, volumeID, lun int) (luninfo LunInfo, err error)
-	DeleteHost(hostID int) (err error)
-	GetLunByHostVolume(hostID, volumeID int) (luninfo LunInfo, err error)
-	GetAllLunByHost(hostID int) (luninfo []LunInfo, err error)
-	UnMapVolumeFromHost(hostID, volumeID int) (err error)
-	GetFCPorts() (fcNodes []FCNode, err error)
-	GetHostPort(hostID int, portAddress string) (hostPort HostPort, err error)
+	CreateVolume(ctx context.Context, volume *VolumeParam, storagePoolName string) (*Volume, error)
+	GetStoragePoolIDByName(ctx context.Context, name string) (id int64, err error)
+	FindStoragePool(ctx context.Context, id int64, name string) (StoragePool, error)
+	GetStoragePool(ctx context.Context, poolID int64, storagepool string) ([]StoragePool, error)
+	GetStoragePoolByName(ctx context.Context, name string) (*StoragePool, error)
+	GetVolumeByName(ctx context.Context, volumename string) (*Volume, error)
+	GetVolumeByPVName(ctx context.Context, pvName string) (*Volume, error)
+	GetVolume(ctx context.Context, volumeid int) (*Volume, error)
+	CreateSnapshotVolume(ctx context.Context, snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error)
+	GetNetworkSpaceByName(ctx context.Context, networkSpaceName string) (nspace NetworkSpace, err error)
+	GetSystem(ctx context.Context) (*System, error)
+	DeleteVolume(ctx context.Context, volumeID int) (err error)
+	UpdateVolume(ctx context.Context, volumeID int, volume Volume) (*Volume, error)
+	GetVolumeSnapshotByParentID(ctx context.Context, volumeID int) (*[]Volume, error)
+	GetVolumesByIDs(ctx context.Context, volumeIDs []int) ([]Volume, error)
+	GetVolumesByParentIDs(ctx context.Context, parentIDs []int) ([]Volume, error)
+
+	GetHostByName(ctx context.Context, hostName string) (host Host, err error)
+	GetAllHosts(ctx context.Context) (hosts []Host, err error)
+	CreateHost(ctx context.Context, hostName string) (host Host, err error)
+	AddHostPort(ctx context.Context, portType, portAddress string, hostID int) (hostPort HostPort, err error)
+	AddHostSecurity(ctx context.Context, chapCreds map[string]string, hostID int) (host Host, err error)
+	MapVolumeToHost(ctx context.Context, hostID, volumeID, lun int) (luninfo LunInfo, err error)
+	DeleteHost(ctx context.Context, hostID int) (err error)
+	GetLunByHostVolume(ctx context.Context, hostID, volumeID int) (luninfo LunInfo, err error)
+	GetAllLunByHost(ctx context.Context, hostID int) (luninfo []LunInfo, err error)
+	GetLunsByVolume(ctx context.Context, volumeID int) (luninfo []LunInfo, err error)
+	UnMapVolumeFromHost(ctx context.Context, hostID, volumeID int) (err error)
+	GetFCPorts(ctx context.Context) (fcNodes []FCNode, err error)
+	GetHostPort(ctx context.Context, hostID int, portAddress string) (hostPort HostPort, err error)
 
 	// for nfs
-	OneTimeValidation(poolname string, networkspace string) (list string, err error)
-	ExportFileSystem(export ExportFileSys) (*ExportResponse, error)
-	DeleteExportPath(exportID int64) (*ExportResponse, error)
-	DeleteFileSystem(fileSystemID int64) (*FileSystem, error)
-	AttachMetadataToObject(objectID int64, body map[string]interface{}) (*[]Metadata, error)
-	DetachMetadataFromObject(objectID int64) (*[]Metadata, error)
-	CreateFilesystem(fileSysparameter map[string]interface{}) (*FileSystem, error)
-	GetExportByFileSystem(filesystemID int64) (*[]ExportResponse, error)
-	AddNodeInExport(exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error)
-	DeleteNodeFromExport(exportID int64, access string, noRootSquash bool, ip string) (*ExportResponse, error)
-	CreateFileSystemSnapshot(snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error)
-	DeleteFileSystemComplete(fileSystemID int64) (err error)
-	DeleteParentFileSystem(fileSystemID int64) (err error)
-	GetParentID(fileSystemID int64) int64
-	GetFileSystemByID(fileSystemID int64) (*FileSystem, error)
-	GetFileSystemByName(fileSystemName string) (*FileSystem, error)
-	GetMetadataStatus(fileSystemID int64) bool
-	FileSystemHasChild(fileSystemID int64) bool
-	DeleteExportRule(fileSystemID int64, ipAddress string) (err error)
-	UpdateFilesystem(fileSystemID int64, fileSystem FileSystem) (*FileSystem, error)
-	GetSnapshotByName(snapshotName string) (*[]FileSystemSnapshotResponce, error)
-	RestoreFileSystemFromSnapShot(parentID, srcSnapShotID int64) (bool, error)
-
-	GetFileSystemsByPoolID(poolID int64, page int) (*FSMetadata, error)
-	GetFilesytemTreeqCount(fileSystemID int64) (treeqCnt int, err error)
-	CreateTreeq(filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error)
-	DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error)
-	GetTreeq(fileSystemID, treeqID int64) (*Treeq, error)
-	UpdateTreeq(fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error)
-	GetTreeqSizeByFileSystemID(filesystemID int64) (int64, error)
-	GetFileSystemCountByPoolID(poolID int64) (int, error)
-	GetTreeqByName(fileSystemID int64, treeqName string) (*Treeq, error)
+	OneTimeValidation(ctx context.Context, poolname string, networkspace string) (list string, err error)
+	ExportFileSystem(ctx context.Context, export ExportFileSys) (*ExportResponse, error)
+	DeleteExportPath(ctx context.Context, exportID int64) (*ExportResponse, error)
+	DeleteFileSystem(ctx context.Context, fileSystemID int64) (*FileSystem, error)
+	AttachMetadataToObject(ctx context.Context, objectID int64, body map[string]interface{}) (*[]Metadata, error)
+	DetachMetadataFromObject(ctx context.Context, objectID int64) (*[]Metadata, error)
+	DetachMetadataKeyFromObject(ctx context.Context, objectID int64, key string) error
+	CreateFilesystem(ctx context.Context, fileSysparameter map[string]interface{}) (*FileSystem, error)
+	GetExportByFileSystem(ctx context.Context, filesystemID int64) (*[]ExportResponse, error)
+	AddNodeInExport(ctx context.Context, exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error)
+	DeleteNodeFromExport(ctx context.Context, exportID int64, access string, noRootSquash bool, ip string) (*ExportResponse, error)
+	CreateFileSystemSnapshot(ctx context.Context, snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error)
+	DeleteFileSystemComplete(ctx context.Context, fileSystemID int64) (err error)
+	DeleteParentFileSystem(ctx context.Context, fileSystemID int64) (err error)
+	GetParentID(ctx context.Context, fileSystemID int64) int64
+	GetFileSystemByID(ctx context.Context, fileSystemID int64) (*FileSystem, error)
+	GetFileSystemByName(ctx context.Context, fileSystemName string) (*FileSystem, error)
+	GetFileSystemByPVName(ctx context.Context, pvName string) (*FileSystem, error)
+	GetMetadataStatus(ctx context.Context, fileSystemID int64) bool
+	GetMetadataValue(ctx context.Context, objectID int64, key string) (string, error)
+	GetMetadataByKey(ctx context.Context, key string) ([]Metadata, error)
+	FileSystemHasChild(ctx context.Context, fileSystemID int64) bool
+	GetFileSystemsByIDs(ctx context.Context, fileSystemIDs []int64) ([]FileSystem, error)
+	GetFileSystemsByParentIDs(ctx context.Context, parentIDs []int64) ([]FileSystem, error)
+	DeleteExportRule(ctx context.Context, fileSystemID int64, ipAddress string) (err error)
+	UpdateFilesystem(ctx context.Context, fileSystemID int64, fileSystem FileSystem) (*FileSystem, error)
+	GetSnapshotByName(ctx context.Context, snapshotName string) (*[]FileSystemSnapshotResponce, error)
+	RestoreFileSystemFromSnapShot(ctx context.Context, parentID, srcSnapShotID int64) (bool, error)
+
+	GetFileSystemsByPoolID(ctx context.Context, poolID int64) ([]FileSystem, error)
+	GetFilesytemTreeqCount(ctx context.Context, fileSystemID int64) (treeqCnt int, err error)
+	CreateTreeq(ctx context.Context, filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error)
+	DeleteTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error)
+	GetTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error)
+	UpdateTreeq(ctx context.Context, fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error)
+	GetTreeqSizeByFileSystemID(ctx context.Context, filesystemID int64) (int64, error)
+	GetFileSystemCountByPoolID(ctx context.Context, poolID int64) (int, error)
+	GetTreeqByName(ctx context.Context, fileSystemID int64, treeqName string) (*Treeq, error)
+	GetTreeqsByFileSystemID(ctx context.Context, fileSystemID int64) ([]Treeq, error)
+
+	// for qos
+	GetQosPolicyByName(ctx context.Context, name string) (*QosPolicy, error)
+	CreateQosPolicy(ctx context.Context, policy QosPolicy) (*QosPolicy, error)
+	AssignQosPolicy(ctx context.Context, policyID, entityID int64) error
+	UnassignQosPolicy(ctx context.Context, policyID, entityID int64) error
+
+	// for replication
+	GetLinkByRemoteSystem(ctx context.Context, remoteSystemName string) (*Link, error)
+	GetReplicaByEntity(ctx context.Context, entityID int64) (*Replica, error)
+	CreateReplica(ctx context.Context, replica Replica) (*Replica, error)
+	DeleteReplica(ctx context.Context, replicaID int64) error
+	SuspendReplica(ctx context.Context, replicaID int64) error
+	ChangeReplicaRole(ctx context.Context, replicaID int64) (*Replica, error)
 }
 
 // ClientService : struct having reference of rest client and will host methods which need rest operations
@@ -109,16 +138,16 @@ func (c *ClientService) NewClient() (*ClientService, error) {
 }
 
 // DeleteVolume : Delete volume by volume id
-func (c *ClientService) DeleteVolume(volumeID int) (err error) {
+func (c *ClientService) DeleteVolume(ctx context.Context, volumeID int) (err error) {
 	klog.V(2).Infof("Delete Volume with ID %d", volumeID)
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("DeleteVolume Panic occured -  " + fmt.Sprint(res))
 		}
 	}()
-	_, err = c.DetachMetadataFromObject(int64(volumeID))
+	_, err = c.DetachMetadataFromObject(ctx, int64(volumeID))
 	if err != nil {
-		if strings.Contains(err.Error(), "METADATA_IS_NOT_SUPPORTED_FOR_ENTITY") {
+		if HasErrorCode(err, ErrMetadataIsNotSupportedForEntity) {
 			err = nil
 		} else {
 			klog.Errorf("failed to delete metadata %v", err)
@@ -127,7 +156,7 @@ func (c *ClientService) DeleteVolume(volumeID int) (err error) {
 	}
 
 	path := "/api/rest/volumes/" + strconv.Itoa(volumeID) + "?approved=true"
-	_, err = c.getJSONResponse(http.MethodDelete, path, nil, nil)
+	_, err = c.getJSONResponse(ctx, http.MethodDelete, path, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -136,7 +165,7 @@ func (c *ClientService) DeleteVolume(volumeID int) (err error) {
 }
 
 // AddHostSecurity - add chap security for host with given details
-func (c *ClientService) AddHostSecurity(chapCreds map[string]string, hostID int) (host Host, err error) {
+func (c *ClientService) AddHostSecurity(ctx context.Context, chapCreds map[string]string, hostID int) (host Host, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("AddHostSecurity Panic occured -  " + fmt.Sprint(res))
@@ -144,7 +173,7 @@ func (c *ClientService) AddHostSecurity(chapCreds map[string]string, hostID int)
 	}()
 	klog.V(2).Infof("add chap atuhentication for hostID %d : ", hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "?approved=true"
-	resp, err := c.getJSONResponse(http.MethodPut, uri, chapCreds, host)
+	resp, err := c.getJSONResponse(ctx, http.MethodPut, uri, chapCreds, host)
 	if err != nil {
 		klog.Errorf("failed to add chap security to host %d with error %v", hostID, err)
 		return host, err
@@ -158,7 +187,7 @@ func (c *ClientService) AddHostSecurity(chapCreds map[string]string, hostID int)
 }
 
 // AddHostPort - add port for host with given details
-func (c *ClientService) AddHostPort(portType, portAddress string, hostID int) (hostPort HostPort, err error) {
+func (c *ClientService) AddHostPort(ctx context.Context, portType, portAddress string, hostID int) (hostPort HostPort, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("AddHostPort Panic occured -  " + fmt.Sprint(res))
@@ -167,9 +196,9 @@ func (c *ClientService) AddHostPort(portType, portAddress string, hostID int) (h
 	klog.V(2).Infof("add port for hostID %s %d : ", portAddress, hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "/ports?approved=true"
 	body := map[string]interface{}{"address": portAddress, "type": portType}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, body, &hostPort)
+	resp, err := c.getJSONResponse(ctx, http.MethodPost, uri, body, &hostPort)
 	if err != nil {
-		if strings.Contains(err.Error(), "PORT_ALREADY_BELONGS_TO_HOST") {
+		if HasErrorCode(err, ErrPortAlreadyBelongsToHost) {
 			klog.V(4).Infof("Success: No need to add port '%s' to host with ID %d, port already belongs to host", portAddress, hostID)
 		} else {
 			klog.Errorf("Error adding port '%s' to host with ID %d, error: %+v", portAddress, hostID, err)
@@ -186,7 +215,7 @@ func (c *ClientService) AddHostPort(portType, portAddress string, hostID int) (h
 }
 
 // CreateVolume : create volume with volume details provided in storage pool provided
-func (c *ClientService) CreateVolume(volume *VolumeParam, storagePoolName string) (*Volume, error) {
+func (c *ClientService) CreateVolume(ctx context.Context, volume *VolumeParam, storagePoolName string) (*Volume, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -196,7 +225,7 @@ func (c *ClientService) CreateVolume(volume *VolumeParam, storagePoolName string
 	klog.V(2).Infof("Create Volume with storagepoolname: %s", storagePoolName)
 
 	path := "/api/rest/volumes"
-	poolID, err := c.GetStoragePoolIDByName(storagePoolName)
+	poolID, err := c.GetStoragePoolIDByName(ctx, storagePoolName)
 	klog.V(4).Infof("CreateVolume fetched storagepool poolID %d", poolID)
 	if err != nil {
 		return nil, err
@@ -209,7 +238,13 @@ func (c *ClientService) CreateVolume(volume *VolumeParam, storagePoolName string
 	valumeParameter["provtype"] = volume.ProvisionType
 	valumeParameter["ssd_enabled"] = volume.SsdEnabled
 	vol := Volume{}
-	resp, err := c.getJSONResponse(http.MethodPost, path, valumeParameter, &vol)
+	resp, err := c.postWithCreationCheck(ctx, path, valumeParameter, &vol, func() (bool, interface{}, error) {
+		existing, err := c.GetVolumeByName(ctx, volume.Name)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, *existing, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +257,7 @@ func (c *ClientService) CreateVolume(volume *VolumeParam, storagePoolName string
 }
 
 // FindStoragePool : Find storage pool either by id or name
-func (c *ClientService) FindStoragePool(id int64, name string) (StoragePool, error) {
+func (c *ClientService) FindStoragePool(ctx context.Context, id int64, name string) (StoragePool, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -230,7 +265,7 @@ func (c *ClientService) FindStoragePool(id int64, name string) (StoragePool, err
 		}
 	}()
 	klog.V(2).Infof("FindStoragePool called with either id %d or name %s", id, name)
-	storagePools, err := c.GetStoragePool(id, name)
+	storagePools, err := c.GetStoragePool(ctx, id, name)
 	if err != nil {
 		return StoragePool{}, fmt.Errorf("Error getting storage pool %s", err)
 	}
@@ -245,7 +280,7 @@ func (c *ClientService) FindStoragePool(id int64, name string) (StoragePool, err
 }
 
 // GetStoragePool : Get storage pool(s) either by id or name
-func (c *ClientService) GetStoragePool(poolID int64, storagepoolname string) ([]StoragePool, error) {
+func (c *ClientService) GetStoragePool(ctx context.Context, poolID int64, storagepoolname string) ([]StoragePool, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -257,23 +292,19 @@ func (c *ClientService) GetStoragePool(poolID int64, storagepoolname string) ([]
 	storagePools := []StoragePool{}
 
 	if storagepoolname == "" && poolID != -1 {
-		resp, err := c.getJSONResponse(http.MethodGet, "/api/rest/pools", nil, &storagePools)
+		err = c.getAllPages(ctx, "/api/rest/pools", nil, &storagePools)
 		if err != nil {
 			return nil, err
 		}
-		if len(storagePools) == 0 {
-			apiresp := resp.(client.ApiResponse)
-			storagePools, _ = apiresp.Result.([]StoragePool)
-		}
 	} else {
-		queryParam := make(map[string]interface{})
+		query := NewQuery()
 		if poolID != -1 {
-			queryParam["id"] = poolID
+			query.Eq("id", poolID)
 		} else {
-			queryParam["name"] = storagepoolname
+			query.Eq("name", storagepoolname)
 		}
 		storagePool := StoragePool{}
-		resp, err := c.getResponseWithQueryString("api/rest/pools", queryParam, &storagePool)
+		resp, err := c.getResponseWithQueryString(ctx, "api/rest/pools", query, &storagePool)
 		if err != nil {
 			return nil, err
 		}
@@ -290,7 +321,7 @@ func (c *ClientService) GetStoragePool(poolID int64, storagepoolname string) ([]
 }
 
 // GetStoragePoolIDByName : Returns poolID of provided pool name
-func (c *ClientService) GetStoragePoolIDByName(name string) (id int64, err error) {
+func (c *ClientService) GetStoragePoolIDByName(ctx context.Context, name string) (id int64, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("error while Get Pool ID  " + fmt.Sprint(res))
@@ -301,9 +332,8 @@ func (c *ClientService) GetStoragePoolIDByName(name string) (id int64, err error
 	// To get the pool_id for corresponding poolname
 	var poolID int64 = -1
 	urlpool := "api/rest/pools"
-	queryParam := make(map[string]interface{})
-	queryParam["name"] = name
-	resp, err := c.getResponseWithQueryString(urlpool, queryParam, &storagePools)
+	query := NewQuery().Eq("name", name).Fields("id", "name")
+	resp, err := c.getResponseWithQueryString(ctx, urlpool, query, &storagePools)
 	if err != nil {
 		klog.Errorf("error %s", err.Error())
 		return -1, fmt.Errorf("failed to get pool ID from pool Name: %s", name)
@@ -316,14 +346,40 @@ func (c *ClientService) GetStoragePoolIDByName(name string) (id int64, err error
 		return storagePools[0].ID, nil
 	}
 	if poolID == -1 {
-		return poolID, errors.New("No such pool: " + name)
+		return poolID, notFoundError(ErrPoolNotFound, "No such pool: "+name)
 	}
 	klog.V(2).Infof("Got ID of a storage pool: %d", poolID)
 	return poolID, nil
 }
 
+// GetStoragePoolByName : Returns the storage pool, including its capacity, of provided pool name
+func (c *ClientService) GetStoragePoolByName(ctx context.Context, name string) (storagePool *StoragePool, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("error while Get Pool  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get storage pool by Name : %s", name)
+	storagePools := []StoragePool{}
+	query := NewQuery().Eq("name", name)
+	resp, err := c.getResponseWithQueryString(ctx, "api/rest/pools", query, &storagePools)
+	if err != nil {
+		klog.Errorf("error %s", err.Error())
+		return nil, fmt.Errorf("failed to get pool from pool Name: %s", name)
+	}
+	if len(storagePools) == 0 {
+		apiresp := resp.(client.ApiResponse)
+		storagePools, _ = apiresp.Result.([]StoragePool)
+	}
+	if len(storagePools) == 0 {
+		return nil, notFoundError(ErrPoolNotFound, "No such pool: "+name)
+	}
+	klog.V(2).Infof("Got storage pool %s with ID %d", storagePools[0].Name, storagePools[0].ID)
+	return &storagePools[0], nil
+}
+
 // GetVolumeByName : find volume with given name
-func (c *ClientService) GetVolumeByName(volumename string) (*Volume, error) {
+func (c *ClientService) GetVolumeByName(ctx context.Context, volumename string) (*Volume, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -333,10 +389,8 @@ func (c *ClientService) GetVolumeByName(volumename string) (*Volume, error) {
 	klog.V(2).Infof("Get a Volume by Name: %s", volumename)
 	voluri := "/api/rest/volumes"
 	volumes := []Volume{}
-	queryParam := make(map[string]interface{})
-	queryParam["name"] = volumename
-	resp, err := c.getResponseWithQueryString(voluri,
-		queryParam, &volumes)
+	query := NewQuery().Eq("name", volumename)
+	resp, err := c.getResponseWithQueryString(ctx, voluri, query, &volumes)
 	if err != nil {
 		return nil, err
 	}
@@ -351,11 +405,40 @@ func (c *ClientService) GetVolumeByName(volumename string) (*Volume, error) {
 		}
 	}
 
-	return nil, errors.New("volume with given name not found")
+	return nil, notFoundError(ErrVolumeNotFound, "volume with given name not found")
+}
+
+// GetVolumeByPVName : find the volume backing the PV of given name by its pv name metadata,
+// regardless of the volume name on the array
+func (c *ClientService) GetVolumeByPVName(ctx context.Context, pvName string) (*Volume, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetVolumeByPVName Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get a Volume of PV: %s", pvName)
+	objectIDs, err := c.getObjectIDsByMetadata(ctx, PVNameMetadataKey, pvName)
+	if err != nil {
+		return nil, err
+	}
+	for _, objectID := range objectIDs {
+		volume, err := c.GetVolume(ctx, int(objectID))
+		if err != nil {
+			// the metadata belongs to an object of another type, e.g. a filesystem
+			if IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		klog.V(2).Infof("Got volume %s of PV %s", volume.Name, pvName)
+		return volume, nil
+	}
+	return nil, notFoundError(ErrVolumeNotFound, "volume of pv "+pvName+" not found")
 }
 
 // GetVolume : get volume by id
-func (c *ClientService) GetVolume(volumeid int) (*Volume, error) {
+func (c *ClientService) GetVolume(ctx context.Context, volumeid int) (*Volume, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -365,7 +448,7 @@ func (c *ClientService) GetVolume(volumeid int) (*Volume, error) {
 	klog.V(2).Infof("Get a Volume of ID: %d", volumeid)
 	volume := Volume{}
 	path := "/api/rest/volumes/" + strconv.Itoa(volumeid)
-	resp, err := c.getJSONResponse(http.MethodGet, path, nil, &volume)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, path, nil, &volume)
 	if err != nil {
 		return nil, err
 	}
@@ -377,7 +460,7 @@ func (c *ClientService) GetVolume(volumeid int) (*Volume, error) {
 }
 
 // CreateSnapshotVolume : Create volume from snapshot
-func (c *ClientService) CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error) {
+func (c *ClientService) CreateSnapshotVolume(ctx context.Context, snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -392,7 +475,14 @@ func (c *ClientService) CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*Sn
 	valumeParameter["name"] = snapshotParam.SnapshotName
 	valumeParameter["write_protected"] = snapshotParam.WriteProtected
 	valumeParameter["ssd_enabled"] = snapshotParam.SsdEnabled
-	resp, err := c.getJSONResponse(http.MethodPost, path, valumeParameter, &snapResp)
+	resp, err := c.postWithCreationCheck(ctx, path, valumeParameter, &snapResp, func() (bool, interface{}, error) {
+		existing, err := c.GetVolumeByName(ctx, snapshotParam.SnapshotName)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, SnapshotVolumesResp{SnapShotID: existing.ID, Size: existing.Size, SsdEnabled: existing.SsdEnabled,
+			ParentID: existing.ParentId, PoolID: int(existing.PoolId), Name: existing.Name, CreatedAt: existing.CreatedAt}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -405,7 +495,7 @@ func (c *ClientService) CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*Sn
 }
 
 // GetNetworkSpaceByName - Get networkspace by name
-func (c *ClientService) GetNetworkSpaceByName(networkSpaceName string) (nspace NetworkSpace, err error) {
+func (c *ClientService) GetNetworkSpaceByName(ctx context.Context, networkSpaceName string) (nspace NetworkSpace, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetNetworkSpaceByName Panic occured -  " + fmt.Sprint(res))
@@ -414,8 +504,8 @@ func (c *ClientService) GetNetworkSpaceByName(networkSpaceName string) (nspace N
 	klog.V(2).Infof("Get network space by name: %s", networkSpaceName)
 	netspaces := []NetworkSpace{}
 	path := "api/rest/network/spaces"
-	queryParam := map[string]interface{}{"name": networkSpaceName}
-	resp, err := c.getResponseWithQueryString(path, queryParam, &netspaces)
+	query := NewQuery().Eq("name", networkSpaceName)
+	resp, err := c.getResponseWithQueryString(ctx, path, query, &netspaces)
 	if err != nil {
 		klog.Errorf("No such network space: %s", networkSpaceName)
 		return nspace, err
@@ -431,8 +521,31 @@ func (c *ClientService) GetNetworkSpaceByName(networkSpaceName string) (nspace N
 	return nspace, nil
 }
 
+// GetSystem - Get the name and serial number of the array
+func (c *ClientService) GetSystem(ctx context.Context) (*System, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetSystem Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get system")
+	system := System{}
+	path := "/api/rest/system"
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, path, nil, &system)
+	if err != nil {
+		return nil, err
+	}
+	if system == (System{}) {
+		apiresp := resp.(client.ApiResponse)
+		system, _ = apiresp.Result.(System)
+	}
+	klog.V(2).Infof("Got system %s of serial number: %d", system.Name, system.SerialNumber)
+	return &system, nil
+}
+
 // DeleteHost - delete host by given host ID
-func (c *ClientService) DeleteHost(hostID int) (err error) {
+func (c *ClientService) DeleteHost(ctx context.Context, hostID int) (err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("DeleteHost Panic occured -  " + fmt.Sprint(res))
@@ -440,9 +553,9 @@ func (c *ClientService) DeleteHost(hostID int) (err error) {
 	}()
 	klog.V(2).Infof("delete host with host ID %d", hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID)
-	_, err = c.getJSONResponse(http.MethodDelete, uri, nil, nil)
+	_, err = c.getJSONResponse(ctx, http.MethodDelete, uri, nil, nil)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+		if !HasErrorCode(err, ErrHostNotFound) {
 			klog.Errorf("failed to delete host with id %d with error %v", hostID, err)
 		}
 		return err
@@ -452,7 +565,7 @@ func (c *ClientService) DeleteHost(hostID int) (err error) {
 }
 
 // CreateHost - create host  with given details
-func (c *ClientService) CreateHost(hostName string) (host Host, err error) {
+func (c *ClientService) CreateHost(ctx context.Context, hostName string) (host Host, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("CreateHost Panic occured -  " + fmt.Sprint(res))
@@ -461,7 +574,13 @@ func (c *ClientService) CreateHost(hostName string) (host Host, err error) {
 	klog.V(2).Infof("create host with name %s", hostName)
 	uri := "api/rest/hosts"
 	body := map[string]interface{}{"name": hostName}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, body, &host)
+	resp, err := c.postWithCreationCheck(ctx, uri, body, &host, func() (bool, interface{}, error) {
+		existing, err := c.GetHostByName(ctx, hostName)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, existing, nil
+	})
 	if err != nil {
 		klog.Errorf("error creating host : %s error : %v", hostName, err)
 		return host, err
@@ -476,7 +595,7 @@ func (c *ClientService) CreateHost(hostName string) (host Host, err error) {
 }
 
 // GetHostPort - get host port details
-func (c *ClientService) GetHostPort(hostID int, portAddress string) (hostPort HostPort, err error) {
+func (c *ClientService) GetHostPort(ctx context.Context, hostID int, portAddress string) (hostPort HostPort, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetHostPort Panic occured -  " + fmt.Sprint(res))
@@ -485,15 +604,11 @@ func (c *ClientService) GetHostPort(hostID int, portAddress string) (hostPort Ho
 	klog.V(2).Infof("get host port by port address %s", portAddress)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "/ports"
 	hostPorts := []HostPort{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &hostPorts)
+	err = c.getAllPages(ctx, uri, nil, &hostPorts)
 	if err != nil {
-		klog.Errorf("unable to get host port %s with error ", portAddress)
+		klog.Errorf("unable to get host port %s with error %v", portAddress, err)
 		return hostPort, err
 	}
-	if len(hostPorts) == 0 {
-		apiresp := resp.(client.ApiResponse)
-		hostPorts, _ = apiresp.Result.([]HostPort)
-	}
 
 	for _, port := range hostPorts {
 		if port.PortAddress == portAddress {
@@ -501,14 +616,14 @@ func (c *ClientService) GetHostPort(hostID int, portAddress string) (hostPort Ho
 		}
 	}
 	if hostPort.HostID == 0 && hostPort.PortAddress == "" {
-		return hostPort, errors.New("HOST_PORT_NOT_FOUND")
+		return hostPort, notFoundError(ErrHostPortNotFound, "host port "+portAddress+" not found")
 	}
 	klog.V(2).Infof("fetched hostPort with address %s", hostPort.PortAddress)
 	return hostPort, nil
 }
 
 // GetHostByName - get host details for given hostname
-func (c *ClientService) GetHostByName(hostName string) (host Host, err error) {
+func (c *ClientService) GetHostByName(ctx context.Context, hostName string) (host Host, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetHostByName Panic occured -  " + fmt.Sprint(res))
@@ -517,8 +632,8 @@ func (c *ClientService) GetHostByName(hostName string) (host Host, err error) {
 	klog.V(2).Infof("get host by name %s", hostName)
 	uri := "api/rest/hosts"
 	hosts := []Host{}
-	queryParam := map[string]interface{}{"name": hostName}
-	resp, err := c.getResponseWithQueryString(uri, queryParam, &hosts)
+	query := NewQuery().Eq("name", hostName)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &hosts)
 	if err != nil {
 		klog.Errorf("host %s not found ", hostName)
 		return host, err
@@ -532,30 +647,44 @@ func (c *ClientService) GetHostByName(hostName string) (host Host, err error) {
 		host = hosts[0]
 	}
 	if host.ID == 0 && host.Name == "" {
-		return host, errors.New("HOST_NOT_FOUND")
+		return host, notFoundError(ErrHostNotFound, "host "+hostName+" not found")
 	}
 	klog.V(2).Infof("fetched host with name %s", host.Name)
 	return host, nil
 }
 
+// GetAllHosts - get all hosts defined on the array
+func (c *ClientService) GetAllHosts(ctx context.Context) (hosts []Host, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetAllHosts Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("get all hosts")
+	uri := "api/rest/hosts"
+	err = c.getAllPages(ctx, uri, nil, &hosts)
+	if err != nil {
+		klog.Errorf("failed to get hosts with error %v", err)
+		return hosts, err
+	}
+	klog.V(2).Infof("got %d hosts", len(hosts))
+	return hosts, nil
+}
+
 // GetFCPorts - get fc ports details
-func (c *ClientService) GetFCPorts() (fcNodes []FCNode, err error) {
+func (c *ClientService) GetFCPorts(ctx context.Context) (fcNodes []FCNode, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetHostByName Panic occured -  " + fmt.Sprint(res))
 		}
 	}()
 	klog.V(2).Infof("get fc ports")
-	uri := "api/rest/components/nodes?fields=fc_ports"
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &fcNodes)
+	uri := "api/rest/components/nodes"
+	err = c.getAllPages(ctx, uri, NewQuery().Fields("fc_ports"), &fcNodes)
 	if err != nil {
-		klog.Errorf("error occured while fetching fc_ports ")
+		klog.Errorf("error occured while fetching fc_ports %v", err)
 		return fcNodes, err
 	}
-	if len(fcNodes) == 0 {
-		apiresp := resp.(client.ApiResponse)
-		fcNodes, _ = apiresp.Result.([]FCNode)
-	}
 
 	if len(fcNodes) == 0 {
 		return fcNodes, errors.New("fc port not found")
@@ -565,7 +694,7 @@ func (c *ClientService) GetFCPorts() (fcNodes []FCNode, err error) {
 }
 
 // UnMapVolumeFromHost - Remove mapping of volume with host
-func (c *ClientService) UnMapVolumeFromHost(hostID, volumeID int) (err error) {
+func (c *ClientService) UnMapVolumeFromHost(ctx context.Context, hostID, volumeID int) (err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("UnMapVolumeFromHost Panic occured -  " + fmt.Sprint(res))
@@ -573,9 +702,9 @@ func (c *ClientService) UnMapVolumeFromHost(hostID, volumeID int) (err error) {
 	}()
 	klog.V(2).Infof("Remove mapping of volume %d from host %d", volumeID, hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "/luns/volume_id/" + strconv.Itoa(volumeID) + "?approved=true"
-	_, err = c.getJSONResponse(http.MethodDelete, uri, nil, nil)
+	_, err = c.getJSONResponse(ctx, http.MethodDelete, uri, nil, nil)
 	if err != nil {
-		if !strings.Contains(err.Error(), "HOST_NOT_FOUND") && !strings.Contains(err.Error(), "VOLUME_NOT_FOUND") && !strings.Contains(err.Error(), "LUN_NOT_FOUND") {
+		if !HasErrorCode(err, ErrHostNotFound, ErrVolumeNotFound, ErrLunNotFound) {
 			klog.Errorf("failed to unmap volume %d from host %d with error %v", volumeID, hostID, err)
 		}
 		return err
@@ -585,7 +714,7 @@ func (c *ClientService) UnMapVolumeFromHost(hostID, volumeID int) (err error) {
 }
 
 // MapVolumeToHost - Map volume with given volumeID to Host with given hostID
-func (c *ClientService) MapVolumeToHost(hostID, volumeID, lun int) (luninfo LunInfo, err error) {
+func (c *ClientService) MapVolumeToHost(ctx context.Context, hostID, volumeID, lun int) (luninfo LunInfo, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("MapVolumeToHost Panic occured -  " + fmt.Sprint(res))
@@ -598,10 +727,10 @@ func (c *ClientService) MapVolumeToHost(hostID, volumeID, lun int) (luninfo LunI
 	if lun != -1 {
 		data["lun"] = lun
 	}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, data, &luninfo)
+	resp, err := c.getJSONResponse(ctx, http.MethodPost, uri, data, &luninfo)
 	if err != nil {
 		// ignore logging for following error code
-		if !strings.Contains(err.Error(), "MAPPING_ALREADY_EXISTS") {
+		if !HasErrorCode(err, ErrMappingAlreadyExists) {
 			klog.Errorf("error occured while mapping volume to host %v", err)
 		}
 		return luninfo, err
@@ -615,7 +744,7 @@ func (c *ClientService) MapVolumeToHost(hostID, volumeID, lun int) (luninfo LunI
 }
 
 // GetLunByHostVolume - Get Lun details for volume and host provided
-func (c *ClientService) GetLunByHostVolume(hostID, volumeID int) (luninfo LunInfo, err error) {
+func (c *ClientService) GetLunByHostVolume(ctx context.Context, hostID, volumeID int) (luninfo LunInfo, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetLunByHostVolume Panic occured -  " + fmt.Sprint(res))
@@ -624,8 +753,8 @@ func (c *ClientService) GetLunByHostVolume(hostID, volumeID int) (luninfo LunInf
 	luns := []LunInfo{}
 	klog.V(2).Infof("get lun for volume %d and host %d", volumeID, hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "/luns"
-	data := map[string]interface{}{"volume_id": volumeID}
-	resp, err := c.getResponseWithQueryString(uri, data, &luns)
+	query := NewQuery().Eq("volume_id", volumeID)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &luns)
 	if err != nil {
 		klog.Errorf("error occured while get luns for volumeID %d and host %d err %v", volumeID, hostID, err)
 		return luninfo, err
@@ -642,7 +771,7 @@ func (c *ClientService) GetLunByHostVolume(hostID, volumeID int) (luninfo LunInf
 }
 
 // GetAllLunByHost - Get all luns for host id provided
-func (c *ClientService) GetAllLunByHost(hostID int) (luninfo []LunInfo, err error) {
+func (c *ClientService) GetAllLunByHost(ctx context.Context, hostID int) (luninfo []LunInfo, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetLunByHostVolume Panic occured -  " + fmt.Sprint(res))
@@ -650,21 +779,35 @@ func (c *ClientService) GetAllLunByHost(hostID int) (luninfo []LunInfo, err erro
 	}()
 	klog.V(2).Infof("Get all lun for host %d", hostID)
 	uri := "api/rest/hosts/" + strconv.Itoa(hostID) + "/luns"
-	resp, err := c.getResponseWithQueryString(uri, nil, &luninfo)
+	err = c.getAllPages(ctx, uri, nil, &luninfo)
 	if err != nil {
 		klog.Errorf("failed to get luns for host %d with error %v", hostID, err)
 		return luninfo, err
 	}
-	if len(luninfo) == 0 {
-		apiresp := resp.(client.ApiResponse)
-		luninfo, _ = apiresp.Result.([]LunInfo)
-	}
 	klog.V(2).Infof("got %d Luns for host %d", len(luninfo), hostID)
 	return luninfo, nil
 }
 
+// GetLunsByVolume - Get the luns mapping volume id provided to hosts
+func (c *ClientService) GetLunsByVolume(ctx context.Context, volumeID int) (luninfo []LunInfo, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetLunsByVolume Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get all lun for volume %d", volumeID)
+	uri := "api/rest/volumes/" + strconv.Itoa(volumeID) + "/luns"
+	err = c.getAllPages(ctx, uri, nil, &luninfo)
+	if err != nil {
+		klog.Errorf("failed to get luns for volume %d with error %v", volumeID, err)
+		return luninfo, err
+	}
+	klog.V(2).Infof("got %d Luns for volume %d", len(luninfo), volumeID)
+	return luninfo, nil
+}
+
 // GetVolumeSnapshotByParentID method return true is the filesystemID has child else false
-func (c *ClientService) GetVolumeSnapshotByParentID(volumeID int) (*[]Volume, error) {
+func (c *ClientService) GetVolumeSnapshotByParentID(ctx context.Context, volumeID int) (*[]Volume, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -673,22 +816,61 @@ func (c *ClientService) GetVolumeSnapshotByParentID(volumeID int) (*[]Volume, er
 	}()
 	voluri := "/api/rest/volumes/"
 	volumes := []Volume{}
-	queryParam := make(map[string]interface{})
-	queryParam["parent_id"] = volumeID
-	resp, err := c.getResponseWithQueryString(voluri, queryParam, &volumes)
+	query := NewQuery().Eq("parent_id", volumeID)
+	err = c.getAllPages(ctx, voluri, query, &volumes)
 	if err != nil {
 		klog.Errorf("failed to check GetVolumeSnapshotByParentID %v", err)
 		return &volumes, err
 	}
-	if len(volumes) == 0 {
-		apiresp := resp.(client.ApiResponse)
-		volumes, _ = apiresp.Result.([]Volume)
-	}
 	return &volumes, err
 }
 
+// GetVolumesByIDs returns the volumes with the given IDs, a volume which does not exist is left out
+func (c *ClientService) GetVolumesByIDs(ctx context.Context, volumeIDs []int) (volumes []Volume, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetVolumesByIDs Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	ids := make([]interface{}, 0, len(volumeIDs))
+	for _, id := range volumeIDs {
+		ids = append(ids, id)
+	}
+	volumes = []Volume{}
+	for _, query := range inQueries("id", ids) {
+		if err = c.getAllPages(ctx, "/api/rest/volumes", query, &volumes); err != nil {
+			klog.Errorf("failed to get volumes by id: %v", err)
+			return nil, err
+		}
+	}
+	klog.V(4).Infof("got %d of %d volumes by id", len(volumes), len(volumeIDs))
+	return volumes, nil
+}
+
+// GetVolumesByParentIDs returns the child volumes, snapshots and clones, of the given volumes
+func (c *ClientService) GetVolumesByParentIDs(ctx context.Context, parentIDs []int) (volumes []Volume, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetVolumesByParentIDs Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	ids := make([]interface{}, 0, len(parentIDs))
+	for _, id := range parentIDs {
+		ids = append(ids, id)
+	}
+	volumes = []Volume{}
+	for _, query := range inQueries("parent_id", ids) {
+		if err = c.getAllPages(ctx, "/api/rest/volumes", query, &volumes); err != nil {
+			klog.Errorf("failed to get children of volumes: %v", err)
+			return nil, err
+		}
+	}
+	klog.V(4).Infof("got %d children of %d volumes", len(volumes), len(parentIDs))
+	return volumes, nil
+}
+
 // UpdateVolume : update volume
-func (c *ClientService) UpdateVolume(volumeID int, volume Volume) (*Volume, error) {
+func (c *ClientService) UpdateVolume(ctx context.Context, volumeID int, volume Volume) (*Volume, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -699,7 +881,7 @@ func (c *ClientService) UpdateVolume(volumeID int, volume Volume) (*Volume, erro
 	uri := "api/rest/volumes/" + strconv.Itoa(volumeID)
 	volumeResp := Volume{}
 
-	resp, err := c.getJSONResponse(http.MethodPut, uri, volume, &volumeResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodPut, uri, volume, &volumeResp)
 	if err != nil {
 		klog.Errorf("Error occured while updating volume : %s", err)
 		return nil, err
@@ -717,7 +899,7 @@ func (c *ClientService) UpdateVolume(volumeID int, volume Volume) (*Volume, erro
 //                                   generic methods to do reset called
 //                                   consume by other method intent to do rese calls
 // **************************************************Util Methods*********************************************
-func (c *ClientService) getJSONResponse(method, apiuri string, body, expectedResp interface{}) (resp interface{}, err error) {
+func (c *ClientService) getJSONResponse(ctx context.Context, method, apiuri string, body, expectedResp interface{}) (resp interface{}, err error) {
 	klog.V(2).Infof("Request made for method: %s and apiuri %s", method, apiuri)
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -731,13 +913,13 @@ func (c *ClientService) getJSONResponse(method, apiuri string, body, expectedRes
 		return nil, err
 	}
 	if method == http.MethodPost {
-		resp, err = c.api.Post(context.Background(), apiuri, hostsecret, body, expectedResp)
+		resp, err = c.api.Post(ctx, apiuri, hostsecret, body, expectedResp)
 	} else if method == http.MethodGet {
-		resp, err = c.api.Get(context.Background(), apiuri, hostsecret, expectedResp)
+		resp, err = c.api.Get(ctx, apiuri, hostsecret, expectedResp)
 	} else if method == http.MethodDelete {
-		resp, err = c.api.Delete(context.Background(), apiuri, hostsecret)
+		resp, err = c.api.Delete(ctx, apiuri, hostsecret)
 	} else if method == http.MethodPut {
-		resp, err = c.api.Put(context.Background(), apiuri, hostsecret, body, expectedResp)
+		resp, err = c.api.Put(ctx, apiuri, hostsecret, body, expectedResp)
 	}
 	if err != nil {
 		klog.Errorf("An API JSON response error occured, URL: %s, error: %+v ", apiuri, err)
@@ -746,7 +928,38 @@ func (c *ClientService) getJSONResponse(method, apiuri string, body, expectedRes
 	return
 }
 
-func (c *ClientService) getResponseWithQueryString(apiuri string, queryParam map[string]interface{}, expectedResp interface{}) (resp interface{}, err error) {
+// postWithCreationCheck posts like getJSONResponse, but allows the rest client to repeat the
+// post after a connection error, using check to find an object created by the lost request
+func (c *ClientService) postWithCreationCheck(ctx context.Context, apiuri string, body, expectedResp interface{}, check client.CreationCheck) (resp interface{}, err error) {
+	klog.V(2).Infof("Request made for method: %s and apiuri %s", http.MethodPost, apiuri)
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			klog.Errorf("Error in postWithCreationCheck while making request on %s url error : %v ", apiuri, err)
+			err = errors.New("error in postWithCreationCheck " + fmt.Sprint(res))
+		}
+	}()
+	hostsecret, err := c.getAPIConfig()
+	if err != nil {
+		klog.Errorf("Error occured: %v ", err)
+		return nil, err
+	}
+	resp, err = c.api.Post(client.WithCreationCheck(ctx, check), apiuri, hostsecret, body, expectedResp)
+	if err != nil {
+		klog.Errorf("An API JSON response error occured, URL: %s, error: %+v ", apiuri, err)
+	}
+	return
+}
+
+// creationNotFound maps the error of a lookup by name to a creation check result: not found
+// means the object was not created, any other error leaves the outcome unknown
+func creationNotFound(err error) (bool, interface{}, error) {
+	if IsNotFound(err) {
+		return false, nil, nil
+	}
+	return false, nil, err
+}
+
+func (c *ClientService) getResponseWithQueryString(ctx context.Context, apiuri string, query *Query, expectedResp interface{}) (resp interface{}, err error) {
 	klog.V(2).Infof("Request made for apiuri %s", apiuri)
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -760,15 +973,9 @@ func (c *ClientService) getResponseWithQueryString(apiuri string, queryParam map
 		return nil, err
 	}
 
-	var queryString string
-	for key, val := range queryParam {
-		if queryString != "" {
-			queryString += ","
-		}
-		queryString += key + "=" + fmt.Sprintf("%v", val)
-	}
-	klog.V(2).Infof("apiuri %s hostsecret %s queryString %s expectedResp %s\n", apiuri, hostsecret, queryString, expectedResp)
-	resp, err = c.api.GetWithQueryString(context.Background(), apiuri, hostsecret, queryString, expectedResp)
+	queryString := query.Encode()
+	klog.V(2).Infof("apiuri %s queryString %s\n", apiuri, queryString)
+	resp, err = c.api.GetWithQueryString(ctx, apiuri, hostsecret, queryString, expectedResp)
 	return resp, err
 }
 
@@ -792,7 +999,62 @@ func (c *ClientService) getAPIConfig() (hostconfig client.HostConfig, err error)
 		klog.V(2).Infof("setting url to %s", hostconfig.ApiHost)
 		hostconfig.UserName = c.SecretsMap["username"]
 		hostconfig.Password = c.SecretsMap["password"]
+		if err := setRetryConfig(&hostconfig, c.SecretsMap); err != nil {
+			return hostconfig, err
+		}
+		if err := setTLSConfig(&hostconfig, c.SecretsMap); err != nil {
+			return hostconfig, err
+		}
 		return hostconfig, nil
 	}
 	return hostconfig, errors.New("host configuration is not valid")
 }
+
+// setRetryConfig applies the optional retry_count, retry_wait_ms and retry_max_wait_ms secret keys
+func setRetryConfig(hostconfig *client.HostConfig, secrets map[string]string) error {
+	hostconfig.RetryCount = client.DefaultRetryCount
+	hostconfig.RetryWaitTime = client.DefaultRetryWaitTime
+	hostconfig.RetryMaxWaitTime = client.DefaultRetryMaxWaitTime
+	if val, ok := secrets["retry_count"]; ok && val != "" {
+		count, err := strconv.Atoi(val)
+		if err != nil || count < 0 {
+			return errors.New("retry_count in secret must be a non-negative integer: " + val)
+		}
+		hostconfig.RetryCount = count
+	}
+	if val, ok := secrets["retry_wait_ms"]; ok && val != "" {
+		ms, err := strconv.Atoi(val)
+		if err != nil || ms <= 0 {
+			return errors.New("retry_wait_ms in secret must be a positive integer: " + val)
+		}
+		hostconfig.RetryWaitTime = time.Duration(ms) * time.Millisecond
+	}
+	if val, ok := secrets["retry_max_wait_ms"]; ok && val != "" {
+		ms, err := strconv.Atoi(val)
+		if err != nil || ms <= 0 {
+			return errors.New("retry_max_wait_ms in secret must be a positive integer: " + val)
+		}
+		hostconfig.RetryMaxWaitTime = time.Duration(ms) * time.Millisecond
+	}
+	return nil
+}
+
+// setTLSConfig applies the optional ca.crt, ca_file, insecure_skip_verify and cert_fingerprints
+// secret keys. TLS verification is on unless insecure_skip_verify is set to true.
+func setTLSConfig(hostconfig *client.HostConfig, secrets map[string]string) error {
+	hostconfig.TLS.CACertificate = secrets["ca.crt"]
+	hostconfig.TLS.CAFile = secrets["ca_file"]
+	if val, ok := secrets["insecure_skip_verify"]; ok && val != "" {
+		insecure, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.New("insecure_skip_verify in secret must be true or false: " + val)
+		}
+		hostconfig.TLS.InsecureSkipVerify = insecure
+	}
+	for _, fingerprint := range strings.Split(secrets["cert_fingerprints"], ",") {
+		if fingerprint = strings.TrimSpace(fingerprint); fingerprint != "" {
+			hostconfig.TLS.PinnedFingerprints = append(hostconfig.TLS.PinnedFingerprints, fingerprint)
+		}
+	}
+	return nil
+}
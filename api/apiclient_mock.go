@@ -69,7 +69,7 @@ func (m *MockApiClient) GetWithQueryString(ctx context.Context, url string, host
 }
 
 // GetStoragePoolIDByName mock
-func (m *MockApiService) GetStoragePoolIDByName(poolName string) (int64, error) {
+func (m *MockApiService) GetStoragePoolIDByName(ctx context.Context, poolName string) (int64, error) {
 	args := m.Called(poolName)
 	resp, _ := args.Get(0).(int64)
 	err, _ := args.Get(1).(error)
@@ -77,15 +77,15 @@ func (m *MockApiService) GetStoragePoolIDByName(poolName string) (int64, error)
 }
 
 // GetFileSystemsByPoolID mock
-func (m *MockApiService) GetFileSystemsByPoolID(poolID int64, page int) (*FSMetadata, error) {
-	args := m.Called(poolID, page)
-	resp, _ := args.Get(0).(FSMetadata)
+func (m *MockApiService) GetFileSystemsByPoolID(ctx context.Context, poolID int64) ([]FileSystem, error) {
+	args := m.Called(poolID)
+	resp, _ := args.Get(0).([]FileSystem)
 	err, _ := args.Get(1).(error)
-	return &resp, err
+	return resp, err
 }
 
 // GetFilesytemTreeqCount mock
-func (m *MockApiService) GetFilesytemTreeqCount(filesystemID int64) (int, error) {
+func (m *MockApiService) GetFilesytemTreeqCount(ctx context.Context, filesystemID int64) (int, error) {
 	args := m.Called(filesystemID)
 	resp, _ := args.Get(0).(int)
 	err, _ := args.Get(1).(error)
@@ -93,15 +93,22 @@ func (m *MockApiService) GetFilesytemTreeqCount(filesystemID int64) (int, error)
 }
 
 // CreateTreeq mock
-func (m *MockApiService) CreateTreeq(filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error) {
+func (m *MockApiService) CreateTreeq(ctx context.Context, filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error) {
 	args := m.Called(filesystemID, treeqParameter)
 	resp, _ := args.Get(0).(Treeq)
 	err, _ := args.Get(1).(error)
 	return &resp, err
 }
 
+// DetachMetadataKeyFromObject mock
+func (m *MockApiService) DetachMetadataKeyFromObject(ctx context.Context, objectID int64, key string) error {
+	args := m.Called(objectID, key)
+	err, _ := args.Get(0).(error)
+	return err
+}
+
 // AttachMetadataToObject mock
-func (m *MockApiService) AttachMetadataToObject(objectID int64, body map[string]interface{}) (*[]Metadata, error) {
+func (m *MockApiService) AttachMetadataToObject(ctx context.Context, objectID int64, body map[string]interface{}) (*[]Metadata, error) {
 	args := m.Called(objectID, body)
 	resp, _ := args.Get(0).([]Metadata)
 	err, _ := args.Get(1).(error)
@@ -109,7 +116,7 @@ func (m *MockApiService) AttachMetadataToObject(objectID int64, body map[string]
 }
 
 // UpdateFilesystem
-func (m *MockApiService) UpdateFilesystem(fileSystemID int64, fileSystem FileSystem) (*FileSystem, error) {
+func (m *MockApiService) UpdateFilesystem(ctx context.Context, fileSystemID int64, fileSystem FileSystem) (*FileSystem, error) {
 	args := m.Called(fileSystemID, fileSystem)
 	var filessy FileSystem
 	if args.Get(0) != nil {
@@ -124,7 +131,7 @@ func (m *MockApiService) UpdateFilesystem(fileSystemID int64, fileSystem FileSys
 }
 
 // GetExportByFileSystem
-func (m *MockApiService) GetExportByFileSystem(fileSystemID int64) (*[]ExportResponse, error) {
+func (m *MockApiService) GetExportByFileSystem(ctx context.Context, fileSystemID int64) (*[]ExportResponse, error) {
 	args := m.Called(fileSystemID)
 	resp, _ := args.Get(0).([]ExportResponse)
 	err, _ := args.Get(1).(error)
@@ -132,7 +139,7 @@ func (m *MockApiService) GetExportByFileSystem(fileSystemID int64) (*[]ExportRes
 }
 
 // GetTreeq
-func (m *MockApiService) GetTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
+func (m *MockApiService) GetTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error) {
 	args := m.Called(fileSystemID, treeqID)
 	resp, _ := args.Get(0).(Treeq)
 	err, _ := args.Get(1).(error)
@@ -140,7 +147,7 @@ func (m *MockApiService) GetTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
 }
 
 // DeleteTreeq
-func (m *MockApiService) DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
+func (m *MockApiService) DeleteTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error) {
 	args := m.Called(fileSystemID, treeqID)
 	resp, _ := args.Get(0).(Treeq)
 	err, _ := args.Get(1).(error)
@@ -148,15 +155,34 @@ func (m *MockApiService) DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error
 }
 
 // GetNetworkSpaceByName
-func (m *MockApiService) GetNetworkSpaceByName(networkSpaceName string) (NetworkSpace, error) {
+func (m *MockApiService) GetNetworkSpaceByName(ctx context.Context, networkSpaceName string) (NetworkSpace, error) {
 	args := m.Called(networkSpaceName)
 	resp, _ := args.Get(0).(NetworkSpace)
 	err, _ := args.Get(1).(error)
 	return resp, err
 }
 
+// GetFCPorts
+func (m *MockApiService) GetFCPorts(ctx context.Context) ([]FCNode, error) {
+	args := m.Called()
+	resp, _ := args.Get(0).([]FCNode)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
+// GetSystem
+func (m *MockApiService) GetSystem(ctx context.Context) (*System, error) {
+	args := m.Called()
+	resp, ok := args.Get(0).(System)
+	err, _ := args.Get(1).(error)
+	if !ok {
+		return nil, err
+	}
+	return &resp, err
+}
+
 // UpdateTreeq
-func (m *MockApiService) UpdateTreeq(fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error) {
+func (m *MockApiService) UpdateTreeq(ctx context.Context, fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error) {
 	args := m.Called(fileSystemID, treeqID, body)
 	resp, _ := args.Get(0).(Treeq)
 	err, _ := args.Get(1).(error)
@@ -164,7 +190,7 @@ func (m *MockApiService) UpdateTreeq(fileSystemID, treeqID int64, body map[strin
 }
 
 // GetFileSystemByID
-func (m *MockApiService) GetFileSystemByID(fileSystemID int64) (*FileSystem, error) {
+func (m *MockApiService) GetFileSystemByID(ctx context.Context, fileSystemID int64) (*FileSystem, error) {
 	args := m.Called(fileSystemID)
 	resp, _ := args.Get(0).(FileSystem)
 	err, _ := args.Get(1).(error)
@@ -172,21 +198,32 @@ func (m *MockApiService) GetFileSystemByID(fileSystemID int64) (*FileSystem, err
 }
 
 // GetTreeqSizeByFileSystemID
-func (m *MockApiService) GetTreeqSizeByFileSystemID(fileSystemID int64) (int64, error) {
+func (m *MockApiService) GetTreeqSizeByFileSystemID(ctx context.Context, fileSystemID int64) (int64, error) {
 	args := m.Called(fileSystemID)
 	resp, _ := args.Get(0).(int64)
 	err, _ := args.Get(1).(error)
 	return resp, err
 }
 
+// GetFileSystemByPVName
+func (m *MockApiService) GetFileSystemByPVName(ctx context.Context, pvName string) (*FileSystem, error) {
+	args := m.Called(pvName)
+	err, _ := args.Get(1).(error)
+	fsystem, ok := args.Get(0).(FileSystem)
+	if !ok {
+		return nil, err
+	}
+	return &fsystem, err
+}
+
 // GetFileSystemByName
-func (m *MockApiService) GetFileSystemByName(fileSystemName string) (*FileSystem, error) {
+func (m *MockApiService) GetFileSystemByName(ctx context.Context, fileSystemName string) (*FileSystem, error) {
 	args := m.Called(fileSystemName)
 	resp, _ := args.Get(0).(FileSystem)
+	err, _ := args.Get(1).(error)
 	if args.Get(0) == nil {
-		return nil, nil
+		return nil, err
 	}
-	err, _ := args.Get(1).(error)
 	return &resp, err
 }
 
@@ -199,7 +236,7 @@ func (m *MockApiService) GetFileSystemCount() (int, error) {
 }
 
 // OneTimeValidation
-func (m *MockApiService) OneTimeValidation(poolname string, networkspace string) (string, error) {
+func (m *MockApiService) OneTimeValidation(ctx context.Context, poolname string, networkspace string) (string, error) {
 	args := m.Called(poolname, networkspace)
 	resp, _ := args.Get(0).(string)
 	err, _ := args.Get(1).(error)
@@ -207,7 +244,7 @@ func (m *MockApiService) OneTimeValidation(poolname string, networkspace string)
 }
 
 // CreateFilesystem
-func (m *MockApiService) CreateFilesystem(fileSysparameter map[string]interface{}) (*FileSystem, error) {
+func (m *MockApiService) CreateFilesystem(ctx context.Context, fileSysparameter map[string]interface{}) (*FileSystem, error) {
 	args := m.Called(fileSysparameter)
 	var resp FileSystem
 	if args.Get(0) != nil {
@@ -221,7 +258,7 @@ func (m *MockApiService) CreateFilesystem(fileSysparameter map[string]interface{
 }
 
 // ExportFileSystem
-func (m *MockApiService) ExportFileSystem(export ExportFileSys) (*ExportResponse, error) {
+func (m *MockApiService) ExportFileSystem(ctx context.Context, export ExportFileSys) (*ExportResponse, error) {
 	argsArray := m.Called(export)
 	args := argsArray[0]
 	var resp ExportResponse
@@ -236,7 +273,7 @@ func (m *MockApiService) ExportFileSystem(export ExportFileSys) (*ExportResponse
 }
 
 // CreateFileSystemSnapshot
-func (m *MockApiService) CreateFileSystemSnapshot(snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error) {
+func (m *MockApiService) CreateFileSystemSnapshot(ctx context.Context, snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error) {
 	args := m.Called(snapshotParam)
 	resp, _ := args.Get(0).(FileSystemSnapshotResponce)
 	err, _ := args.Get(1).(error)
@@ -244,35 +281,35 @@ func (m *MockApiService) CreateFileSystemSnapshot(snapshotParam *FileSystemSnaps
 }
 
 // FileSystemHasChild
-func (m *MockApiService) FileSystemHasChild(fileSystemID int64) bool {
+func (m *MockApiService) FileSystemHasChild(ctx context.Context, fileSystemID int64) bool {
 	args := m.Called(fileSystemID)
 	err, _ := args.Get(0).(bool)
 	return err
 }
 
 // GetParentID
-func (m *MockApiService) GetParentID(fileSystemID int64) int64 {
+func (m *MockApiService) GetParentID(ctx context.Context, fileSystemID int64) int64 {
 	args := m.Called(fileSystemID)
 	resp, _ := args.Get(0).(int64)
 	return resp
 }
 
 // DeleteFileSystemComplete
-func (m *MockApiService) DeleteFileSystemComplete(fileSystemID int64) (err error) {
+func (m *MockApiService) DeleteFileSystemComplete(ctx context.Context, fileSystemID int64) (err error) {
 	args := m.Called(fileSystemID)
 	err, _ = args.Get(0).(error)
 	return err
 }
 
 // DeleteParentFileSystem
-func (m *MockApiService) DeleteParentFileSystem(fileSystemID int64) (err error) {
+func (m *MockApiService) DeleteParentFileSystem(ctx context.Context, fileSystemID int64) (err error) {
 	args := m.Called(fileSystemID)
 	err, _ = args.Get(0).(error)
 	return err
 }
 
 // GetVolume
-func (m *MockApiService) GetVolume(volumeid int) (*Volume, error) {
+func (m *MockApiService) GetVolume(ctx context.Context, volumeid int) (*Volume, error) {
 	args := m.Called(volumeid)
 	resp, _ := args.Get(0).(Volume)
 	err, _ := args.Get(1).(error)
@@ -280,29 +317,76 @@ func (m *MockApiService) GetVolume(volumeid int) (*Volume, error) {
 }
 
 // GetVolumeSnapshotByParentID
-func (m *MockApiService) GetVolumeSnapshotByParentID(volumeID int) (*[]Volume, error) {
+func (m *MockApiService) GetVolumeSnapshotByParentID(ctx context.Context, volumeID int) (*[]Volume, error) {
 	args := m.Called(volumeID)
 	resp, _ := args.Get(0).([]Volume)
 	err, _ := args.Get(1).(error)
 	return &resp, err
 }
 
+// GetVolumesByIDs mock
+func (m *MockApiService) GetVolumesByIDs(ctx context.Context, volumeIDs []int) ([]Volume, error) {
+	args := m.Called(volumeIDs)
+	resp, _ := args.Get(0).([]Volume)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
+// GetVolumesByParentIDs mock
+func (m *MockApiService) GetVolumesByParentIDs(ctx context.Context, parentIDs []int) ([]Volume, error) {
+	args := m.Called(parentIDs)
+	resp, _ := args.Get(0).([]Volume)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
 // DeleteVolume
-func (m *MockApiService) DeleteVolume(volumeID int) (err error) {
+func (m *MockApiService) DeleteVolume(ctx context.Context, volumeID int) (err error) {
 	args := m.Called(volumeID)
 	err, _ = args.Get(0).(error)
 	return err
 }
 
 // GetMetadataStatus
-func (m *MockApiService) GetMetadataStatus(fileSystemID int64) bool {
+func (m *MockApiService) GetMetadataStatus(ctx context.Context, fileSystemID int64) bool {
 	args := m.Called(fileSystemID)
 	err, _ := args.Get(0).(bool)
 	return err
 }
 
+// GetMetadataValue mock
+func (m *MockApiService) GetMetadataValue(ctx context.Context, objectID int64, key string) (string, error) {
+	args := m.Called(objectID, key)
+	err, _ := args.Get(1).(error)
+	return args.String(0), err
+}
+
+// GetFileSystemsByIDs mock
+func (m *MockApiService) GetFileSystemsByIDs(ctx context.Context, fileSystemIDs []int64) ([]FileSystem, error) {
+	args := m.Called(fileSystemIDs)
+	resp, _ := args.Get(0).([]FileSystem)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
+// GetFileSystemsByParentIDs mock
+func (m *MockApiService) GetFileSystemsByParentIDs(ctx context.Context, parentIDs []int64) ([]FileSystem, error) {
+	args := m.Called(parentIDs)
+	resp, _ := args.Get(0).([]FileSystem)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
+// GetMetadataByKey mock
+func (m *MockApiService) GetMetadataByKey(ctx context.Context, key string) ([]Metadata, error) {
+	args := m.Called(key)
+	resp, _ := args.Get(0).([]Metadata)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
 // GetSnapshotByName
-func (m *MockApiService) GetSnapshotByName(snapshotName string) (*[]FileSystemSnapshotResponce, error) {
+func (m *MockApiService) GetSnapshotByName(ctx context.Context, snapshotName string) (*[]FileSystemSnapshotResponce, error) {
 	args := m.Called(snapshotName)
 	resp, _ := args.Get(0).([]FileSystemSnapshotResponce)
 	err, _ := args.Get(1).(error)
@@ -310,7 +394,7 @@ func (m *MockApiService) GetSnapshotByName(snapshotName string) (*[]FileSystemSn
 }
 
 // AddNodeInExport
-func (m *MockApiService) AddNodeInExport(exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
+func (m *MockApiService) AddNodeInExport(ctx context.Context, exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
 	argsArray := m.Called(exportID, access, noRootSquash, ip)
 	args := argsArray[0]
 	var resp ExportResponse
@@ -325,14 +409,14 @@ func (m *MockApiService) AddNodeInExport(exportID int, access string, noRootSqua
 }
 
 // DeleteExportRule
-func (m *MockApiService) DeleteExportRule(fileSystemID int64, ipAddress string) error {
+func (m *MockApiService) DeleteExportRule(ctx context.Context, fileSystemID int64, ipAddress string) error {
 	args := m.Called(fileSystemID, ipAddress)
 	err, _ := args.Get(0).(error)
 	return err
 }
 
 // DeleteExportRule
-func (m *MockApiService) GetFileSystemCountByPoolID(poolID int64) (int, error) {
+func (m *MockApiService) GetFileSystemCountByPoolID(ctx context.Context, poolID int64) (int, error) {
 	args := m.Called(poolID)
 	cnt, _ := args.Get(0).(int)
 	err, _ := args.Get(1).(error)
@@ -340,26 +424,45 @@ func (m *MockApiService) GetFileSystemCountByPoolID(poolID int64) (int, error) {
 }
 
 // GetTreeqByName
-func (m *MockApiService) GetTreeqByName(fileSystemID int64, treeqName string) (*Treeq, error) {
+func (m *MockApiService) GetTreeqByName(ctx context.Context, fileSystemID int64, treeqName string) (*Treeq, error) {
 	args := m.Called(fileSystemID, treeqName)
 	trq, _ := args.Get(0).(Treeq)
 	err, _ := args.Get(1).(error)
 	return &trq, err
 }
 
+// GetTreeqsByFileSystemID mock
+func (m *MockApiService) GetTreeqsByFileSystemID(ctx context.Context, fileSystemID int64) ([]Treeq, error) {
+	args := m.Called(fileSystemID)
+	resp, _ := args.Get(0).([]Treeq)
+	err, _ := args.Get(1).(error)
+	return resp, err
+}
+
+// GetVolumeByPVName
+func (m *MockApiService) GetVolumeByPVName(ctx context.Context, pvName string) (*Volume, error) {
+	args := m.Called(pvName)
+	err, _ := args.Get(1).(error)
+	vol, ok := args.Get(0).(Volume)
+	if !ok {
+		return nil, err
+	}
+	return &vol, err
+}
+
 // GetVolumeByName
-func (m *MockApiService) GetVolumeByName(volumename string) (*Volume, error) {
+func (m *MockApiService) GetVolumeByName(ctx context.Context, volumename string) (*Volume, error) {
 	args := m.Called(volumename)
 	vol, _ := args.Get(0).(Volume)
+	err, _ := args.Get(1).(error)
 	if args.Get(0) == nil {
-		return nil, nil
+		return nil, err
 	}
-	err, _ := args.Get(1).(error)
 	return &vol, err
 }
 
 // CreateVolume
-func (m *MockApiService) CreateVolume(volume *VolumeParam, storagePoolName string) (*Volume, error) {
+func (m *MockApiService) CreateVolume(ctx context.Context, volume *VolumeParam, storagePoolName string) (*Volume, error) {
 	args := m.Called(volume, storagePoolName)
 	var vol Volume
 	if args.Get(0) != nil {
@@ -370,7 +473,7 @@ func (m *MockApiService) CreateVolume(volume *VolumeParam, storagePoolName strin
 }
 
 // FindStoragePool
-func (m *MockApiService) FindStoragePool(id int64, name string) (StoragePool, error) {
+func (m *MockApiService) FindStoragePool(ctx context.Context, id int64, name string) (StoragePool, error) {
 	args := m.Called(id, name)
 	var storage StoragePool
 	if args.Get(0) != nil {
@@ -381,15 +484,23 @@ func (m *MockApiService) FindStoragePool(id int64, name string) (StoragePool, er
 }
 
 // GetStoragePool
-func (m *MockApiService) GetStoragePool(poolID int64, storagepoolname string) ([]StoragePool, error) {
+func (m *MockApiService) GetStoragePool(ctx context.Context, poolID int64, storagepoolname string) ([]StoragePool, error) {
 	args := m.Called(poolID, storagepoolname)
 	storageArry, _ := args.Get(0).([]StoragePool)
 	err, _ := args.Get(1).(error)
 	return storageArry, err
 }
 
+// GetStoragePoolByName
+func (m *MockApiService) GetStoragePoolByName(ctx context.Context, name string) (*StoragePool, error) {
+	args := m.Called(name)
+	resp, _ := args.Get(0).(StoragePool)
+	err, _ := args.Get(1).(error)
+	return &resp, err
+}
+
 // CreateSnapshotVolume
-func (m *MockApiService) CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error) {
+func (m *MockApiService) CreateSnapshotVolume(ctx context.Context, snapshotParam *VolumeSnapshot) (*SnapshotVolumesResp, error) {
 	args := m.Called(snapshotParam)
 	snapshotVolumesResp, _ := args.Get(0).(SnapshotVolumesResp)
 	err, _ := args.Get(1).(error)
@@ -397,23 +508,39 @@ func (m *MockApiService) CreateSnapshotVolume(snapshotParam *VolumeSnapshot) (*S
 }
 
 // GetHostByName
-func (m *MockApiService) GetHostByName(hostName string) (Host, error) {
+func (m *MockApiService) GetHostByName(ctx context.Context, hostName string) (Host, error) {
 	args := m.Called(hostName)
 	host, _ := args.Get(0).(Host)
 	err, _ := args.Get(1).(error)
 	return host, err
 }
 
+// GetAllHosts
+func (m *MockApiService) GetAllHosts(ctx context.Context) ([]Host, error) {
+	args := m.Called()
+	hosts, _ := args.Get(0).([]Host)
+	err, _ := args.Get(1).(error)
+	return hosts, err
+}
+
 // GetAllLunByHost
-func (m *MockApiService) GetAllLunByHost(hostID int) ([]LunInfo, error) {
+func (m *MockApiService) GetAllLunByHost(ctx context.Context, hostID int) ([]LunInfo, error) {
 	args := m.Called(hostID)
 	lunInfo, _ := args.Get(0).([]LunInfo)
 	err, _ := args.Get(1).(error)
 	return lunInfo, err
 }
 
+// GetLunsByVolume
+func (m *MockApiService) GetLunsByVolume(ctx context.Context, volumeID int) ([]LunInfo, error) {
+	args := m.Called(volumeID)
+	lunInfo, _ := args.Get(0).([]LunInfo)
+	err, _ := args.Get(1).(error)
+	return lunInfo, err
+}
+
 // MapVolumeToHost
-func (m *MockApiService) MapVolumeToHost(hostID, volumeID, lun int) (LunInfo, error) {
+func (m *MockApiService) MapVolumeToHost(ctx context.Context, hostID, volumeID, lun int) (LunInfo, error) {
 	args := m.Called(hostID)
 	lunInfo, _ := args.Get(0).(LunInfo)
 	err, _ := args.Get(1).(error)
@@ -421,7 +548,7 @@ func (m *MockApiService) MapVolumeToHost(hostID, volumeID, lun int) (LunInfo, er
 }
 
 // GetLunByHostVolume
-func (m *MockApiService) GetLunByHostVolume(hostID, volumeID int) (LunInfo, error) {
+func (m *MockApiService) GetLunByHostVolume(ctx context.Context, hostID, volumeID int) (LunInfo, error) {
 	args := m.Called(hostID)
 	lunInfo, _ := args.Get(0).(LunInfo)
 	err, _ := args.Get(1).(error)
@@ -429,23 +556,117 @@ func (m *MockApiService) GetLunByHostVolume(hostID, volumeID int) (LunInfo, erro
 }
 
 // UnMapVolumeFromHost
-func (m *MockApiService) UnMapVolumeFromHost(hostID, volumeID int) error {
+func (m *MockApiService) UnMapVolumeFromHost(ctx context.Context, hostID, volumeID int) error {
 	args := m.Called(hostID, volumeID)
 	err, _ := args.Get(0).(error)
 	return err
 }
 
 // DeleteHost
-func (m *MockApiService) DeleteHost(hostID int) error {
+func (m *MockApiService) DeleteHost(ctx context.Context, hostID int) error {
 	args := m.Called(hostID)
 	err, _ := args.Get(0).(error)
 	return err
 }
 
 // UpdateVolume
-func (m *MockApiService) UpdateVolume(volumeID int, volume Volume) (*Volume, error) {
+func (m *MockApiService) UpdateVolume(ctx context.Context, volumeID int, volume Volume) (*Volume, error) {
 	args := m.Called(volumeID, volume)
 	vol, _ := args.Get(0).(Volume)
 	err, _ := args.Get(1).(error)
 	return &vol, err
 }
+
+// GetQosPolicyByName mock
+func (m *MockApiService) GetQosPolicyByName(ctx context.Context, name string) (*QosPolicy, error) {
+	args := m.Called(name)
+	err, _ := args.Get(1).(error)
+	policy, ok := args.Get(0).(QosPolicy)
+	if !ok {
+		return nil, err
+	}
+	return &policy, err
+}
+
+// CreateQosPolicy mock
+func (m *MockApiService) CreateQosPolicy(ctx context.Context, policy QosPolicy) (*QosPolicy, error) {
+	args := m.Called(policy)
+	err, _ := args.Get(1).(error)
+	created, ok := args.Get(0).(QosPolicy)
+	if !ok {
+		return nil, err
+	}
+	return &created, err
+}
+
+// AssignQosPolicy mock
+func (m *MockApiService) AssignQosPolicy(ctx context.Context, policyID, entityID int64) error {
+	args := m.Called(policyID, entityID)
+	err, _ := args.Get(0).(error)
+	return err
+}
+
+// UnassignQosPolicy mock
+func (m *MockApiService) UnassignQosPolicy(ctx context.Context, policyID, entityID int64) error {
+	args := m.Called(policyID, entityID)
+	err, _ := args.Get(0).(error)
+	return err
+}
+
+// GetLinkByRemoteSystem mock
+func (m *MockApiService) GetLinkByRemoteSystem(ctx context.Context, remoteSystemName string) (*Link, error) {
+	args := m.Called(remoteSystemName)
+	err, _ := args.Get(1).(error)
+	link, ok := args.Get(0).(Link)
+	if !ok {
+		return nil, err
+	}
+	return &link, err
+}
+
+// GetReplicaByEntity mock
+func (m *MockApiService) GetReplicaByEntity(ctx context.Context, entityID int64) (*Replica, error) {
+	args := m.Called(entityID)
+	err, _ := args.Get(1).(error)
+	replica, ok := args.Get(0).(Replica)
+	if !ok {
+		return nil, err
+	}
+	return &replica, err
+}
+
+// CreateReplica mock
+func (m *MockApiService) CreateReplica(ctx context.Context, replica Replica) (*Replica, error) {
+	args := m.Called(replica)
+	err, _ := args.Get(1).(error)
+	created, ok := args.Get(0).(Replica)
+	if !ok {
+		return nil, err
+	}
+	return &created, err
+}
+
+// DeleteReplica mock
+func (m *MockApiService) DeleteReplica(ctx context.Context, replicaID int64) error {
+	args := m.Called(replicaID)
+	err, _ := args.Get(0).(error)
+	return err
+}
+
+// SuspendReplica mock
+func (m *MockApiService) SuspendReplica(ctx context.Context, replicaID int64) error {
+	args := m.Called(replicaID)
+	err, _ := args.Get(0).(error)
+	return err
+}
+
+// ChangeReplicaRole mock
+func (m *MockApiService) ChangeReplicaRole(ctx context.Context, replicaID int64) (*Replica, error) {
+	args := m.Called(replicaID)
+	err, _ := args.Get(1).(error)
+	replica, ok := args.Get(0).(Replica)
+	if !ok {
+		return nil, err
+	}
+	return &replica, err
+}
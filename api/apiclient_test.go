@@ -11,14 +11,21 @@ limitations under the License.*/
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"infinibox-csi-driver/api/client"
 	tests "infinibox-csi-driver/test_helper"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (suite *ApiTestSuite) SetupTest() {
@@ -45,7 +52,7 @@ func (suite *ApiTestSuite) Test_CreateVolumeGetStoragePoolIDByName_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	volume := VolumeParam{Name: "test_volume"}
-	_, err := service.CreateVolume(&volume, "test_storage_pool")
+	_, err := service.CreateVolume(context.Background(), &volume, "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -68,7 +75,7 @@ func (suite *ApiTestSuite) Test_CreateVolume_Fail() {
 		PoolId:     1000,
 		VolumeSize: 1000000000,
 	}
-	_, err := service.CreateVolume(&volume, "test_storage_pool")
+	_, err := service.CreateVolume(context.Background(), &volume, "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -87,7 +94,7 @@ func (suite *ApiTestSuite) Test_CreateVolume_Success() {
 
 	// Act
 	volumeparam := VolumeParam{Name: "test_volume", PoolId: 5307, VolumeSize: 1000000000, ProvisionType: "THIN"}
-	response, _ := service.CreateVolume(&volumeparam, "test_name")
+	response, _ := service.CreateVolume(context.Background(), &volumeparam, "test_name")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -100,7 +107,7 @@ func (suite *ApiTestSuite) Test_GetStoragePool_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetStoragePool(1001, "test_storage_pool")
+	_, err := service.GetStoragePool(context.Background(), 1001, "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -116,7 +123,7 @@ func (suite *ApiTestSuite) Test_GetStoragePool_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetStoragePool(1001, "test_storage_pool")
+	response, _ := service.GetStoragePool(context.Background(), 1001, "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -129,7 +136,7 @@ func (suite *ApiTestSuite) Test_GetStoragePoolIDByName_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetStoragePoolIDByName("test_storage_pool")
+	_, err := service.GetStoragePoolIDByName(context.Background(), "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -142,20 +149,61 @@ func (suite *ApiTestSuite) Test_GetStoragePoolIDByName_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetStoragePoolIDByName("test_storage_pool")
+	response, _ := service.GetStoragePoolIDByName(context.Background(), "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
 	assert.Equal(suite.T(), poolID, response, "Response not returned as expected")
 }
 
+func (suite *ApiTestSuite) Test_GetStoragePoolByName_Fail() {
+	expectedError := errors.New("failed to get pool from pool Name: test_storage_pool")
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetStoragePoolByName(context.Background(), "test_storage_pool")
+
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetStoragePoolByName_NoSuchPool() {
+	expectedResponse := client.ApiResponse{Result: []StoragePool{}}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetStoragePoolByName(context.Background(), "test_storage_pool")
+
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+	assert.True(suite.T(), HasErrorCode(err, ErrPoolNotFound), "Error not returned as expected")
+	assert.Equal(suite.T(), "POOL_NOT_FOUND No such pool: test_storage_pool", err.Error())
+}
+
+func (suite *ApiTestSuite) Test_GetStoragePoolByName_Success() {
+	storagePools := []StoragePool{{ID: 10, Name: "test_storage_pool", FreePhysicalSpace: 1000}}
+	expectedResponse := client.ApiResponse{Result: storagePools}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetStoragePoolByName(context.Background(), "test_storage_pool")
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), storagePools[0], *response, "Response not returned as expected")
+}
+
 func (suite *ApiTestSuite) Test_GetVolumeByName_Fail() {
 	expectedError := errors.New("Unable to get given volume by name")
 	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetVolumeByName("test_storage_pool")
+	_, err := service.GetVolumeByName(context.Background(), "test_storage_pool")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -171,7 +219,7 @@ func (suite *ApiTestSuite) Test_GetVolumeByName_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetVolumeByName("test1")
+	response, _ := service.GetVolumeByName(context.Background(), "test1")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -185,7 +233,7 @@ func (suite *ApiTestSuite) Test_CreateSnapshotVolume_Fail() {
 
 	// Act
 	snapshotParams := VolumeSnapshot{ParentID: 1001}
-	_, err := service.CreateSnapshotVolume(&snapshotParams)
+	_, err := service.CreateSnapshotVolume(context.Background(), &snapshotParams)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -201,20 +249,65 @@ func (suite *ApiTestSuite) Test_CreateSnapshotVolume_Success() {
 
 	// Act
 	snapshotParams := VolumeSnapshot{ParentID: 1001, SnapshotName: "test_volume_resp"}
-	response, _ := service.CreateSnapshotVolume(&snapshotParams)
+	response, _ := service.CreateSnapshotVolume(context.Background(), &snapshotParams)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
 	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
 }
 
+func (suite *ApiTestSuite) Test_GetVolumeByPVName_Success() {
+	metadata := []Metadata{
+		{ObjectId: 100, Key: PVNameMetadataKey, Value: "pvc-1"},
+		{ObjectId: 101, Key: PVNameMetadataKey, Value: "pvc-1"},
+	}
+	volume := Volume{ID: 101, Name: "csi-pvc-1"}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: metadata}, nil)
+	suite.clientMock.On("Get").Return(nil, &APIError{StatusCode: http.StatusNotFound, Code: ErrVolumeNotFound}).Once()
+	suite.clientMock.On("Get").Return(client.ApiResponse{Result: volume}, nil).Once()
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetVolumeByPVName(context.Background(), "pvc-1")
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), volume, *response, "volume named differently than its PV expected")
+}
+
+func (suite *ApiTestSuite) Test_GetVolumeByPVName_NotFound() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: []Metadata{}}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetVolumeByPVName(context.Background(), "pvc-1")
+
+	// Assert
+	assert.True(suite.T(), IsNotFound(err), "not found error expected")
+	suite.clientMock.AssertNotCalled(suite.T(), "Get")
+}
+
+func (suite *ApiTestSuite) Test_GetFileSystemByPVName_Error() {
+	metadata := []Metadata{{ObjectId: 100, Key: PVNameMetadataKey, Value: "pvc-1"}}
+	expectedError := errors.New("some error")
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: metadata}, nil)
+	suite.clientMock.On("Get").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetFileSystemByPVName(context.Background(), "pvc-1")
+
+	// Assert
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
+}
+
 func (suite *ApiTestSuite) Test_GetVolume_Fail() {
 	expectedError := errors.New("Unable to get given volume")
 	suite.clientMock.On("Get").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetVolume(101)
+	_, err := service.GetVolume(context.Background(), 101)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -227,7 +320,7 @@ func (suite *ApiTestSuite) Test_GetVolume_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetVolume(101)
+	response, _ := service.GetVolume(context.Background(), 101)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -240,7 +333,7 @@ func (suite *ApiTestSuite) Test_GetNetworkSpaceByName_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetNetworkSpaceByName("test_network_space")
+	_, err := service.GetNetworkSpaceByName(context.Background(), "test_network_space")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -256,20 +349,46 @@ func (suite *ApiTestSuite) Test_GetNetworkSpaceByName_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetNetworkSpaceByName("test_network_space")
+	response, _ := service.GetNetworkSpaceByName(context.Background(), "test_network_space")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
 	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
 }
 
+func (suite *ApiTestSuite) Test_GetSystem_Fail() {
+	expectedError := errors.New("Unable to get system")
+	suite.clientMock.On("Get").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetSystem(context.Background())
+
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetSystem_Success() {
+	expectedResponse := client.ApiResponse{Result: System{Name: "ibox1", SerialNumber: 36000}}
+	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetSystem(context.Background())
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), int64(36000), response.SerialNumber, "Serial number not returned as expected")
+}
+
 func (suite *ApiTestSuite) Test_GetHostByName_Fail() {
 	expectedError := errors.New("Unable to get host by given name")
 	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetHostByName("test_host")
+	_, err := service.GetHostByName(context.Background(), "test_host")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -283,20 +402,98 @@ func (suite *ApiTestSuite) Test_GetHostByName_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetHostByName("test_host")
+	response, _ := service.GetHostByName(context.Background(), "test_host")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
 	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
 }
 
+func (suite *ApiTestSuite) Test_GetAllHosts_Fail() {
+	expectedError := errors.New("Unable to get hosts")
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetAllHosts(context.Background())
+
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetAllHosts_Success() {
+	hosts := []Host{{ID: 10, Name: "test_host"}}
+	expectedResponse := client.ApiResponse{Result: hosts}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetAllHosts(context.Background())
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), hosts, response, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetHostPort_Success() {
+	hostPorts := []HostPort{{HostID: 10, PortAddress: "iqn.1"}, {HostID: 10, PortAddress: "iqn.2"}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: hostPorts}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetHostPort(context.Background(), 10, "iqn.2")
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), hostPorts[1], response, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetHostPort_NotFound() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: []HostPort{}}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetHostPort(context.Background(), 10, "iqn.2")
+
+	// Assert
+	assert.True(suite.T(), IsNotFound(err), "Error should be not found")
+}
+
+func (suite *ApiTestSuite) Test_GetFCPorts_Success() {
+	fcNodes := []FCNode{{Ports: []FCPort{{PortID: 1, Enabled: true}}}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: fcNodes}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetFCPorts(context.Background())
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), fcNodes, response, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetLunsByVolume_Success() {
+	luns := []LunInfo{{HostID: 10, VolumeID: 2, Lun: 1}}
+	expectedResponse := client.ApiResponse{Result: luns}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetLunsByVolume(context.Background(), 2)
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), luns, response, "Response not returned as expected")
+}
+
 func (suite *ApiTestSuite) Test_MapVolumeToHost_Fail() {
 	expectedError := errors.New("Volume ID is missing")
 	suite.clientMock.On("Post").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.MapVolumeToHost(1, 2, 2)
+	_, err := service.MapVolumeToHost(context.Background(), 1, 2, 2)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -310,7 +507,7 @@ func (suite *ApiTestSuite) Test_MapVolumeToHost_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.MapVolumeToHost(1001, 2, 2)
+	response, _ := service.MapVolumeToHost(context.Background(), 1001, 2, 2)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -327,7 +524,7 @@ func (suite *ApiTestSuite) Test_UpdateFilesystem_Fail() {
 
 	// Act
 	fileSystem := FileSystem{}
-	_, err := service.UpdateFilesystem(1001, fileSystem)
+	_, err := service.UpdateFilesystem(context.Background(), 1001, fileSystem)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -342,7 +539,7 @@ func (suite *ApiTestSuite) Test_UpdateFilesystem_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	fileSystem := FileSystem{Size: 100}
-	response, _ := service.UpdateFilesystem(1001, fileSystem)
+	response, _ := service.UpdateFilesystem(context.Background(), 1001, fileSystem)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -360,7 +557,7 @@ func (suite *ApiTestSuite) Test_CreateFileSystemSnapshot_Fail() {
 		ParentID:       1000,
 		WriteProtected: true,
 	}
-	_, err := service.CreateFileSystemSnapshot(fileSystemSnapshot)
+	_, err := service.CreateFileSystemSnapshot(context.Background(), fileSystemSnapshot)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -379,7 +576,7 @@ func (suite *ApiTestSuite) Test_CreateFileSystemSnapshot_Success() {
 	}
 
 	// Act
-	response, _ := service.CreateFileSystemSnapshot(fileSystemSnapshot)
+	response, _ := service.CreateFileSystemSnapshot(context.Background(), fileSystemSnapshot)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -393,7 +590,7 @@ func (suite *ApiTestSuite) Test_DeleteFileSystem_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.DeleteFileSystem(1001)
+	_, err := service.DeleteFileSystem(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -405,7 +602,7 @@ func (suite *ApiTestSuite) Test_DeleteFileSystem_Success() {
 	suite.clientMock.On("Delete").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	response, _ := service.DeleteFileSystem(1001)
+	response, _ := service.DeleteFileSystem(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -415,10 +612,10 @@ func (suite *ApiTestSuite) Test_DeleteFileSystem_Success() {
 //****************************************
 func (suite *ApiTestSuite) Test_GetFilesytemTreeqCount_error() {
 	expectedError := errors.New("some error")
-	suite.clientMock.On("Get").Return(nil, expectedError)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	response, err := service.GetFilesytemTreeqCount(1001)
+	response, err := service.GetFilesytemTreeqCount(context.Background(), 1001)
 	var expectedResponse int = 0
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
@@ -427,10 +624,10 @@ func (suite *ApiTestSuite) Test_GetFilesytemTreeqCount_error() {
 
 func (suite *ApiTestSuite) Test_GetFilesytemTreeqCount_Success() {
 	expectedResponse := client.ApiResponse{MetaData: client.Resultmetadata{NoOfObject: 10}}
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	response, err := service.GetFilesytemTreeqCount(1001)
+	response, err := service.GetFilesytemTreeqCount(context.Background(), 1001)
 	var expectedvalue int = 10
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
@@ -438,10 +635,10 @@ func (suite *ApiTestSuite) Test_GetFilesytemTreeqCount_Success() {
 }
 
 func (suite *ApiTestSuite) Test_GetFilesytemTreeqCount_panic() {
-	suite.clientMock.On("Get").Return(nil, nil)
+	suite.clientMock.On("GetWithQueryString").Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	_, err := service.GetFilesytemTreeqCount(1001)
+	_, err := service.GetFilesytemTreeqCount(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
@@ -460,7 +657,7 @@ func (suite *ApiTestSuite) Test_CreateTreeq_success() {
 	treeqParameter["name"] = pvName
 	treeqParameter["hard_capacity"] = 100
 
-	response, err := service.CreateTreeq(fileSysID, treeqParameter)
+	response, err := service.CreateTreeq(context.Background(), fileSysID, treeqParameter)
 
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
@@ -482,7 +679,7 @@ func (suite *ApiTestSuite) Test_CreateTreeq_Error() {
 	treeqParameter["path"] = "\\" + pvName
 	treeqParameter["name"] = pvName
 	treeqParameter["hard_capacity"] = 100
-	response, err := service.CreateTreeq(fileSysID, treeqParameter)
+	response, err := service.CreateTreeq(context.Background(), fileSysID, treeqParameter)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 	assert.Nil(suite.T(), response, "response should be nil")
@@ -490,38 +687,123 @@ func (suite *ApiTestSuite) Test_CreateTreeq_Error() {
 
 func (suite *ApiTestSuite) Test_GetFileSystemsByPoolID_success() {
 	expectedResponse := client.ApiResponse{Result: getFilesystemArry(), MetaData: getMetaData()}
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var poolID int64 = 1
-	var page int = 1
-	response, err := service.GetFileSystemsByPoolID(poolID, page)
+	response, err := service.GetFileSystemsByPoolID(context.Background(), poolID)
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
-	assert.Equal(suite.T(), 50, response.Filemetadata.PageSize, "response should be nil")
+	assert.Equal(suite.T(), 2*len(getFilesystemArry()), len(response), "filesystems of both pages should be returned")
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 2)
 }
 
 func (suite *ApiTestSuite) Test_GetFileSystemsByPoolID_Error() {
 	// expectedResponse := client.ApiResponse{Result: getFilesystemArry(), MetaData: getMetaData()}
 	expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(nil, expectedErr)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var poolID int64 = 1
-	var page int = 1
-	_, err := service.GetFileSystemsByPoolID(poolID, page)
+	_, err := service.GetFileSystemsByPoolID(context.Background(), poolID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
 
-func (suite *ApiTestSuite) Test_GetFileSystemsByPoolID_panic() {
-	//	expectedResponse := client.ApiResponse{Result: getFilesystem(), MetaData: getMetaData()}
-	suite.clientMock.On("Get").Return(nil, nil)
-	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+func (suite *ApiTestSuite) Test_GetFileSystemsByPoolID_invalidPageSize() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: getFilesystemArry()}, nil)
+	secrets := setSecret()
+	secrets["page_size"] = "5000"
+	service := ClientService{api: suite.clientMock, SecretsMap: secrets}
 	// Act
 	var poolID int64 = 1
-	var page int = 1
-	_, err := service.GetFileSystemsByPoolID(poolID, page)
+	_, err := service.GetFileSystemsByPoolID(context.Background(), poolID)
+	// Assert
+	assert.NotNil(suite.T(), err, "Response should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_GetMetadataByKey_success() {
+	metadata := []Metadata{{ID: 1, ObjectId: 100, Key: "host.k8s.pvname", Value: "pvc-1"}}
+	expectedResponse := client.ApiResponse{Result: metadata, MetaData: getMetaData()}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	response, err := service.GetMetadataByKey(context.Background(), "host.k8s.pvname")
+	// Assert
+	assert.Nil(suite.T(), err, "Response should not be nil")
+	assert.Equal(suite.T(), 2, len(response), "entries of both pages should be returned")
+	assert.Equal(suite.T(), 100, response[0].ObjectId, "object id should match")
+}
+
+func (suite *ApiTestSuite) Test_GetMetadataByKey_Error() {
+	expectedErr := errors.New("some error")
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	_, err := service.GetMetadataByKey(context.Background(), "host.k8s.pvname")
+	// Assert
+	assert.NotNil(suite.T(), err, "Response should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_GetFileSystemsByIDs_success() {
+	filesystems := []FileSystem{{ID: 100}, {ID: 101}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: filesystems}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	response, err := service.GetFileSystemsByIDs(context.Background(), []int64{100, 101})
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), filesystems, response, "filesystems should match")
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 1)
+}
+
+func (suite *ApiTestSuite) Test_GetFileSystemsByIDs_Error() {
+	suite.clientMock.On("GetWithQueryString").Return(nil, errors.New("some error"))
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	_, err := service.GetFileSystemsByIDs(context.Background(), []int64{100})
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_GetFileSystemsByParentIDs_success() {
+	children := []FileSystem{{ID: 101, ParentID: 100, WriteProtected: true}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: children}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	response, err := service.GetFileSystemsByParentIDs(context.Background(), []int64{100, 102})
+	// Assert
+	assert.Nil(suite.T(), err, "Response should not be nil")
+	assert.Equal(suite.T(), int64(101), response[0].ID, "child id should match")
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 1)
+}
+
+func (suite *ApiTestSuite) Test_GetFileSystemsByParentIDs_Error() {
+	suite.clientMock.On("GetWithQueryString").Return(nil, errors.New("some error"))
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	_, err := service.GetFileSystemsByParentIDs(context.Background(), []int64{100})
+	// Assert
+	assert.NotNil(suite.T(), err, "Response should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_GetTreeqsByFileSystemID_success() {
+	treeqs := []Treeq{{ID: 1, FilesystemID: 100, Name: "treeq", HardCapacity: 100}}
+	expectedResponse := client.ApiResponse{Result: treeqs, MetaData: getMetaData()}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	response, err := service.GetTreeqsByFileSystemID(context.Background(), 100)
+	// Assert
+	assert.Nil(suite.T(), err, "Response should not be nil")
+	assert.Equal(suite.T(), 2, len(response), "treeqs of both pages should be returned")
+}
+
+func (suite *ApiTestSuite) Test_GetTreeqsByFileSystemID_Error() {
+	suite.clientMock.On("GetWithQueryString").Return(nil, errors.New("some error"))
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	// Act
+	_, err := service.GetTreeqsByFileSystemID(context.Background(), 100)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
@@ -532,7 +814,7 @@ func (suite *ApiTestSuite) Test_DeleteTreeq_Success() {
 	// Act
 	var FilesystemID int64 = 3111
 	var treeqID int64 = 20000
-	_, err := service.DeleteTreeq(FilesystemID, treeqID)
+	_, err := service.DeleteTreeq(context.Background(), FilesystemID, treeqID)
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
 }
@@ -544,7 +826,7 @@ func (suite *ApiTestSuite) Test_DeleteTreeq_Error() {
 	// Act
 	var FilesystemID int64 = 3111
 	var treeqID int64 = 20000
-	_, err := service.DeleteTreeq(FilesystemID, treeqID)
+	_, err := service.DeleteTreeq(context.Background(), FilesystemID, treeqID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
@@ -552,11 +834,11 @@ func (suite *ApiTestSuite) Test_DeleteTreeq_Error() {
 func (suite *ApiTestSuite) Test_GetSnapshotByName_Fail() {
 	// Test volume snapshot will not be created
 	expectedError := errors.New("Missing parameters")
-	suite.clientMock.On("Get").Return(nil, expectedError)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetSnapshotByName("test_snapshot")
+	_, err := service.GetSnapshotByName(context.Background(), "test_snapshot")
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -564,28 +846,28 @@ func (suite *ApiTestSuite) Test_GetSnapshotByName_Fail() {
 }
 
 func (suite *ApiTestSuite) Test_GetSnapshotByName_Success() {
-	var snapResponse []FileSystemSnapshotResponce
-	expectedResponse := client.ApiResponse{Result: &snapResponse}
+	snapResponse := []FileSystemSnapshotResponce{{SnapshotID: 100, Name: "test_snapshot"}}
+	expectedResponse := client.ApiResponse{Result: snapResponse}
 
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetSnapshotByName("test_snapshot")
+	response, _ := service.GetSnapshotByName(context.Background(), "test_snapshot")
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
-	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
+	assert.Equal(suite.T(), &snapResponse, response, "Response not returned as expected")
 }
 
 func (suite *ApiTestSuite) Test_GetExportByFileSystem_Fail() {
 	// Test volume snapshot will not be created
 	expectedError := errors.New("Missing parameters")
-	suite.clientMock.On("Get").Return(nil, expectedError)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetExportByFileSystem(1001)
+	_, err := service.GetExportByFileSystem(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -593,18 +875,18 @@ func (suite *ApiTestSuite) Test_GetExportByFileSystem_Fail() {
 }
 
 func (suite *ApiTestSuite) Test_GetExportByFileSystem_Success() {
-	var exportResponse []ExportResponse
+	exportResponse := []ExportResponse{{ID: 10, ExportPath: "/test_export"}}
 
-	expectedResponse := client.ApiResponse{Result: &exportResponse}
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	expectedResponse := client.ApiResponse{Result: exportResponse}
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetExportByFileSystem(1001)
+	response, _ := service.GetExportByFileSystem(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
-	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
+	assert.Equal(suite.T(), &exportResponse, response, "Response not returned as expected")
 }
 
 func (suite *ApiTestSuite) Test_RestoreFileSystemFromSnapShot_Fail() {
@@ -614,7 +896,7 @@ func (suite *ApiTestSuite) Test_RestoreFileSystemFromSnapShot_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.RestoreFileSystemFromSnapShot(1001, 1002)
+	_, err := service.RestoreFileSystemFromSnapShot(context.Background(), 1001, 1002)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -627,7 +909,7 @@ func (suite *ApiTestSuite) Test_RestoreFileSystemFromSnapShot_Success() {
 	suite.clientMock.On("Post").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	response, _ := service.RestoreFileSystemFromSnapShot(1001, 1002)
+	response, _ := service.RestoreFileSystemFromSnapShot(context.Background(), 1001, 1002)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -644,7 +926,7 @@ func (suite *ApiTestSuite) Test_UpdateVolume_Fail() {
 
 	// Act
 	volume := Volume{}
-	_, err := service.UpdateVolume(1001, volume)
+	_, err := service.UpdateVolume(context.Background(), 1001, volume)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -659,7 +941,7 @@ func (suite *ApiTestSuite) Test_UpdateVolume_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	volume := Volume{Size: 100}
-	response, _ := service.UpdateVolume(1001, volume)
+	response, _ := service.UpdateVolume(context.Background(), 1001, volume)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
@@ -673,7 +955,7 @@ func (suite *ApiTestSuite) Test_GetVolumeSnapshotByParentID_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	_, err := service.GetVolumeSnapshotByParentID(1001)
+	_, err := service.GetVolumeSnapshotByParentID(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
@@ -681,18 +963,89 @@ func (suite *ApiTestSuite) Test_GetVolumeSnapshotByParentID_Fail() {
 }
 
 func (suite *ApiTestSuite) Test_GetVolumeSnapshotByParentID_Success() {
-	var volumeResponse []Volume
-	expectedResponse := client.ApiResponse{Result: &volumeResponse}
+	volumeResponse := []Volume{{ID: 1002, ParentId: 1001}}
+	expectedResponse := client.ApiResponse{Result: volumeResponse}
 
 	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	response, _ := service.GetVolumeSnapshotByParentID(1001)
+	response, _ := service.GetVolumeSnapshotByParentID(context.Background(), 1001)
 
 	// Assert
 	assert.NotNil(suite.T(), response, "Response should not be nil")
-	assert.Equal(suite.T(), expectedResponse.Result, response, "Response not returned as expected")
+	assert.Equal(suite.T(), &volumeResponse, response, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetVolumesByIDs_chunked() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: []Volume{{ID: 1001}}}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	volumeIDs := make([]int, MaxIDsPerQuery+1)
+	for i := range volumeIDs {
+		volumeIDs[i] = 1001 + i
+	}
+
+	// Act
+	response, err := service.GetVolumesByIDs(context.Background(), volumeIDs)
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), 2, len(response), "expected the volumes of both queries")
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 2)
+}
+
+func (suite *ApiTestSuite) Test_GetVolumesByIDs_none() {
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	response, err := service.GetVolumesByIDs(context.Background(), nil)
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), 0, len(response), "expected no volumes")
+	suite.clientMock.AssertNotCalled(suite.T(), "GetWithQueryString")
+}
+
+func (suite *ApiTestSuite) Test_GetVolumesByIDs_Fail() {
+	expectedError := errors.New("some error")
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetVolumesByIDs(context.Background(), []int{1001})
+
+	// Assert
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetVolumesByParentIDs_chunked() {
+	children := []Volume{{ID: 2001, ParentId: 1001, WriteProtected: true}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: children}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	parentIDs := make([]int, 2*MaxIDsPerQuery)
+	for i := range parentIDs {
+		parentIDs[i] = 1001 + i
+	}
+
+	// Act
+	response, err := service.GetVolumesByParentIDs(context.Background(), parentIDs)
+
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), 2, len(response), "expected the children of both queries")
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 2)
+}
+
+func (suite *ApiTestSuite) Test_GetVolumesByParentIDs_Fail() {
+	expectedError := errors.New("some error")
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedError)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	// Act
+	_, err := service.GetVolumesByParentIDs(context.Background(), []int{1001})
+
+	// Assert
+	assert.Equal(suite.T(), expectedError, err, "Error not returned as expected")
 }
 
 func (suite *ApiTestSuite) Test_DeleteVolume_Fail() {
@@ -704,7 +1057,7 @@ func (suite *ApiTestSuite) Test_DeleteVolume_Fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
 	// Act
-	err := service.DeleteVolume(1001)
+	err := service.DeleteVolume(context.Background(), 1001)
 
 	// Assert
 	assert.Equal(suite.T(), nil, err, "Error not returned as expected")
@@ -717,7 +1070,7 @@ func (suite *ApiTestSuite) Test_DeleteVolume_Success() {
 	suite.clientMock.On("Delete").Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	err := service.DeleteVolume(1001)
+	err := service.DeleteVolume(context.Background(), 1001)
 
 	// Assert
 	assert.Equal(suite.T(), nil, err, "Response not returned as expected")
@@ -730,7 +1083,7 @@ func (suite *ApiTestSuite) Test_GetTreeq_Success() {
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	resp, err := service.GetTreeq(FilesystemID, treeqID)
+	resp, err := service.GetTreeq(context.Background(), FilesystemID, treeqID)
 	// Assert
 	assert.Nil(suite.T(), err, "err should  nil")
 	assert.Equal(suite.T(), FilesystemID, resp.FilesystemID, "file systemID should be equal")
@@ -744,7 +1097,7 @@ func (suite *ApiTestSuite) Test_GetTreeq_fail() {
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
-	_, err := service.GetTreeq(FilesystemID, treeqID)
+	_, err := service.GetTreeq(context.Background(), FilesystemID, treeqID)
 	// Assert
 	assert.NotNil(suite.T(), err, "err should  nil")
 }
@@ -757,7 +1110,7 @@ func (suite *ApiTestSuite) Test_UpdateTreeq_Success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	body := map[string]interface{}{"hard_capacity": 1000000}
-	resp, err := service.UpdateTreeq(FilesystemID, treeqID, body)
+	resp, err := service.UpdateTreeq(context.Background(), FilesystemID, treeqID, body)
 	// Assert
 	assert.Nil(suite.T(), err, "err should  nil")
 	assert.Equal(suite.T(), FilesystemID, resp.FilesystemID, "file systemID should be equal")
@@ -772,7 +1125,7 @@ func (suite *ApiTestSuite) Test_UpdateTreeq_fail() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	body := map[string]interface{}{"hard_capacity": 1000000}
-	_, err := service.UpdateTreeq(FilesystemID, treeqID, body)
+	_, err := service.UpdateTreeq(context.Background(), FilesystemID, treeqID, body)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 	assert.Equal(suite.T(), expectedErr, err, "Error not returned as expected")
@@ -783,11 +1136,11 @@ func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_fail() {
 	//	var treeqID int64 = 20000
 	// expectedResponse := client.ApiResponse{Result: Treeq{ID: treeqID, FilesystemID: FilesystemID, HardCapacity: 10000, Name: "treeq1", Path: "/treeqPath", UsedCapacity: 10}}
 	expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(nil, expectedErr)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	// body := map[string]interface{}{"hard_capacity": 1000000}
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -797,12 +1150,12 @@ func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_export_delete_fail() {
 	//	var treeqID int64 = 20000
 	// expectedResponse := client.ApiResponse{Result: Treeq{ID: treeqID, FilesystemID: FilesystemID, HardCapacity: 10000, Name: "treeq1", Path: "/treeqPath", UsedCapacity: 10}}
 	expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(getExportResponse(), nil)
+	suite.clientMock.On("GetWithQueryString").Return(getExportResponse(), nil)
 	suite.clientMock.On("Delete").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	// body := map[string]interface{}{"hard_capacity": 1000000}
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -811,14 +1164,14 @@ func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_metadata_delete_fail()
 	var FilesystemID int64 = 3111
 	//	var treeqID int64 = 20000
 	// expectedResponse := client.ApiResponse{Result: Treeq{ID: treeqID, FilesystemID: FilesystemID, HardCapacity: 10000, Name: "treeq1", Path: "/treeqPath", UsedCapacity: 10}}
-	expectedErr := errors.New("EXPORT_NOT_FOUND")
-	suite.clientMock.On("Get").Return(nil, expectedErr)
+	expectedErr := &APIError{Code: ErrExportNotFound}
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	// suite.clientMock.On("Delete").Return(nil, nil)
 	suite.clientMock.On("Delete").Return([]Metadata{}, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	// body := map[string]interface{}{"hard_capacity": 1000000}
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -827,29 +1180,29 @@ func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_delete_fail1() {
 	var FilesystemID int64 = 3111
 	//	var treeqID int64 = 20000
 	// expectedResponse := client.ApiResponse{Result: Treeq{ID: treeqID, FilesystemID: FilesystemID, HardCapacity: 10000, Name: "treeq1", Path: "/treeqPath", UsedCapacity: 10}}
-	exportNotFoundErr := errors.New("EXPORT_NOT_FOUND")
-	suite.clientMock.On("Get").Return(nil, exportNotFoundErr)
+	exportNotFoundErr := &APIError{Code: ErrExportNotFound}
+	suite.clientMock.On("GetWithQueryString").Return(nil, exportNotFoundErr)
 	// suite.clientMock.On("Delete").Return(nil, nil)
-	metaDataErr := errors.New("METADATA_IS_NOT_SUPPORTED_FOR_ENTITY")
+	metaDataErr := &APIError{Code: ErrMetadataIsNotSupportedForEntity}
 	suite.clientMock.On("Delete").Return([]Metadata{}, metaDataErr)
 	expectedErr := errors.New("some Error")
 	suite.clientMock.On("Delete").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	// body := map[string]interface{}{"hard_capacity": 1000000}
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
 
 func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_delete_success() {
 	var FilesystemID int64 = 3111
-	exportNotFoundErr := errors.New("EXPORT_NOT_FOUND")
-	suite.clientMock.On("Get").Return(nil, exportNotFoundErr)
+	exportNotFoundErr := &APIError{Code: ErrExportNotFound}
+	suite.clientMock.On("GetWithQueryString").Return(nil, exportNotFoundErr)
 	suite.clientMock.On("Delete").Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -859,14 +1212,14 @@ func (suite *ApiTestSuite) Test_DeleteFileSystemComplete_deletes() {
 	//	var treeqID int64 = 20000
 	// expectedResponse := client.ApiResponse{Result: Treeq{ID: treeqID, FilesystemID: FilesystemID, HardCapacity: 10000, Name: "treeq1", Path: "/treeqPath", UsedCapacity: 10}}
 	// expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(getExportResponse(), nil)
+	suite.clientMock.On("GetWithQueryString").Return(getExportResponse(), nil)
 	suite.clientMock.On("Delete").Return(nil, nil)
 	suite.clientMock.On("Delete").Return(nil, nil)
 	suite.clientMock.On("Delete").Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	// body := map[string]interface{}{"hard_capacity": 1000000}
-	err := service.DeleteFileSystemComplete(FilesystemID)
+	err := service.DeleteFileSystemComplete(context.Background(), FilesystemID)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -876,7 +1229,7 @@ func (suite *ApiTestSuite) Test_DeleteParentFileSystem_haschild_false() {
 	suite.clientMock.On("Get").Return(false)
 	suite.clientMock.On("GetWithQueryString", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	err := service.DeleteParentFileSystem(FilesystemID)
+	err := service.DeleteParentFileSystem(context.Background(), FilesystemID)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -889,7 +1242,7 @@ func (suite *ApiTestSuite) Test_DeleteParentFileSystem() {
 	suite.clientMock.On("Delete").Return(nil)
 	suite.clientMock.On("GetWithQueryString", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	err := service.DeleteParentFileSystem(FilesystemID)
+	err := service.DeleteParentFileSystem(context.Background(), FilesystemID)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -899,7 +1252,7 @@ func (suite *ApiTestSuite) Test_GetParentID() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Get").Return(0, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	parentID := service.GetParentID(FilesystemID)
+	parentID := service.GetParentID(context.Background(), FilesystemID)
 	// Assert
 	assert.Equal(suite.T(), int64(0), parentID)
 }
@@ -910,7 +1263,7 @@ func (suite *ApiTestSuite) Test_GetParentID_success() {
 	expectedResponse := client.ApiResponse{Result: FileSystem{ParentID: 100}}
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	parentID := service.GetParentID(FilesystemID)
+	parentID := service.GetParentID(context.Background(), FilesystemID)
 	// Assert
 	assert.Equal(suite.T(), int64(100), parentID)
 }
@@ -922,7 +1275,7 @@ func (suite *ApiTestSuite) Test_GetFileSystemByID_success() {
 	expectedResponse := client.ApiResponse{Result: FileSystem{ParentID: 100}}
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	filesys, err := service.GetFileSystemByID(FilesystemID)
+	filesys, err := service.GetFileSystemByID(context.Background(), FilesystemID)
 	// Assert
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), filesys.ParentID, parentID)
@@ -935,7 +1288,7 @@ func (suite *ApiTestSuite) Test_GetFileSystemByID_Error() {
 	// expectedResponse := client.ApiResponse{Result: FileSystem{ParentID: 100}}
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.GetFileSystemByID(FilesystemID)
+	_, err := service.GetFileSystemByID(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err)
 }
@@ -946,7 +1299,7 @@ func (suite *ApiTestSuite) Test_GetMetadataStatus_success() {
 	expectedResponse := client.ApiResponse{Result: Metadata{Value: "true"}}
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	status := service.GetMetadataStatus(FilesystemID)
+	status := service.GetMetadataStatus(context.Background(), FilesystemID)
 	// Assert
 	assert.True(suite.T(), status)
 }
@@ -956,7 +1309,7 @@ func (suite *ApiTestSuite) Test_GetMetadataStatus_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	err := service.GetMetadataStatus(FilesystemID)
+	err := service.GetMetadataStatus(context.Background(), FilesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -969,7 +1322,7 @@ func (suite *ApiTestSuite) Test_FileSystemHasChild_success() {
 	expectedResponse := client.ApiResponse{Result: fileSysArry}
 	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	status := service.FileSystemHasChild(FilesystemID)
+	status := service.FileSystemHasChild(context.Background(), FilesystemID)
 	// Assert
 	assert.True(suite.T(), status)
 }
@@ -979,7 +1332,7 @@ func (suite *ApiTestSuite) Test_FileSystemHasChild_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	status := service.FileSystemHasChild(FilesystemID)
+	status := service.FileSystemHasChild(context.Background(), FilesystemID)
 	// Assert
 	assert.False(suite.T(), status)
 }
@@ -992,7 +1345,7 @@ func (suite *ApiTestSuite) Test_CreateFilesystem_success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	fileSysparameter := make(map[string]interface{})
 	fileSysparameter["ID"] = "100"
-	_, err := service.CreateFilesystem(fileSysparameter)
+	_, err := service.CreateFilesystem(context.Background(), fileSysparameter)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1004,7 +1357,7 @@ func (suite *ApiTestSuite) Test_CreateFilesystem_Error() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	fileSysparameter := make(map[string]interface{})
 	fileSysparameter["ID"] = "100"
-	_, err := service.CreateFilesystem(fileSysparameter)
+	_, err := service.CreateFilesystem(context.Background(), fileSysparameter)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1023,7 +1376,7 @@ func (suite *ApiTestSuite) Test_AttachMetadataToObject_success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	fileSysparameter := make(map[string]interface{})
 	fileSysparameter["ID"] = "100"
-	_, err := service.AttachMetadataToObject(ObjectID, fileSysparameter)
+	_, err := service.AttachMetadataToObject(context.Background(), ObjectID, fileSysparameter)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1036,7 +1389,26 @@ func (suite *ApiTestSuite) Test_AttachMetadataToObject_Error() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	fileSysparameter := make(map[string]interface{})
 	fileSysparameter["ID"] = "100"
-	_, err := service.AttachMetadataToObject(ObjectID, fileSysparameter)
+	_, err := service.AttachMetadataToObject(context.Background(), ObjectID, fileSysparameter)
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_DetachMetadataKeyFromObject_NotFound() {
+	suite.clientMock.On("Delete").Return(nil, notFoundError("METADATA_KEY_NOT_FOUND", "metadata key not found"))
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	err := service.DetachMetadataKeyFromObject(context.Background(), 3111, "host.k8s.treeq.10")
+	// Assert
+	assert.Nil(suite.T(), err, "missing key should be ignored")
+}
+
+func (suite *ApiTestSuite) Test_DetachMetadataKeyFromObject_Error() {
+	expectedErr := errors.New("some error")
+	suite.clientMock.On("Delete").Return(nil, expectedErr)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	err := service.DetachMetadataKeyFromObject(context.Background(), 3111, "host.k8s.treeq.10")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1047,7 +1419,7 @@ func (suite *ApiTestSuite) Test_DeleteExportPath_Error() {
 	suite.clientMock.On("Delete").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
-	_, err := service.DeleteExportPath(exportID)
+	_, err := service.DeleteExportPath(context.Background(), exportID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1059,7 +1431,7 @@ func (suite *ApiTestSuite) Test_DeleteExportPath_Success() {
 	expectedResponse := client.ApiResponse{Result: exportReps}
 	suite.clientMock.On("Delete").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.DeleteExportPath(exportID)
+	_, err := service.DeleteExportPath(context.Background(), exportID)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should  be nil")
 }
@@ -1068,7 +1440,7 @@ func (suite *ApiTestSuite) Test_OneTimeValidation_PoolIDByName_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.OneTimeValidation("poolName", "newtworkSpace")
+	_, err := service.OneTimeValidation(context.Background(), "poolName", "newtworkSpace")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1078,7 +1450,7 @@ func (suite *ApiTestSuite) Test_OneTimeValidation_NetworkSpaceByName_Error() {
 	suite.clientMock.On("Get").Return("networkSpace", nil)
 	suite.clientMock.On("Get").Return("networkSpace", expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.OneTimeValidation("poolName", "newtworkSpace")
+	_, err := service.OneTimeValidation(context.Background(), "poolName", "newtworkSpace")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1100,7 +1472,7 @@ func (suite *ApiTestSuite) Test_OneTimeValidation_NetworkSpaceByName_notmatch()
 
 	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.OneTimeValidation("poolName", "newtworkSpace")
+	_, err := service.OneTimeValidation(context.Background(), "poolName", "newtworkSpace")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1109,7 +1481,7 @@ func (suite *ApiTestSuite) Test_GetFileSystemByName_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.GetFileSystemByName("fs_name")
+	_, err := service.GetFileSystemByName(context.Background(), "fs_name")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1124,7 +1496,7 @@ func (suite *ApiTestSuite) Test_GetFileSystemByName_Success() {
 
 	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.GetFileSystemByName("fs_1")
+	_, err := service.GetFileSystemByName(context.Background(), "fs_1")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1139,7 +1511,7 @@ func (suite *ApiTestSuite) Test_GetFileSystemByName_file_not_found() {
 
 	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.GetFileSystemByName("fs_1")
+	_, err := service.GetFileSystemByName(context.Background(), "fs_1")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1152,7 +1524,7 @@ func (suite *ApiTestSuite) Test_ExportFileSystem_Error() {
 	export.Name = "exportName"
 	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.ExportFileSystem(export)
+	_, err := service.ExportFileSystem(context.Background(), export)
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1172,7 +1544,7 @@ func (suite *ApiTestSuite) Test_ExportFileSystem_Success() {
 
 	suite.clientMock.On("Post").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.ExportFileSystem(export)
+	_, err := service.ExportFileSystem(context.Background(), export)
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1181,7 +1553,7 @@ func (suite *ApiTestSuite) Test_AddNodeInExport_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.AddNodeInExport(100, "", false, "10.20.30.40")
+	_, err := service.AddNodeInExport(context.Background(), 100, "", false, "10.20.30.40")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1203,7 +1575,7 @@ func (suite *ApiTestSuite) Test_AddNodeInExport_IPAddress_exist_success() {
 	expectedResponse := client.ApiResponse{Result: exportResp}
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.AddNodeInExport(100, "RW", false, "10.20.30.40")
+	_, err := service.AddNodeInExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1231,7 +1603,7 @@ func (suite *ApiTestSuite) Test_AddNodeInExport_IP_not_exist_success() {
 
 	suite.clientMock.On("Put").Return(updateResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.AddNodeInExport(100, "RW", false, "10.20.30.40")
+	_, err := service.AddNodeInExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should be nil")
 }
@@ -1260,7 +1632,7 @@ func (suite *ApiTestSuite) Test_AddNodeInExport_IP_outside_range_added_success()
 	suite.clientMock.On("Put").Return(updateResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 
-	_, err := service.AddNodeInExport(100, "RW", false, "10.20.30.99")
+	_, err := service.AddNodeInExport(context.Background(), 100, "RW", false, "10.20.30.99")
 
 	// Assert
 	assert.Nil(suite.T(), err, "Error should be nil")
@@ -1286,7 +1658,7 @@ func (suite *ApiTestSuite) Test_AddNodeInExport_update_error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Put").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.AddNodeInExport(100, "RW", false, "10.20.30.40")
+	_, err := service.AddNodeInExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1296,7 +1668,7 @@ func (suite *ApiTestSuite) Test_DeleteExportRule_Error() {
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	var fsID int64 = 100
-	err := service.DeleteExportRule(fsID, "10.20.30.40")
+	err := service.DeleteExportRule(context.Background(), fsID, "10.20.30.40")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1309,14 +1681,12 @@ func (suite *ApiTestSuite) Test_DeleteExportRule_success() {
 	exportRespArry = append(exportRespArry, expoResp)
 	expectedResponse := client.ApiResponse{Result: exportRespArry}
 
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
-
-	expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(nil, expectedErr)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
+	suite.clientMock.On("Get").Return(client.ApiResponse{Result: expoResp}, nil)
 
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	var fsID int64 = 100
-	err := service.DeleteExportRule(fsID, "10.20.30.40")
+	err := service.DeleteExportRule(context.Background(), fsID, "10.20.30.40")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1325,7 +1695,7 @@ func (suite *ApiTestSuite) Test_DeleteNodeFromExport_Error() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Get").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.DeleteNodeFromExport(100, "RW", false, "10.20.30.40")
+	_, err := service.DeleteNodeFromExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1347,7 +1717,7 @@ func (suite *ApiTestSuite) Test_DeleteNodeFromExport_already_deleted() {
 
 	suite.clientMock.On("Get").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.DeleteNodeFromExport(100, "RW", false, "10.20.30.40")
+	_, err := service.DeleteNodeFromExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
@@ -1371,7 +1741,7 @@ func (suite *ApiTestSuite) Test_DeleteNodeFromExport_updateError() {
 	expectedErr := errors.New("some error")
 	suite.clientMock.On("Put").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.DeleteNodeFromExport(100, "RW", false, "10.20.30.40")
+	_, err := service.DeleteNodeFromExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.NotNil(suite.T(), err, "Error should not be nil")
 }
@@ -1396,18 +1766,18 @@ func (suite *ApiTestSuite) Test_DeleteNodeFromExport_update_success() {
 	response := client.ApiResponse{Result: ExportResponse{}}
 	suite.clientMock.On("Put").Return(response, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
-	_, err := service.DeleteNodeFromExport(100, "RW", false, "10.20.30.40")
+	_, err := service.DeleteNodeFromExport(context.Background(), 100, "RW", false, "10.20.30.40")
 	// Assert
 	assert.Nil(suite.T(), err, "Error should not be nil")
 }
 
 func (suite *ApiTestSuite) Test_GetFileSystemCountByPoolID_success() {
 	expectedResponse := client.ApiResponse{Result: getFilesystemArry(), MetaData: client.Resultmetadata{NoOfObject: 100}}
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var poolID int64 = 1
-	response, err := service.GetFileSystemCountByPoolID(poolID)
+	response, err := service.GetFileSystemCountByPoolID(context.Background(), poolID)
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
 	assert.Equal(suite.T(), 100, response, "response should be nil")
@@ -1415,21 +1785,21 @@ func (suite *ApiTestSuite) Test_GetFileSystemCountByPoolID_success() {
 
 func (suite *ApiTestSuite) Test_GetFileSystemCountByPoolID_Error() {
 	expectedErr := errors.New("some error")
-	suite.clientMock.On("Get").Return(nil, expectedErr)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var poolID int64 = 1
-	_, err := service.GetFileSystemCountByPoolID(poolID)
+	_, err := service.GetFileSystemCountByPoolID(context.Background(), poolID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
 
 func (suite *ApiTestSuite) Test_GetFileSystemCountByPoolID_Panic() {
-	suite.clientMock.On("Get").Return(nil, nil)
+	suite.clientMock.On("GetWithQueryString").Return(nil, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var poolID int64 = 1
-	_, err := service.GetFileSystemCountByPoolID(poolID)
+	_, err := service.GetFileSystemCountByPoolID(context.Background(), poolID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
@@ -1443,24 +1813,24 @@ func (suite *ApiTestSuite) Test_GetTreeqSizeByFileSystemID_success() {
 	treeqArr = append(treeqArr, tq)
 
 	expectedResponse := client.ApiResponse{Result: treeqArr}
-	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	suite.clientMock.On("GetWithQueryString").Return(expectedResponse, nil)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var filesystemID int64 = 100
-	_, err := service.GetTreeqSizeByFileSystemID(filesystemID)
+	response, err := service.GetTreeqSizeByFileSystemID(context.Background(), filesystemID)
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
-	// assert.Equal(suite.T(), 100, response, "response should be nil")
+	assert.Equal(suite.T(), int64(100), response, "response should be nil")
 }
 
 func (suite *ApiTestSuite) Test_GetTreeqSizeByFileSystemID_Error() {
 	// expectedResponse := client.ApiResponse{Result: treeqArr}
 	expecteErr := errors.New("some Error")
-	suite.clientMock.On("Get").Return(nil, expecteErr)
+	suite.clientMock.On("GetWithQueryString").Return(nil, expecteErr)
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var filesystemID int64 = 100
-	_, err := service.GetTreeqSizeByFileSystemID(filesystemID)
+	_, err := service.GetTreeqSizeByFileSystemID(context.Background(), filesystemID)
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 	// assert.Equal(suite.T(), 100, response, "response should be nil")
@@ -1472,7 +1842,7 @@ func (suite *ApiTestSuite) Test_GetTreeqByName_Error() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var filesystemID int64 = 100
-	_, err := service.GetTreeqByName(filesystemID, "treeqName")
+	_, err := service.GetTreeqByName(context.Background(), filesystemID, "treeqName")
 	// Assert
 	assert.NotNil(suite.T(), err, "Response should not be nil")
 }
@@ -1490,7 +1860,7 @@ func (suite *ApiTestSuite) Test_GetTreeqByName_success() {
 	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
 	// Act
 	var filesystemID int64 = 100
-	_, err := service.GetTreeqByName(filesystemID, "treeqName")
+	_, err := service.GetTreeqByName(context.Background(), filesystemID, "treeqName")
 	// Assert
 	assert.Nil(suite.T(), err, "Response should not be nil")
 	// assert.Equal(suite.T(), 100, response, "response should be nil")
@@ -1507,6 +1877,177 @@ func getExportResponse() *[]ExportResponse {
 	return &exportRespArry
 }
 
+func (suite *ApiTestSuite) Test_getAPIConfig_retryDefaults() {
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+	hostconfig, err := service.getAPIConfig()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), client.DefaultRetryCount, hostconfig.RetryCount)
+	assert.Equal(suite.T(), client.DefaultRetryWaitTime, hostconfig.RetryWaitTime)
+	assert.Equal(suite.T(), client.DefaultRetryMaxWaitTime, hostconfig.RetryMaxWaitTime)
+}
+
+func (suite *ApiTestSuite) Test_getAPIConfig_retryFromSecret() {
+	secrets := setSecret()
+	secrets["retry_count"] = "0"
+	secrets["retry_wait_ms"] = "250"
+	secrets["retry_max_wait_ms"] = "2000"
+	service := ClientService{api: suite.clientMock, SecretsMap: secrets}
+	hostconfig, err := service.getAPIConfig()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), 0, hostconfig.RetryCount)
+	assert.Equal(suite.T(), 250*time.Millisecond, hostconfig.RetryWaitTime)
+	assert.Equal(suite.T(), 2*time.Second, hostconfig.RetryMaxWaitTime)
+}
+
+func (suite *ApiTestSuite) Test_getAPIConfig_invalidRetryCount() {
+	secrets := setSecret()
+	secrets["retry_count"] = "-1"
+	service := ClientService{api: suite.clientMock, SecretsMap: secrets}
+	_, err := service.getAPIConfig()
+	assert.NotNil(suite.T(), err, "err should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_getAPIConfig_TLS() {
+	secrets := setSecret()
+	service := ClientService{api: suite.clientMock, SecretsMap: secrets}
+	hostconfig, err := service.getAPIConfig()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.False(suite.T(), hostconfig.TLS.InsecureSkipVerify, "TLS verification should be on by default")
+
+	secrets["insecure_skip_verify"] = "true"
+	secrets["cert_fingerprints"] = "AB:CD, ef01"
+	hostconfig, err = service.getAPIConfig()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.True(suite.T(), hostconfig.TLS.InsecureSkipVerify, "TLS verification should be disabled")
+	assert.Equal(suite.T(), []string{"AB:CD", "ef01"}, hostconfig.TLS.PinnedFingerprints)
+
+	secrets["insecure_skip_verify"] = "maybe"
+	_, err = service.getAPIConfig()
+	assert.NotNil(suite.T(), err, "err should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_creationNotFound() {
+	found, _, err := creationNotFound(notFoundError(ErrVolumeNotFound, "volume with given name not found"))
+	assert.False(suite.T(), found, "object should not be found")
+	assert.Nil(suite.T(), err, "err should be nil")
+
+	found, _, err = creationNotFound(&APIError{Code: ErrHostNotFound})
+	assert.False(suite.T(), found, "object should not be found")
+	assert.Nil(suite.T(), err, "err should be nil")
+
+	_, _, err = creationNotFound(errors.New("connection refused"))
+	assert.NotNil(suite.T(), err, "err should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_APIError_helpers() {
+	err := fmt.Errorf("failed to get volume: %w", &APIError{StatusCode: http.StatusNotFound, Code: ErrVolumeNotFound, Message: "Volume not found"})
+	assert.True(suite.T(), HasErrorCode(err, ErrHostNotFound, ErrVolumeNotFound), "wrapped error code should match")
+	assert.True(suite.T(), IsNotFound(err), "VOLUME_NOT_FOUND should be not found")
+	assert.False(suite.T(), IsAlreadyExists(err), "VOLUME_NOT_FOUND should not be already exists")
+
+	assert.True(suite.T(), IsNotFound(&APIError{Code: ErrTreeqIDDoesNotExist}), "TREEQ_ID_DOES_NOT_EXIST should be not found")
+	assert.True(suite.T(), IsNotFound(&APIError{StatusCode: http.StatusNotFound}), "404 without code should be not found")
+	assert.False(suite.T(), IsNotFound(&APIError{StatusCode: http.StatusNotFound, Code: "BAD_REQUEST"}), "code should win over status")
+	assert.True(suite.T(), IsAlreadyExists(&APIError{Code: ErrMappingAlreadyExists}), "MAPPING_ALREADY_EXISTS should be already exists")
+
+	plain := errors.New("VOLUME_NOT_FOUND")
+	assert.False(suite.T(), HasErrorCode(plain, ErrVolumeNotFound), "plain errors carry no code")
+	assert.False(suite.T(), IsNotFound(plain), "plain errors are not api errors")
+}
+
+func (suite *ApiTestSuite) Test_GRPCCode() {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&APIError{StatusCode: http.StatusNotFound, Code: ErrFileSystemNotFound}, codes.NotFound},
+		{&APIError{StatusCode: http.StatusConflict, Code: ErrPortAlreadyBelongsToHost}, codes.AlreadyExists},
+		{&APIError{StatusCode: http.StatusUnauthorized}, codes.Unauthenticated},
+		{&APIError{StatusCode: http.StatusForbidden, Code: "PERMISSION_DENIED"}, codes.PermissionDenied},
+		{&APIError{StatusCode: http.StatusServiceUnavailable}, codes.Unavailable},
+		{&APIError{StatusCode: http.StatusBadRequest, Code: "BAD_REQUEST"}, codes.Internal},
+		{errors.New("connection refused"), codes.Internal},
+		{status.Errorf(codes.Unavailable, "array unavailable"), codes.Unavailable},
+	}
+	for _, test := range tests {
+		assert.Equal(suite.T(), test.code, GRPCCode(test.err), "unexpected code for %v", test.err)
+	}
+}
+
+func (suite *ApiTestSuite) Test_getAllPages() {
+	page1 := client.ApiResponse{Result: []Host{{ID: 1}, {ID: 2}}, MetaData: client.Resultmetadata{Page: 1, TotalPages: 3}}
+	page2 := client.ApiResponse{Result: []Host{{ID: 3}, {ID: 4}}, MetaData: client.Resultmetadata{Page: 2, TotalPages: 3}}
+	page3 := client.ApiResponse{Result: []Host{{ID: 5}}, MetaData: client.Resultmetadata{Page: 3, TotalPages: 3}}
+	suite.clientMock.On("GetWithQueryString").Return(page1, nil).Once()
+	suite.clientMock.On("GetWithQueryString").Return(page2, nil).Once()
+	suite.clientMock.On("GetWithQueryString").Return(page3, nil).Once()
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	hosts := []Host{}
+	err := service.getAllPages(context.Background(), "api/rest/hosts", nil, &hosts)
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), []Host{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}, hosts)
+	suite.clientMock.AssertNumberOfCalls(suite.T(), "GetWithQueryString", 3)
+}
+
+func (suite *ApiTestSuite) Test_getAllPages_errorOnLaterPage() {
+	page1 := client.ApiResponse{Result: []Host{{ID: 1}}, MetaData: client.Resultmetadata{Page: 1, TotalPages: 2}}
+	expectedErr := errors.New("some error")
+	suite.clientMock.On("GetWithQueryString").Return(page1, nil).Once()
+	suite.clientMock.On("GetWithQueryString").Return(nil, expectedErr).Once()
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	hosts := []Host{}
+	err := service.getAllPages(context.Background(), "api/rest/hosts", nil, &hosts)
+	assert.Equal(suite.T(), expectedErr, err)
+}
+
+func (suite *ApiTestSuite) Test_pageSize() {
+	service := ClientService{SecretsMap: setSecret()}
+	size, err := service.pageSize()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), DefaultPageSize, size)
+
+	service.SecretsMap["page_size"] = "250"
+	size, err = service.pageSize()
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), 250, size)
+
+	for _, invalid := range []string{"0", "-1", "1001", "many"} {
+		service.SecretsMap["page_size"] = invalid
+		_, err = service.pageSize()
+		assert.NotNil(suite.T(), err, "page_size %s should be rejected", invalid)
+	}
+}
+
+func (suite *ApiTestSuite) Test_Query_Encode() {
+	query := NewQuery().Eq("name", "pvc-1").In("id", 1, 2, 3).Like("key", "host.").
+		Sort("-size", "id").Fields("id", "name").PageSize(1)
+	values, err := url.ParseQuery(query.Encode())
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), "eq:pvc-1", values.Get("name"))
+	assert.Equal(suite.T(), "in:[1,2,3]", values.Get("id"))
+	assert.Equal(suite.T(), "like:host.", values.Get("key"))
+	assert.Equal(suite.T(), "-size,id", values.Get("sort"))
+	assert.Equal(suite.T(), "id,name", values.Get("fields"))
+	assert.Equal(suite.T(), "1", values.Get("page_size"))
+
+	var empty *Query
+	assert.Equal(suite.T(), "", empty.Encode())
+}
+
+func (suite *ApiTestSuite) Test_Query_page() {
+	query := NewQuery().Eq("pool_id", 10).PageSize(50)
+	assert.Equal(suite.T(), 50, query.pageSize())
+
+	values, err := url.ParseQuery(query.page(3, 50).Encode())
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), "eq:10", values.Get("pool_id"))
+	assert.Equal(suite.T(), "3", values.Get("page"))
+	assert.Equal(suite.T(), "50", values.Get("page_size"))
+	assert.Equal(suite.T(), "", query.values.Get("page"), "paging should not modify the query")
+}
+
 func setSecret() map[string]string {
 	secretMap := make(map[string]string)
 	secretMap["username"] = "admin"
@@ -1515,6 +2056,109 @@ func setSecret() map[string]string {
 	return secretMap
 }
 
+func (suite *ApiTestSuite) Test_GetMetadataValue_Success() {
+	expectedResponse := client.ApiResponse{Result: Metadata{ObjectId: 100, Key: "host.k8s.snapshot_lock_duration", Value: "30d"}}
+	suite.clientMock.On("Get").Return(expectedResponse, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	value, err := service.GetMetadataValue(context.Background(), 100, "host.k8s.snapshot_lock_duration")
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), "30d", value)
+}
+
+func (suite *ApiTestSuite) Test_GetMetadataValue_NotFound() {
+	suite.clientMock.On("Get").Return(nil, notFoundError("METADATA_KEY_NOT_FOUND", "metadata key not found"))
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	value, err := service.GetMetadataValue(context.Background(), 100, "host.k8s.snapshot_lock_duration")
+	// Assert
+	assert.Nil(suite.T(), err, "missing key should not fail")
+	assert.Equal(suite.T(), "", value)
+}
+
+func (suite *ApiTestSuite) Test_GetQosPolicyByName_Success() {
+	policies := []QosPolicy{{ID: 20, Name: "gold", Type: QosPolicyTypeVolume, MaxOps: 1000}}
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: policies}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	policy, err := service.GetQosPolicyByName(context.Background(), "gold")
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), policies[0], *policy, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_GetQosPolicyByName_NotFound() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: []QosPolicy{}}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	_, err := service.GetQosPolicyByName(context.Background(), "gold")
+	// Assert
+	assert.True(suite.T(), HasErrorCode(err, ErrQosPolicyNotFound), "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_CreateQosPolicy_Success() {
+	expected := QosPolicy{ID: 21, Name: "csi-volume-iops1000-bps0", Type: QosPolicyTypeVolume, MaxOps: 1000}
+	suite.clientMock.On("Post").Return(client.ApiResponse{Result: expected}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	policy, err := service.CreateQosPolicy(context.Background(), QosPolicy{Name: expected.Name, Type: expected.Type, MaxOps: 1000})
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), expected, *policy, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_AssignQosPolicy_Error() {
+	expectedErr := errors.New("some error")
+	suite.clientMock.On("Post").Return(nil, expectedErr)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	err := service.AssignQosPolicy(context.Background(), 20, 100)
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_GetLinkByRemoteSystem_NotFound() {
+	suite.clientMock.On("GetWithQueryString").Return(client.ApiResponse{Result: []Link{}}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	_, err := service.GetLinkByRemoteSystem(context.Background(), "ibox-dr")
+	// Assert
+	assert.True(suite.T(), HasErrorCode(err, ErrLinkNotFound), "Error not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_CreateReplica_Success() {
+	expected := Replica{ID: 60, LocalEntityID: 100, LinkID: 5, State: "INITIALIZING"}
+	suite.clientMock.On("Post").Return(client.ApiResponse{Result: expected}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	replica, err := service.CreateReplica(context.Background(), Replica{LocalEntityID: 100, LinkID: 5, RemotePoolID: 40})
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), expected, *replica, "Response not returned as expected")
+}
+
+func (suite *ApiTestSuite) Test_DeleteReplica_Error() {
+	expectedErr := errors.New("some error")
+	suite.clientMock.On("Delete").Return(nil, expectedErr)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	err := service.DeleteReplica(context.Background(), 60)
+	// Assert
+	assert.NotNil(suite.T(), err, "Error should not be nil")
+}
+
+func (suite *ApiTestSuite) Test_ChangeReplicaRole_Success() {
+	expected := Replica{ID: 60, Role: ReplicaRoleSource}
+	suite.clientMock.On("Post").Return(client.ApiResponse{Result: expected}, nil)
+	service := ClientService{api: suite.clientMock, SecretsMap: setSecret()}
+
+	replica, err := service.ChangeReplicaRole(context.Background(), 60)
+	// Assert
+	assert.Nil(suite.T(), err, "Error should be nil")
+	assert.Equal(suite.T(), expected, *replica, "Response not returned as expected")
+}
+
 func getFilesystemArry() []FileSystem {
 	var filesystems []FileSystem
 	fs1 := FileSystem{}
@@ -12,11 +12,18 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	//"infinibox-csi-driver/helper"
@@ -29,6 +36,107 @@ type HostConfig struct {
 	ApiHost  string
 	UserName string
 	Password string
+	// RetryCount is the number of times a failed request is repeated, 0 disables retries
+	RetryCount int
+	// RetryWaitTime is the initial backoff, doubled on every retry up to RetryMaxWaitTime
+	RetryWaitTime    time.Duration
+	RetryMaxWaitTime time.Duration
+	// TLS controls how the management API certificate is verified
+	TLS TLSConfig
+}
+
+// TLSConfig : server certificate verification for the management API. By default the
+// certificate must chain to the system roots or to the given CA certificates.
+type TLSConfig struct {
+	// CACertificate holds PEM encoded CA certificates, CAFile names a file holding them
+	CACertificate string
+	CAFile        string
+	// InsecureSkipVerify disables chain and host name verification
+	InsecureSkipVerify bool
+	// PinnedFingerprints are SHA-256 fingerprints of accepted server certificates, checked
+	// in addition to the chain, and still checked when InsecureSkipVerify is set
+	PinnedFingerprints []string
+}
+
+// key identifies the resty client built for these settings
+func (t TLSConfig) key() string {
+	sum := sha256.Sum256([]byte(t.CACertificate))
+	return fmt.Sprintf("%x|%s|%t|%s", sum, t.CAFile, t.InsecureSkipVerify, strings.Join(t.PinnedFingerprints, ","))
+}
+
+// newTLSClientConfig builds the tls.Config for the settings
+func (t TLSConfig) newTLSClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	caCerts := []byte(t.CACertificate)
+	if t.CAFile != "" {
+		fileCerts, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %v", t.CAFile, err)
+		}
+		caCerts = append(append(caCerts, '\n'), fileCerts...)
+	}
+	if strings.TrimSpace(string(caCerts)) != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("no valid PEM certificate found in the CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(t.PinnedFingerprints) > 0 {
+		pins := make(map[string]bool)
+		for _, pin := range t.PinnedFingerprints {
+			pins[normalizeFingerprint(pin)] = true
+		}
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			// only the leaf is checked, the server proved it owns its key during the handshake
+			if len(rawCerts) > 0 {
+				sum := sha256.Sum256(rawCerts[0])
+				if pins[hex.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+			return errors.New("management API certificate does not match any pinned fingerprint")
+		}
+	}
+	return tlsConfig, nil
+}
+
+// normalizeFingerprint accepts fingerprints as printed by openssl, e.g. AB:CD:..., or plain hex
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(fingerprint)))
+}
+
+const (
+	// DefaultRetryCount is used when the secret does not set retry_count
+	DefaultRetryCount = 3
+	// DefaultRetryWaitTime is used when the secret does not set retry_wait_ms
+	DefaultRetryWaitTime = 1 * time.Second
+	// DefaultRetryMaxWaitTime is used when the secret does not set retry_max_wait_ms
+	DefaultRetryMaxWaitTime = 10 * time.Second
+)
+
+// CreationCheck reports whether the object a POST was meant to create already exists,
+// returning it in the form the caller expects to find in ApiResponse.Result
+type CreationCheck func() (found bool, object interface{}, err error)
+
+type creationCheckKey struct{}
+
+// WithCreationCheck returns a context allowing Post to be repeated after a connection error.
+// Before each repeat the check is run, so an object created by a request whose response was
+// lost is returned instead of being created twice.
+func WithCreationCheck(ctx context.Context, check CreationCheck) context.Context {
+	return context.WithValue(ctx, creationCheckKey{}, check)
+}
+
+func creationCheckFromContext(ctx context.Context) CreationCheck {
+	if ctx == nil {
+		return nil
+	}
+	check, _ := ctx.Value(creationCheckKey{}).(CreationCheck)
+	return check
 }
 
 type Resultmetadata struct {
@@ -44,20 +152,111 @@ type ApiResponse struct {
 	Error    interface{}    `json:"error,omitempty"`
 }
 
-var rClient *resty.Client
+// LoginURL is the management API endpoint opening a session
+const LoginURL = "api/rest/users/login"
+
+// arrayClient is the resty client of one array endpoint, user, password and TLS settings.
+// Requests are authenticated by the session cookie kept in the client's cookie jar.
+type arrayClient struct {
+	client   *resty.Client
+	userName string
+	password string
+
+	sessionMutex sync.Mutex
+	// session counts logins, 0 means not logged in yet
+	session int
+}
+
+var (
+	clientsMutex sync.Mutex
+	// rClients holds one resty client per array endpoint, username and digest of the password
+	// and TLS settings, so that secrets of the same user with different passwords or TLS
+	// settings keep their own sessions. Each client has its own host URL, credentials, timeout,
+	// connection pool and cookie jar, and is never modified once built, so concurrent requests
+	// to different arrays cannot mix them up.
+	rClients = make(map[string]*arrayClient)
+)
 
 // NewRestClient : Initialize http client
 func NewRestClient() (*restclient, error) {
-	if rClient == nil {
-		rClient = resty.New()
-		rClient.SetHeader("Content-Type", "application/json")
-		rClient.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-		rClient.SetDisableWarn(true)
-		rClient.SetTimeout(60 * time.Second)
-	}
 	return &restclient{}, nil
 }
 
+// getRestyClient returns the client of the host endpoint, username, password and TLS settings,
+// creating it on first use
+func getRestyClient(hostconfig HostConfig) (*arrayClient, error) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	passwordSum := sha256.Sum256([]byte(hostconfig.Password))
+	key := fmt.Sprintf("%s|%s|%x|%s", hostconfig.ApiHost, hostconfig.UserName, passwordSum, hostconfig.TLS.key())
+	if cached, ok := rClients[key]; ok {
+		return cached, nil
+	}
+	tlsConfig, err := hostconfig.TLS.newTLSClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if hostconfig.TLS.InsecureSkipVerify {
+		klog.Warningf("TLS verification of the management API certificate is disabled for %s", hostconfig.ApiHost)
+	}
+	rClient := resty.New()
+	rClient.SetHostURL(hostconfig.ApiHost)
+	rClient.SetHeader("Content-Type", "application/json")
+	rClient.SetTLSClientConfig(tlsConfig)
+	rClient.SetDisableWarn(true)
+	rClient.SetTimeout(60 * time.Second)
+	ac := &arrayClient{client: rClient, userName: hostconfig.UserName, password: hostconfig.Password}
+	rClients[key] = ac
+	return ac, nil
+}
+
+// login opens a session unless one newer than the given one is already open, so that
+// requests failing together with a 401 log in only once. A failed login returns its
+// response, which is then checked like the response of the request itself.
+func (ac *arrayClient) login(ctx context.Context, expired int) (session int, failed *resty.Response, err error) {
+	ac.sessionMutex.Lock()
+	defer ac.sessionMutex.Unlock()
+	if ac.session > expired {
+		return ac.session, nil, nil
+	}
+	klog.V(2).Infof("logging in to %s as %s", ac.client.HostURL, ac.userName)
+	body := map[string]string{"username": ac.userName, "password": ac.password}
+	response, err := ac.client.R().SetContext(ctx).SetBody(body).Post(LoginURL)
+	if err != nil {
+		return ac.session, response, err
+	}
+	if response.IsError() {
+		klog.Errorf("login to %s failed with %s", ac.client.HostURL, response.Status())
+		return ac.session, response, nil
+	}
+	ac.session++
+	return ac.session, nil, nil
+}
+
+// authenticated sends a request within a session, logging in again once when the
+// session turns out to have expired
+func (ac *arrayClient) authenticated(ctx context.Context, send func() (*resty.Response, error)) (*resty.Response, error) {
+	ac.sessionMutex.Lock()
+	session := ac.session
+	ac.sessionMutex.Unlock()
+	if session == 0 {
+		var failed *resty.Response
+		var err error
+		if session, failed, err = ac.login(ctx, 0); err != nil || failed != nil {
+			return failed, err
+		}
+	}
+	response, err := send()
+	if err == nil && response.StatusCode() == http.StatusUnauthorized {
+		klog.V(2).Infof("session on %s expired, logging in again", ac.client.HostURL)
+		if _, failed, err := ac.login(ctx, session); err != nil || failed != nil {
+			return failed, err
+		}
+		response, err = send()
+	}
+	return response, err
+}
+
 // RestClient : implement to make rest client
 type RestClient interface {
 	Get(ctx context.Context, url string, hostconfig HostConfig, expectedResp interface{}) (interface{}, error)
@@ -82,12 +281,14 @@ func (rc *restclient) Get(ctx context.Context, url string, hostconfig HostConfig
 			err = errors.New("error in Get() " + fmt.Sprint(res))
 		}
 	}()
-	if err := checkHttpClient(); err != nil {
-		klog.Errorf("checkHttpClient returned err %v ", err)
+	ac, err := getRestyClient(hostconfig)
+	if err != nil {
+		klog.Errorf("getRestyClient returned err %v ", err)
 		return nil, err
 	}
-	response, err := rClient.SetHostURL(hostconfig.ApiHost).
-		SetBasicAuth(hostconfig.UserName, hostconfig.Password).R().Get(url)
+	response, _, err := rc.execute(ctx, ac, http.MethodGet, url, hostconfig, nil, func() (*resty.Response, error) {
+		return ac.client.R().SetContext(ctx).Get(url)
+	})
 	resp, err := rc.checkResponse(response, err, expectedResp)
 	if err != nil {
 		klog.Errorf("error in validating response %v", err)
@@ -106,13 +307,14 @@ func (rc *restclient) GetWithQueryString(ctx context.Context, url string, hostco
 			err = errors.New("error in GetWithQueryString " + fmt.Sprint(res))
 		}
 	}()
-	if err := checkHttpClient(); err != nil {
-		klog.Errorf("checkHttpClient returned err %v  ", err)
+	ac, err := getRestyClient(hostconfig)
+	if err != nil {
+		klog.Errorf("getRestyClient returned err %v ", err)
 		return nil, err
 	}
-	response, err := rClient.SetHostURL(hostconfig.ApiHost).
-		SetBasicAuth(hostconfig.UserName, hostconfig.Password).
-		R().SetQueryString(queryString).Get(url)
+	response, _, err := rc.execute(ctx, ac, http.MethodGet, url, hostconfig, nil, func() (*resty.Response, error) {
+		return ac.client.R().SetContext(ctx).SetQueryString(queryString).Get(url)
+	})
 
 	res, err := rc.checkResponse(response, err, expectedResp)
 	if err != nil {
@@ -132,14 +334,20 @@ func (rc *restclient) Post(ctx context.Context, url string, hostconfig HostConfi
 			err = errors.New("error in Post " + fmt.Sprint(res))
 		}
 	}()
-	if err := checkHttpClient(); err != nil {
-		klog.Errorf("checkHttpClient returned err %v  ", err)
+	ac, err := getRestyClient(hostconfig)
+	if err != nil {
+		klog.Errorf("getRestyClient returned err %v ", err)
 		return nil, err
 	}
-	response, err := rClient.SetHostURL(hostconfig.ApiHost).
-		SetBasicAuth(hostconfig.UserName, hostconfig.Password).R().
-		SetBody(body).
-		Post(url)
+	response, created, err := rc.execute(ctx, ac, http.MethodPost, url, hostconfig, creationCheckFromContext(ctx), func() (*resty.Response, error) {
+		return ac.client.R().SetContext(ctx).
+			SetBody(body).
+			Post(url)
+	})
+	if err == nil && created != nil {
+		klog.V(2).Infof("Post on %s was applied by an earlier attempt", url)
+		return ApiResponse{Result: created}, nil
+	}
 	res, err := rc.checkResponse(response, err, expectedResp)
 	if err != nil {
 		klog.Errorf("error in validating response %v ", err)
@@ -159,13 +367,14 @@ func (rc *restclient) Put(ctx context.Context, url string, hostconfig HostConfig
 			err = errors.New("error in Put " + fmt.Sprint(res))
 		}
 	}()
-	if err := checkHttpClient(); err != nil {
-		klog.Errorf("checkHttpClient returned err %v ", err)
+	ac, err := getRestyClient(hostconfig)
+	if err != nil {
+		klog.Errorf("getRestyClient returned err %v ", err)
 		return nil, err
 	}
-	response, err := rClient.SetHostURL(hostconfig.ApiHost).
-		SetBasicAuth(hostconfig.UserName, hostconfig.Password).
-		R().SetBody(body).Put(url)
+	response, _, err := rc.execute(ctx, ac, http.MethodPut, url, hostconfig, nil, func() (*resty.Response, error) {
+		return ac.client.R().SetContext(ctx).SetBody(body).Put(url)
+	})
 	res, err := rc.checkResponse(response, err, expectedResp)
 	if err != nil {
 		klog.Errorf("error in validating response %v ", err)
@@ -184,13 +393,14 @@ func (rc *restclient) Delete(ctx context.Context, url string, hostconfig HostCon
 		}
 	}()
 	klog.V(2).Infof("called client.Delete with url %s  ", url)
-	if err := checkHttpClient(); err != nil {
-		klog.Errorf("checkHttpClient returned err %v ", err)
+	ac, err := getRestyClient(hostconfig)
+	if err != nil {
+		klog.Errorf("getRestyClient returned err %v ", err)
 		return nil, err
 	}
-	response, err := rClient.SetHostURL(hostconfig.ApiHost).
-		SetBasicAuth(hostconfig.UserName, hostconfig.Password).
-		R().Delete(url)
+	response, _, err := rc.execute(ctx, ac, http.MethodDelete, url, hostconfig, nil, func() (*resty.Response, error) {
+		return ac.client.R().SetContext(ctx).Delete(url)
+	})
 	res, err := rc.checkResponse(response, err, nil)
 	if err != nil {
 		klog.Errorf("checkResponse returned error: %+v", err)
@@ -200,12 +410,101 @@ func (rc *restclient) Delete(ctx context.Context, url string, hostconfig HostCon
 	return res, err
 }
 
-func checkHttpClient() error {
-	if rClient == nil {
-		_, err := NewRestClient()
-		return err
+// execute sends a request, repeating it with exponential backoff and jitter while the failure
+// is one that is safe to retry for the method. For POST, the creation check is consulted
+// before every retry of a request with unknown outcome; an object found by it is returned in
+// place of a response.
+func (rc *restclient) execute(ctx context.Context, ac *arrayClient, method, url string, hostconfig HostConfig, check CreationCheck,
+	send func() (*resty.Response, error)) (response *resty.Response, created interface{}, err error) {
+	for attempt := 0; ; attempt++ {
+		response, err = ac.authenticated(ctx, send)
+		if attempt >= hostconfig.RetryCount || !isRetryable(ctx, method, response, err, check != nil) {
+			return response, nil, err
+		}
+		wait := retryWaitTime(hostconfig, attempt)
+		if err != nil {
+			klog.Warningf("%s %s failed with error %v, retry %d of %d in %s", method, url, err, attempt+1, hostconfig.RetryCount, wait)
+		} else {
+			klog.Warningf("%s %s failed with status %s, retry %d of %d in %s", method, url, response.Status(), attempt+1, hostconfig.RetryCount, wait)
+		}
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, nil, err
+		case <-timer.C:
+		}
+		if check != nil && outcomeUnknown(response, err) {
+			found, object, checkErr := check()
+			if checkErr != nil {
+				klog.Errorf("%s %s: unable to check whether the earlier attempt was applied: %v", method, url, checkErr)
+				return response, nil, err
+			}
+			if found {
+				return response, object, nil
+			}
+		}
 	}
-	return nil
+}
+
+// isRetryable decides whether a failed request may be sent again. A 503 or 429 means the
+// request was not processed, so any method can be repeated. A connection error or a gateway
+// failure leaves the outcome unknown: only idempotent methods, and POSTs whose result can be
+// checked, are repeated then.
+func isRetryable(ctx context.Context, method string, response *resty.Response, err error, canCheck bool) bool {
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	idempotent := method != http.MethodPost || canCheck
+	if err != nil {
+		return idempotent
+	}
+	if response == nil {
+		return false
+	}
+	switch response.StatusCode() {
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// outcomeUnknown reports whether a failed request may have been applied by the array: the
+// connection failed, or a gateway gave up waiting for the answer
+func outcomeUnknown(response *resty.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if response == nil {
+		return false
+	}
+	status := response.StatusCode()
+	return status == http.StatusBadGateway || status == http.StatusGatewayTimeout
+}
+
+// retryWaitTime returns the backoff before the given retry, doubling from RetryWaitTime up to
+// RetryMaxWaitTime. Half of it is randomized so that callers failing together spread out.
+func retryWaitTime(hostconfig HostConfig, attempt int) time.Duration {
+	wait := hostconfig.RetryWaitTime
+	if wait <= 0 {
+		wait = DefaultRetryWaitTime
+	}
+	maxWait := hostconfig.RetryMaxWaitTime
+	if maxWait <= 0 {
+		maxWait = DefaultRetryMaxWaitTime
+	}
+	for i := 0; i < attempt && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
 }
 
 // Method to check the response is valid or not
@@ -216,12 +515,19 @@ func (rc *restclient) checkResponse(res *resty.Response, err error, respStruct i
 		}
 	}()
 
+	if res == nil {
+		if err == nil {
+			err = errors.New("empty response")
+		}
+		return apiresp, err
+	}
+
 	if res.StatusCode() == http.StatusUnauthorized {
-		return apiresp, errors.New("Request authentication failed for: " + res.Request.URL)
+		return apiresp, &APIError{StatusCode: res.StatusCode(), Message: "Request authentication failed for: " + res.Request.URL, URL: res.Request.URL}
 	}
 
 	if res.StatusCode() == http.StatusServiceUnavailable {
-		return apiresp, errors.New(res.Status() + " for: " + res.Request.URL)
+		return apiresp, &APIError{StatusCode: res.StatusCode(), Message: res.Status() + " for: " + res.Request.URL, URL: res.Request.URL}
 	}
 
 	if err != nil {
@@ -236,10 +542,10 @@ func (rc *restclient) checkResponse(res *resty.Response, err error, respStruct i
 			return apiresp, err
 		}
 		if res != nil {
-			if str, iserr := rc.parseError(apiresp.Error); iserr {
+			if apiErr := rc.parseError(res, apiresp.Error); apiErr != nil {
 				klog.Errorf("checkResponse: %s", res)
-				klog.Errorf("checkResponse parseError, err: %s", str)
-				return apiresp, errors.New(str)
+				klog.Errorf("checkResponse parseError, err: %s", apiErr)
+				return apiresp, apiErr
 			}
 			if apiresp.Result == nil {
 				return apiresp, errors.New("result part of response is nil for request " + res.Request.URL)
@@ -259,9 +565,9 @@ func (rc *restclient) checkResponse(res *resty.Response, err error, respStruct i
 		if res != nil {
 			responseinmap := response.(map[string]interface{})
 			if responseinmap != nil {
-				if str, iserr := rc.parseError(responseinmap["error"]); iserr {
-					klog.Errorf("checkResponse parseError, err: %s", str)
-					return apiresp, errors.New(str)
+				if apiErr := rc.parseError(res, responseinmap["error"]); apiErr != nil {
+					klog.Errorf("checkResponse parseError, err: %s", apiErr)
+					return apiresp, apiErr
 				}
 				apiresp.Result = responseinmap["result"]
 				if apiresp.Result == nil {
@@ -278,18 +584,36 @@ func (rc *restclient) checkResponse(res *resty.Response, err error, respStruct i
 	}
 }
 
+// APIError is an error reported by the management api. Code holds the
+// InfiniBox error code (e.g. VOLUME_NOT_FOUND) when the array returned one.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	URL        string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + " " + e.Message
+}
+
 // Method to check error response from management api
-func (rc *restclient) parseError(responseinmap interface{}) (str string, iserr bool) {
+func (rc *restclient) parseError(res *resty.Response, responseinmap interface{}) (apiErr *APIError) {
+	if responseinmap == nil {
+		return nil
+	}
+	apiErr = &APIError{StatusCode: res.StatusCode(), URL: res.Request.URL}
 	defer func() {
-		if res := recover(); res != nil {
-			str = "recovered in parseError  " + fmt.Sprint(res)
-			iserr = true
+		if recovered := recover(); recovered != nil {
+			apiErr.Message = "recovered in parseError  " + fmt.Sprint(recovered)
 		}
 	}()
 
-	if responseinmap != nil {
-		resultmap := responseinmap.(map[string]interface{})
-		return resultmap["code"].(string) + " " + resultmap["message"].(string), true
-	}
-	return "", false
+	resultmap := responseinmap.(map[string]interface{})
+	apiErr.Code = resultmap["code"].(string)
+	apiErr.Message = resultmap["message"].(string)
+	return apiErr
 }
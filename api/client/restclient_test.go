@@ -0,0 +1,424 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObject struct {
+	ID int `json:"id,omitempty"`
+}
+
+func getHostConfig(apiHost string) HostConfig {
+	return HostConfig{
+		ApiHost:          apiHost,
+		UserName:         "admin",
+		Password:         "123456",
+		RetryCount:       2,
+		RetryWaitTime:    time.Millisecond,
+		RetryMaxWaitTime: 5 * time.Millisecond,
+	}
+}
+
+// withLogin answers login requests with a session cookie and passes on all others
+func withLogin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+LoginURL {
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+			_, _ = w.Write([]byte(`{"result":{},"error":null,"metadata":{}}`))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getTestServer answers with the given status codes in turn, then with a result
+func getTestServer(calls *int32, statuses ...int) *httptest.Server {
+	return httptest.NewServer(withLogin(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(calls, 1)
+		if int(call) <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"id":100},"error":null,"metadata":{}}`))
+	}))
+}
+
+// getDroppingServer closes the connection of the first request without answering
+func getDroppingServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(withLogin(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"id":100},"error":null,"metadata":{}}`))
+	}))
+}
+
+func Test_Get_retryOnServiceUnavailable(t *testing.T) {
+	var calls int32
+	server := getTestServer(&calls, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	resp, err := rc.Get(context.Background(), "api/rest/volumes/100", getHostConfig(server.URL), &testObject{})
+	assert.Nil(t, err, "expected GET to succeed after retries")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, 100, resp.(ApiResponse).Result.(*testObject).ID)
+}
+
+func Test_Get_retriesExhausted(t *testing.T) {
+	var calls int32
+	server := getTestServer(&calls, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getHostConfig(server.URL), &testObject{})
+	assert.NotNil(t, err, "expected GET to fail once retries are exhausted")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func Test_Get_noRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := getTestServer(&calls, http.StatusBadRequest)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, _ = rc.Get(context.Background(), "api/rest/volumes/100", getHostConfig(server.URL), &testObject{})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_Post_retryOnServiceUnavailable(t *testing.T) {
+	var calls int32
+	server := getTestServer(&calls, http.StatusServiceUnavailable)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Post(context.Background(), "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.Nil(t, err, "expected POST to succeed after a 503")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_Post_noRetryOnConnectionErrorWithoutCheck(t *testing.T) {
+	var calls int32
+	server := getDroppingServer(&calls)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Post(context.Background(), "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.NotNil(t, err, "expected POST without creation check not to be repeated")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_Post_connectionErrorObjectCreated(t *testing.T) {
+	var calls int32
+	server := getDroppingServer(&calls)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	ctx := WithCreationCheck(context.Background(), func() (bool, interface{}, error) {
+		return true, testObject{ID: 200}, nil
+	})
+	resp, err := rc.Post(ctx, "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.Nil(t, err, "expected POST to return the object created by the lost request")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 200, resp.(ApiResponse).Result.(testObject).ID)
+}
+
+func Test_Post_connectionErrorObjectNotCreated(t *testing.T) {
+	var calls int32
+	server := getDroppingServer(&calls)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	ctx := WithCreationCheck(context.Background(), func() (bool, interface{}, error) {
+		return false, nil, nil
+	})
+	_, err := rc.Post(ctx, "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.Nil(t, err, "expected POST to be repeated")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_Post_gatewayTimeoutObjectCreated(t *testing.T) {
+	var calls int32
+	server := getTestServer(&calls, http.StatusGatewayTimeout)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	ctx := WithCreationCheck(context.Background(), func() (bool, interface{}, error) {
+		return true, testObject{ID: 200}, nil
+	})
+	resp, err := rc.Post(ctx, "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.Nil(t, err, "expected POST to return the object created behind the gateway timeout")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 200, resp.(ApiResponse).Result.(testObject).ID)
+}
+
+func Test_Post_serviceUnavailableNotChecked(t *testing.T) {
+	var calls, checks int32
+	server := getTestServer(&calls, http.StatusServiceUnavailable)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	ctx := WithCreationCheck(context.Background(), func() (bool, interface{}, error) {
+		atomic.AddInt32(&checks, 1)
+		return false, nil, nil
+	})
+	_, err := rc.Post(ctx, "api/rest/volumes", getHostConfig(server.URL), map[string]string{}, &testObject{})
+	assert.Nil(t, err, "expected POST to succeed after a 503")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&checks), "expected no creation check after a request the array did not process")
+}
+
+func Test_retryWaitTime(t *testing.T) {
+	hostconfig := HostConfig{RetryWaitTime: 100 * time.Millisecond, RetryMaxWaitTime: 300 * time.Millisecond}
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		wait := retryWaitTime(hostconfig, attempt)
+		assert.True(t, wait >= max*time.Millisecond/2 && wait <= max*time.Millisecond, "attempt %d waited %s", attempt, wait)
+	}
+}
+
+func getTLSTestServer() *httptest.Server {
+	return httptest.NewTLSServer(withLogin(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result":{"id":100},"error":null,"metadata":{}}`))
+	}))
+}
+
+func getTLSHostConfig(server *httptest.Server, tlsConfig TLSConfig) HostConfig {
+	hostconfig := getHostConfig(server.URL)
+	hostconfig.RetryCount = 0
+	hostconfig.TLS = tlsConfig
+	return hostconfig
+}
+
+func Test_Get_TLSUnknownAuthority(t *testing.T) {
+	server := getTLSTestServer()
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, TLSConfig{}), &testObject{})
+	assert.NotNil(t, err, "expected certificate verification to fail by default")
+}
+
+func Test_Get_TLSCACertificate(t *testing.T) {
+	server := getTLSTestServer()
+	defer server.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, TLSConfig{CACertificate: caCert}), &testObject{})
+	assert.Nil(t, err, "expected certificate to verify against the CA certificate")
+}
+
+func Test_Get_TLSInvalidCACertificate(t *testing.T) {
+	server := getTLSTestServer()
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, TLSConfig{CACertificate: "not a certificate"}), &testObject{})
+	assert.NotNil(t, err, "expected an invalid CA certificate to be rejected")
+}
+
+func Test_Get_TLSInsecureSkipVerify(t *testing.T) {
+	server := getTLSTestServer()
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, TLSConfig{InsecureSkipVerify: true}), &testObject{})
+	assert.Nil(t, err, "expected insecure mode to skip verification")
+}
+
+func Test_Get_TLSPinnedFingerprint(t *testing.T) {
+	server := getTLSTestServer()
+	defer server.Close()
+	sum := sha256.Sum256(server.Certificate().Raw)
+	rc, _ := NewRestClient()
+
+	pinned := TLSConfig{InsecureSkipVerify: true, PinnedFingerprints: []string{hex.EncodeToString(sum[:])}}
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, pinned), &testObject{})
+	assert.Nil(t, err, "expected the pinned certificate to be accepted")
+
+	mismatch := TLSConfig{InsecureSkipVerify: true, PinnedFingerprints: []string{"AB:CD"}}
+	_, err = rc.Get(context.Background(), "api/rest/volumes/100", getTLSHostConfig(server, mismatch), &testObject{})
+	assert.NotNil(t, err, "expected a certificate not matching the pin to be rejected")
+}
+
+// getArrayTestServer logs in only the user of its array and answers requests carrying the
+// session cookie of that array with the array ID
+func getArrayTestServer(arrayID int, userName, password string) *httptest.Server {
+	sessionID := "array" + strconv.Itoa(arrayID)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+LoginURL {
+			creds := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&creds)
+			if creds["username"] != userName || creds["password"] != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: sessionID, Path: "/"})
+			_, _ = w.Write([]byte(`{"result":{},"error":null,"metadata":{}}`))
+			return
+		}
+		if cookie, err := r.Cookie("JSESSIONID"); err != nil || cookie.Value != sessionID {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"id":` + strconv.Itoa(arrayID) + `},"error":null,"metadata":{}}`))
+	}))
+}
+
+func Test_Get_parallelArrays(t *testing.T) {
+	arrays := []struct {
+		id     int
+		server *httptest.Server
+		config HostConfig
+	}{
+		{id: 1}, {id: 2},
+	}
+	for i := range arrays {
+		userName := "user" + strconv.Itoa(arrays[i].id)
+		password := "password" + strconv.Itoa(arrays[i].id)
+		arrays[i].server = getArrayTestServer(arrays[i].id, userName, password)
+		defer arrays[i].server.Close()
+		arrays[i].config = HostConfig{ApiHost: arrays[i].server.URL, UserName: userName, Password: password}
+	}
+
+	var wg sync.WaitGroup
+	var failures int32
+	for i := 0; i < 50; i++ {
+		for _, array := range arrays {
+			wg.Add(1)
+			go func(arrayID int, hostconfig HostConfig) {
+				defer wg.Done()
+				rc, _ := NewRestClient()
+				resp, err := rc.Get(context.Background(), "api/rest/system", hostconfig, &testObject{})
+				if err != nil || resp.(ApiResponse).Result.(*testObject).ID != arrayID {
+					atomic.AddInt32(&failures, 1)
+				}
+			}(array.id, array.config)
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failures), "requests were sent to the wrong array or with the wrong credentials")
+}
+
+func Test_getRestyClient_perArray(t *testing.T) {
+	first, err := getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "secret"})
+	assert.Nil(t, err)
+	second, err := getRestyClient(HostConfig{ApiHost: "https://array2/", UserName: "admin", Password: "secret"})
+	assert.Nil(t, err)
+	assert.NotSame(t, first, second, "expected a client per array")
+
+	again, _ := getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "secret"})
+	assert.Same(t, first, again, "expected the client to be cached")
+
+	rotated, _ := getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "changed"})
+	assert.NotSame(t, first, rotated, "expected a new client after a password change")
+	again, _ = getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "secret"})
+	assert.Same(t, first, again, "expected the clients of both passwords to stay cached")
+
+	insecure, _ := getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "secret", TLS: TLSConfig{InsecureSkipVerify: true}})
+	assert.NotSame(t, first, insecure, "expected a client per TLS settings")
+	again, _ = getRestyClient(HostConfig{ApiHost: "https://array1/", UserName: "admin", Password: "secret"})
+	assert.Same(t, first, again, "expected the clients of both TLS settings to stay cached")
+}
+
+func Test_Get_contextDeadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(withLogin(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"result":{"id":100},"error":null,"metadata":{}}`))
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(ctx, "api/rest/volumes/100", getHostConfig(server.URL), &testObject{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "expected no retry once the deadline expired")
+}
+
+// getSessionTestServer counts logins and expires the session after the first request
+func getSessionTestServer(logins, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+LoginURL {
+			login := atomic.AddInt32(logins, 1)
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session" + strconv.Itoa(int(login)), Path: "/"})
+			_, _ = w.Write([]byte(`{"result":{},"error":null,"metadata":{}}`))
+			return
+		}
+		if _, _, ok := r.BasicAuth(); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		call := atomic.AddInt32(calls, 1)
+		if cookie, err := r.Cookie("JSESSIONID"); err != nil || (call > 1 && cookie.Value == "session1") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result":{"id":100},"error":null,"metadata":{}}`))
+	}))
+}
+
+func Test_Get_sessionReauthentication(t *testing.T) {
+	var logins, calls int32
+	server := getSessionTestServer(&logins, &calls)
+	defer server.Close()
+	rc, _ := NewRestClient()
+	hostconfig := getHostConfig(server.URL)
+
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", hostconfig, &testObject{})
+	assert.Nil(t, err, "expected the first request to log in")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+
+	_, err = rc.Get(context.Background(), "api/rest/volumes/100", hostconfig, &testObject{})
+	assert.Nil(t, err, "expected the expired session to be renewed")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+
+	_, err = rc.Get(context.Background(), "api/rest/volumes/100", hostconfig, &testObject{})
+	assert.Nil(t, err, "expected the renewed session to be reused")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+}
+
+func Test_Get_loginFailed(t *testing.T) {
+	server := getArrayTestServer(3, "user3", "password3")
+	defer server.Close()
+	rc, _ := NewRestClient()
+	hostconfig := getHostConfig(server.URL)
+	hostconfig.UserName = "user3"
+	hostconfig.Password = "wrong"
+	_, err := rc.Get(context.Background(), "api/rest/system", hostconfig, &testObject{})
+	assert.NotNil(t, err, "expected the login to fail")
+	assert.Contains(t, err.Error(), "authentication failed")
+}
+
+func Test_Get_APIError(t *testing.T) {
+	server := httptest.NewServer(withLogin(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"result":null,"error":{"code":"VOLUME_NOT_FOUND","message":"Volume 100 not found"},"metadata":{"ready":true}}`))
+	}))
+	defer server.Close()
+	rc, _ := NewRestClient()
+	_, err := rc.Get(context.Background(), "api/rest/volumes/100", getHostConfig(server.URL), &testObject{})
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr), "expected an APIError, got %v", err) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "VOLUME_NOT_FOUND", apiErr.Code)
+		assert.Equal(t, "Volume 100 not found", apiErr.Message)
+		assert.Contains(t, apiErr.URL, "api/rest/volumes/100")
+		assert.Equal(t, "VOLUME_NOT_FOUND Volume 100 not found", err.Error())
+	}
+}
@@ -23,6 +23,8 @@ import (
 type KubeClient interface {
 	GetSecret(secretName, nameSpace string) (map[string]string, error)
 	GetClusterVerion() (string, error)
+	GetPersistantVolumeByName(volumeName string) (*v1.PersistentVolume, error)
+	GetPersistentVolumeClaim(name, nameSpace string) (*v1.PersistentVolumeClaim, error)
 }
 
 type kubeclient struct {
@@ -105,6 +107,54 @@ func (kc *kubeclient) GetPersistantVolumeByName(volumeName string) (*v1.Persiste
 	return persistVol, nil
 }
 
+// GetPersistentVolumes returns the PVs of the cluster
+func (kc *kubeclient) GetPersistentVolumes() ([]v1.PersistentVolume, error) {
+	pvs, err := kc.client.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("Error listing PVs: %v", err)
+		return nil, err
+	}
+	return pvs.Items, nil
+}
+
+// GetPersistentVolumeClaim returns the named PVC of a namespace
+func (kc *kubeclient) GetPersistentVolumeClaim(name, nameSpace string) (*v1.PersistentVolumeClaim, error) {
+	pvc, err := kc.client.CoreV1().PersistentVolumeClaims(nameSpace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Error getting PVC %s/%s: %v", nameSpace, name, err)
+		return nil, err
+	}
+	return pvc, nil
+}
+
+// GetClusterID returns the UID of the kube-system namespace, which identifies the cluster
+func (kc *kubeclient) GetClusterID() (string, error) {
+	ns, err := kc.client.CoreV1().Namespaces().Get(context.TODO(), "kube-system", metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Error getting kube-system namespace: %v", err)
+		return "", err
+	}
+	return string(ns.UID), nil
+}
+
+// GetCSINodeIDs returns the node IDs the CSI driver of driverName reported on the nodes of the cluster
+func (kc *kubeclient) GetCSINodeIDs(driverName string) ([]string, error) {
+	csiNodes, err := kc.client.StorageV1().CSINodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("Error listing CSINodes: %v", err)
+		return nil, err
+	}
+	nodeIDs := []string{}
+	for _, csiNode := range csiNodes.Items {
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name == driverName {
+				nodeIDs = append(nodeIDs, driver.NodeID)
+			}
+		}
+	}
+	return nodeIDs, nil
+}
+
 func (kc *kubeclient) GetNodeIdByNodeName(nodeName string) (InternalIp string, err error) {
 	node, err := kc.client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
 	if err != nil {
@@ -0,0 +1,120 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package api
+
+import (
+	"errors"
+	"infinibox-csi-driver/api/client"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is an error reported by the management api, carrying the http status,
+// InfiniBox error code, message and request url
+type APIError = client.APIError
+
+// InfiniBox error codes the driver acts upon
+const (
+	ErrVolumeNotFound                  = "VOLUME_NOT_FOUND"
+	ErrHostNotFound                    = "HOST_NOT_FOUND"
+	ErrHostPortNotFound                = "HOST_PORT_NOT_FOUND"
+	ErrLunNotFound                     = "LUN_NOT_FOUND"
+	ErrFileSystemNotFound              = "FILESYSTEM_NOT_FOUND"
+	ErrExportNotFound                  = "EXPORT_NOT_FOUND"
+	ErrTreeqNotFound                   = "TREEQ_NOT_FOUND"
+	ErrTreeqIDDoesNotExist             = "TREEQ_ID_DOES_NOT_EXIST"
+	ErrPoolNotFound                    = "POOL_NOT_FOUND"
+	ErrQosPolicyNotFound               = "QOS_POLICY_NOT_FOUND"
+	ErrLinkNotFound                    = "LINK_NOT_FOUND"
+	ErrReplicaNotFound                 = "REPLICA_NOT_FOUND"
+	ErrMappingAlreadyExists            = "MAPPING_ALREADY_EXISTS"
+	ErrPortAlreadyBelongsToHost        = "PORT_ALREADY_BELONGS_TO_HOST"
+	ErrMetadataIsNotSupportedForEntity = "METADATA_IS_NOT_SUPPORTED_FOR_ENTITY"
+)
+
+// notFoundError returns the error of a lookup by name which matched no object
+func notFoundError(code, message string) error {
+	return &APIError{StatusCode: http.StatusNotFound, Code: code, Message: message}
+}
+
+// AsAPIError returns the management api error in the chain of err, if any
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// HasErrorCode reports whether err is a management api error with one of the given codes
+func HasErrorCode(err error, codes ...string) bool {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err tells the requested object does not exist on the array
+func IsNotFound(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	if apiErr.Code == "" {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return strings.HasSuffix(apiErr.Code, "_NOT_FOUND") || strings.HasSuffix(apiErr.Code, "_DOES_NOT_EXIST")
+}
+
+// IsAlreadyExists reports whether err tells the object, mapping or port is already present on the array
+func IsAlreadyExists(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	return strings.Contains(apiErr.Code, "ALREADY")
+}
+
+// GRPCCode maps a management api error to the gRPC status code reported to the CO, a gRPC
+// status error keeps its code and other errors map to codes.Internal
+func GRPCCode(err error) codes.Code {
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		if st, isStatus := status.FromError(err); isStatus && err != nil {
+			return st.Code()
+		}
+		return codes.Internal
+	}
+	switch {
+	case IsNotFound(err):
+		return codes.NotFound
+	case IsAlreadyExists(err):
+		return codes.AlreadyExists
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
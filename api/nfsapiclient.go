@@ -12,11 +12,13 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/api/client"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -25,7 +27,7 @@ import (
 )
 
 // OneTimeValidation :
-func (c *ClientService) OneTimeValidation(poolname string, networkspace string) (list string, err error) {
+func (c *ClientService) OneTimeValidation(ctx context.Context, poolname string, networkspace string) (list string, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("error while One Time Validation   " + fmt.Sprint(res))
@@ -33,7 +35,7 @@ func (c *ClientService) OneTimeValidation(poolname string, networkspace string)
 	}()
 	// validating pool
 	validList := ""
-	_, err = c.GetStoragePoolIDByName(poolname)
+	_, err = c.GetStoragePoolIDByName(ctx, poolname)
 	if err != nil {
 		return validList, err
 	}
@@ -42,7 +44,7 @@ func (c *ClientService) OneTimeValidation(poolname string, networkspace string)
 	var arrayOfValidnetspaces []string
 
 	for _, name := range arrayofNetworkSpaces {
-		nspace, err := c.GetNetworkSpaceByName(name)
+		nspace, err := c.GetNetworkSpaceByName(ctx, name)
 		if err != nil {
 			klog.Errorf(err.Error())
 		}
@@ -59,7 +61,7 @@ func (c *ClientService) OneTimeValidation(poolname string, networkspace string)
 }
 
 // DeleteExportPath :
-func (c *ClientService) DeleteExportPath(exportID int64) (*ExportResponse, error) {
+func (c *ClientService) DeleteExportPath(ctx context.Context, exportID int64) (*ExportResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -69,7 +71,7 @@ func (c *ClientService) DeleteExportPath(exportID int64) (*ExportResponse, error
 	klog.V(2).Infof("Deleting export path with ID %d", exportID)
 	uri := "api/rest/exports/" + strconv.FormatInt(exportID, 10) + "?approved=true"
 	eResp := ExportResponse{}
-	resp, err := c.getJSONResponse(http.MethodDelete, uri, nil, &eResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodDelete, uri, nil, &eResp)
 	if err != nil {
 		klog.Errorf("Error occured while deleting export path : %s ", err)
 		return nil, err
@@ -83,7 +85,7 @@ func (c *ClientService) DeleteExportPath(exportID int64) (*ExportResponse, error
 }
 
 // DeleteFileSystem :
-func (c *ClientService) DeleteFileSystem(fileSystemID int64) (*FileSystem, error) {
+func (c *ClientService) DeleteFileSystem(ctx context.Context, fileSystemID int64) (*FileSystem, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -93,7 +95,7 @@ func (c *ClientService) DeleteFileSystem(fileSystemID int64) (*FileSystem, error
 	klog.V(2).Infof("Delete filesystem with ID %d", fileSystemID)
 	uri := "api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "?approved=true"
 	fileSystem := FileSystem{}
-	resp, err := c.getJSONResponse(http.MethodDelete, uri, nil, &fileSystem)
+	resp, err := c.getJSONResponse(ctx, http.MethodDelete, uri, nil, &fileSystem)
 	if err != nil {
 		klog.Errorf("Error occured while deleting file System : %s ", err)
 		return nil, err
@@ -107,7 +109,7 @@ func (c *ClientService) DeleteFileSystem(fileSystemID int64) (*FileSystem, error
 }
 
 // AttachMetadataToObject :
-func (c *ClientService) AttachMetadataToObject(objectID int64, body map[string]interface{}) (*[]Metadata, error) {
+func (c *ClientService) AttachMetadataToObject(ctx context.Context, objectID int64, body map[string]interface{}) (*[]Metadata, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -117,7 +119,7 @@ func (c *ClientService) AttachMetadataToObject(objectID int64, body map[string]i
 	klog.V(2).Infof("Attach metadata: %v to object id: %d", body, objectID)
 	uri := "api/rest/metadata/" + strconv.FormatInt(objectID, 10)
 	metadata := []Metadata{}
-	resp, err := c.getJSONResponse(http.MethodPut, uri, body, &metadata)
+	resp, err := c.getJSONResponse(ctx, http.MethodPut, uri, body, &metadata)
 	if err != nil {
 		klog.Errorf("Error occured while attaching metadata to object id: %d, %s", objectID, err)
 		return nil, err
@@ -131,7 +133,7 @@ func (c *ClientService) AttachMetadataToObject(objectID int64, body map[string]i
 }
 
 // DetachMetadataFromObject :
-func (c *ClientService) DetachMetadataFromObject(objectID int64) (*[]Metadata, error) {
+func (c *ClientService) DetachMetadataFromObject(ctx context.Context, objectID int64) (*[]Metadata, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -141,9 +143,9 @@ func (c *ClientService) DetachMetadataFromObject(objectID int64) (*[]Metadata, e
 	klog.V(2).Infof("Detach metadata from object with ID %d", objectID)
 	uri := "api/rest/metadata/" + strconv.FormatInt(objectID, 10) + "?approved=true"
 	metadata := []Metadata{}
-	resp, err := c.getJSONResponse(http.MethodDelete, uri, nil, &metadata)
+	resp, err := c.getJSONResponse(ctx, http.MethodDelete, uri, nil, &metadata)
 	if err != nil {
-		if strings.Contains(err.Error(), "METADATA_IS_NOT_SUPPORTED_FOR_ENTITY") {
+		if HasErrorCode(err, ErrMetadataIsNotSupportedForEntity) {
 			err = nil
 		}
 		klog.Errorf("Error occured while detaching metadata from object : %s ", err)
@@ -157,8 +159,29 @@ func (c *ClientService) DetachMetadataFromObject(objectID int64) (*[]Metadata, e
 	return &metadata, nil
 }
 
+// DetachMetadataKeyFromObject removes a single metadata key of an object, a key which is not set is ignored
+func (c *ClientService) DetachMetadataKeyFromObject(ctx context.Context, objectID int64, key string) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("DetachMetadataKeyFromObject Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Detach metadata %s from object with ID %d", key, objectID)
+	uri := "api/rest/metadata/" + strconv.FormatInt(objectID, 10) + "/" + url.PathEscape(key) + "?approved=true"
+	metadata := Metadata{}
+	_, err = c.getJSONResponse(ctx, http.MethodDelete, uri, nil, &metadata)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("Error occured while detaching metadata %s from object %d : %s ", key, objectID, err)
+		return err
+	}
+	return nil
+}
+
 // CreateFilesystem :
-func (c *ClientService) CreateFilesystem(fileSysparameter map[string]interface{}) (*FileSystem, error) {
+func (c *ClientService) CreateFilesystem(ctx context.Context, fileSysparameter map[string]interface{}) (*FileSystem, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -168,7 +191,14 @@ func (c *ClientService) CreateFilesystem(fileSysparameter map[string]interface{}
 	klog.V(2).Infof("Create filesystem")
 	uri := "api/rest/filesystems/"
 	fileSystemResp := FileSystem{}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, fileSysparameter, &fileSystemResp)
+	resp, err := c.postWithCreationCheck(ctx, uri, fileSysparameter, &fileSystemResp, func() (bool, interface{}, error) {
+		name, _ := fileSysparameter["name"].(string)
+		existing, err := c.GetFileSystemByName(ctx, name)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, *existing, nil
+	})
 	if err != nil {
 		klog.Errorf("Error occured while creating filesystem : %s", err)
 		return nil, err
@@ -182,7 +212,7 @@ func (c *ClientService) CreateFilesystem(fileSysparameter map[string]interface{}
 }
 
 // ExportFileSystem :
-func (c *ClientService) ExportFileSystem(export ExportFileSys) (*ExportResponse, error) {
+func (c *ClientService) ExportFileSystem(ctx context.Context, export ExportFileSys) (*ExportResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -192,7 +222,7 @@ func (c *ClientService) ExportFileSystem(export ExportFileSys) (*ExportResponse,
 	klog.V(2).Infof("Export FileSystem with ID %d", export.FilesystemID)
 	urlPost := "api/rest/exports"
 	exportResp := ExportResponse{}
-	resp, err := c.getJSONResponse(http.MethodPost, urlPost, export, &exportResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodPost, urlPost, export, &exportResp)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +236,7 @@ func (c *ClientService) ExportFileSystem(export ExportFileSys) (*ExportResponse,
 }
 
 // GetExportByFileSystem :
-func (c *ClientService) GetExportByFileSystem(fileSystemID int64) (*[]ExportResponse, error) {
+func (c *ClientService) GetExportByFileSystem(ctx context.Context, fileSystemID int64) (*[]ExportResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -214,17 +244,14 @@ func (c *ClientService) GetExportByFileSystem(fileSystemID int64) (*[]ExportResp
 		}
 	}()
 	klog.V(2).Infof("Get export paths of filesystem with ID %d", fileSystemID)
-	uri := "api/rest/exports?filesystem_id=" + strconv.FormatInt(fileSystemID, 10)
+	uri := "api/rest/exports"
+	query := NewQuery().Eq("filesystem_id", fileSystemID)
 	eResp := []ExportResponse{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+	err = c.getAllPages(ctx, uri, query, &eResp)
 	if err != nil {
 		klog.Errorf("Error occured while getting export path : %s", err)
 		return nil, err
 	}
-	if len(eResp) == 0 {
-		apiresp := resp.(client.ApiResponse)
-		eResp, _ = apiresp.Result.([]ExportResponse)
-	}
 	klog.V(2).Infof("Got export paths of filesystem with ID %d", fileSystemID)
 	return &eResp, nil
 }
@@ -246,7 +273,7 @@ func compareClientIP(permissionIP, ip string) bool {
 }
 
 // AddNodeInExport : Export should be updated in case of node addition in k8s cluster
-func (c *ClientService) AddNodeInExport(exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
+func (c *ClientService) AddNodeInExport(ctx context.Context, exportID int, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -262,7 +289,7 @@ func (c *ClientService) AddNodeInExport(exportID int, access string, noRootSquas
 	uri := "api/rest/exports/" + strconv.Itoa(exportID)
 	eResp := ExportResponse{}
 
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &eResp)
 	if err != nil {
 		klog.Errorf("Error occurred while getting export path for export with ID %d: %s", exportID, err)
 		return nil, err
@@ -328,7 +355,7 @@ func (c *ClientService) AddNodeInExport(exportID int, access string, noRootSquas
 		exportPermissions := ExportPermissions{}
 		exportPermissions.Permissions = permissionList
 		klog.V(4).Infof("Setting export with ID %d permissions to %+v", exportID, exportPermissions)
-		resp, err = c.getJSONResponse(http.MethodPut, uri, exportPermissions, &eResp)
+		resp, err = c.getJSONResponse(ctx, http.MethodPut, uri, exportPermissions, &eResp)
 		if err != nil {
 			klog.Errorf("Error occurred while updating export rule for export with ID %d: %s", exportID, err)
 			return nil, err
@@ -341,7 +368,7 @@ func (c *ClientService) AddNodeInExport(exportID int, access string, noRootSquas
 }
 
 // DeleteExportRule method
-func (c *ClientService) DeleteExportRule(fileSystemID int64, ipAddress string) error {
+func (c *ClientService) DeleteExportRule(ctx context.Context, fileSystemID int64, ipAddress string) error {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -349,7 +376,7 @@ func (c *ClientService) DeleteExportRule(fileSystemID int64, ipAddress string) e
 		}
 	}()
 	klog.V(2).Infof("Delete export rule from filesystem with file system ID %d", fileSystemID)
-	exportArray, err := c.GetExportByFileSystem(fileSystemID)
+	exportArray, err := c.GetExportByFileSystem(ctx, fileSystemID)
 	if err != nil {
 		klog.Errorf("Error occured while getting export : %v", err)
 		return err
@@ -357,7 +384,7 @@ func (c *ClientService) DeleteExportRule(fileSystemID int64, ipAddress string) e
 	for _, export := range *exportArray {
 		uri := "api/rest/exports/" + strconv.FormatInt(export.ID, 10)
 		eResp := ExportResponse{}
-		resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+		resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &eResp)
 		if err != nil {
 			klog.Errorf("Error occured while getting export path : %s", err)
 			return err
@@ -369,7 +396,7 @@ func (c *ClientService) DeleteExportRule(fileSystemID int64, ipAddress string) e
 		permissionList := eResp.Permissions
 		for _, permission := range permissionList {
 			if permission.Client == ipAddress {
-				_, err = c.DeleteNodeFromExport(export.ID, permission.Access, permission.NoRootSquash, ipAddress)
+				_, err = c.DeleteNodeFromExport(ctx, export.ID, permission.Access, permission.NoRootSquash, ipAddress)
 				if err != nil {
 					klog.Errorf("Error occured while getting export path : %s", err)
 					return err
@@ -382,7 +409,7 @@ func (c *ClientService) DeleteExportRule(fileSystemID int64, ipAddress string) e
 }
 
 // DeleteNodeFromExport Export should be updated in case of node deletion in k8s cluster
-func (c *ClientService) DeleteNodeFromExport(exportID int64, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
+func (c *ClientService) DeleteNodeFromExport(ctx context.Context, exportID int64, access string, noRootSquash bool, ip string) (*ExportResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -395,7 +422,7 @@ func (c *ClientService) DeleteNodeFromExport(exportID int64, access string, noRo
 	exportPathRef := ExportPathRef{}
 	uri := "api/rest/exports/" + strconv.FormatInt(exportID, 10)
 	eResp := ExportResponse{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &eResp)
 	if err != nil {
 		klog.Errorf("Error occured while getting export path : %s", err)
 		return nil, err
@@ -422,7 +449,7 @@ func (c *ClientService) DeleteNodeFromExport(exportID int64, access string, noRo
 			permissionList = append(permissionList, defaultPermission)
 		}
 		exportPathRef.Permissions = permissionList
-		resp, err = c.getJSONResponse(http.MethodPut, uri, exportPathRef, &eResp)
+		resp, err = c.getJSONResponse(ctx, http.MethodPut, uri, exportPathRef, &eResp)
 		if err != nil {
 			klog.Errorf("Error occured while updating permission : %s", err)
 			return nil, err
@@ -438,7 +465,7 @@ func (c *ClientService) DeleteNodeFromExport(exportID int64, access string, noRo
 }
 
 // CreateFileSystemSnapshot method create the filesystem snapshot
-func (c *ClientService) CreateFileSystemSnapshot(snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error) {
+func (c *ClientService) CreateFileSystemSnapshot(ctx context.Context, snapshotParam *FileSystemSnapshot) (*FileSystemSnapshotResponce, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -448,7 +475,14 @@ func (c *ClientService) CreateFileSystemSnapshot(snapshotParam *FileSystemSnapsh
 	klog.V(2).Infof("Create a snapshot of filesystem ID %d", snapshotParam.ParentID)
 	path := "/api/rest/filesystems"
 	snapShotResponse := FileSystemSnapshotResponce{}
-	resp, err := c.getJSONResponse(http.MethodPost, path, snapshotParam, &snapShotResponse)
+	resp, err := c.postWithCreationCheck(ctx, path, snapshotParam, &snapShotResponse, func() (bool, interface{}, error) {
+		existing, err := c.GetFileSystemByName(ctx, snapshotParam.SnapshotName)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, FileSystemSnapshotResponce{SnapshotID: existing.ID, Name: existing.Name, ParentId: existing.ParentID,
+			Size: existing.Size, CreatedAt: int64(existing.CreatedAt)}, nil
+	})
 	if err != nil {
 		klog.Errorf("failed to create %v", err)
 		return nil, err
@@ -462,7 +496,7 @@ func (c *ClientService) CreateFileSystemSnapshot(snapshotParam *FileSystemSnapsh
 }
 
 // FileSystemHasChild method return true is the filesystemID has child else false
-func (c *ClientService) FileSystemHasChild(fileSystemID int64) bool {
+func (c *ClientService) FileSystemHasChild(ctx context.Context, fileSystemID int64) bool {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -472,9 +506,8 @@ func (c *ClientService) FileSystemHasChild(fileSystemID int64) bool {
 	hasChild := false
 	voluri := "/api/rest/filesystems/"
 	filesystem := []FileSystem{}
-	queryParam := make(map[string]interface{})
-	queryParam["parent_id"] = fileSystemID
-	resp, err := c.getResponseWithQueryString(voluri, queryParam, &filesystem)
+	query := NewQuery().Eq("parent_id", fileSystemID).Fields("id").PageSize(1)
+	resp, err := c.getResponseWithQueryString(ctx, voluri, query, &filesystem)
 	if err != nil {
 		klog.Errorf("failed to check FileSystemHasChild %v", err)
 		return hasChild
@@ -489,13 +522,129 @@ func (c *ClientService) FileSystemHasChild(fileSystemID int64) bool {
 	return hasChild
 }
 
+// GetFileSystemsByIDs returns the filesystems with the given IDs, a filesystem which does not exist is left out
+func (c *ClientService) GetFileSystemsByIDs(ctx context.Context, fileSystemIDs []int64) (filesystems []FileSystem, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetFileSystemsByIDs Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	ids := make([]interface{}, 0, len(fileSystemIDs))
+	for _, id := range fileSystemIDs {
+		ids = append(ids, id)
+	}
+	filesystems = []FileSystem{}
+	for _, query := range inQueries("id", ids) {
+		if err = c.getAllPages(ctx, "/api/rest/filesystems", query, &filesystems); err != nil {
+			klog.Errorf("failed to get filesystems by id: %v", err)
+			return nil, err
+		}
+	}
+	klog.V(4).Infof("got %d of %d filesystems by id", len(filesystems), len(fileSystemIDs))
+	return filesystems, nil
+}
+
+// GetFileSystemsByParentIDs returns the child filesystems, snapshots and clones, of the given filesystems
+func (c *ClientService) GetFileSystemsByParentIDs(ctx context.Context, parentIDs []int64) (filesystems []FileSystem, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetFileSystemsByParentIDs Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	ids := make([]interface{}, 0, len(parentIDs))
+	for _, id := range parentIDs {
+		ids = append(ids, id)
+	}
+	filesystems = []FileSystem{}
+	for _, query := range inQueries("parent_id", ids) {
+		if err = c.getAllPages(ctx, "/api/rest/filesystems", query, &filesystems); err != nil {
+			klog.Errorf("failed to get children of filesystems: %v", err)
+			return nil, err
+		}
+	}
+	klog.V(4).Infof("got %d children of %d filesystems", len(filesystems), len(parentIDs))
+	return filesystems, nil
+}
+
 const (
 	// TOBEDELETED status
 	TOBEDELETED = "host.k8s.to_be_deleted"
+	// PVNameMetadataKey metadata key holding the name of the PV an IBox object backs
+	PVNameMetadataKey = "host.k8s.pvname"
 )
 
+// GetMetadataByKey returns the metadata entries with the given key, across all IBox objects
+func (c *ClientService) GetMetadataByKey(ctx context.Context, key string) (metadata []Metadata, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetMetadataByKey Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get metadata with key %s", key)
+	uri := "/api/rest/metadata"
+	query := NewQuery().Eq("key", key).Sort("object_id")
+	err = c.getAllPages(ctx, uri, query, &metadata)
+	if err != nil {
+		klog.Errorf("error occured while fetching metadata with key %s : %s ", key, err)
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// GetMetadataValue returns the value of a metadata key of an IBox object, empty when the key is not set
+func (c *ClientService) GetMetadataValue(ctx context.Context, objectID int64, key string) (value string, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetMetadataValue Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get metadata %s of IBox object with ID %d", key, objectID)
+	uri := "/api/rest/metadata/" + strconv.FormatInt(objectID, 10) + "/" + url.PathEscape(key)
+	metadata := Metadata{}
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &metadata)
+	if err != nil {
+		if IsNotFound(err) {
+			return "", nil
+		}
+		klog.Errorf("error occured while fetching metadata %s of object %d : %s ", key, objectID, err)
+		return "", err
+	}
+	if metadata == (Metadata{}) {
+		apiresp := resp.(client.ApiResponse)
+		metadata, _ = apiresp.Result.(Metadata)
+	}
+	return metadata.Value, nil
+}
+
+// getObjectIDsByMetadata returns the ids of the IBox objects holding the metadata key with the given value
+func (c *ClientService) getObjectIDsByMetadata(ctx context.Context, key, value string) (objectIDs []int64, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("getObjectIDsByMetadata Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	uri := "/api/rest/metadata"
+	query := NewQuery().Eq("key", key).Eq("value", value)
+	metadata := []Metadata{}
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &metadata)
+	if err != nil {
+		klog.Errorf("error occured while fetching metadata %s=%s : %s ", key, value, err)
+		return nil, err
+	}
+	if len(metadata) == 0 {
+		apiresp := resp.(client.ApiResponse)
+		metadata, _ = apiresp.Result.([]Metadata)
+	}
+	for _, m := range metadata {
+		if m.Key == key && m.Value == value {
+			objectIDs = append(objectIDs, int64(m.ObjectId))
+		}
+	}
+	return objectIDs, nil
+}
+
 // GetMetadataStatus :
-func (c *ClientService) GetMetadataStatus(fileSystemID int64) bool {
+func (c *ClientService) GetMetadataStatus(ctx context.Context, fileSystemID int64) bool {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -505,7 +654,7 @@ func (c *ClientService) GetMetadataStatus(fileSystemID int64) bool {
 	klog.V(2).Infof("Get metadata status of IBox object with ID %d", fileSystemID)
 	path := "/api/rest/metadata/" + strconv.FormatInt(fileSystemID, 10) + "/" + TOBEDELETED
 	metadata := Metadata{}
-	resp, err := c.getJSONResponse(http.MethodGet, path, nil, &metadata)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, path, nil, &metadata)
 	if err != nil {
 		klog.V(4).Infof("Getting metadata did not return a value: %s", err)
 		return false
@@ -528,7 +677,7 @@ func (c *ClientService) GetMetadataStatus(fileSystemID int64) bool {
 }
 
 // GetFileSystemByName :
-func (c *ClientService) GetFileSystemByName(fileSystemName string) (*FileSystem, error) {
+func (c *ClientService) GetFileSystemByName(ctx context.Context, fileSystemName string) (*FileSystem, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -538,10 +687,8 @@ func (c *ClientService) GetFileSystemByName(fileSystemName string) (*FileSystem,
 	klog.V(2).Infof("Get filesystem %s", fileSystemName)
 	uri := "/api/rest/filesystems"
 	fsystems := []FileSystem{}
-	queryParam := make(map[string]interface{})
-	queryParam["name"] = fileSystemName
-	resp, err := c.getResponseWithQueryString(uri,
-		queryParam, &fsystems)
+	query := NewQuery().Eq("name", fileSystemName)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &fsystems)
 	if err != nil {
 		return nil, err
 	}
@@ -555,11 +702,40 @@ func (c *ClientService) GetFileSystemByName(fileSystemName string) (*FileSystem,
 			return &fsystem, nil
 		}
 	}
-	return nil, errors.New("filesystem with given name not found")
+	return nil, notFoundError(ErrFileSystemNotFound, "filesystem with given name not found")
+}
+
+// GetFileSystemByPVName : find the filesystem backing the PV of given name by its pv name metadata,
+// regardless of the filesystem name on the array
+func (c *ClientService) GetFileSystemByPVName(ctx context.Context, pvName string) (*FileSystem, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetFileSystemByPVName Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get filesystem of PV %s", pvName)
+	objectIDs, err := c.getObjectIDsByMetadata(ctx, PVNameMetadataKey, pvName)
+	if err != nil {
+		return nil, err
+	}
+	for _, objectID := range objectIDs {
+		fsystem, err := c.GetFileSystemByID(ctx, objectID)
+		if err != nil {
+			// the metadata belongs to an object of another type, e.g. a volume
+			if IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		klog.V(2).Infof("Got filesystem %s of PV %s", fsystem.Name, pvName)
+		return fsystem, nil
+	}
+	return nil, notFoundError(ErrFileSystemNotFound, "filesystem of pv "+pvName+" not found")
 }
 
 // GetFileSystemByID :
-func (c *ClientService) GetFileSystemByID(fileSystemID int64) (*FileSystem, error) {
+func (c *ClientService) GetFileSystemByID(ctx context.Context, fileSystemID int64) (*FileSystem, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -569,7 +745,7 @@ func (c *ClientService) GetFileSystemByID(fileSystemID int64) (*FileSystem, erro
 	klog.V(2).Infof("Get filesystem with ID %d", fileSystemID)
 	uri := "/api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10)
 	eResp := FileSystem{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &eResp)
 	if err != nil {
 		klog.Errorf("Error occured while getting fileSystem: %s", err)
 		return nil, err
@@ -583,7 +759,7 @@ func (c *ClientService) GetFileSystemByID(fileSystemID int64) (*FileSystem, erro
 }
 
 // GetParentID method return the
-func (c *ClientService) GetParentID(fileSystemID int64) int64 {
+func (c *ClientService) GetParentID(ctx context.Context, fileSystemID int64) int64 {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -591,7 +767,7 @@ func (c *ClientService) GetParentID(fileSystemID int64) int64 {
 		}
 	}()
 	klog.V(2).Infof("Get parent of file system with ID %d", fileSystemID)
-	fileSystem, err := c.GetFileSystemByID(fileSystemID)
+	fileSystem, err := c.GetFileSystemByID(ctx, fileSystemID)
 	if err != nil {
 		klog.Errorf("Error occured while getting file system: %s", err)
 		return 0
@@ -601,23 +777,23 @@ func (c *ClientService) GetParentID(fileSystemID int64) int64 {
 }
 
 // DeleteParentFileSystem method delete the ascenders of fileystem
-func (c *ClientService) DeleteParentFileSystem(fileSystemID int64) (err error) { // delete fileystem's parent ID
+func (c *ClientService) DeleteParentFileSystem(ctx context.Context, fileSystemID int64) (err error) { // delete fileystem's parent ID
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = fmt.Errorf("DeleteParentFileSystem called with file system ID %d. Panic occurred: %v", fileSystemID, res)
 		}
 	}()
 	// first check .. hasChild ...
-	hasChild := c.FileSystemHasChild(fileSystemID)
-	if !hasChild && c.GetMetadataStatus(fileSystemID) { // If No child and to_be_delete_status =true in metadata then
-		parentID := c.GetParentID(fileSystemID)        // get the parentID .. before delete
-		err = c.DeleteFileSystemComplete(fileSystemID) // delete the filesystem
+	hasChild := c.FileSystemHasChild(ctx, fileSystemID)
+	if !hasChild && c.GetMetadataStatus(ctx, fileSystemID) { // If No child and to_be_delete_status =true in metadata then
+		parentID := c.GetParentID(ctx, fileSystemID)        // get the parentID .. before delete
+		err = c.DeleteFileSystemComplete(ctx, fileSystemID) // delete the filesystem
 		if err != nil {
 			klog.Errorf("Failed to delete filesystem with ID %d: %v", fileSystemID, err)
 			return
 		}
 		if parentID != 0 {
-			err = c.DeleteParentFileSystem(parentID)
+			err = c.DeleteParentFileSystem(ctx, parentID)
 			if err != nil {
 				klog.Errorf("Failed to delete parent filesystem with parent ID %d: %v", fileSystemID, err)
 				return
@@ -628,7 +804,7 @@ func (c *ClientService) DeleteParentFileSystem(fileSystemID int64) (err error) {
 }
 
 // DeleteFileSystemComplete method delete the fileystem
-func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error) {
+func (c *ClientService) DeleteFileSystemComplete(ctx context.Context, fileSystemID int64) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("DeleteFileSystemComplete panic error " + fmt.Sprint(res))
@@ -636,9 +812,9 @@ func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error)
 	}()
 
 	// 1. Delete export path
-	exportResp, err := c.GetExportByFileSystem(fileSystemID)
+	exportResp, err := c.GetExportByFileSystem(ctx, fileSystemID)
 	if err != nil {
-		if strings.Contains(err.Error(), "EXPORT_NOT_FOUND") {
+		if HasErrorCode(err, ErrExportNotFound) {
 			err = nil
 		} else {
 			klog.Errorf("failed to delete export path %v", err)
@@ -647,9 +823,9 @@ func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error)
 	}
 	if exportResp != nil {
 		for _, ep := range *exportResp {
-			_, err = c.DeleteExportPath(ep.ID)
+			_, err = c.DeleteExportPath(ctx, ep.ID)
 			if err != nil {
-				if strings.Contains(err.Error(), "EXPORT_NOT_FOUND") {
+				if HasErrorCode(err, ErrExportNotFound) {
 					err = nil
 				} else {
 					klog.Errorf("failed to delete export path %v", err)
@@ -662,9 +838,9 @@ func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error)
 	klog.V(4).Infof("Export path deleted successfully")
 
 	// 2.delete metadata
-	_, err = c.DetachMetadataFromObject(fileSystemID)
+	_, err = c.DetachMetadataFromObject(ctx, fileSystemID)
 	if err != nil {
-		if strings.Contains(err.Error(), "METADATA_IS_NOT_SUPPORTED_FOR_ENTITY") {
+		if HasErrorCode(err, ErrMetadataIsNotSupportedForEntity) {
 			err = nil
 		} else {
 			klog.Errorf("failed to delete metadata %v", err)
@@ -674,7 +850,7 @@ func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error)
 
 	// 3. delete file system
 	klog.V(2).Infof("delete FileSystem FileSystemID %d", fileSystemID)
-	_, err = c.DeleteFileSystem(fileSystemID)
+	_, err = c.DeleteFileSystem(ctx, fileSystemID)
 	if err != nil {
 		klog.Errorf("failed to delete filesystem %v", err)
 		return
@@ -683,7 +859,7 @@ func (c *ClientService) DeleteFileSystemComplete(fileSystemID int64) (err error)
 }
 
 // UpdateFilesystem : update file system
-func (c *ClientService) UpdateFilesystem(fileSystemID int64, fileSystem FileSystem) (*FileSystem, error) {
+func (c *ClientService) UpdateFilesystem(ctx context.Context, fileSystemID int64, fileSystem FileSystem) (*FileSystem, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -694,7 +870,7 @@ func (c *ClientService) UpdateFilesystem(fileSystemID int64, fileSystem FileSyst
 	uri := "api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10)
 	fileSystemResp := FileSystem{}
 
-	resp, err := c.getJSONResponse(http.MethodPut, uri, fileSystem, &fileSystemResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodPut, uri, fileSystem, &fileSystemResp)
 	if err != nil {
 		klog.Errorf("Error occured while updating filesystem : %s", err)
 		return nil, err
@@ -709,7 +885,7 @@ func (c *ClientService) UpdateFilesystem(fileSystemID int64, fileSystem FileSyst
 }
 
 // RestoreFileSystemFromSnapShot :
-func (c *ClientService) RestoreFileSystemFromSnapShot(parentID, srcSnapShotID int64) (bool, error) {
+func (c *ClientService) RestoreFileSystemFromSnapShot(ctx context.Context, parentID, srcSnapShotID int64) (bool, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -720,7 +896,7 @@ func (c *ClientService) RestoreFileSystemFromSnapShot(parentID, srcSnapShotID in
 	uri := "api/rest/filesystems/" + strconv.FormatInt(parentID, 10) + "/restore?approved=true"
 	var result bool
 	body := map[string]interface{}{"source_id": srcSnapShotID}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, body, &result)
+	resp, err := c.getJSONResponse(ctx, http.MethodPost, uri, body, &result)
 	if err != nil {
 		klog.Errorf("Error occured while updating filesystem : %s", err)
 		return false, err
@@ -739,7 +915,7 @@ func removeIndex(s []Permissions, index int) []Permissions {
 }
 
 // GetSnapshotByName :
-func (c *ClientService) GetSnapshotByName(snapshotName string) (*[]FileSystemSnapshotResponce, error) {
+func (c *ClientService) GetSnapshotByName(ctx context.Context, snapshotName string) (*[]FileSystemSnapshotResponce, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -747,31 +923,35 @@ func (c *ClientService) GetSnapshotByName(snapshotName string) (*[]FileSystemSna
 		}
 	}()
 	klog.V(2).Infof("Get snapshot %s", snapshotName)
-	uri := "api/rest/filesystems?name=" + snapshotName
+	uri := "api/rest/filesystems"
+	query := NewQuery().Eq("name", snapshotName)
 	snapshot := []FileSystemSnapshotResponce{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &snapshot)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &snapshot)
 	if err != nil {
 		klog.Errorf("Error occured while getting snapshot : %s ", err)
 		return nil, err
 	}
 	if len(snapshot) == 0 {
-		snapshot, _ = resp.([]FileSystemSnapshotResponce)
+		apiresp := resp.(client.ApiResponse)
+		snapshot, _ = apiresp.Result.([]FileSystemSnapshotResponce)
 	}
 	klog.V(2).Infof("Got snapshot %s", snapshotName)
 	return &snapshot, nil
 }
 
 // GetFileSystemCountByPoolID :
-func (c *ClientService) GetFileSystemCountByPoolID(poolID int64) (fileSysCnt int, err error) {
+func (c *ClientService) GetFileSystemCountByPoolID(ctx context.Context, poolID int64) (fileSysCnt int, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetFileSystemCount Panic occured -  " + fmt.Sprint(res))
 		}
 	}()
 	klog.V(2).Infof("Get FileSystem Count")
-	uri := "api/rest/filesystems?pool_id=" + strconv.FormatInt(poolID, 10)
+	uri := "api/rest/filesystems"
+	// only the object count of the result metadata is needed
+	query := NewQuery().Eq("pool_id", poolID).Fields("id").PageSize(1)
 	filesystems := []FileSystem{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &filesystems)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &filesystems)
 	if err != nil {
 		klog.Errorf("error occured while fetching filesystems : %s ", err)
 		return
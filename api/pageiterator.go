@@ -0,0 +1,120 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinibox-csi-driver/api/client"
+	"reflect"
+	"strconv"
+
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultPageSize is the number of objects requested per page of a collection endpoint
+	DefaultPageSize = 1000
+	// MaxPageSize is the largest page size accepted by the management api
+	MaxPageSize = 1000
+)
+
+// pageIterator walks the pages of an InfiniBox collection endpoint, following the page
+// count of the result metadata
+type pageIterator struct {
+	c          *ClientService
+	uri        string
+	query      *Query
+	pageSize   int
+	page       int
+	pagesTotal int
+}
+
+// pages returns an iterator over the collection at uri, filtered by query
+func (c *ClientService) pages(uri string, query *Query) *pageIterator {
+	return &pageIterator{c: c, uri: uri, query: query}
+}
+
+// next fetches the next page into pageResp, a pointer to a slice of the collection's objects,
+// and reports false once every page was read
+func (it *pageIterator) next(ctx context.Context, pageResp interface{}) (more bool, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("error while reading page of " + it.uri + " " + fmt.Sprint(res))
+		}
+	}()
+	if it.page > 0 && it.page >= it.pagesTotal {
+		return false, nil
+	}
+	if it.pageSize == 0 {
+		if it.pageSize = it.query.pageSize(); it.pageSize == 0 {
+			if it.pageSize, err = it.c.pageSize(); err != nil {
+				return false, err
+			}
+		}
+	}
+	hostconfig, err := it.c.getAPIConfig()
+	if err != nil {
+		klog.Errorf("Error occured: %v ", err)
+		return false, err
+	}
+	query := it.query.page(it.page+1, it.pageSize)
+	klog.V(4).Infof("get page %d of %s", it.page+1, it.uri)
+	resp, err := it.c.api.GetWithQueryString(ctx, it.uri, hostconfig, query.Encode(), pageResp)
+	if err != nil {
+		klog.Errorf("failed to get page %d of %s: %v", it.page+1, it.uri, err)
+		return false, err
+	}
+	it.page++
+	if apiresp, ok := resp.(client.ApiResponse); ok {
+		it.pagesTotal = apiresp.MetaData.TotalPages
+		objects := reflect.ValueOf(pageResp).Elem()
+		if result := reflect.ValueOf(apiresp.Result); objects.Len() == 0 && result.IsValid() && result.Type().AssignableTo(objects.Type()) {
+			objects.Set(result)
+		}
+	}
+	return true, nil
+}
+
+// all appends the objects of the remaining pages to result, a pointer to a slice
+func (it *pageIterator) all(ctx context.Context, result interface{}) error {
+	objects := reflect.ValueOf(result).Elem()
+	for {
+		page := reflect.New(objects.Type())
+		more, err := it.next(ctx, page.Interface())
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+		objects.Set(reflect.AppendSlice(objects, page.Elem()))
+	}
+}
+
+// getAllPages reads every page of the collection at uri into result, a pointer to a slice
+func (c *ClientService) getAllPages(ctx context.Context, uri string, query *Query, result interface{}) error {
+	return c.pages(uri, query).all(ctx, result)
+}
+
+// pageSize returns the page size set by the optional page_size secret key
+func (c *ClientService) pageSize() (int, error) {
+	val := c.SecretsMap["page_size"]
+	if val == "" {
+		return DefaultPageSize, nil
+	}
+	size, err := strconv.Atoi(val)
+	if err != nil || size <= 0 || size > MaxPageSize {
+		return 0, fmt.Errorf("page_size in secret must be an integer from 1 to %d: %s", MaxPageSize, val)
+	}
+	return size, nil
+}
@@ -0,0 +1,127 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinibox-csi-driver/api/client"
+	"net/http"
+	"strconv"
+
+	"k8s.io/klog"
+)
+
+// QoS policy types, a policy limits each entity it is assigned to
+const (
+	QosPolicyTypeVolume     = "VOLUME"
+	QosPolicyTypeFilesystem = "FILESYSTEM"
+)
+
+// QosPolicy struct
+type QosPolicy struct {
+	ID           int64  `json:"id,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Type         string `json:"type,omitempty"`
+	MaxOps       int64  `json:"max_ops,omitempty"`
+	MaxBps       int64  `json:"max_bps,omitempty"`
+	BurstEnabled bool   `json:"burst_enabled"`
+}
+
+// GetQosPolicyByName returns the QoS policy with the given name
+func (c *ClientService) GetQosPolicyByName(ctx context.Context, name string) (policy *QosPolicy, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetQosPolicyByName Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get QoS policy by name : %s", name)
+	policies := []QosPolicy{}
+	resp, err := c.getResponseWithQueryString(ctx, "api/rest/qos/policies", NewQuery().Eq("name", name), &policies)
+	if err != nil {
+		klog.Errorf("Error occured while getting QoS policy %s : %s", name, err)
+		return nil, err
+	}
+	if len(policies) == 0 {
+		apiresp := resp.(client.ApiResponse)
+		policies, _ = apiresp.Result.([]QosPolicy)
+	}
+	if len(policies) == 0 {
+		return nil, notFoundError(ErrQosPolicyNotFound, "No such QoS policy: "+name)
+	}
+	return &policies[0], nil
+}
+
+// CreateQosPolicy creates a QoS policy
+func (c *ClientService) CreateQosPolicy(ctx context.Context, policy QosPolicy) (*QosPolicy, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("CreateQosPolicy Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Create QoS policy %s", policy.Name)
+	created := QosPolicy{}
+	resp, err := c.postWithCreationCheck(ctx, "api/rest/qos/policies", policy, &created, func() (bool, interface{}, error) {
+		existing, err := c.GetQosPolicyByName(ctx, policy.Name)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, *existing, nil
+	})
+	if err != nil {
+		klog.Errorf("Error occured while creating QoS policy %s : %s", policy.Name, err)
+		return nil, err
+	}
+	if created == (QosPolicy{}) {
+		apiresp := resp.(client.ApiResponse)
+		created, _ = apiresp.Result.(QosPolicy)
+	}
+	klog.V(2).Infof("QoS policy created : %s", created.Name)
+	return &created, nil
+}
+
+// AssignQosPolicy assigns a QoS policy to a volume or filesystem
+func (c *ClientService) AssignQosPolicy(ctx context.Context, policyID, entityID int64) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("AssignQosPolicy Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Assign QoS policy %d to entity %d", policyID, entityID)
+	uri := "api/rest/qos/policies/" + strconv.FormatInt(policyID, 10) + "/assign_entity"
+	body := map[string]interface{}{"entity_id": entityID}
+	policy := QosPolicy{}
+	if _, err = c.getJSONResponse(ctx, http.MethodPost, uri, body, &policy); err != nil {
+		klog.Errorf("Error occured while assigning QoS policy %d to entity %d : %s", policyID, entityID, err)
+		return err
+	}
+	return nil
+}
+
+// UnassignQosPolicy removes a QoS policy from a volume or filesystem
+func (c *ClientService) UnassignQosPolicy(ctx context.Context, policyID, entityID int64) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("UnassignQosPolicy Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Unassign QoS policy %d from entity %d", policyID, entityID)
+	uri := "api/rest/qos/policies/" + strconv.FormatInt(policyID, 10) + "/unassign_entity"
+	body := map[string]interface{}{"entity_id": entityID}
+	policy := QosPolicy{}
+	if _, err = c.getJSONResponse(ctx, http.MethodPost, uri, body, &policy); err != nil {
+		klog.Errorf("Error occured while unassigning QoS policy %d from entity %d : %s", policyID, entityID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query builds the query string of an InfiniBox collection request in the filter
+// syntax of the management api, e.g. name=eq:pvc-1&sort=-size&fields=id,name&page_size=1
+type Query struct {
+	values url.Values
+}
+
+// NewQuery returns an empty query
+func NewQuery() *Query {
+	return &Query{values: url.Values{}}
+}
+
+// Eq filters the objects whose field equals value
+func (q *Query) Eq(field string, value interface{}) *Query {
+	q.values.Add(field, "eq:"+fmt.Sprint(value))
+	return q
+}
+
+// In filters the objects whose field equals one of values
+func (q *Query) In(field string, values ...interface{}) *Query {
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		items = append(items, fmt.Sprint(value))
+	}
+	q.values.Add(field, "in:["+strings.Join(items, ",")+"]")
+	return q
+}
+
+// Like filters the objects whose field contains pattern
+func (q *Query) Like(field, pattern string) *Query {
+	q.values.Add(field, "like:"+pattern)
+	return q
+}
+
+// Sort orders the objects by fields, a field prefixed by - sorts descending
+func (q *Query) Sort(fields ...string) *Query {
+	q.values.Set("sort", strings.Join(fields, ","))
+	return q
+}
+
+// Fields limits the returned attributes of each object to fields
+func (q *Query) Fields(fields ...string) *Query {
+	q.values.Set("fields", strings.Join(fields, ","))
+	return q
+}
+
+// PageSize sets the number of objects returned per page
+func (q *Query) PageSize(size int) *Query {
+	q.values.Set("page_size", strconv.Itoa(size))
+	return q
+}
+
+// Encode returns the url encoded query string, empty for a nil query
+func (q *Query) Encode() string {
+	if q == nil {
+		return ""
+	}
+	return q.values.Encode()
+}
+
+// MaxIDsPerQuery is the largest number of object IDs filtered by one in: query, keeping the
+// query string of a bulk request short
+const MaxIDsPerQuery = 100
+
+// inQueries returns the queries filtering field on ids, at most MaxIDsPerQuery ids each
+func inQueries(field string, ids []interface{}) []*Query {
+	queries := []*Query{}
+	for start := 0; start < len(ids); start += MaxIDsPerQuery {
+		end := start + MaxIDsPerQuery
+		if end > len(ids) {
+			end = len(ids)
+		}
+		queries = append(queries, NewQuery().In(field, ids[start:end]...))
+	}
+	return queries
+}
+
+// page returns a copy of the query requesting the given page of size objects
+func (q *Query) page(page, size int) *Query {
+	paged := NewQuery()
+	if q != nil {
+		for key, values := range q.values {
+			paged.values[key] = append([]string(nil), values...)
+		}
+	}
+	paged.values.Set("page", strconv.Itoa(page))
+	paged.values.Set("page_size", strconv.Itoa(size))
+	return paged
+}
+
+// pageSize returns the page size set on the query, 0 when unset
+func (q *Query) pageSize() int {
+	if q == nil {
+		return 0
+	}
+	size, _ := strconv.Atoi(q.values.Get("page_size"))
+	return size
+}
@@ -0,0 +1,194 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinibox-csi-driver/api/client"
+	"net/http"
+	"strconv"
+
+	"k8s.io/klog"
+)
+
+// Replica entity types, replication types, roles and states
+const (
+	ReplicaEntityTypeVolume = "VOLUME"
+	ReplicationTypeAsync    = "ASYNC"
+	ReplicaRoleSource       = "SOURCE"
+	ReplicaRoleTarget       = "TARGET"
+	ReplicaStateActive      = "ACTIVE"
+)
+
+// Link struct, a replication link to a remote InfiniBox system
+type Link struct {
+	ID               int64  `json:"id,omitempty"`
+	Name             string `json:"name,omitempty"`
+	RemoteSystemName string `json:"remote_system_name,omitempty"`
+	LinkState        string `json:"link_state,omitempty"`
+}
+
+// Replica struct, an asynchronous replica of a local entity on the remote system of a link.
+// RpoValue and SyncInterval are in milliseconds
+type Replica struct {
+	ID               int64  `json:"id,omitempty"`
+	EntityType       string `json:"entity_type,omitempty"`
+	LocalEntityID    int64  `json:"local_entity_id,omitempty"`
+	RemoteEntityID   int64  `json:"remote_entity_id,omitempty"`
+	LinkID           int64  `json:"link_id,omitempty"`
+	RemotePoolID     int64  `json:"remote_pool_id,omitempty"`
+	ReplicationType  string `json:"replication_type,omitempty"`
+	RpoValue         int64  `json:"rpo_value,omitempty"`
+	SyncInterval     int64  `json:"sync_interval,omitempty"`
+	BaseAction       string `json:"base_action,omitempty"`
+	Role             string `json:"role,omitempty"`
+	State            string `json:"state,omitempty"`
+	SyncState        string `json:"sync_state,omitempty"`
+	LastSynchronized int64  `json:"last_synchronized,omitempty"`
+}
+
+// GetLinkByRemoteSystem returns the replication link to the named remote system
+func (c *ClientService) GetLinkByRemoteSystem(ctx context.Context, remoteSystemName string) (link *Link, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetLinkByRemoteSystem Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get replication link to remote system : %s", remoteSystemName)
+	links := []Link{}
+	resp, err := c.getResponseWithQueryString(ctx, "api/rest/links", NewQuery().Eq("remote_system_name", remoteSystemName), &links)
+	if err != nil {
+		klog.Errorf("Error occured while getting replication link to %s : %s", remoteSystemName, err)
+		return nil, err
+	}
+	if len(links) == 0 {
+		apiresp := resp.(client.ApiResponse)
+		links, _ = apiresp.Result.([]Link)
+	}
+	if len(links) == 0 {
+		return nil, notFoundError(ErrLinkNotFound, "No replication link to remote system: "+remoteSystemName)
+	}
+	return &links[0], nil
+}
+
+// GetReplicaByEntity returns the replica of a local volume
+func (c *ClientService) GetReplicaByEntity(ctx context.Context, entityID int64) (replica *Replica, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetReplicaByEntity Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Get replica of entity %d", entityID)
+	replicas := []Replica{}
+	resp, err := c.getResponseWithQueryString(ctx, "api/rest/replicas", NewQuery().Eq("local_entity_id", entityID), &replicas)
+	if err != nil {
+		klog.Errorf("Error occured while getting replica of entity %d : %s", entityID, err)
+		return nil, err
+	}
+	if len(replicas) == 0 {
+		apiresp := resp.(client.ApiResponse)
+		replicas, _ = apiresp.Result.([]Replica)
+	}
+	if len(replicas) == 0 {
+		return nil, notFoundError(ErrReplicaNotFound, "No replica of entity: "+strconv.FormatInt(entityID, 10))
+	}
+	return &replicas[0], nil
+}
+
+// CreateReplica replicates a local volume to a new volume in the remote pool of the replica
+func (c *ClientService) CreateReplica(ctx context.Context, replica Replica) (*Replica, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("CreateReplica Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Create replica of entity %d over link %d", replica.LocalEntityID, replica.LinkID)
+	replica.BaseAction = "NEW"
+	created := Replica{}
+	resp, err := c.postWithCreationCheck(ctx, "api/rest/replicas", replica, &created, func() (bool, interface{}, error) {
+		existing, err := c.GetReplicaByEntity(ctx, replica.LocalEntityID)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, *existing, nil
+	})
+	if err != nil {
+		klog.Errorf("Error occured while creating replica of entity %d : %s", replica.LocalEntityID, err)
+		return nil, err
+	}
+	if created == (Replica{}) {
+		apiresp := resp.(client.ApiResponse)
+		created, _ = apiresp.Result.(Replica)
+	}
+	klog.V(2).Infof("replica created : %d", created.ID)
+	return &created, nil
+}
+
+// DeleteReplica stops a replication, the local and remote volumes are kept
+func (c *ClientService) DeleteReplica(ctx context.Context, replicaID int64) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("DeleteReplica Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Delete replica %d", replicaID)
+	uri := "api/rest/replicas/" + strconv.FormatInt(replicaID, 10) + "?approved=true"
+	if _, err = c.getJSONResponse(ctx, http.MethodDelete, uri, nil, nil); err != nil {
+		klog.Errorf("Error occured while deleting replica %d : %s", replicaID, err)
+		return err
+	}
+	return nil
+}
+
+// SuspendReplica stops replicating to the target of a replica until it is resumed
+func (c *ClientService) SuspendReplica(ctx context.Context, replicaID int64) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("SuspendReplica Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Suspend replica %d", replicaID)
+	uri := "api/rest/replicas/" + strconv.FormatInt(replicaID, 10) + "/suspend"
+	replica := Replica{}
+	if _, err = c.getJSONResponse(ctx, http.MethodPost, uri, nil, &replica); err != nil {
+		klog.Errorf("Error occured while suspending replica %d : %s", replicaID, err)
+		return err
+	}
+	return nil
+}
+
+// ChangeReplicaRole switches the role of the local side of a replica, making a target volume
+// writable as the source of the replica
+func (c *ClientService) ChangeReplicaRole(ctx context.Context, replicaID int64) (*Replica, error) {
+	var err error
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("ChangeReplicaRole Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("Change role of replica %d", replicaID)
+	uri := "api/rest/replicas/" + strconv.FormatInt(replicaID, 10) + "/change_role?approved=true"
+	replica := Replica{}
+	resp, err := c.getJSONResponse(ctx, http.MethodPost, uri, nil, &replica)
+	if err != nil {
+		klog.Errorf("Error occured while changing role of replica %d : %s", replicaID, err)
+		return nil, err
+	}
+	if replica == (Replica{}) {
+		apiresp := resp.(client.ApiResponse)
+		replica, _ = apiresp.Result.(Replica)
+	}
+	klog.V(2).Infof("replica %d role changed to %s", replicaID, replica.Role)
+	return &replica, nil
+}
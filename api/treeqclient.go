@@ -11,6 +11,7 @@ limitations under the License.*/
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/api/client"
@@ -25,12 +26,6 @@ const (
 	TREEQCOUNT = "host.k8s.treeqs"
 )
 
-// FSMetadata struct
-type FSMetadata struct {
-	FileSystemArry []FileSystem
-	Filemetadata   FileSystemMetaData
-}
-
 // Treeq struct
 type Treeq struct {
 	ID           int64  `json:"id,omitempty"`
@@ -41,49 +36,35 @@ type Treeq struct {
 	UsedCapacity int64  `json:"used_capacity,omitempty"`
 }
 
-// GetFileSystemsByPoolID get filesystem by poolID
-func (c *ClientService) GetFileSystemsByPoolID(poolID int64, page int) (fsmetadata *FSMetadata, err error) {
+// GetFileSystemsByPoolID returns the filesystems of a pool, sorted by size
+func (c *ClientService) GetFileSystemsByPoolID(ctx context.Context, poolID int64) (filesystems []FileSystem, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetFileSystemsByPoolID Panic occured -  " + fmt.Sprint(res))
 		}
 	}()
-	uri := "/api/rest/filesystems?pool_id=" + strconv.FormatInt(poolID, 10) + "&sort=size&page=" + strconv.Itoa(page) + "&page_size=1000&fields=id,size,name"
-	filesystems := []FileSystem{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &filesystems)
+	uri := "/api/rest/filesystems"
+	query := NewQuery().Eq("pool_id", poolID).Sort("size").Fields("id", "size", "name")
+	err = c.getAllPages(ctx, uri, query, &filesystems)
 	if err != nil {
 		klog.Errorf("error occured while fetching filesystems from pool : %s ", err)
-		return
-	}
-	apiresp := resp.(client.ApiResponse)
-	mdata := apiresp.MetaData
-	if len(filesystems) == 0 {
-		filesystems, _ = apiresp.Result.([]FileSystem)
+		return nil, err
 	}
-	fileMetadata := FileSystemMetaData{}
-	fileMetadata.NumberOfObjects = mdata.NoOfObject
-	fileMetadata.Page = mdata.Page
-	fileMetadata.PageSize = mdata.PageSize
-	fileMetadata.PagesTotal = mdata.TotalPages
-
-	var fs FSMetadata
-	fs.FileSystemArry = filesystems
-	fs.Filemetadata = fileMetadata
-	fsmetadata = &fs
-
-	return
+	return filesystems, nil
 }
 
 // GetFilesytemTreeqCount method return the treeq count
-func (c *ClientService) GetFilesytemTreeqCount(fileSystemID int64) (treeqCnt int, err error) {
+func (c *ClientService) GetFilesytemTreeqCount(ctx context.Context, fileSystemID int64) (treeqCnt int, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("GetFilesytemTreeqCount Panic occured -  " + fmt.Sprint(res))
 		}
 	}()
 	path := "/api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs"
+	// only the object count of the result metadata is needed
+	query := NewQuery().Fields("id").PageSize(1)
 	treeqArry := []Treeq{}
-	resp, err := c.getJSONResponse(http.MethodGet, path, nil, &treeqArry)
+	resp, err := c.getResponseWithQueryString(ctx, path, query, &treeqArry)
 	if err != nil {
 		klog.Errorf("Error occured while getting treeq count value: %s", err)
 		return
@@ -101,7 +82,7 @@ func (c *ClientService) GetFilesytemTreeqCount(fileSystemID int64) (treeqCnt int
 }
 
 // CreateTreeq method create treeq
-func (c *ClientService) CreateTreeq(filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error) {
+func (c *ClientService) CreateTreeq(ctx context.Context, filesystemID int64, treeqParameter map[string]interface{}) (*Treeq, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -111,7 +92,14 @@ func (c *ClientService) CreateTreeq(filesystemID int64, treeqParameter map[strin
 	klog.V(2).Infof("Create filesystem")
 	uri := "api/rest/filesystems/" + strconv.FormatInt(filesystemID, 10) + "/treeqs"
 	treeq := Treeq{}
-	resp, err := c.getJSONResponse(http.MethodPost, uri, treeqParameter, &treeq)
+	resp, err := c.postWithCreationCheck(ctx, uri, treeqParameter, &treeq, func() (bool, interface{}, error) {
+		name, _ := treeqParameter["name"].(string)
+		existing, err := c.GetTreeqByName(ctx, filesystemID, name)
+		if err != nil {
+			return creationNotFound(err)
+		}
+		return true, *existing, nil
+	})
 	if err != nil {
 		klog.Errorf("Error occured while creating treeq  : %s", err)
 		return nil, err
@@ -126,7 +114,7 @@ func (c *ClientService) CreateTreeq(filesystemID int64, treeqParameter map[strin
 }
 
 // getTreeqSizeByFileSystemID method return the sum of size
-func (c *ClientService) GetTreeqSizeByFileSystemID(filesystemID int64) (int64, error) {
+func (c *ClientService) GetTreeqSizeByFileSystemID(ctx context.Context, filesystemID int64) (int64, error) {
 	var err error
 	var size int64
 	defer func() {
@@ -136,7 +124,7 @@ func (c *ClientService) GetTreeqSizeByFileSystemID(filesystemID int64) (int64, e
 	}()
 	uri := "api/rest/filesystems/" + strconv.FormatInt(filesystemID, 10) + "/treeqs"
 	treeqArray := []Treeq{}
-	_, err = c.getJSONResponse(http.MethodGet, uri, nil, &treeqArray)
+	err = c.getAllPages(ctx, uri, nil, &treeqArray)
 	if err != nil {
 		klog.Errorf("error occured while fetching treeq list : %s ", err)
 		return 0, err
@@ -148,7 +136,7 @@ func (c *ClientService) GetTreeqSizeByFileSystemID(filesystemID int64) (int64, e
 }
 
 // DeleteTreeq :
-func (c *ClientService) DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
+func (c *ClientService) DeleteTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -157,7 +145,7 @@ func (c *ClientService) DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error)
 	}()
 	uri := "api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs/" + strconv.FormatInt(treeqID, 10)
 	treeq := Treeq{}
-	resp, err := c.getJSONResponse(http.MethodDelete, uri, nil, &treeq)
+	resp, err := c.getJSONResponse(ctx, http.MethodDelete, uri, nil, &treeq)
 	if err != nil {
 		klog.Errorf("Error occured while deleting treeq : %s ", err)
 		return nil, err
@@ -171,7 +159,7 @@ func (c *ClientService) DeleteTreeq(fileSystemID, treeqID int64) (*Treeq, error)
 }
 
 // GetTreeq
-func (c *ClientService) GetTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
+func (c *ClientService) GetTreeq(ctx context.Context, fileSystemID, treeqID int64) (*Treeq, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -180,7 +168,7 @@ func (c *ClientService) GetTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
 	}()
 	uri := "/api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs/" + strconv.FormatInt(treeqID, 10)
 	eResp := Treeq{}
-	resp, err := c.getJSONResponse(http.MethodGet, uri, nil, &eResp)
+	resp, err := c.getJSONResponse(ctx, http.MethodGet, uri, nil, &eResp)
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +180,7 @@ func (c *ClientService) GetTreeq(fileSystemID, treeqID int64) (*Treeq, error) {
 }
 
 // UpdateTreeq :
-func (c *ClientService) UpdateTreeq(fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error) {
+func (c *ClientService) UpdateTreeq(ctx context.Context, fileSystemID, treeqID int64, body map[string]interface{}) (*Treeq, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -201,7 +189,7 @@ func (c *ClientService) UpdateTreeq(fileSystemID, treeqID int64, body map[string
 	}()
 	uri := "api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs/" + strconv.FormatInt(treeqID, 10)
 	treeq := Treeq{}
-	resp, err := c.getJSONResponse(http.MethodPut, uri, body, &treeq)
+	resp, err := c.getJSONResponse(ctx, http.MethodPut, uri, body, &treeq)
 	if err != nil {
 		klog.Errorf("Error occured while updating file System : %s ", err)
 		return nil, err
@@ -215,7 +203,7 @@ func (c *ClientService) UpdateTreeq(fileSystemID, treeqID int64, body map[string
 }
 
 // GetFileSystemByName :
-func (c *ClientService) GetTreeqByName(fileSystemID int64, treeqName string) (*Treeq, error) {
+func (c *ClientService) GetTreeqByName(ctx context.Context, fileSystemID int64, treeqName string) (*Treeq, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -224,9 +212,8 @@ func (c *ClientService) GetTreeqByName(fileSystemID int64, treeqName string) (*T
 	}()
 	uri := "api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs"
 	treeq := []Treeq{}
-	queryParam := make(map[string]interface{})
-	queryParam["name"] = treeqName
-	resp, err := c.getResponseWithQueryString(uri, queryParam, &treeq)
+	query := NewQuery().Eq("name", treeqName)
+	resp, err := c.getResponseWithQueryString(ctx, uri, query, &treeq)
 	if err != nil {
 		return nil, err
 	}
@@ -240,5 +227,21 @@ func (c *ClientService) GetTreeqByName(fileSystemID int64, treeqName string) (*T
 			return &fsystem, nil
 		}
 	}
-	return nil, errors.New("treeq with given name not found")
+	return nil, notFoundError(ErrTreeqNotFound, "treeq with given name not found")
+}
+
+// GetTreeqsByFileSystemID returns the treeqs of a filesystem
+func (c *ClientService) GetTreeqsByFileSystemID(ctx context.Context, fileSystemID int64) (treeqs []Treeq, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("GetTreeqsByFileSystemID Panic occured -  " + fmt.Sprint(res))
+		}
+	}()
+	uri := "/api/rest/filesystems/" + strconv.FormatInt(fileSystemID, 10) + "/treeqs"
+	err = c.getAllPages(ctx, uri, nil, &treeqs)
+	if err != nil {
+		klog.Errorf("error occured while fetching treeqs of filesystem %d : %s ", fileSystemID, err)
+		return nil, err
+	}
+	return treeqs, nil
 }
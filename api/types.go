@@ -47,6 +47,7 @@ type Volume struct {
 	Depth                 int    `json:"depth,omitempty"`
 	WriteProtected        bool   `json:"write_protected,omitempty"`
 	Mapped                bool   `json:"mapped,omitempty"`
+	QosPolicyID           int64  `json:"qos_policy_id,omitempty"`
 }
 
 type VolumeParam struct {
@@ -55,6 +56,8 @@ type VolumeParam struct {
 	Name          string `json:"name,omitempty"`
 	ProvisionType string `json:"provtype,omitempty"`
 	SsdEnabled    bool   `json:"ssd_enabled,omitempty"`
+	// CompressionEnabled is nil to keep the default of the pool
+	CompressionEnabled *bool `json:"compression_enabled,omitempty"`
 }
 
 type VolumeResp struct {
@@ -103,6 +106,7 @@ type SnapshotVolumesResp struct {
 	ParentID   int    `json:"parent_id,omitempty"`
 	PoolID     int    `json:"pool_id,omitempty"`
 	Name       string `json:"name,omitempty"`
+	CreatedAt  int64  `json:"created_at,omitempty"`
 }
 
 type NetworkSpace struct {
@@ -244,6 +248,9 @@ type FileSystem struct {
 	ParentID   int64  `json:"parent_id,omitempty"`
 	PoolName   string `json:"pool_name,omitempty"`
 	CreatedAt  int    `json:"created_at,omitempty"`
+	// WriteProtected is set for snapshots, clones restored from snapshots are writable
+	WriteProtected bool  `json:"write_protected,omitempty"`
+	QosPolicyID    int64 `json:"qos_policy_id,omitempty"`
 }
 
 // FileSystemMetaData
@@ -287,6 +294,8 @@ type FileSystemSnapshot struct {
 	ParentID       int64  `json:"parent_id"`
 	SnapshotName   string `json:"name"`
 	WriteProtected bool   `json:"write_protected"`
+	// LockExpiresAt locks the snapshot until the given time, in milliseconds since the epoch
+	LockExpiresAt int64 `json:"lock_expires_at,omitempty"`
 }
 
 // FileSystemSnapshotResponce file system snapshot Response
@@ -311,6 +320,15 @@ type VolumeSnapshot struct {
 	SnapshotName   string `json:"name"`
 	WriteProtected bool   `json:"write_protected"`
 	SsdEnabled     bool   `json:"ssd_enabled,omitempty"`
+	// LockExpiresAt locks the snapshot until the given time, in milliseconds since the epoch
+	LockExpiresAt int64 `json:"lock_expires_at,omitempty"`
+}
+
+// System is the InfiniBox array the client is connected to
+type System struct {
+	Name         string `json:"name,omitempty"`
+	SerialNumber int64  `json:"serial_number,omitempty"`
+	Version      string `json:"version,omitempty"`
 }
 
 // FC
@@ -0,0 +1,162 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+
+// infinibox-replication calls the replication service of the driver controller, to fail replicated
+// volumes over to the array of a second cluster. It is shipped in the driver image and run in the
+// driver container of the controller, whose CSI_ENDPOINT it dials by default.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"infinibox-csi-driver/replication"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rexray/gocsi/utils"
+	"google.golang.org/grpc"
+)
+
+const usage = `Usage: infinibox-replication [flags] COMMAND
+
+Commands:
+  promote VOLUME_ID
+        make the replication target volume VOLUME_ID, '<id>$$<proto>', writable
+  import PV_NAME VOLUME_ID [PARAMETER=VALUE...]
+        print the volumeHandle and volumeAttributes of the pre-provisioned PV PV_NAME backed
+        by VOLUME_ID, given the StorageClass parameters of the PV
+
+The driver uses its own secret to reach the array of the volume.
+
+Flags:
+`
+
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses and runs the command of args, printing its result to stdout and usage errors to stderr
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("infinibox-replication", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	endpoint := flags.String("endpoint", os.Getenv(utils.CSIEndpoint), "the CSI endpoint of the driver controller")
+	timeout := flags.Duration("timeout", time.Minute, "the timeout of the call")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var call func(context.Context, replication.ReplicationClient) error
+	switch command := args[0]; {
+	case command == "promote" && len(args) == 2:
+		call = func(ctx context.Context, client replication.ReplicationClient) error {
+			return promote(ctx, client, args[1], stdout)
+		}
+	case command == "import" && len(args) >= 3:
+		parameters, err := parseParameters(args[3:])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return errUsage
+		}
+		call = func(ctx context.Context, client replication.ReplicationClient) error {
+			return importVolume(ctx, client, args[1], args[2], parameters, stdout)
+		}
+	default:
+		flags.Usage()
+		return errUsage
+	}
+
+	conn, err := dial(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return call(ctx, replication.NewReplicationClient(conn))
+}
+
+// dial connects to the CSI endpoint, a unix socket path or a '<network>://<address>' URL
+func dial(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+	network, address, err := utils.ParseProtoAddr(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint '%s': %v", endpoint, err)
+	}
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}
+	return grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithContextDialer(dialer))
+}
+
+// parseParameters parses the PARAMETER=VALUE arguments of import
+func parseParameters(args []string) (map[string]string, error) {
+	parameters := make(map[string]string)
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid parameter '%s', expected PARAMETER=VALUE", arg)
+		}
+		parameters[kv[0]] = kv[1]
+	}
+	return parameters, nil
+}
+
+func promote(ctx context.Context, client replication.ReplicationClient, volumeID string, stdout io.Writer) error {
+	if _, err := client.PromoteVolume(ctx, &replication.PromoteVolumeRequest{VolumeId: volumeID}); err != nil {
+		return fmt.Errorf("failed to promote volume %s: %v", volumeID, err)
+	}
+	fmt.Fprintf(stdout, "promoted volume %s\n", volumeID)
+	return nil
+}
+
+// importVolume prints the csi section of the PV spec, to complete the PV with
+func importVolume(ctx context.Context, client replication.ReplicationClient, name, volumeID string, parameters map[string]string, stdout io.Writer) error {
+	resp, err := client.ImportVolume(ctx, &replication.ImportVolumeRequest{Name: name, VolumeId: volumeID, Parameters: parameters})
+	if err != nil {
+		return fmt.Errorf("failed to import volume %s: %v", volumeID, err)
+	}
+	volume := resp.GetVolume()
+	fmt.Fprintf(stdout, "volumeHandle: %s\n", strconv.Quote(volume.GetVolumeId()))
+	keys := make([]string, 0, len(volume.GetVolumeContext()))
+	for key := range volume.GetVolumeContext() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		fmt.Fprintln(stdout, "volumeAttributes:")
+	}
+	for _, key := range keys {
+		fmt.Fprintf(stdout, "  %s: %s\n", key, strconv.Quote(volume.GetVolumeContext()[key]))
+	}
+	return nil
+}
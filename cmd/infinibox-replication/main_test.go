@@ -0,0 +1,118 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"infinibox-csi-driver/replication"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type MainTestSuite struct {
+	suite.Suite
+	server   *replicationServerMock
+	endpoint string
+}
+
+func TestMainTestSuite(t *testing.T) {
+	suite.Run(t, new(MainTestSuite))
+}
+
+// replicationServerMock records the requests it is called with
+type replicationServerMock struct {
+	replication.UnimplementedReplicationServer
+	promoteReq *replication.PromoteVolumeRequest
+	importReq  *replication.ImportVolumeRequest
+}
+
+func (m *replicationServerMock) PromoteVolume(ctx context.Context, req *replication.PromoteVolumeRequest) (*replication.PromoteVolumeResponse, error) {
+	m.promoteReq = req
+	if req.VolumeId == "200$$iscsi" {
+		return nil, status.Error(codes.NotFound, "volume not found")
+	}
+	return &replication.PromoteVolumeResponse{}, nil
+}
+
+func (m *replicationServerMock) ImportVolume(ctx context.Context, req *replication.ImportVolumeRequest) (*replication.ImportVolumeResponse, error) {
+	m.importReq = req
+	return &replication.ImportVolumeResponse{Volume: &replication.Volume{
+		VolumeId:      req.VolumeId,
+		VolumeContext: map[string]string{"storage_protocol": "iscsi", "pool_name": "pool"},
+	}}, nil
+}
+
+func (suite *MainTestSuite) SetupTest() {
+	suite.endpoint = filepath.Join(suite.T().TempDir(), "csi.sock")
+	listener, err := net.Listen("unix", suite.endpoint)
+	suite.Require().Nil(err)
+	suite.server = &replicationServerMock{}
+	server := grpc.NewServer()
+	replication.RegisterReplicationServer(server, suite.server)
+	go server.Serve(listener)
+	suite.T().Cleanup(server.Stop)
+}
+
+func (suite *MainTestSuite) run(args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), append([]string{"--endpoint", suite.endpoint}, args...), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func (suite *MainTestSuite) Test_promote() {
+	stdout, _, err := suite.run("promote", "100$$iscsi")
+	assert.Nil(suite.T(), err, "expected to succeed: promote")
+	assert.Equal(suite.T(), "100$$iscsi", suite.server.promoteReq.GetVolumeId())
+	assert.Equal(suite.T(), "promoted volume 100$$iscsi\n", stdout)
+}
+
+func (suite *MainTestSuite) Test_promote_error() {
+	_, _, err := suite.run("promote", "200$$iscsi")
+	assert.NotNil(suite.T(), err, "expected to fail: promote of a missing volume")
+	assert.Contains(suite.T(), err.Error(), "volume not found")
+}
+
+func (suite *MainTestSuite) Test_import() {
+	stdout, _, err := suite.run("import", "pv-dr", "100$$iscsi", "pool_name=pool", "fs_type=xfs")
+	assert.Nil(suite.T(), err, "expected to succeed: import")
+	assert.Equal(suite.T(), "pv-dr", suite.server.importReq.GetName())
+	assert.Equal(suite.T(), "100$$iscsi", suite.server.importReq.GetVolumeId())
+	assert.Equal(suite.T(), map[string]string{"pool_name": "pool", "fs_type": "xfs"}, suite.server.importReq.GetParameters())
+	assert.Equal(suite.T(), "volumeHandle: \"100$$iscsi\"\nvolumeAttributes:\n  pool_name: \"pool\"\n  storage_protocol: \"iscsi\"\n", stdout)
+}
+
+func (suite *MainTestSuite) Test_import_invalidParameter() {
+	_, stderr, err := suite.run("import", "pv-dr", "100$$iscsi", "pool_name")
+	assert.Equal(suite.T(), errUsage, err, "expected to fail: import with an invalid parameter")
+	assert.Contains(suite.T(), stderr, "invalid parameter 'pool_name'")
+	assert.Nil(suite.T(), suite.server.importReq, "expected import not to be called")
+}
+
+func (suite *MainTestSuite) Test_invalidCommand() {
+	_, stderr, err := suite.run("promote")
+	assert.Equal(suite.T(), errUsage, err, "expected to fail: promote without a volume ID")
+	assert.Contains(suite.T(), stderr, "Usage: infinibox-replication")
+}
+
+func (suite *MainTestSuite) Test_noEndpoint() {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"--endpoint", "", "promote", "100$$iscsi"}, &stdout, &stderr)
+	assert.NotNil(suite.T(), err, "expected to fail: promote without an endpoint")
+	assert.Contains(suite.T(), err.Error(), "invalid endpoint")
+}
@@ -19,8 +19,8 @@ require (
 	k8s.io/client-go v0.21.0
 	k8s.io/klog v1.0.0
 	k8s.io/kubernetes v1.21.0
-	k8s.io/mount-utils v0.21.1-rc.0
-	k8s.io/utils v0.0.0-20210305010621-2afb4311ab10
+	k8s.io/mount-utils v0.23.0
+	k8s.io/utils v0.0.0-20210930125809-cb0fa318a74b
 )
 
 replace k8s.io/api => k8s.io/api v0.21.0
@@ -29,7 +29,7 @@ replace k8s.io/apimachinery => k8s.io/apimachinery v0.21.1-rc.0
 
 replace k8s.io/client-go => k8s.io/client-go v0.21.0
 
-replace k8s.io/mount-utils => k8s.io/mount-utils v0.21.1-rc.0
+replace k8s.io/mount-utils => k8s.io/mount-utils v0.23.0
 
 replace k8s.io/apiextensions-apiserver => k8s.io/apiextensions-apiserver v0.21.0
 
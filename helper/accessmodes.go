@@ -52,6 +52,10 @@ func (a AccessMode) IsValidAccessMode(volume *api.Volume, req *csi.ControllerPub
 		if isIboxVolWriteProtected {
 			return false, fmt.Errorf("IBox Volume name '%s' (%s) is write protected, but the requested access mode is '%s'", volName, volId, friendlyModeName)
 		}
+		// a replication target only becomes writable once promoted to the source of its replica
+		if volume.RmrTarget {
+			return false, fmt.Errorf("IBox Volume name '%s' (%s) is a replication target, promote it before requesting access mode '%s'", volName, volId, friendlyModeName)
+		}
 		return true, nil
 	case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
 		csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
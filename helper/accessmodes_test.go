@@ -0,0 +1,41 @@
+package helper
+
+import (
+	"infinibox-csi-driver/api"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// TestIsValidAccessMode tests that write access is refused to write protected volumes and
+// unpromoted replication targets.
+func TestIsValidAccessMode(t *testing.T) {
+	tests := []struct {
+		volume  api.Volume
+		mode    csi.VolumeCapability_AccessMode_Mode
+		wanterr string
+	}{
+		{api.Volume{}, csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, ""},
+		{api.Volume{WriteProtected: true}, csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "write protected"},
+		{api.Volume{WriteProtected: true}, csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY, ""},
+		{api.Volume{RmrTarget: true}, csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "replication target"},
+		{api.Volume{RmrTarget: true}, csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY, ""},
+		{api.Volume{RmrSource: true}, csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, ""},
+	}
+
+	for _, test := range tests {
+		req := &csi.ControllerPublishVolumeRequest{
+			VolumeId: "100$$iscsi",
+			VolumeCapability: &csi.VolumeCapability{
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: test.mode},
+			},
+		}
+		valid, err := AccessMode{}.IsValidAccessMode(&test.volume, req)
+		if !ErrorContains(err, test.wanterr) {
+			t.Errorf(`IsValidAccessMode(%+v, %s) has err: %v`, test.volume, test.mode, err)
+		}
+		if valid != (test.wanterr == "") {
+			t.Errorf(`IsValidAccessMode(%+v, %s) != %t`, test.volume, test.mode, test.wanterr == "")
+		}
+	}
+}
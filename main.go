@@ -82,6 +82,15 @@ func getConfigParams() map[string]string {
 	if driverversion, ok := csictx.LookupEnv(context.Background(), "CSI_DRIVER_VERSION"); ok {
 		configParams["driverversion"] = driverversion
 	}
+	if secretname, ok := csictx.LookupEnv(context.Background(), "CSI_DRIVER_SECRET_NAME"); ok {
+		configParams["secretname"] = secretname
+	}
+	if secretnamespace, ok := csictx.LookupEnv(context.Background(), "CSI_DRIVER_SECRET_NAMESPACE"); ok {
+		configParams["secretnamespace"] = secretnamespace
+	}
+	if topology, ok := csictx.LookupEnv(context.Background(), "CSI_DRIVER_TOPOLOGY"); ok {
+		configParams["topology"] = topology
+	}
 	return configParams
 }
 
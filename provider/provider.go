@@ -20,10 +20,11 @@ import (
 func New(config map[string]string) gocsi.StoragePluginProvider {
 	srvc := service.New(config)
 	return &gocsi.StoragePlugin{
-		Controller:  srvc,
-		Node:        srvc,
-		Identity:    srvc,
-		BeforeServe: srvc.BeforeServe,
+		Controller:   srvc,
+		Node:         srvc,
+		Identity:     srvc,
+		BeforeServe:  srvc.BeforeServe,
+		Interceptors: service.Interceptors,
 		EnvVars: []string{
 			// Enable request validation
 			gocsi.EnvVarSpecReqValidation + "=true",
@@ -0,0 +1,38 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package replication
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnknownServiceHandler serves the Replication service with srv on a server it cannot be registered on,
+// such as the gocsi server of the CSI services, when installed with grpc.UnknownServiceHandler. The
+// generated method handlers decode the requests and run interceptor, which may be nil, as a registered
+// service would
+func UnknownServiceHandler(srv ReplicationServer, interceptor grpc.UnaryServerInterceptor) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		fullMethod, _ := grpc.MethodFromServerStream(stream)
+		for _, method := range _Replication_serviceDesc.Methods {
+			if fullMethod != "/"+_Replication_serviceDesc.ServiceName+"/"+method.MethodName {
+				continue
+			}
+			resp, err := method.Handler(srv, stream.Context(), stream.RecvMsg, interceptor)
+			if err != nil {
+				return err
+			}
+			return stream.SendMsg(resp)
+		}
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+}
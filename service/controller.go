@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/storage"
+	"sort"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -69,6 +70,15 @@ func (s *service) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest
 		err = status.Errorf(codes.Internal, "failed to initialize storage controller while creating volume '%s'", volName)
 		return nil, err
 	}
+	// check the array is accessible from the requisite topology before creating the volume
+	var accessibleTopology []*csi.Topology
+	if topologyProvider, ok := storageController.(storage.TopologyProvider); ok {
+		accessibleTopology, err = topologyProvider.GetAccessibleTopology(ctx, storageprotocol, req.GetParameters(), req.GetAccessibilityRequirements())
+		if err != nil {
+			klog.Errorf("CreateVolume error: %v", err)
+			return nil, err
+		}
+	}
 	createVolResp, err = storageController.CreateVolume(ctx, req)
 	if err != nil {
 		klog.Errorf("CreateVolume error: %v", err)
@@ -85,6 +95,7 @@ func (s *service) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest
 		return nil, err
 	}
 	createVolResp.Volume.VolumeId = createVolResp.Volume.VolumeId + "$$" + storageprotocol
+	createVolResp.Volume.AccessibleTopology = accessibleTopology
 	klog.V(2).Infof("CreateVolume success, resp: %v", createVolResp)
 	return
 }
@@ -270,16 +281,221 @@ func (s *service) ValidateVolumeCapabilities(ctx context.Context, req *csi.Valid
 	return
 }
 
-func (s *service) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+// storageProtocols lists the protocols whose volumes are walked by ListVolumes
+var storageProtocols = []string{"fc", "iscsi", "nfs", "nfs_treeq"}
+
+// ListVolumes method lists the volumes of all protocols, on every array the driver PVs were provisioned on.
+// The request carries no secrets, the arrays are named by the secrets of the PVs and the driver's own secret
+func (s *service) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (listVolResp *csi.ListVolumesResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from CSI ListVolumes  " + fmt.Sprint(res))
+		}
+	}()
+
+	klog.V(2).Infof("ListVolumes called with max entries %d and starting token '%s'", req.GetMaxEntries(), req.GetStartingToken())
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ListVolumes max_entries %d must not be negative", req.GetMaxEntries())
+	}
+
+	refs, err := s.getArraySecretRefs()
+	if err != nil {
+		klog.Errorf("ListVolumes failed to list PVs: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "ListVolumes failed to list PVs: %v", err)
+	}
+
+	config := make(map[string]string)
+	config["nodeid"] = s.nodeID
+	config["driverversion"] = s.driverVersion
+
+	entries := []*csi.ListVolumesResponse_Entry{}
+	listed := make(map[string]bool)
+	for _, ref := range refs {
+		secrets, err := s.getArraySecrets(ref)
+		if err != nil {
+			klog.Errorf("ListVolumes failed to get secret %s/%s: %v", ref.namespace, ref.name, err)
+			return nil, status.Errorf(codes.FailedPrecondition, "ListVolumes failed to get secret %s/%s: %v", ref.namespace, ref.name, err)
+		}
+		// the controllers of all protocols share one scan of the array metadata
+		arrayCtx := storage.WithPVObjectScan(ctx)
+		for _, protocol := range storageProtocols {
+			storageController, err := storage.NewStorageController(protocol, config, secrets)
+			if err != nil || storageController == nil {
+				klog.Errorf("ListVolumes failed to initialise storage controller %s: %v", protocol, err)
+				return nil, status.Errorf(codes.Internal, "ListVolumes failed to initialise storage controller: %s", protocol)
+			}
+			resp, err := storageController.ListVolumes(arrayCtx, &csi.ListVolumesRequest{})
+			if err != nil {
+				klog.Errorf("ListVolumes failed for protocol %s: %v", protocol, err)
+				return nil, err
+			}
+			for _, entry := range resp.GetEntries() {
+				entry.Volume.VolumeId = entry.Volume.VolumeId + "$$" + protocol
+				// secrets of several users of one array list its volumes once
+				if listed[entry.Volume.VolumeId] {
+					continue
+				}
+				listed[entry.Volume.VolumeId] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	// a stable order keeps the numeric starting_token meaningful across calls
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Volume.VolumeId < entries[j].Volume.VolumeId
+	})
+	start, end, nextToken, err := getPage(len(entries), req.GetStartingToken(), req.GetMaxEntries())
+	if err != nil {
+		klog.Errorf("ListVolumes failed: %v", err)
+		return nil, err
+	}
+	klog.V(2).Infof("ListVolumes returning %d of %d volumes, next token '%s'", end-start, len(entries), nextToken)
+	return &csi.ListVolumesResponse{
+		Entries:   entries[start:end],
+		NextToken: nextToken,
+	}, nil
+}
+
+// ListSnapshots method lists the snapshots matching the snapshot_id or source_volume_id filter, or the snapshots of all protocols
+func (s *service) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (listSnapshotsResp *csi.ListSnapshotsResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from CSI ListSnapshots  " + fmt.Sprint(res))
+		}
+	}()
+
+	klog.V(2).Infof("ListSnapshots called with snapshot ID '%s', source volume ID '%s', max entries %d and starting token '%s'",
+		req.GetSnapshotId(), req.GetSourceVolumeId(), req.GetMaxEntries(), req.GetStartingToken())
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ListSnapshots max_entries %d must not be negative", req.GetMaxEntries())
+	}
+
+	protocols := storageProtocols
+	filterID := req.GetSnapshotId()
+	if filterID == "" {
+		filterID = req.GetSourceVolumeId()
+	}
+	if filterID != "" {
+		volproto, err := s.validateVolumeID(filterID)
+		if err != nil || !isStorageProtocol(volproto.StorageType) {
+			// no snapshot can match an ID we did not create
+			klog.V(4).Infof("ListSnapshots ID '%s' is not a driver ID, returning no snapshots", filterID)
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		protocols = []string{volproto.StorageType}
+	}
+
+	secrets, err := s.getSecretsOrDefault(req.GetSecrets())
+	if err != nil {
+		klog.Errorf("ListSnapshots failed to get secrets: %v", err)
+		return nil, status.Errorf(codes.FailedPrecondition, "ListSnapshots failed to get secrets: %v", err)
+	}
+
+	config := make(map[string]string)
+	config["nodeid"] = s.nodeID
+	config["driverversion"] = s.driverVersion
+
+	// the controllers of all protocols share one scan of the array metadata
+	ctx = storage.WithPVObjectScan(ctx)
+	entries := []*csi.ListSnapshotsResponse_Entry{}
+	for _, protocol := range protocols {
+		storageController, err := storage.NewStorageController(protocol, config, secrets)
+		if err != nil || storageController == nil {
+			klog.Errorf("ListSnapshots failed to initialise storage controller %s: %v", protocol, err)
+			return nil, status.Errorf(codes.Internal, "ListSnapshots failed to initialise storage controller: %s", protocol)
+		}
+		resp, err := storageController.ListSnapshots(ctx, req)
+		if err != nil {
+			klog.Errorf("ListSnapshots failed for protocol %s: %v", protocol, err)
+			return nil, err
+		}
+		entries = append(entries, resp.GetEntries()...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Snapshot.SnapshotId < entries[j].Snapshot.SnapshotId
+	})
+	start, end, nextToken, err := getPage(len(entries), req.GetStartingToken(), req.GetMaxEntries())
+	if err != nil {
+		klog.Errorf("ListSnapshots failed: %v", err)
+		return nil, err
+	}
+	klog.V(2).Infof("ListSnapshots returning %d of %d snapshots, next token '%s'", end-start, len(entries), nextToken)
+	return &csi.ListSnapshotsResponse{
+		Entries:   entries[start:end],
+		NextToken: nextToken,
+	}, nil
 }
 
-func (s *service) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+func isStorageProtocol(protocol string) bool {
+	for _, p := range storageProtocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *service) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+// GetCapacity method returns the free capacity of the pool named by the StorageClass parameters, on the array
+// of the StorageClass provisioner secret, none for a topology whose nodes do not reach the array
+func (s *service) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (capacityResp *csi.GetCapacityResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from CSI GetCapacity  " + fmt.Sprint(res))
+		}
+	}()
+
+	params := req.GetParameters()
+	storageprotocol := params["storage_protocol"]
+	klog.V(2).Infof("GetCapacity called with storage_protocol '%s' pool_name '%s' topology %v",
+		storageprotocol, params["pool_name"], req.GetAccessibleTopology())
+	if len(storageprotocol) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no 'storage_protocol' provided to GetCapacity")
+	}
+	if len(params["pool_name"]) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no 'pool_name' provided to GetCapacity")
+	}
+
+	// the request carries no secrets, the pool is on the array of the StorageClass provisioner secret
+	secrets, err := s.getStorageClassSecrets(params)
+	if err != nil {
+		klog.Errorf("GetCapacity failed to get the StorageClass secret: %v", err)
+		return nil, status.Errorf(codes.FailedPrecondition, "GetCapacity failed to get the StorageClass secret: %v", err)
+	}
+
+	config := make(map[string]string)
+	config["nodeid"] = s.nodeID
+	config["driverversion"] = s.driverVersion
+
+	storageController, err := storage.NewStorageController(storageprotocol, config, secrets)
+	if err != nil || storageController == nil {
+		klog.Errorf("GetCapacity failed to initialise storage controller %s: %v", storageprotocol, err)
+		return nil, status.Errorf(codes.InvalidArgument, "GetCapacity failed to initialise storage controller: %s", storageprotocol)
+	}
+	// the pool has no capacity for the nodes of a topology which does not reach the array
+	if topology := req.GetAccessibleTopology(); topology != nil {
+		topologyProvider, ok := storageController.(storage.TopologyProvider)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "GetCapacity does not support topology for storage_protocol %s", storageprotocol)
+		}
+		requirements := &csi.TopologyRequirement{Requisite: []*csi.Topology{topology}}
+		if _, err = topologyProvider.GetAccessibleTopology(ctx, storageprotocol, params, requirements); err != nil {
+			if status.Code(err) == codes.ResourceExhausted {
+				klog.V(2).Infof("GetCapacity pool %s is not accessible from topology %v", params["pool_name"], topology.GetSegments())
+				return &csi.GetCapacityResponse{AvailableCapacity: 0}, nil
+			}
+			klog.Errorf("GetCapacity failed to check topology %v: %v", topology.GetSegments(), err)
+			return nil, err
+		}
+	}
+	capacityResp, err = storageController.GetCapacity(ctx, req)
+	if err != nil {
+		klog.Errorf("GetCapacity failed for pool %s: %v", params["pool_name"], err)
+		return nil, err
+	}
+	klog.V(2).Infof("GetCapacity pool %s has %d bytes available", params["pool_name"], capacityResp.GetAvailableCapacity())
+	return capacityResp, nil
 }
 
 func (s *service) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
@@ -292,21 +508,21 @@ func (s *service) ControllerGetCapabilities(ctx context.Context, req *csi.Contro
 					},
 				},
 			},
-			// &csi.ControllerServiceCapability{
-			// 	Type: &csi.ControllerServiceCapability_Rpc{
-			// 		Rpc: &csi.ControllerServiceCapability_RPC{
-			// 			Type: csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
-			// 		},
-			// 	},
-			// },
-
-			// {
-			// 	Type: &csi.ControllerServiceCapability_Rpc{
-			// 		Rpc: &csi.ControllerServiceCapability_RPC{
-			// 			Type: csi.ControllerServiceCapability_RPC_GET_CAPACITY,
-			// 		},
-			// 	},
-			// },
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+					},
+				},
+			},
+
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+					},
+				},
+			},
 
 			{
 				Type: &csi.ControllerServiceCapability_Rpc{
@@ -329,13 +545,13 @@ func (s *service) ControllerGetCapabilities(ctx context.Context, req *csi.Contro
 					},
 				},
 			},
-			// &csi.ControllerServiceCapability{
-			// 	Type: &csi.ControllerServiceCapability_Rpc{
-			// 		Rpc: &csi.ControllerServiceCapability_RPC{
-			// 			Type: csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
-			// 		},
-			// 	},
-			// },
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+					},
+				},
+			},
 			{
 				Type: &csi.ControllerServiceCapability_Rpc{
 					Rpc: &csi.ControllerServiceCapability_RPC{
@@ -343,6 +559,20 @@ func (s *service) ControllerGetCapabilities(ctx context.Context, req *csi.Contro
 					},
 				},
 			},
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_GET_VOLUME,
+					},
+				},
+			},
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -441,10 +671,52 @@ func (s *service) ControllerExpandVolume(ctx context.Context, req *csi.Controlle
 	return
 }
 
-func (s *service) ControllerGetVolume(
-	_ context.Context, _ *csi.ControllerGetVolumeRequest,
-) (*csi.ControllerGetVolumeResponse, error) {
+// ControllerGetVolume method returns the published nodes and condition of a volume, read from the array of the secret of its PV
+func (s *service) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (getVolumeResp *csi.ControllerGetVolumeResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from CSI ControllerGetVolume  " + fmt.Sprint(res))
+		}
+	}()
 
-	// Infinidat does not support ControllerGetVolume
-	return nil, status.Error(codes.Unimplemented, "")
+	volumeId := req.GetVolumeId()
+	klog.V(2).Infof("ControllerGetVolume called with volume ID %s", volumeId)
+	volproto, err := s.validateVolumeID(volumeId)
+	if err != nil {
+		klog.Errorf("failed to validate volume ID %s: %v", volumeId, err)
+		return nil, err
+	}
+
+	// the request carries no secrets, the volume is on the array of the secret of its PV
+	ref, err := s.getVolumeSecretRef(volumeId)
+	if err != nil {
+		klog.Errorf("ControllerGetVolume failed to list PVs: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "ControllerGetVolume failed to list PVs: %v", err)
+	}
+	secrets, err := s.getArraySecrets(ref)
+	if err != nil {
+		klog.Errorf("ControllerGetVolume failed to get secret %s/%s: %v", ref.namespace, ref.name, err)
+		return nil, status.Errorf(codes.FailedPrecondition, "ControllerGetVolume failed to get secret %s/%s: %v", ref.namespace, ref.name, err)
+	}
+
+	config := make(map[string]string)
+	config["nodeid"] = s.nodeID
+	config["driverversion"] = s.driverVersion
+
+	storageController, err := storage.NewStorageController(volproto.StorageType, config, secrets)
+	if err != nil || storageController == nil {
+		klog.Errorf("ControllerGetVolume failed to initialise storage controller %s: %v", volproto.StorageType, err)
+		return nil, status.Errorf(codes.NotFound, "ControllerGetVolume failed to initialise storage controller: %s", volproto.StorageType)
+	}
+	req.VolumeId = volproto.VolumeID
+	getVolumeResp, err = storageController.ControllerGetVolume(ctx, req)
+	req.VolumeId = volumeId
+	if err != nil {
+		klog.Errorf("ControllerGetVolume failed for volume ID %s: %v", volumeId, err)
+		return nil, err
+	}
+	getVolumeResp.Volume.VolumeId = volumeId
+	klog.V(2).Infof("ControllerGetVolume volume ID %s published to %v, condition: %v",
+		volumeId, getVolumeResp.GetStatus().GetPublishedNodeIds(), getVolumeResp.GetStatus().GetVolumeCondition())
+	return getVolumeResp, nil
 }
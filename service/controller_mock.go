@@ -6,6 +6,8 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ControllerMock struct {
@@ -34,11 +36,11 @@ func (m *ControllerMock) ValidateVolumeCapabilities(context.Context, *csi.Valida
 }
 
 func (m *ControllerMock) ListVolumes(context.Context, *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	return &csi.ListVolumesResponse{}, nil
+	return &csi.ListVolumesResponse{Entries: []*csi.ListVolumesResponse_Entry{{Volume: &csi.Volume{VolumeId: "100"}}}}, nil
 }
 
 func (m *ControllerMock) GetCapacity(context.Context, *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	return &csi.GetCapacityResponse{}, nil
+	return &csi.GetCapacityResponse{AvailableCapacity: 1073741824}, nil
 }
 
 func (m *ControllerMock) ControllerGetCapabilities(context.Context, *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
@@ -57,10 +59,58 @@ func (s *ControllerMock) ControllerExpandVolume(ctx context.Context, req *csi.Co
 	return &csi.ControllerExpandVolumeResponse{}, nil
 }
 
-func (s *ControllerMock) ListSnapshots(context.Context, *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return &csi.ListSnapshotsResponse{}, nil
+func (s *ControllerMock) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	return &csi.ListSnapshotsResponse{Entries: []*csi.ListSnapshotsResponse_Entry{{Snapshot: &csi.Snapshot{SnapshotId: "200$$nfs", SourceVolumeId: "100$$nfs"}}}}, nil
 }
 
 func (s *ControllerMock) ControllerGetVolume(context.Context, *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	return &csi.ControllerGetVolumeResponse{}, nil
+	return &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{VolumeId: "100"},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{
+			PublishedNodeIds: []string{"host1"},
+			VolumeCondition:  &csi.VolumeCondition{Abnormal: false, Message: "volume is healthy"},
+		},
+	}, nil
+}
+
+// ArrayControllerMock is the storage controller of another array, listing a volume of its own
+type ArrayControllerMock struct {
+	ControllerMock
+	volumeID string
+}
+
+func (m *ArrayControllerMock) ListVolumes(context.Context, *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	return &csi.ListVolumesResponse{Entries: []*csi.ListVolumesResponse_Entry{{Volume: &csi.Volume{VolumeId: m.volumeID}}}}, nil
+}
+
+// ReplicatorMock is the storage controller of a protocol replicating volumes
+type ReplicatorMock struct {
+	ControllerMock
+}
+
+func (m *ReplicatorMock) PromoteVolume(ctx context.Context, volumeID string) error {
+	return nil
+}
+
+func (m *ReplicatorMock) ImportVolume(ctx context.Context, pvName, volumeID string, params map[string]string) (*csi.Volume, error) {
+	return &csi.Volume{VolumeId: volumeID, VolumeContext: params}, nil
+}
+
+// ReplicatorErrorMock is the storage controller of a protocol replicating volumes, failing to promote them
+type ReplicatorErrorMock struct {
+	ReplicatorMock
+	err error
+}
+
+func (m *ReplicatorErrorMock) PromoteVolume(ctx context.Context, volumeID string) error {
+	return m.err
+}
+
+// TopologyProviderMock is the storage controller of an array out of the requisite topology
+type TopologyProviderMock struct {
+	ControllerMock
+}
+
+func (m *TopologyProviderMock) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error) {
+	return nil, status.Error(codes.ResourceExhausted, "array is not accessible from the requisite topology")
 }
@@ -12,14 +12,23 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"infinibox-csi-driver/api"
+	"infinibox-csi-driver/replication"
 	"infinibox-csi-driver/storage"
 	tests "infinibox-csi-driver/test_helper"
+	"net/http"
 	"testing"
+	"time"
 
 	"bou.ke/monkey"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
 )
 
 type ControllerTestSuite struct {
@@ -56,6 +65,20 @@ func (suite *ControllerTestSuite) Test_CreateVolume_InvalidStorageProtocol() {
 	assert.NotNil(suite.T(), err, "expected to fail: Controller CreateVolume storage_protocol invalid")
 }
 
+func (suite *ControllerTestSuite) Test_CreateVolume_topology_fail() {
+	parameterMap := getControllerCreateVolumeParameters()
+	createVolumeReq := tests.GetCreateVolumeRequest("pvcName", parameterMap, "")
+	s := getService()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &TopologyProviderMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	_, err := s.CreateVolume(context.Background(), createVolumeReq)
+	assert.NotNil(suite.T(), err, "expected to fail: Controller CreateVolume array out of the requisite topology")
+	assert.Equal(suite.T(), codes.ResourceExhausted, status.Code(err))
+}
+
 func (suite *ControllerTestSuite) Test_CreateVolume_No_VolumeCapabilities_fail() {
 	parameterMap := getControllerCreateVolumeParameters()
 	createVolumeReq := tests.GetCreateVolumeRequest("pvcName", parameterMap, "")
@@ -232,6 +255,47 @@ func (suite *ControllerTestSuite) Test_CreateSnapshot_success() {
 	assert.Nil(suite.T(), err, "expected to succeed: Controller CreateSnapshot")
 }
 
+func (suite *ControllerTestSuite) Test_PromoteVolume_notReplicated() {
+	s := getService()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	_, err := s.PromoteVolume(context.Background(), &replication.PromoteVolumeRequest{VolumeId: "100$$nfs", Secrets: tests.GetSecret()})
+	assert.Equal(suite.T(), codes.Unimplemented, status.Code(err), "expected to fail: PromoteVolume of an nfs volume")
+}
+
+func (suite *ControllerTestSuite) Test_PromoteVolume_success() {
+	s := getService()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ReplicatorMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	_, err := s.PromoteVolume(context.Background(), &replication.PromoteVolumeRequest{VolumeId: "100$$iscsi", Secrets: tests.GetSecret()})
+	assert.Nil(suite.T(), err, "expected to succeed: PromoteVolume")
+}
+
+func (suite *ControllerTestSuite) Test_ImportVolume_success() {
+	s := getService()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ReplicatorMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.ImportVolume(context.Background(), &replication.ImportVolumeRequest{Name: "pv-dr", VolumeId: "100$$fc", Secrets: tests.GetSecret()})
+	assert.Nil(suite.T(), err, "expected to succeed: ImportVolume")
+	assert.Equal(suite.T(), "100$$fc", resp.Volume.VolumeId)
+	assert.Equal(suite.T(), "fc", resp.Volume.VolumeContext["storage_protocol"])
+}
+
+func (suite *ControllerTestSuite) Test_ImportVolume_noName() {
+	s := getService()
+	_, err := s.ImportVolume(context.Background(), &replication.ImportVolumeRequest{VolumeId: "100$$fc", Secrets: tests.GetSecret()})
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: ImportVolume without a name")
+}
+
 func (suite *ControllerTestSuite) Test_DeleteSnapshot_InvalidID_success() {
 	deleteSnapshotReq := getControllerDeleteSnapshotRequest()
 	deleteSnapshotReq.SnapshotId = "100"
@@ -311,22 +375,309 @@ func (suite *ControllerTestSuite) Test_ValidateVolumeCapabilities() {
 	assert.Nil(suite.T(), err, "expected to succeed: Controller ValidateVolumeCapabilities")
 }
 
-func (suite *ControllerTestSuite) Test_ListVolumes_unimplemented() {
+func (suite *ControllerTestSuite) Test_ListVolumes_noSecret() {
 	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return nil, nil
+	})
+	defer pvPatch.Unpatch()
 	_, err := s.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
-	assert.NotNil(suite.T(), err, "expected to fail: Controller ListVolumes unimplemented")
+	assert.NotNil(suite.T(), err, "expected to fail: Controller ListVolumes without controller secret")
+}
+
+func (suite *ControllerTestSuite) Test_ListVolumes_paging() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return nil, nil
+	})
+	defer pvPatch.Unpatch()
+	secretPatch := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer secretPatch.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.ListVolumes(context.Background(), &csi.ListVolumesRequest{MaxEntries: 3})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ListVolumes first page")
+	assert.Equal(suite.T(), 3, len(resp.Entries))
+	assert.Equal(suite.T(), "100$$fc", resp.Entries[0].Volume.VolumeId)
+	assert.Equal(suite.T(), "3", resp.NextToken)
+
+	resp, err = s.ListVolumes(context.Background(), &csi.ListVolumesRequest{MaxEntries: 3, StartingToken: resp.NextToken})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ListVolumes last page")
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "100$$nfs_treeq", resp.Entries[0].Volume.VolumeId)
+	assert.Equal(suite.T(), "", resp.NextToken)
+}
+
+func (suite *ControllerTestSuite) Test_ListVolumes_invalidToken() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return nil, nil
+	})
+	defer pvPatch.Unpatch()
+	secretPatch := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer secretPatch.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	_, err := s.ListVolumes(context.Background(), &csi.ListVolumesRequest{StartingToken: "bad"})
+	assert.Equal(suite.T(), codes.Aborted, status.Code(err), "expected to fail: Controller ListVolumes invalid starting token")
+}
+
+func (suite *ControllerTestSuite) Test_ListVolumes_arrays() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return []v1.PersistentVolume{
+			getDriverPV("100$$iscsi", map[string]string{
+				"volume.kubernetes.io/provisioner-deletion-secret-name":      "ibox2-creds",
+				"volume.kubernetes.io/provisioner-deletion-secret-namespace": "infi",
+			}),
+			getDriverPV("200$$iscsi", nil),
+			{Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "other-driver", VolumeHandle: "1"}}}},
+		}, nil
+	})
+	defer pvPatch.Unpatch()
+	var secretRefs []string
+	secretPatch := monkey.Patch(getControllerSecrets, func(name, namespace string) (map[string]string, error) {
+		secretRefs = append(secretRefs, namespace+"/"+name)
+		return map[string]string{"hostname": name}, nil
+	})
+	defer secretPatch.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, params ...map[string]string) (storage.Storageoperations, error) {
+		if params[1]["hostname"] == "ibox2-creds" {
+			return &ArrayControllerMock{volumeID: "200"}, nil
+		}
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ListVolumes")
+	assert.Equal(suite.T(), []string{"/", "infi/ibox2-creds"}, secretRefs, "expected the driver secret and the secret of the PV")
+	assert.Equal(suite.T(), 8, len(resp.Entries), "expected the volumes of both arrays")
+	assert.Equal(suite.T(), "200$$nfs_treeq", resp.Entries[7].Volume.VolumeId)
 }
 
-func (suite *ControllerTestSuite) Test_ListSnapshots_unimplemented() {
+func (suite *ControllerTestSuite) Test_ListSnapshots_noSecret() {
 	s := getService()
 	_, err := s.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
-	assert.NotNil(suite.T(), err, "expected to fail: Controller ListSnapshots unimplemented")
+	assert.NotNil(suite.T(), err, "expected to fail: Controller ListSnapshots without secrets")
+}
+
+func (suite *ControllerTestSuite) Test_ListSnapshots_bySnapshotID() {
+	s := getService()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "200$$nfs", Secrets: tests.GetSecret()})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ListSnapshots by snapshot ID")
+	assert.Equal(suite.T(), 1, len(resp.Entries), "expected only the nfs controller to be queried")
+}
+
+func (suite *ControllerTestSuite) Test_ListSnapshots_unknownID() {
+	s := getService()
+	resp, err := s.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SourceVolumeId: "100", Secrets: tests.GetSecret()})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ListSnapshots unknown ID")
+	assert.Equal(suite.T(), 0, len(resp.Entries))
+}
+
+func (suite *ControllerTestSuite) Test_ControllerGetVolume_invalidID() {
+	s := getService()
+	_, err := s.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err), "expected to fail: Controller ControllerGetVolume invalid ID")
 }
 
-func (suite *ControllerTestSuite) Test_GetCapacity_unimplemented() {
+func (suite *ControllerTestSuite) Test_ControllerGetVolume() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return nil, nil
+	})
+	defer pvPatch.Unpatch()
+	patchSecrets := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer patchSecrets.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ControllerGetVolume")
+	assert.Equal(suite.T(), "100$$iscsi", resp.Volume.VolumeId, "expected the protocol suffix to be restored")
+	assert.Equal(suite.T(), []string{"host1"}, resp.Status.PublishedNodeIds)
+}
+
+func (suite *ControllerTestSuite) Test_ControllerGetVolume_pvSecret() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		pv := getDriverPV("100$$iscsi", nil)
+		pv.Spec.CSI.ControllerExpandSecretRef = &v1.SecretReference{Name: "ibox2-creds", Namespace: "infi"}
+		return []v1.PersistentVolume{getDriverPV("200$$iscsi", nil), pv}, nil
+	})
+	defer pvPatch.Unpatch()
+	var secretRef string
+	secretPatch := monkey.Patch(getControllerSecrets, func(name, namespace string) (map[string]string, error) {
+		secretRef = namespace + "/" + name
+		return tests.GetSecret(), nil
+	})
+	defer secretPatch.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	_, err := s.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller ControllerGetVolume")
+	assert.Equal(suite.T(), "infi/ibox2-creds", secretRef, "expected the array of the secret of the PV")
+}
+
+func (suite *ControllerTestSuite) Test_ControllerGetVolume_listPVsError() {
+	s := getService()
+	pvPatch := monkey.Patch(getPersistentVolumes, func() ([]v1.PersistentVolume, error) {
+		return nil, fmt.Errorf("some error")
+	})
+	defer pvPatch.Unpatch()
+
+	_, err := s.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100$$iscsi"})
+	assert.Equal(suite.T(), codes.Unavailable, status.Code(err), "expected to fail: Controller ControllerGetVolume without PVs")
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity_noParameters() {
 	s := getService()
 	_, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{})
-	assert.NotNil(suite.T(), err, "expected to fail: Controller GetCapacity unimplemented")
+	assert.NotNil(suite.T(), err, "expected to fail: Controller GetCapacity without parameters")
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity() {
+	s := getService()
+	patchSecrets := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer patchSecrets.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	resp, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: getControllerCreateVolumeParameters()})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller GetCapacity")
+	assert.Equal(suite.T(), int64(1073741824), resp.AvailableCapacity)
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity_storageClassSecret() {
+	s := getService()
+	var secretRef string
+	patchSecrets := monkey.Patch(getControllerSecrets, func(name, namespace string) (map[string]string, error) {
+		secretRef = namespace + "/" + name
+		return tests.GetSecret(), nil
+	})
+	defer patchSecrets.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	params := getControllerCreateVolumeParameters()
+	params["csi.storage.k8s.io/provisioner-secret-name"] = "ibox2-creds"
+	params["csi.storage.k8s.io/provisioner-secret-namespace"] = "infi"
+	_, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: params})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller GetCapacity")
+	assert.Equal(suite.T(), "infi/ibox2-creds", secretRef, "expected the array of the StorageClass secret")
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity_templatedSecret() {
+	s := getService()
+	params := getControllerCreateVolumeParameters()
+	params["csi.storage.k8s.io/provisioner-secret-name"] = "${pvc.name}"
+	params["csi.storage.k8s.io/provisioner-secret-namespace"] = "infi"
+	_, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: params})
+	assert.Equal(suite.T(), codes.FailedPrecondition, status.Code(err), "expected to fail: Controller GetCapacity with a secret templated on the PVC")
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity_topologyNotAccessible() {
+	s := getService()
+	patchSecrets := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer patchSecrets.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &TopologyProviderMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	topology := &csi.Topology{Segments: map[string]string{storage.TopologyKeyNetwork: "10.20.40.0-24"}}
+	resp, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: getControllerCreateVolumeParameters(), AccessibleTopology: topology})
+	assert.Nil(suite.T(), err, "expected to succeed: Controller GetCapacity out of the topology")
+	assert.Equal(suite.T(), int64(0), resp.AvailableCapacity)
+}
+
+func (suite *ControllerTestSuite) Test_GetCapacity_topologyUnsupported() {
+	s := getService()
+	patchSecrets := monkey.Patch(getControllerSecrets, func(_, _ string) (map[string]string, error) {
+		return tests.GetSecret(), nil
+	})
+	defer patchSecrets.Unpatch()
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ControllerMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	topology := &csi.Topology{Segments: map[string]string{storage.TopologyKeyNetwork: "10.20.30.0-24"}}
+	_, err := s.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: getControllerCreateVolumeParameters(), AccessibleTopology: topology})
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: Controller GetCapacity with unsupported topology")
+}
+
+func (suite *ControllerTestSuite) Test_ContextErrorInterceptor_deadlineExceeded() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "failed to get volume")
+	}
+	_, err := ContextErrorInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(suite.T(), codes.DeadlineExceeded, status.Code(err))
+}
+
+func (suite *ControllerTestSuite) Test_ContextErrorInterceptor_wrappedDeadline() {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("Get volume: %w", context.DeadlineExceeded)
+	}
+	_, err := ContextErrorInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(suite.T(), codes.DeadlineExceeded, status.Code(err))
+}
+
+func (suite *ControllerTestSuite) Test_ContextErrorInterceptor_otherError() {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "volume not found")
+	}
+	_, err := ContextErrorInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err))
+}
+
+func (suite *ControllerTestSuite) Test_APIErrorInterceptor() {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, &api.APIError{StatusCode: http.StatusNotFound, Code: api.ErrHostNotFound, Message: "Host not found"}
+	}
+	_, err := APIErrorInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err))
+	assert.Equal(suite.T(), "HOST_NOT_FOUND Host not found", status.Convert(err).Message())
+
+	handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "MAPPING_ALREADY_EXISTS")
+	}
+	_, err = APIErrorInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(suite.T(), codes.Internal, status.Code(err), "status errors should be kept")
 }
 
 //=============================
@@ -390,6 +741,13 @@ func getControllerCreateVolumeParameters() map[string]string {
 	return map[string]string{"storage_protocol": "nfs", "pool_name": "pool_name1", "network_space": "network_space1", "nfs_export_permissions": "[{'access':'RW','client':'192.168.147.190-192.168.147.199','no_root_squash':false},{'access':'RW','client':'192.168.147.10-192.168.147.20','no_root_squash':'false'}]"}
 }
 
+func getDriverPV(volumeHandle string, annotations map[string]string) v1.PersistentVolume {
+	pv := v1.PersistentVolume{}
+	pv.Annotations = annotations
+	pv.Spec.CSI = &v1.CSIPersistentVolumeSource{Driver: "csi-driver", VolumeHandle: volumeHandle}
+	return pv
+}
+
 func getService() Service {
 	configParam := make(map[string]string)
 	configParam["nodeid"] = "10.20.30.50"
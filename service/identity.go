@@ -35,14 +35,13 @@ func (s *service) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginC
 					},
 				},
 			},
-			// To be reported when topology contstraints are supported
-			// {
-			// 	Type: &csi.PluginCapability_Service_{
-			// 		Service: &csi.PluginCapability_Service{
-			// 			Type: csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS,
-			// 		},
-			// 	},
-			// },
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -16,7 +16,6 @@ import (
 	"fmt"
 	"infinibox-csi-driver/helper"
 	"infinibox-csi-driver/storage"
-	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -101,13 +100,13 @@ func (s *service) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapab
 					},
 				},
 			},
-			// {
-			// 	Type: &csi.NodeServiceCapability_Rpc{
-			// 		Rpc: &csi.NodeServiceCapability_RPC{
-			// 			Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
-			// 		},
-			// 	},
-			// },
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+					},
+				},
+			},
 			{
 				Type: &csi.NodeServiceCapability_Rpc{
 					Rpc: &csi.NodeServiceCapability_RPC{
@@ -115,6 +114,20 @@ func (s *service) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapab
 					},
 				},
 			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+					},
+				},
+			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -123,9 +136,14 @@ func (s *service) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest)
 	nodeFQDN := s.getNodeFQDN()
 	k8sNodeID := nodeFQDN + "$$" + s.nodeID
 	klog.V(2).Infof("NodeGetInfo NodeId: %s", k8sNodeID)
-	return &csi.NodeGetInfoResponse{
+	resp := &csi.NodeGetInfoResponse{
 		NodeId: k8sNodeID,
-	}, nil
+	}
+	if s.topology {
+		resp.AccessibleTopology = &csi.Topology{Segments: storage.NodeTopology(s.nodeID)}
+		klog.V(2).Infof("NodeGetInfo AccessibleTopology: %v", resp.AccessibleTopology.GetSegments())
+	}
+	return resp, nil
 }
 
 func (s service) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
@@ -195,10 +213,66 @@ func (s *service) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVol
 	return resp, err
 }
 
-func (s *service) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+func (s *service) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (statsResp *csi.NodeGetVolumeStatsResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = fmt.Errorf("Recovered from NodeGetVolumeStats with volume ID %s: %s", req.GetVolumeId(), res)
+		}
+	}()
+
+	volumeId := req.GetVolumeId()
+	klog.V(4).Infof("NodeGetVolumeStats called with volume ID %s and volume path %s", volumeId, req.GetVolumePath())
+	volproto, err := s.validateVolumeID(volumeId)
+	if err != nil {
+		klog.Errorf("NodeGetVolumeStats failed with volume ID %s: %s", volumeId, err)
+		return nil, err
+	}
+	protocolOperation, err := storage.NewStorageNode(volproto.StorageType, nil, nil)
+	if err != nil {
+		klog.Errorf("NodeGetVolumeStats failed with volume ID %s: %s", volumeId, err)
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	statsResp, err = protocolOperation.NodeGetVolumeStats(ctx, req)
+	if err != nil {
+		klog.Errorf("NodeGetVolumeStats failed with volume ID %s: %s", volumeId, err)
+		return nil, err
+	}
+	klog.V(4).Infof("NodeGetVolumeStats succeeded with volume ID %s: %v", volumeId, statsResp.GetUsage())
+	return statsResp, nil
 }
 
-func (s *service) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+func (s *service) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (expandResp *csi.NodeExpandVolumeResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = fmt.Errorf("Recovered from NodeExpandVolume with volume ID %s: %s", req.GetVolumeId(), res)
+		}
+
+		isLocking := false
+		_ = helper.ManageNodeVolumeMutex(isLocking, "NodeExpandVolume", req.GetVolumeId())
+	}()
+
+	volumeId := req.GetVolumeId()
+
+	isLocking := true
+	_ = helper.ManageNodeVolumeMutex(isLocking, "NodeExpandVolume", volumeId)
+
+	klog.V(2).Infof("NodeExpandVolume called with volume ID %s, volume path %s and required bytes %d",
+		volumeId, req.GetVolumePath(), req.GetCapacityRange().GetRequiredBytes())
+	volproto, err := s.validateVolumeID(volumeId)
+	if err != nil {
+		klog.Errorf("NodeExpandVolume failed with volume ID %s: %s", volumeId, err)
+		return nil, err
+	}
+	protocolOperation, err := storage.NewStorageNode(volproto.StorageType, nil, nil)
+	if err != nil {
+		klog.Errorf("NodeExpandVolume failed with volume ID %s: %s", volumeId, err)
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	expandResp, err = protocolOperation.NodeExpandVolume(ctx, req)
+	if err != nil {
+		klog.Errorf("NodeExpandVolume failed with volume ID %s: %s", volumeId, err)
+		return nil, err
+	}
+	klog.V(2).Infof("NodeExpandVolume succeeded with volume ID %s", volumeId)
+	return expandResp, nil
 }
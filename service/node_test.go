@@ -71,6 +71,15 @@ func (suite *NodeTestSuite) Test_NodeGetInfo() {
 	assert.Nil(suite.T(), err)
 }
 
+func (suite *NodeTestSuite) Test_NodeGetInfo_topology() {
+	s := New(map[string]string{"nodeid": "127.0.0.1", "topology": "true"})
+	resp, err := s.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	assert.Nil(suite.T(), err)
+	segments := resp.GetAccessibleTopology().GetSegments()
+	assert.Equal(suite.T(), "127.0.0.0-8", segments[storage.TopologyKeyNetwork])
+	assert.Contains(suite.T(), segments, storage.TopologyKeyFCFabric, "expected the same keys on every node")
+}
+
 func (suite *NodeTestSuite) Test_NodeStageVolume_invalid_protocol() {
 	nodeStageReq := getNodeStageVolumeRequest()
 	nodeStageReq.VolumeContext = map[string]string{"storage_protocol": "unknown"}
@@ -103,6 +112,13 @@ func (suite *NodeTestSuite) Test_NodeGetVolumeStats() {
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *NodeTestSuite) Test_NodeGetVolumeStats_nfs() {
+	s := getService()
+	resp, err := s.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{VolumeId: "100$$nfs", VolumePath: suite.T().TempDir()})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(resp.Usage))
+}
+
 // func (suite *NodeTestSuite) Test_NodeExpandVolume_invalid_ID() {
 // 	nodeNodeExpandReq := getNodeExpandVolumeRequest()
 // 	nodeNodeExpandReq.VolumeId = ""
@@ -118,6 +134,24 @@ func (suite *NodeTestSuite) Test_NodeExpandVolume_unimplemented() {
 	assert.Error(suite.T(), err)
 }
 
+func (suite *NodeTestSuite) Test_NodeExpandVolume_invalidProtocol() {
+	nodeNodeExpandReq := getNodeExpandVolumeRequest()
+	nodeNodeExpandReq.VolumeId = "100$$unknown"
+	s := getService()
+	_, err := s.NodeExpandVolume(context.Background(), nodeNodeExpandReq)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *NodeTestSuite) Test_NodeExpandVolume_iscsiBlock() {
+	nodeNodeExpandReq := getNodeExpandVolumeRequest()
+	nodeNodeExpandReq.VolumeId = "100$$iscsi"
+	nodeNodeExpandReq.VolumePath = "/var/lib/kubelet/pods/volumeDevices/pvc-100"
+	nodeNodeExpandReq.VolumeCapability = &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}}
+	s := getService()
+	_, err := s.NodeExpandVolume(context.Background(), nodeNodeExpandReq)
+	assert.Nil(suite.T(), err, "expected raw block volumes to need no filesystem expansion")
+}
+
 //======================Data generator
 
 func getNodeExpandVolumeRequest() *csi.NodeExpandVolumeRequest {
@@ -0,0 +1,104 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinibox-csi-driver/api"
+	"infinibox-csi-driver/replication"
+	"infinibox-csi-driver/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+// getReplicator returns the storage controller of a volume, if its protocol replicates volumes
+func (s *service) getReplicator(volumeID string, secrets map[string]string) (storage.Replicator, api.VolumeProtocolConfig, error) {
+	volproto, err := s.validateVolumeID(volumeID)
+	if err != nil {
+		return nil, volproto, err
+	}
+	secrets, err = s.getSecretsOrDefault(secrets)
+	if err != nil {
+		klog.Errorf("failed to get secrets for volume %s: %v", volumeID, err)
+		return nil, volproto, status.Errorf(codes.InvalidArgument, "no secrets for volume %s: %v", volumeID, err)
+	}
+	config := make(map[string]string)
+	config["nodeid"] = s.nodeID
+	storageController, err := storage.NewStorageController(volproto.StorageType, config, secrets)
+	if err != nil || storageController == nil {
+		klog.Errorf("failed to initialize storage controller for volume %s: %v", volumeID, err)
+		return nil, volproto, status.Errorf(codes.Internal, "failed to initialize storage controller for volume %s", volumeID)
+	}
+	replicator, ok := storageController.(storage.Replicator)
+	if !ok {
+		return nil, volproto, status.Errorf(codes.Unimplemented, "replication is not supported for %s volumes", volproto.StorageType)
+	}
+	return replicator, volproto, nil
+}
+
+// PromoteVolume makes a replication target volume writable, so that PVs of the volume can be published
+func (s *service) PromoteVolume(ctx context.Context, req *replication.PromoteVolumeRequest) (resp *replication.PromoteVolumeResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from PromoteVolume  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("PromoteVolume called with volume ID %s", req.VolumeId)
+	replicator, volproto, err := s.getReplicator(req.VolumeId, req.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	if err = replicator.PromoteVolume(ctx, volproto.VolumeID); err != nil {
+		klog.Errorf("PromoteVolume error: %v", err)
+		return nil, err
+	}
+	klog.V(2).Infof("PromoteVolume success, volume ID %s", req.VolumeId)
+	return &replication.PromoteVolumeResponse{}, nil
+}
+
+// ImportVolume backs a pre-provisioned PV with an existing volume, typically the replication target
+// of a volume provisioned on another cluster
+func (s *service) ImportVolume(ctx context.Context, req *replication.ImportVolumeRequest) (resp *replication.ImportVolumeResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from ImportVolume  " + fmt.Sprint(res))
+		}
+	}()
+	klog.V(2).Infof("ImportVolume called with name %s and volume ID %s", req.Name, req.VolumeId)
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "no name provided to ImportVolume")
+	}
+	replicator, volproto, err := s.getReplicator(req.VolumeId, req.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string)
+	for key, value := range req.Parameters {
+		params[key] = value
+	}
+	params["storage_protocol"] = volproto.StorageType
+	volume, err := replicator.ImportVolume(ctx, req.Name, volproto.VolumeID, params)
+	if err != nil {
+		klog.Errorf("ImportVolume error: %v", err)
+		return nil, err
+	}
+	volume.VolumeId = volume.VolumeId + "$$" + volproto.StorageType
+	klog.V(2).Infof("ImportVolume success, volume: %v", volume)
+	return &replication.ImportVolumeResponse{Volume: &replication.Volume{
+		VolumeId:      volume.VolumeId,
+		CapacityBytes: volume.CapacityBytes,
+		VolumeContext: volume.VolumeContext,
+	}}, nil
+}
@@ -0,0 +1,104 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package service
+
+import (
+	"context"
+	"infinibox-csi-driver/api"
+	"infinibox-csi-driver/replication"
+	"infinibox-csi-driver/storage"
+	tests "infinibox-csi-driver/test_helper"
+	"net"
+	"net/http"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/rexray/gocsi"
+	csictx "github.com/rexray/gocsi/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ReplicationTestSuite struct {
+	suite.Suite
+}
+
+func TestReplicationTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplicationTestSuite))
+}
+
+// serve serves the driver as gocsi does in mode, and returns a connection to it
+func (suite *ReplicationTestSuite) serve(mode string) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err)
+	ctx := csictx.WithEnviron(context.Background(), []string{gocsi.EnvVarMode + "=" + mode})
+	sp := &gocsi.StoragePlugin{}
+	suite.Require().Nil(getService().BeforeServe(ctx, sp, listener))
+	server := grpc.NewServer(sp.ServerOpts...)
+	go server.Serve(listener)
+	suite.T().Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	suite.Require().Nil(err)
+	suite.T().Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func (suite *ReplicationTestSuite) Test_PromoteVolume_served() {
+	client := replication.NewReplicationClient(suite.serve("controller"))
+	_, err := client.PromoteVolume(context.Background(), &replication.PromoteVolumeRequest{VolumeId: "100"})
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err), "expected the PromoteVolume error of an invalid volume ID")
+}
+
+func (suite *ReplicationTestSuite) Test_PromoteVolume_apiError() {
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ReplicatorErrorMock{err: &api.APIError{StatusCode: http.StatusNotFound, Code: api.ErrVolumeNotFound}}, nil
+	})
+	defer patch.Unpatch()
+
+	client := replication.NewReplicationClient(suite.serve("controller"))
+	_, err := client.PromoteVolume(context.Background(), &replication.PromoteVolumeRequest{VolumeId: "100$$iscsi", Secrets: tests.GetSecret()})
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err), "expected the api error to be mapped by the interceptors")
+}
+
+func (suite *ReplicationTestSuite) Test_ImportVolume_served() {
+	patch := monkey.Patch(storage.NewStorageController, func(_ string, _ ...map[string]string) (storage.Storageoperations, error) {
+		return &ReplicatorMock{}, nil
+	})
+	defer patch.Unpatch()
+
+	client := replication.NewReplicationClient(suite.serve(""))
+	resp, err := client.ImportVolume(context.Background(), &replication.ImportVolumeRequest{Name: "pv-dr", VolumeId: "100$$iscsi", Parameters: map[string]string{"pool_name": "pool"}, Secrets: tests.GetSecret()})
+	assert.Nil(suite.T(), err, "expected to succeed: ImportVolume")
+	assert.Equal(suite.T(), "100$$iscsi", resp.GetVolume().GetVolumeId())
+	assert.Equal(suite.T(), map[string]string{"pool_name": "pool", "storage_protocol": "iscsi"}, resp.GetVolume().GetVolumeContext())
+}
+
+func (suite *ReplicationTestSuite) Test_ImportVolume_noName() {
+	client := replication.NewReplicationClient(suite.serve(""))
+	_, err := client.ImportVolume(context.Background(), &replication.ImportVolumeRequest{VolumeId: "100$$iscsi"})
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected the ImportVolume error of a missing name")
+}
+
+func (suite *ReplicationTestSuite) Test_unknownMethod() {
+	conn := suite.serve("controller")
+	err := conn.Invoke(context.Background(), "/infinibox.replication.v1.Replication/DemoteVolume", &replication.PromoteVolumeRequest{VolumeId: "100$$iscsi"}, &replication.PromoteVolumeResponse{})
+	assert.Equal(suite.T(), codes.Unimplemented, status.Code(err), "expected unknown method to be unimplemented")
+}
+
+func (suite *ReplicationTestSuite) Test_notServedByNode() {
+	client := replication.NewReplicationClient(suite.serve("node"))
+	_, err := client.PromoteVolume(context.Background(), &replication.PromoteVolumeRequest{VolumeId: "100$$iscsi"})
+	assert.Equal(suite.T(), codes.Unimplemented, status.Code(err), "expected node mode not to serve replication")
+}
@@ -15,19 +15,35 @@ import (
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/api"
+	"infinibox-csi-driver/api/clientgo"
+	"infinibox-csi-driver/replication"
+	"infinibox-csi-driver/storage"
 	"net"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/rexray/gocsi"
+	csictx "github.com/rexray/gocsi/context"
+	"github.com/rexray/gocsi/utils"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
 )
 
 const (
 	ServiceName = "infinibox-csi-driver"
+
+	// StorageClass parameters naming the secret of the array a StorageClass provisions on
+	provisionerSecretNameKey      = "csi.storage.k8s.io/provisioner-secret-name"
+	provisionerSecretNamespaceKey = "csi.storage.k8s.io/provisioner-secret-namespace"
+
+	// PV annotations recording the provisioner secret of the array a PV was provisioned on, for its deletion
+	provisionerDeletionSecretNameKey      = "volume.kubernetes.io/provisioner-deletion-secret-name"
+	provisionerDeletionSecretNamespaceKey = "volume.kubernetes.io/provisioner-deletion-secret-namespace"
 )
 
 type service struct {
@@ -38,6 +54,13 @@ type service struct {
 	nodeName      string
 	driverName    string
 	driverVersion string
+
+	// secret holding the array credentials, for requests which carry no secrets
+	secretName      string
+	secretNamespace string
+
+	// whether the node reports the topology segments of its FC fabric and network
+	topology bool
 }
 
 // Service is the CSI Mock service provider.
@@ -45,6 +68,7 @@ type Service interface {
 	csi.ControllerServer
 	csi.IdentityServer
 	csi.NodeServer
+	replication.ReplicationServer
 
 	BeforeServe(context.Context, *gocsi.StoragePlugin, net.Listener) error
 }
@@ -58,10 +82,21 @@ func New(configParam map[string]string) Service {
 		nodeName:      configParam["nodeName"],
 		driverName:    configParam["drivername"],
 		driverVersion: configParam["driverversion"],
+
+		secretName:      configParam["secretname"],
+		secretNamespace: configParam["secretnamespace"],
+
+		topology: configParam["topology"] == "true",
 	}
 }
 
 func (s *service) BeforeServe(ctx context.Context, sp *gocsi.StoragePlugin, listener net.Listener) error {
+	if !strings.EqualFold(csictx.Getenv(ctx, gocsi.EnvVarMode), "node") {
+		storage.LoadClusterID()
+		// gocsi registers the CSI services only, the replication service is served for unknown services
+		handler := replication.UnknownServiceHandler(s, utils.ChainUnaryServer(Interceptors...))
+		sp.ServerOpts = append(sp.ServerOpts, grpc.UnknownServiceHandler(handler))
+	}
 	return s.verifyController()
 }
 
@@ -128,6 +163,141 @@ func (s *service) validateVolumeID(str string) (volprotoconf api.VolumeProtocolC
 	return volprotoconf, nil
 }
 
+// getControllerSecrets reads the array credentials from the driver's own secret
+func getControllerSecrets(secretName, secretNamespace string) (map[string]string, error) {
+	if secretName == "" || secretNamespace == "" {
+		return nil, errors.New("controller secret name or namespace not configured")
+	}
+	kc, err := clientgo.BuildClient()
+	if err != nil {
+		klog.Errorf("failed to build kubernetes client: %v", err)
+		return nil, err
+	}
+	secrets, err := kc.GetSecret(secretName, secretNamespace)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("read controller secret %s from namespace %s", secretName, secretNamespace)
+	return secrets, nil
+}
+
+// getSecretsOrDefault returns the request secrets, or the driver's own secret for requests which carry none
+func (s *service) getSecretsOrDefault(secrets map[string]string) (map[string]string, error) {
+	if len(secrets) > 0 {
+		return secrets, nil
+	}
+	return getControllerSecrets(s.secretName, s.secretNamespace)
+}
+
+// getStorageClassSecrets returns the credentials of the array named by the provisioner secret of the
+// StorageClass parameters, or the driver's own secret for a StorageClass which names none
+func (s *service) getStorageClassSecrets(params map[string]string) (map[string]string, error) {
+	secretName, secretNamespace := params[provisionerSecretNameKey], params[provisionerSecretNamespaceKey]
+	if secretName == "" && secretNamespace == "" {
+		return s.getSecretsOrDefault(nil)
+	}
+	// a secret templated on the PVC names no array until a PVC is provisioned
+	if strings.Contains(secretName, "${") || strings.Contains(secretNamespace, "${") {
+		return nil, fmt.Errorf("provisioner secret %s/%s is templated on the PVC", secretNamespace, secretName)
+	}
+	return getControllerSecrets(secretName, secretNamespace)
+}
+
+// secretRef names the secret holding the credentials of an array, the empty reference names the driver's own secret
+type secretRef struct {
+	name      string
+	namespace string
+}
+
+// getPersistentVolumes returns the PVs of the cluster
+func getPersistentVolumes() ([]v1.PersistentVolume, error) {
+	kc, err := clientgo.BuildClient()
+	if err != nil {
+		klog.Errorf("failed to build kubernetes client: %v", err)
+		return nil, err
+	}
+	return kc.GetPersistentVolumes()
+}
+
+// getPVSecretRef returns the secret of the array the PV was provisioned on, as recorded by the provisioner
+// for its deletion or referenced by its CSI source, the empty reference for a PV naming no secret
+func getPVSecretRef(pv *v1.PersistentVolume) secretRef {
+	if name := pv.Annotations[provisionerDeletionSecretNameKey]; name != "" {
+		return secretRef{name: name, namespace: pv.Annotations[provisionerDeletionSecretNamespaceKey]}
+	}
+	for _, ref := range []*v1.SecretReference{pv.Spec.CSI.ControllerExpandSecretRef, pv.Spec.CSI.ControllerPublishSecretRef} {
+		if ref != nil && ref.Name != "" {
+			return secretRef{name: ref.Name, namespace: ref.Namespace}
+		}
+	}
+	return secretRef{}
+}
+
+// getArraySecrets returns the credentials of the secret ref, the driver's own secret for the empty reference
+func (s *service) getArraySecrets(ref secretRef) (map[string]string, error) {
+	if ref.name == "" {
+		return s.getSecretsOrDefault(nil)
+	}
+	return getControllerSecrets(ref.name, ref.namespace)
+}
+
+// getVolumeSecretRef returns the secret of the array of the driver PV with the given volume handle,
+// the empty reference when no PV has the handle
+func (s *service) getVolumeSecretRef(volumeID string) (secretRef, error) {
+	pvs, err := getPersistentVolumes()
+	if err != nil {
+		return secretRef{}, err
+	}
+	for i := range pvs {
+		if source := pvs[i].Spec.CSI; source != nil && source.Driver == s.driverName && source.VolumeHandle == volumeID {
+			return getPVSecretRef(&pvs[i]), nil
+		}
+	}
+	klog.V(4).Infof("no PV has volume handle %s", volumeID)
+	return secretRef{}, nil
+}
+
+// getArraySecretRefs returns the secrets of the arrays the driver PVs were provisioned on,
+// the driver's own secret first
+func (s *service) getArraySecretRefs() ([]secretRef, error) {
+	pvs, err := getPersistentVolumes()
+	if err != nil {
+		return nil, err
+	}
+	refs := []secretRef{{}}
+	seen := map[secretRef]bool{{}: true}
+	for i := range pvs {
+		if source := pvs[i].Spec.CSI; source == nil || source.Driver != s.driverName {
+			continue
+		}
+		if ref := getPVSecretRef(&pvs[i]); !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// getPage returns the bounds of the page of a list request starting at startingToken,
+// and the token of the following page, if any
+func getPage(total int, startingToken string, maxEntries int32) (start, end int, nextToken string, err error) {
+	if maxEntries < 0 {
+		return 0, 0, "", status.Errorf(codes.InvalidArgument, "max_entries %d must not be negative", maxEntries)
+	}
+	if startingToken != "" {
+		start, err = strconv.Atoi(startingToken)
+		if err != nil || start < 0 || start > total {
+			return 0, 0, "", status.Errorf(codes.Aborted, "invalid starting_token '%s'", startingToken)
+		}
+	}
+	end = total
+	if maxEntries > 0 && start+int(maxEntries) < total {
+		end = start + int(maxEntries)
+		nextToken = strconv.Itoa(end)
+	}
+	return start, end, nextToken, nil
+}
+
 // Controller expand volume request validation
 func (s *service) validateExpandVolumeRequest(req *csi.ControllerExpandVolumeRequest) error {
 	if req.GetVolumeId() == "" {
@@ -139,3 +309,49 @@ func (s *service) validateExpandVolumeRequest(req *csi.ControllerExpandVolumeReq
 	}
 	return nil
 }
+
+// Interceptors map expired and cancelled request contexts and management api errors to the matching gRPC
+// codes, for the CSI services and the replication service
+var Interceptors = []grpc.UnaryServerInterceptor{ContextErrorInterceptor, APIErrorInterceptor}
+
+// ContextErrorInterceptor reports failed RPCs whose context expired or was cancelled as
+// DeadlineExceeded or Canceled, whichever code the failing array call was wrapped in
+func ContextErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		err = contextError(ctx, err)
+	}
+	return resp, err
+}
+
+func contextError(ctx context.Context, err error) error {
+	code := status.Code(err)
+	if code == codes.DeadlineExceeded || code == codes.Canceled {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+		klog.Errorf("request deadline exceeded: %v", err)
+		return status.Error(codes.DeadlineExceeded, status.Convert(err).Message())
+	}
+	if errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+		klog.Errorf("request cancelled: %v", err)
+		return status.Error(codes.Canceled, status.Convert(err).Message())
+	}
+	return err
+}
+
+// APIErrorInterceptor reports management api errors returned without a gRPC status
+// with the code api.GRPCCode maps their InfiniBox error code to
+func APIErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, isStatus := status.FromError(err); isStatus {
+		return resp, err
+	}
+	if _, isAPIError := api.AsAPIError(err); isAPIError {
+		return resp, status.Error(api.GRPCCode(err), err.Error())
+	}
+	return resp, err
+}
@@ -0,0 +1,84 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package storage
+
+import (
+	"context"
+	"infinibox-csi-driver/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+// Clones are created as writable snapshots, which the array places in the pool of their source with the
+// size of their source. The array has no copy of a volume or filesystem into another pool, and moves a
+// snapshot only with its whole family, so a clone must be requested in the pool of its source. A clone
+// requested larger than its source is grown once created.
+
+// validateClonePool rejects clones requested in a pool other than the pool of their source
+func validateClonePool(sourceID string, sourcePoolID, poolID int64, poolName string) error {
+	if poolID != sourcePoolID {
+		klog.Errorf("requested pool %s (%d) is not pool %d of source %s", poolName, poolID, sourcePoolID, sourceID)
+		return status.Errorf(codes.InvalidArgument, "cloning %s into another pool is not supported, "+
+			"the StorageClass must name the pool of the source instead of %s", sourceID, poolName)
+	}
+	return nil
+}
+
+// validateCloneSize rejects clones smaller than their source, which would lose data
+func validateCloneSize(sourceID string, sourceSize, size int64) error {
+	if size < sourceSize {
+		klog.Errorf("requested size %d is smaller than size %d of source %s", size, sourceSize, sourceID)
+		return status.Errorf(codes.InvalidArgument, "requested size %d bytes is smaller than size %d bytes of source %s", size, sourceSize, sourceID)
+	}
+	return nil
+}
+
+// expandVolumeClone grows a block volume clone to size, then returns the updated clone. The clone is
+// deleted when it cannot be grown, so that CreateVolume can be retried
+func (cs *commonservice) expandVolumeClone(ctx context.Context, clone *api.Volume, size int64) (*api.Volume, error) {
+	if clone.Size >= size {
+		return clone, nil
+	}
+	if _, err := cs.api.UpdateVolume(ctx, clone.ID, api.Volume{Size: size}); err != nil {
+		err = status.Errorf(api.GRPCCode(err), "failed to expand clone %s to %d bytes: %v", clone.Name, size, err)
+		klog.Errorf("%v, deleting the clone", err)
+		if deleteErr := cs.api.DeleteVolume(ctx, clone.ID); deleteErr != nil {
+			klog.Errorf("failed to delete clone %s: %v", clone.Name, deleteErr)
+		}
+		return nil, err
+	}
+	klog.V(2).Infof("Clone %s expanded to %d bytes", clone.Name, size)
+	expanded, err := cs.api.GetVolume(ctx, clone.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not retrieve created volume: %d", clone.ID)
+	}
+	return expanded, nil
+}
+
+// expandFileSystemClone grows a filesystem clone of size cloneSize to size. The clone is deleted when it
+// cannot be grown, so that CreateVolume can be retried
+func (cs *commonservice) expandFileSystemClone(ctx context.Context, cloneID, cloneSize, size int64) error {
+	if cloneSize >= size {
+		return nil
+	}
+	if _, err := cs.api.UpdateFilesystem(ctx, cloneID, api.FileSystem{Size: size}); err != nil {
+		err = status.Errorf(api.GRPCCode(err), "failed to expand clone %d to %d bytes: %v", cloneID, size, err)
+		klog.Errorf("%v, deleting the clone", err)
+		if deleteErr := cs.api.DeleteFileSystemComplete(ctx, cloneID); deleteErr != nil {
+			klog.Errorf("failed to delete clone %d: %v", cloneID, deleteErr)
+		}
+		return err
+	}
+	klog.V(2).Infof("Clone %d expanded to %d bytes", cloneID, size)
+	return nil
+}
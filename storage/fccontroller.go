@@ -24,7 +24,6 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (fc *fcstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
@@ -73,19 +72,32 @@ func (fc *fcstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 	// Volume name to be created - already verified in controller.go
 	name := req.GetName()
 
+	// Name of the volume on the array, rendered from the optional volume_name_template parameter
+	volName, err := getVolumeName(params, name)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Pool name - already verified earlier
 	poolName := params["pool_name"]
 
-	targetVol, err := fc.cs.api.GetVolumeByName(name)
+	targetVol, err := fc.cs.getVolumeOfPV(ctx, name, volName, "fc", params)
 	if err != nil {
-		if !strings.Contains(err.Error(), "volume with given name not found") {
-			return nil, status.Errorf(codes.NotFound, "CreateVolume failed: %v", err)
-		}
+		return nil, err
 	}
 	if targetVol != nil {
 		klog.V(2).Infof("volume: %s found, size: %d requested: %d", name, targetVol.Size, sizeBytes)
 		if targetVol.Size == sizeBytes {
-			existingVolumeInfo := fc.cs.getCSIResponse(targetVol, req)
+			// reconcile the QoS policy, the storage class may have changed since the volume was created
+			if err = fc.cs.setQosPolicy(ctx, params, api.QosPolicyTypeVolume, int64(targetVol.ID), targetVol.QosPolicyID); err != nil {
+				klog.Errorf("failed to set QoS policy of volume %s, %v", name, err)
+				return nil, err
+			}
+			if err = fc.cs.createReplica(ctx, params, int64(targetVol.ID)); err != nil {
+				klog.Errorf("failed to replicate volume %s, %v", name, err)
+				return nil, err
+			}
+			existingVolumeInfo := fc.cs.getCSIResponse(ctx, targetVol, req)
 			copyRequestParameters(params, existingVolumeInfo.VolumeContext)
 			return &csi.CreateVolumeResponse{
 				Volume: existingVolumeInfo,
@@ -99,20 +111,22 @@ func (fc *fcstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 	// Volume content source support volume and snapshots
 	contentSource := req.GetVolumeContentSource()
 	if contentSource != nil {
-		return fc.createVolumeFromVolumeContent(req, name, sizeBytes, poolName)
+		return fc.createVolumeFromVolumeContent(ctx, req, volName, sizeBytes, poolName)
 	}
+	compressionEnabled, _ := getCompressionEnabled(params) // already validated
 	volumeParam := &api.VolumeParam{
-		Name:          name,
-		VolumeSize:    sizeBytes,
-		ProvisionType: volType,
-		SsdEnabled:    ssdEnabled,
+		Name:               volName,
+		VolumeSize:         sizeBytes,
+		ProvisionType:      volType,
+		SsdEnabled:         ssdEnabled,
+		CompressionEnabled: compressionEnabled,
 	}
-	volumeResp, err := fc.cs.api.CreateVolume(volumeParam, poolName)
+	volumeResp, err := fc.cs.api.CreateVolume(ctx, volumeParam, poolName)
 	if err != nil {
-		klog.Errorf("error creating volume: %s pool %s error: %s", name, poolName, err.Error())
-		return nil, status.Errorf(codes.Internal, "error when creating volume %s storagepool %s, err: %s", name, poolName, err.Error())
+		klog.Errorf("error creating volume: %s pool %s error: %s", volName, poolName, err.Error())
+		return nil, status.Errorf(api.GRPCCode(err), "error when creating volume %s storagepool %s, err: %s", name, poolName, err.Error())
 	}
-	vi := fc.cs.getCSIResponse(volumeResp, req)
+	vi := fc.cs.getCSIResponse(ctx, volumeResp, req)
 
 	// check volume id format
 	volID, err := strconv.Atoi(vi.VolumeId)
@@ -123,10 +137,10 @@ func (fc *fcstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 	// confirm volume creation
 	var vol *api.Volume
 	var counter int
-	vol, err = fc.cs.api.GetVolume(volID)
+	vol, err = fc.cs.api.GetVolume(ctx, volID)
 	for vol == nil && counter < 100 {
 		time.Sleep(3 * time.Millisecond)
-		vol, err = fc.cs.api.GetVolume(volID)
+		vol, err = fc.cs.api.GetVolume(ctx, volID)
 		counter = counter + 1
 	}
 	if vol == nil {
@@ -140,14 +154,21 @@ func (fc *fcstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 	}
 
 	// attach metadata to volume object
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = volumeResp.Name
+	metadata := fc.cs.getVolumeMetadata(name, "fc", params)
 	// metadata["host.filesystem_type"] = req.GetParameters()["fstype"] // TODO: set this correctly according to what fcnode.go does, not the fstype parameter originally captured in this function ... which is likely overwritten by the VolumeCapability
-	_, err = fc.cs.api.AttachMetadataToObject(int64(volumeResp.ID), metadata)
+	_, err = fc.cs.api.AttachMetadataToObject(ctx, int64(volumeResp.ID), metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for volume: %s, err: %v", name, err)
 		return nil, status.Errorf(codes.Internal, "failed to attach metadata")
 	}
+	if err = fc.cs.setQosPolicy(ctx, params, api.QosPolicyTypeVolume, int64(volumeResp.ID), volumeResp.QosPolicyID); err != nil {
+		klog.Errorf("failed to set QoS policy of volume %s, %v", name, err)
+		return nil, err
+	}
+	if err = fc.cs.createReplica(ctx, params, int64(volumeResp.ID)); err != nil {
+		klog.Errorf("failed to replicate volume %s, %v", name, err)
+		return nil, err
+	}
 
 	klog.V(2).Infof("CreateVolume resp: %v", *csiResp)
 	klog.Infof("created volume: %s id: %d", name, volID)
@@ -170,15 +191,15 @@ func (fc *fcstorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		return nil, status.Errorf(codes.Internal,
 			"error parsing volume id : %s", err.Error())
 	}
-	err = fc.ValidateDeleteVolume(id)
+	err = fc.ValidateDeleteVolume(ctx, id)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal,
+		return nil, status.Errorf(api.GRPCCode(err),
 			"error deleting volume : %s", err.Error())
 	}
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
-func (fc *fcstorage) createVolumeFromVolumeContent(req *csi.CreateVolumeRequest, name string, sizeInKbytes int64, storagePool string) (*csi.CreateVolumeResponse, error) {
+func (fc *fcstorage) createVolumeFromVolumeContent(ctx context.Context, req *csi.CreateVolumeRequest, name string, sizeInKbytes int64, storagePool string) (*csi.CreateVolumeResponse, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -208,27 +229,24 @@ func (fc *fcstorage) createVolumeFromVolumeContent(req *csi.CreateVolumeRequest,
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, restoreType+" invalid: %s", volumeContentID)
 	}
-	srcVol, err := fc.cs.api.GetVolume(ID)
+	srcVol, err := fc.cs.api.GetVolume(ctx, ID)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, restoreType+" not found: %d", ID)
 	}
 
-	// Validate the size is the same.
-	if int64(srcVol.Size) != sizeInKbytes {
-		return nil, status.Errorf(codes.InvalidArgument,
-			restoreType+" %s has incompatible size %d kbytes with requested %d kbytes",
-			volumeContentID, srcVol.Size, sizeInKbytes)
+	// The clone may be larger than its source, it is grown once created.
+	if err = validateCloneSize(volumeContentID, srcVol.Size, sizeInKbytes); err != nil {
+		return nil, err
 	}
 
-	// Validate the storagePool is the same.
-	storagePoolID, err := fc.cs.api.GetStoragePoolIDByName(storagePool)
+	// Validate the storagePool is the same, the clone is a snapshot in the pool of its source.
+	storagePoolID, err := fc.cs.api.GetStoragePoolIDByName(ctx, storagePool)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal,
 			"error while getting storagepoolid with name %s ", storagePool)
 	}
-	if storagePoolID != srcVol.PoolId {
-		return nil, status.Errorf(codes.InvalidArgument,
-			"volume storage pool is different than the requested storage pool %s", storagePool)
+	if err = validateClonePool(volumeContentID, srcVol.PoolId, storagePoolID, storagePool); err != nil {
+		return nil, err
 	}
 	ssd := req.GetParameters()["ssd_enabled"]
 	if ssd == "" {
@@ -242,29 +260,40 @@ func (fc *fcstorage) createVolumeFromVolumeContent(req *csi.CreateVolumeRequest,
 		SsdEnabled:     ssdEnabled,
 	}
 	// Create snapshot
-	snapResponse, err := fc.cs.api.CreateSnapshotVolume(snapshotParam)
+	snapResponse, err := fc.cs.api.CreateSnapshotVolume(ctx, snapshotParam)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to create snapshot: %s", err.Error())
+		return nil, status.Errorf(api.GRPCCode(err), "Failed to create snapshot: %s", err.Error())
 	}
 
 	// Retrieve created destination volume
 	volID := snapResponse.SnapShotID
-	dstVol, err := fc.cs.api.GetVolume(volID)
+	dstVol, err := fc.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not retrieve created volume: %d", volID)
 	}
+	dstVol, err = fc.cs.expandVolumeClone(ctx, dstVol, sizeInKbytes)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a volume response and return it
-	csiVolume := fc.cs.getCSIResponse(dstVol, req)
+	csiVolume := fc.cs.getCSIResponse(ctx, dstVol, req)
 	copyRequestParameters(req.GetParameters(), csiVolume.VolumeContext)
 
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = dstVol.Name
+	metadata := fc.cs.getVolumeMetadata(req.GetName(), "fc", req.GetParameters())
 	// metadata["host.filesystem_type"] = req.GetParameters()["fstype"] // TODO: set this correctly according to what fcnode.go does, not the fstype parameter originally captured in this function ... which is likely overwritten by the VolumeCapability
-	_, err = fc.cs.api.AttachMetadataToObject(int64(dstVol.ID), metadata)
+	_, err = fc.cs.api.AttachMetadataToObject(ctx, int64(dstVol.ID), metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for volume: %s, err: %v", dstVol.Name, err)
-		return nil, status.Errorf(codes.Internal, "failed to attach metadata to volume: %s, err: %v", dstVol.Name, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to attach metadata to volume: %s, err: %v", dstVol.Name, err)
+	}
+	if err = fc.cs.setQosPolicy(ctx, req.GetParameters(), api.QosPolicyTypeVolume, int64(dstVol.ID), dstVol.QosPolicyID); err != nil {
+		klog.Errorf("failed to set QoS policy of volume %s, %v", dstVol.Name, err)
+		return nil, err
+	}
+	if err = fc.cs.createReplica(ctx, req.GetParameters(), int64(dstVol.ID)); err != nil {
+		klog.Errorf("failed to replicate volume %s, %v", dstVol.Name, err)
+		return nil, err
 	}
 	klog.Errorf("Volume (from snap) %s (%s) storage pool %s",
 		csiVolume.VolumeContext["Name"], csiVolume.VolumeId, csiVolume.VolumeContext["StoragePoolName"])
@@ -286,12 +315,12 @@ func (fc *fcstorage) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 	}
 	hostName := nodeNameIP[0]
 
-	host, err := fc.cs.validateHost(hostName)
+	host, err := fc.cs.validateHost(ctx, hostName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
-	v, err := fc.cs.api.GetVolume(volID)
+	v, err := fc.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		klog.Errorf("Failed to find volume by volume ID '%s': %v", req.GetVolumeId(), err)
 		return nil, errors.New("error getting volume by id")
@@ -300,10 +329,10 @@ func (fc *fcstorage) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 	// TODO: revisit this as part of CSIC-343
 	_, err = fc.cs.accessModesHelper.IsValidAccessMode(v, req)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
-	lunList, err := fc.cs.api.GetAllLunByHost(host.ID)
+	lunList, err := fc.cs.api.GetAllLunByHost(ctx, host.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -344,10 +373,10 @@ func (fc *fcstorage) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 	}
 	// map volume to host
 	klog.V(4).Infof("mapping volume %d to host %s", volID, host.Name)
-	luninfo, err := fc.cs.mapVolumeTohost(volID, host.ID)
+	luninfo, err := fc.cs.mapVolumeTohost(ctx, volID, host.ID)
 	if err != nil {
 		klog.Errorf("Failed to map volume to host with error %v", err)
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
 	volCtx := make(map[string]string)
@@ -371,9 +400,9 @@ func (fc *fcstorage) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		return nil, errors.New("Node ID not found")
 	}
 	hostName := nodeNameIP[0]
-	host, err := fc.cs.api.GetHostByName(hostName)
+	host, err := fc.cs.api.GetHostByName(ctx, hostName)
 	if err != nil {
-		if strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+		if api.HasErrorCode(err, api.ErrHostNotFound) {
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 		klog.Errorf("failed to get host details with error %v", err)
@@ -382,22 +411,22 @@ func (fc *fcstorage) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 	if len(host.Luns) > 0 {
 		volID, _ := strconv.Atoi(volproto.VolumeID)
 		klog.V(4).Infof("unmap volume %d from host %d", volID, host.ID)
-		err = fc.cs.unmapVolumeFromHost(host.ID, volID)
+		err = fc.cs.unmapVolumeFromHost(ctx, host.ID, volID)
 		if err != nil {
 			klog.Errorf("failed to unmap volume %d from host %d with error %v", volID, host.ID, err)
-			return nil, status.Error(codes.Internal, err.Error())
+			return nil, status.Error(api.GRPCCode(err), err.Error())
 		}
 	}
 	if len(host.Luns) < 2 {
-		luns, err := fc.cs.api.GetAllLunByHost(host.ID)
+		luns, err := fc.cs.api.GetAllLunByHost(ctx, host.ID)
 		if err != nil {
 			klog.Errorf("failed to retrive luns for host %d with error %v", host.ID, err)
 		}
 		if len(luns) == 0 {
-			err = fc.cs.api.DeleteHost(host.ID)
-			if err != nil && !strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+			err = fc.cs.api.DeleteHost(ctx, host.ID)
+			if err != nil && !api.HasErrorCode(err, api.ErrHostNotFound) {
 				klog.Errorf("failed to delete host with error %v", err)
-				return nil, status.Error(codes.Internal, err.Error())
+				return nil, status.Error(api.GRPCCode(err), err.Error())
 			}
 		}
 	}
@@ -414,7 +443,7 @@ func (fc *fcstorage) ValidateVolumeCapabilities(ctx context.Context, req *csi.Va
 	volID, _ := strconv.Atoi(volproto.VolumeID)
 
 	klog.V(4).Infof("volID: %d", volID)
-	v, err := fc.cs.api.GetVolume(volID)
+	v, err := fc.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		klog.Errorf("Failed to find volume ID: %d, %v", volID, err)
 		err = status.Errorf(codes.NotFound, "ValidateVolumeCapabilities failed to find volume ID: %d, %v", volID, err)
@@ -435,16 +464,38 @@ func (fc *fcstorage) ValidateVolumeCapabilities(ctx context.Context, req *csi.Va
 	return
 }
 
+// ListVolumes returns all fc volumes, paging across protocols is done by the caller
 func (fc *fcstorage) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (resp *csi.ListVolumesResponse, err error) {
-	return &csi.ListVolumesResponse{}, nil
+	entries, err := fc.cs.listVolumeEntries(ctx, "fc")
+	if err != nil {
+		klog.Errorf("failed to list fc volumes: %v", err)
+		return nil, err
+	}
+	return &csi.ListVolumesResponse{Entries: entries}, nil
 }
 
+// ListSnapshots returns the fc snapshots matching the request filters, paging across protocols is done by the caller
 func (fc *fcstorage) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (resp *csi.ListSnapshotsResponse, err error) {
-	return &csi.ListSnapshotsResponse{}, nil
+	entries, err := fc.cs.listSnapshotEntries(ctx, "fc", req)
+	if err != nil {
+		klog.Errorf("failed to list fc snapshots: %v", err)
+		return nil, err
+	}
+	return &csi.ListSnapshotsResponse{Entries: entries}, nil
 }
 
+// GetCapacity returns the free capacity of the StorageClass pool
 func (fc *fcstorage) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (resp *csi.GetCapacityResponse, err error) {
-	return &csi.GetCapacityResponse{}, nil
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from FC GetCapacity  " + fmt.Sprint(res))
+		}
+	}()
+	capacity, err := fc.cs.getPoolCapacity(ctx, req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetCapacityResponse{AvailableCapacity: capacity}, nil
 }
 
 func (fc *fcstorage) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (resp *csi.ControllerGetCapabilitiesResponse, err error) {
@@ -468,7 +519,7 @@ func (fc *fcstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 	}
 
 	sourceVolumeID, _ := strconv.Atoi(volproto.VolumeID)
-	volumeSnapshot, err := fc.cs.api.GetVolumeByName(snapshotName)
+	volumeSnapshot, err := fc.cs.api.GetVolumeByName(ctx, snapshotName)
 	if err != nil {
 		klog.V(4).Infof("Snapshot with given name not found : %s", snapshotName)
 	} else if volumeSnapshot.ParentId == sourceVolumeID {
@@ -478,7 +529,7 @@ func (fc *fcstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 				SizeBytes:      volumeSnapshot.Size,
 				SnapshotId:     snapshotID,
 				SourceVolumeId: req.GetSourceVolumeId(),
-				CreationTime:   timestamppb.Now(),
+				CreationTime:   getTimestamp(volumeSnapshot.CreatedAt),
 				ReadyToUse:     true,
 			},
 		}, nil
@@ -486,24 +537,36 @@ func (fc *fcstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshot
 		return nil, status.Error(codes.AlreadyExists, "snapshot with already existing name and different source volume ID")
 	}
 
+	lockExpiresAt, err := fc.cs.getSnapshotLockExpiry(ctx, int64(sourceVolumeID))
+	if err != nil {
+		klog.Errorf("failed to get snapshot lock of volume %d, %v", sourceVolumeID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshot lock of volume %d: %v", sourceVolumeID, err)
+	}
 	snapshotParam := &api.VolumeSnapshot{
 		ParentID:       sourceVolumeID,
 		SnapshotName:   snapshotName,
 		WriteProtected: true,
+		LockExpiresAt:  lockExpiresAt,
 	}
 
-	snapshot, err := fc.cs.api.CreateSnapshotVolume(snapshotParam)
+	snapshot, err := fc.cs.api.CreateSnapshotVolume(ctx, snapshotParam)
 	if err != nil {
 		klog.Errorf("Failed to create snapshot %s error %v", snapshotName, err)
 		return
 	}
 
+	// map the snapshot to its VolumeSnapshot, a failure leaves the snapshot usable
+	metadata := fc.cs.getSnapshotMetadata(volproto.StorageType, req.GetParameters())
+	if _, metadataErr := fc.cs.api.AttachMetadataToObject(ctx, int64(snapshot.SnapShotID), metadata); metadataErr != nil {
+		klog.Errorf("failed to attach metadata to snapshot %s, %v", snapshotName, metadataErr)
+	}
+
 	snapshotID = strconv.Itoa(snapshot.SnapShotID) + "$$" + volproto.StorageType
 	csiSnapshot := &csi.Snapshot{
 		SnapshotId:     snapshotID,
 		SourceVolumeId: req.GetSourceVolumeId(),
 		ReadyToUse:     true,
-		CreationTime:   timestamppb.Now(),
+		CreationTime:   getTimestamp(snapshot.CreatedAt),
 		SizeBytes:      snapshot.Size,
 	}
 	klog.V(4).Infof("CreateFileSystemSnapshot resp: %v", csiSnapshot)
@@ -519,7 +582,7 @@ func (fc *fcstorage) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshot
 	}()
 
 	snapshotID, _ := strconv.Atoi(req.GetSnapshotId())
-	err = fc.ValidateDeleteVolume(snapshotID)
+	err = fc.ValidateDeleteVolume(ctx, snapshotID)
 	if err != nil {
 		klog.Errorf("failed to delete snapshot %v", err)
 		return nil, err
@@ -527,15 +590,15 @@ func (fc *fcstorage) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshot
 	return &csi.DeleteSnapshotResponse{}, nil
 }
 
-func (fc *fcstorage) ValidateDeleteVolume(volumeID int) (err error) {
+func (fc *fcstorage) ValidateDeleteVolume(ctx context.Context, volumeID int) (err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("Recovered from FC DeleteSnapshot  " + fmt.Sprint(res))
 		}
 	}()
-	vol, err := fc.cs.api.GetVolume(volumeID)
+	vol, err := fc.cs.api.GetVolume(ctx, volumeID)
 	if err != nil {
-		if strings.Contains(err.Error(), "VOLUME_NOT_FOUND") {
+		if api.HasErrorCode(err, api.ErrVolumeNotFound) {
 			klog.V(2).Infof("volume is already deleted %d", volumeID)
 			return nil
 		}
@@ -543,28 +606,35 @@ func (fc *fcstorage) ValidateDeleteVolume(volumeID int) (err error) {
 			"error while validating volume status : %s",
 			err.Error())
 	}
-	childVolumes, err := fc.cs.api.GetVolumeSnapshotByParentID(vol.ID)
+	childVolumes, err := fc.cs.api.GetVolumeSnapshotByParentID(ctx, vol.ID)
 	if len(*childVolumes) > 0 {
 		metadata := make(map[string]interface{})
 		metadata[TOBEDELETED] = true
-		_, err = fc.cs.api.AttachMetadataToObject(int64(vol.ID), metadata)
+		_, err = fc.cs.api.AttachMetadataToObject(ctx, int64(vol.ID), metadata)
 		if err != nil {
 			klog.Errorf("failed to update host.k8s.to_be_deleted for volume %s error: %v", vol.Name, err)
 			err = errors.New("error while Set metadata host.k8s.to_be_deleted")
 		}
 		return
 	}
+	if vol.RmrSource {
+		err = fc.cs.deleteReplica(ctx, int64(vol.ID))
+		if err != nil {
+			return status.Errorf(api.GRPCCode(err),
+				"error deleting replica of volume: %s", err.Error())
+		}
+	}
 	klog.V(2).Infof("Deleting volume name: %s id: %d", vol.Name, vol.ID)
-	err = fc.cs.api.DeleteVolume(vol.ID)
+	err = fc.cs.api.DeleteVolume(ctx, vol.ID)
 	if err != nil {
 		return status.Errorf(codes.Internal,
 			"error removing volume: %s", err.Error())
 	}
 	if vol.ParentId != 0 {
 		klog.V(2).Infof("checkingif parent volume can be name: %s id: %d", vol.Name, vol.ID)
-		tobedel := fc.cs.api.GetMetadataStatus(int64(vol.ParentId))
+		tobedel := fc.cs.api.GetMetadataStatus(ctx, int64(vol.ParentId))
 		if tobedel {
-			err = fc.ValidateDeleteVolume(vol.ParentId)
+			err = fc.ValidateDeleteVolume(ctx, vol.ParentId)
 			if err != nil {
 				return
 			}
@@ -595,14 +665,15 @@ func (fc *fcstorage) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 	// Expand volume size
 	var volume api.Volume
 	volume.Size = capacity
-	_, err = fc.cs.api.UpdateVolume(volumeID, volume)
+	_, err = fc.cs.api.UpdateVolume(ctx, volumeID, volume)
 	if err != nil {
 		klog.Errorf("Failed to update file system %v", err)
 		return
 	}
 	klog.V(2).Info("Volume size updated successfully")
+	// mounted filesystems are grown by NodeExpandVolume, raw block consumers see the new LUN size
 	return &csi.ControllerExpandVolumeResponse{
 		CapacityBytes:         capacity,
-		NodeExpansionRequired: false,
+		NodeExpansionRequired: req.GetVolumeCapability().GetBlock() == nil,
 	}, nil
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (suite *FCControllerSuite) SetupTest() {
@@ -49,9 +51,9 @@ func (suite *FCControllerSuite) Test_CreateVolume_GetName_fail() {
 	createVolReq := tests.GetCreateVolumeRequest("PVName", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), expectedErr)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(getVolume(), expectedErr)
 	_, err := service.CreateVolume(context.Background(), createVolReq)
-	assert.NotNil(suite.T(), err, "expected to fail: fc CreateVolume GetVolumeByName")
+	assert.NotNil(suite.T(), err, "expected to fail: fc CreateVolume GetVolumeByPVName")
 }
 
 func (suite *FCControllerSuite) Test_CreateVolume_fail() {
@@ -60,7 +62,7 @@ func (suite *FCControllerSuite) Test_CreateVolume_fail() {
 	createVolReq := tests.GetCreateVolumeRequest("PVName", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(nil, expectedErr)
@@ -74,7 +76,7 @@ func (suite *FCControllerSuite) Test_CreateVolume_success() {
 	parameterMap := getFCCreateVolumeParameter()
 	createVolReq := tests.GetCreateVolumeRequest("PVName", parameterMap, "")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
@@ -91,7 +93,7 @@ func (suite *FCControllerSuite) Test_CreateVolume_metadataError() {
 	createVolReq := tests.GetCreateVolumeRequest("PVName", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
@@ -139,6 +141,20 @@ func (suite *FCControllerSuite) Test_DeleteVolume_GetVolumeSnapshot_metadataErro
 	assert.NotNil(suite.T(), err, "expected to fail: fc DeleteVolume attach metadata")
 }
 
+func (suite *FCControllerSuite) Test_DeleteVolume_deleteReplicaUnavailable() {
+	service := fcstorage{cs: *suite.cs}
+	vol := getVolume()
+	vol.RmrSource = true
+	suite.api.On("GetVolume", mock.Anything).Return(vol, nil)
+	suite.api.On("GetVolumeSnapshotByParentID", mock.Anything).Return([]api.Volume{}, nil)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(nil, &api.APIError{StatusCode: 503})
+	suite.api.On("GetMetadataStatus", mock.Anything).Return(false)
+
+	_, err := service.DeleteVolume(context.Background(), getISCSIDeleteRequest())
+	assert.Equal(suite.T(), codes.Unavailable, status.Code(err), "expected to fail: fc DeleteVolume with the array unavailable")
+	suite.api.AssertNotCalled(suite.T(), "DeleteVolume", mock.Anything)
+}
+
 func (suite *FCControllerSuite) Test_DeleteVolume_Error() {
 	service := fcstorage{cs: *suite.cs}
 	createVolReq := getISCSIDeleteRequest()
@@ -165,7 +181,7 @@ func (suite *FCControllerSuite) Test_DeleteVolume_success() {
 func (suite *FCControllerSuite) Test_DeleteVolume_AlreadyDelete() {
 	service := fcstorage{cs: *suite.cs}
 	createVolReq := getISCSIDeleteRequest()
-	expectedErr := errors.New("VOLUME_NOT_FOUND")
+	expectedErr := &api.APIError{Code: api.ErrVolumeNotFound}
 	suite.api.On("GetVolume", mock.Anything).Return(nil, expectedErr)
 
 	_, err := service.DeleteVolume(context.Background(), createVolReq)
@@ -176,7 +192,7 @@ func (suite *FCControllerSuite) Test_CreateVolume_content_success() {
 	service := fcstorage{cs: *suite.cs}
 	parameterMap := getFCCreateVolumeParameter()
 	createVolReq := tests.GetCreateVolumeRequest("volumeName", parameterMap, "1$$fc")
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
 	var poolID int64 = 10
@@ -194,7 +210,7 @@ func (suite *FCControllerSuite) Test_CreateVolume_content_AttachMetadataToObject
 	parameterMap := getFCCreateVolumeParameter()
 	createVolReq := tests.GetCreateVolumeRequest("volumeName", parameterMap, "1$$fc")
 	expectedErr := errors.New("some Error")
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
 	var poolID int64 = 10
@@ -310,7 +326,9 @@ func (suite *FCControllerSuite) Test_CreateSnapshot_GetVolumeByNameErr() {
 	//	var parameterMap map[string]string
 	unpublishVolReq := getISCSICreateSnapshotRequest()
 	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), expectedErr)
+	suite.api.On("GetMetadataValue", mock.Anything, SNAPSHOTLOCK).Return("", nil)
 	suite.api.On("CreateSnapshotVolume", mock.Anything).Return(getSnapshotResp(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
 
 	_, err := service.CreateSnapshot(context.Background(), unpublishVolReq)
 	assert.Nil(suite.T(), err, "expected to fail: fc CreateSnapshot GetVolumeByName")
@@ -322,7 +340,9 @@ func (suite *FCControllerSuite) Test_CreateSnapshot_already_Created() {
 	unpublishVolReq := getISCSICreateSnapshotRequest()
 	unpublishVolReq.SourceVolumeId = "1001$$iscsi"
 	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetMetadataValue", mock.Anything, SNAPSHOTLOCK).Return("", nil)
 	suite.api.On("CreateSnapshotVolume", mock.Anything).Return(getSnapshotResp(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
 
 	_, err := service.CreateSnapshot(context.Background(), unpublishVolReq)
 	assert.Nil(suite.T(), err, "expected to succeed: fc CreateSnapshot when already exists")
@@ -346,8 +366,9 @@ func (suite *FCControllerSuite) Test_ControllerExpandVolume() {
 	//	var parameterMap map[string]string
 	ctrExpandValReq := getISCSIExpandVolumeRequest()
 	suite.api.On("UpdateVolume", mock.Anything, mock.Anything).Return(nil, nil)
-	_, err := service.ControllerExpandVolume(context.Background(), ctrExpandValReq)
+	resp, err := service.ControllerExpandVolume(context.Background(), ctrExpandValReq)
 	assert.Nil(suite.T(), err, "expected to succeed: fc ControllerExpandVolume")
+	assert.True(suite.T(), resp.NodeExpansionRequired, "expected filesystem volumes to need node expansion")
 }
 
 func (suite *FCControllerSuite) Test_ValidateVolumeCapabilities() {
@@ -362,20 +383,34 @@ func (suite *FCControllerSuite) Test_ValidateVolumeCapabilities() {
 
 func (suite *FCControllerSuite) Test_ListVolumes() {
 	service := fcstorage{cs: *suite.cs}
-	_, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	fcVolume := getVolume()
+	fcVolume.ID = 101
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByIDs", []int{101}).Return([]api.Volume{fcVolume}, nil)
+	resp, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
 	assert.Nil(suite.T(), err, "expected to succeed: fc ListVolumes")
+	assert.Equal(suite.T(), 1, len(resp.Entries), "expected only the fc volume")
+	assert.Equal(suite.T(), "101", resp.Entries[0].Volume.VolumeId)
 }
 
 func (suite *FCControllerSuite) Test_ListSnapshots() {
 	service := fcstorage{cs: *suite.cs}
-	_, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
+	suite.api.On("GetVolumesByParentIDs", []int{101}).Return(getVolumeChildren(101), nil)
+	suite.api.On("GetVolumesByParentIDs", []int{200}).Return([]api.Volume{}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SourceVolumeId: "101$$fc"})
 	assert.Nil(suite.T(), err, "expected to succeed: fc ListSnapshots")
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "200$$fc", resp.Entries[0].Snapshot.SnapshotId)
 }
 
 func (suite *FCControllerSuite) Test_GetCapacity() {
 	service := fcstorage{cs: *suite.cs}
-	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{})
+	suite.api.On("GetStoragePoolByName", "pool_name1").Return(getStoragePool(), nil)
+	resp, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: map[string]string{"pool_name": "pool_name1"}})
 	assert.Nil(suite.T(), err, "expected to succeed: fc GetCapacity")
+	assert.Equal(suite.T(), int64(4000), resp.AvailableCapacity)
 }
 
 // Test data ===========
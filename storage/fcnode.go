@@ -23,7 +23,6 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -99,12 +98,12 @@ func (fc *fcstorage) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolu
 	for _, fcp := range fcPorts {
 		if !strings.Contains(ports, fcp) {
 			klog.V(4).Infof("host port %s is not created, creating it", fcp)
-			err = fc.cs.AddPortForHost(hostId, "FC", fcp)
+			err = fc.cs.AddPortForHost(ctx, hostId, "FC", fcp)
 			if err != nil {
 				klog.Errorf("error creating host port %v", err)
 				return nil, status.Error(codes.Internal, err.Error())
 			}
-			_, err := fc.cs.api.GetHostPort(hostId, fcp)
+			_, err := fc.cs.api.GetHostPort(ctx, hostId, fcp)
 			if err != nil {
 				klog.Errorf("failed to get host port %s with error %v", fcp, err)
 				return nil, status.Error(codes.Internal, err.Error())
@@ -130,7 +129,7 @@ func (fc *fcstorage) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 	klog.V(4).Infof("NodePublishVolume called with volume ID %s", req.GetVolumeId())
 	helper.CheckMultipath()
 
-	fcDetails, err := fc.getFCDiskDetails(req)
+	fcDetails, err := fc.getFCDiskDetails(ctx, req)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -277,11 +276,11 @@ func (fc *fcstorage) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReques
 func (fc *fcstorage) NodeGetVolumeStats(
 	ctx context.Context, req *csi.NodeGetVolumeStatsRequest,
 ) (*csi.NodeGetVolumeStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+	return fc.cs.getVolumeStats(req.GetVolumePath())
 }
 
 func (fc *fcstorage) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+	return fc.cs.expandMpathVolume(req)
 }
 
 // ------------------------------------ Supporting methods  ---------------------------
@@ -385,6 +384,8 @@ func (fc *fcstorage) MountFCDisk(fm FCMounter, devicePath string) error {
 				klog.Errorf(msg)
 				return status.Errorf(codes.Internal, msg)
 			}
+		} else {
+			resizeMountedFilesystem(devicePath, fm.TargetPath)
 		}
 	}
 	dskinfo := diskInfo{}
@@ -425,7 +426,7 @@ func getPortName() []string {
 	return ports
 }
 
-func (fc *fcstorage) getFCDiskDetails(req *csi.NodePublishVolumeRequest) (*fcDevice, error) {
+func (fc *fcstorage) getFCDiskDetails(ctx context.Context, req *csi.NodePublishVolumeRequest) (*fcDevice, error) {
 	var err error
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -438,7 +439,7 @@ func (fc *fcstorage) getFCDiskDetails(req *csi.NodePublishVolumeRequest) (*fcDev
 	wwids := req.GetVolumeContext()["WWIDs"]
 	wwidList := strings.Split(wwids, ",")
 	targetList := []string{}
-	fcNodes, err := fc.cs.api.GetFCPorts()
+	fcNodes, err := fc.cs.api.GetFCPorts(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Error getting fiber channel details")
 	}
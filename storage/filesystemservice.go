@@ -11,6 +11,8 @@ limitations under the License.*/
 package storage
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/api"
@@ -22,6 +24,7 @@ import (
 
 	"k8s.io/klog"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -37,6 +40,9 @@ const (
 
 	// Treeq count
 	TREEQCOUNT = "host.k8s.treeqs"
+	// TREEQOWNER prefixes the hosting filesystem metadata key naming the PV of a treeq,
+	// treeqs themselves do not support metadata
+	TREEQOWNER = "host.k8s.treeq."
 )
 
 // service type
@@ -88,13 +94,17 @@ func getFilesystemService(serviceType string, c commonservice) *FilesystemServic
 
 // FileSystemInterface interface
 type FileSystemInterface interface {
-	CreateTreeqVolume(config map[string]string, capacity int64, pvName string) (map[string]string, error)
-	DeleteTreeqVolume(filesystemID, treeqID int64) error
-	UpdateTreeqVolume(filesystemID, treeqID, capacity int64, maxSize string) error
-	IsTreeqAlreadyExist(pool_name, network_space, pVName string) (treeqVolume map[string]string, err error)
+	CreateTreeqVolume(ctx context.Context, config map[string]string, capacity int64, pvName string) (map[string]string, error)
+	DeleteTreeqVolume(ctx context.Context, filesystemID, treeqID int64) error
+	UpdateTreeqVolume(ctx context.Context, filesystemID, treeqID, capacity int64, maxSize string) error
+	IsTreeqAlreadyExist(ctx context.Context, pool_name, network_space, pVName string) (treeqVolume map[string]string, err error)
+	ListTreeqVolumes(ctx context.Context) (treeqs []api.Treeq, err error)
+	GetPoolCapacity(ctx context.Context, params map[string]string) (capacity int64, err error)
+	GetTreeqVolume(ctx context.Context, filesystemID, treeqID int64) (*csi.ControllerGetVolumeResponse, error)
+	GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error)
 }
 
-func (filesystem *FilesystemService) checkTreeqName(FileSystemArry []api.FileSystem, pVName string) (treeqData *api.Treeq) {
+func (filesystem *FilesystemService) checkTreeqName(ctx context.Context, FileSystemArry []api.FileSystem, pVName string) (treeqData *api.Treeq) {
 	type item struct {
 		treeq *api.Treeq
 		err   error
@@ -107,7 +117,7 @@ func (filesystem *FilesystemService) checkTreeqName(FileSystemArry []api.FileSys
 		go func(f api.FileSystem) {
 			var it item
 			defer wg.Done()
-			it.treeq, it.err = filesystem.cs.api.GetTreeqByName(f.ID, pVName)
+			it.treeq, it.err = filesystem.cs.api.GetTreeqByName(ctx, f.ID, pVName)
 			itmArry = append(itmArry, it)
 		}(f)
 	}
@@ -122,97 +132,81 @@ func (filesystem *FilesystemService) checkTreeqName(FileSystemArry []api.FileSys
 }
 
 // IsTreeqAlreadyExist check the treeq exist or not
-func (filesystem *FilesystemService) IsTreeqAlreadyExist(pool_name, network_space, pVName string) (treeqVolume map[string]string, err error) {
+func (filesystem *FilesystemService) IsTreeqAlreadyExist(ctx context.Context, pool_name, network_space, pVName string) (treeqVolume map[string]string, err error) {
 	treeqVolume = make(map[string]string)
-	poolID, err := filesystem.cs.api.GetStoragePoolIDByName(pool_name)
+	poolID, err := filesystem.cs.api.GetStoragePoolIDByName(ctx, pool_name)
 	if err != nil {
 		klog.Errorf("failed to get poolID from poolName %s", pool_name)
 		return
 	}
 	filesystem.poolID = poolID
-	page := 1
-	for {
-		fsMetaData, poolErr := filesystem.cs.api.GetFileSystemsByPoolID(poolID, page)
-		if poolErr != nil {
-			klog.Errorf("failed to get filesystems from poolID %d and page no %d error %v", poolID, page, err)
-			err = errors.New("failed to get filesystems from poolName " + pool_name)
-			return
+	fileSystems, poolErr := filesystem.cs.api.GetFileSystemsByPoolID(ctx, poolID)
+	if poolErr != nil {
+		klog.Errorf("failed to get filesystems from poolID %d error %v", poolID, poolErr)
+		err = errors.New("failed to get filesystems from poolName " + pool_name)
+		return
+	}
+	if len(fileSystems) == 0 {
+		return
+	}
+	treeqData := filesystem.checkTreeqName(ctx, fileSystems, pVName)
+	if treeqData != nil {
+		exportErr := filesystem.getExportPath(ctx, treeqData.FilesystemID) // fetch export path and set to filesystem exportPath
+		if exportErr != nil {
+			err = exportErr
 		}
-		if fsMetaData != nil && len(fsMetaData.FileSystemArry) == 0 {
+		ipAddress, networkErr := filesystem.cs.getNetworkSpaceIP(ctx, network_space)
+		if networkErr != nil {
+			klog.Errorf("failed to get networkspace ipaddress %v", networkErr)
+			err = exportErr
 			return
 		}
-		treeqData := filesystem.checkTreeqName(fsMetaData.FileSystemArry, pVName)
-		if treeqData != nil {
-			exportErr := filesystem.getExportPath(treeqData.FilesystemID) // fetch export path and set to filesystem exportPath
-			if exportErr != nil {
-				err = exportErr
-			}
-			ipAddress, networkErr := filesystem.cs.getNetworkSpaceIP(network_space)
-			if networkErr != nil {
-				klog.Errorf("failed to get networkspace ipaddress %v", networkErr)
-				err = exportErr
-				return
-			}
-			filesystem.ipAddress = ipAddress
-			treeqVolume["ID"] = strconv.FormatInt(treeqData.FilesystemID, 10)
-			treeqVolume["TREEQID"] = strconv.FormatInt(treeqData.ID, 10)
-			treeqVolume["ipAddress"] = filesystem.ipAddress
-			treeqVolume["volumePath"] = path.Join(filesystem.exportpath, treeqData.Path)
-			return
-		}
-		// inner for loop closed
-		if fsMetaData.Filemetadata.PagesTotal == fsMetaData.Filemetadata.Page {
-			break
-		}
-		page++ // check the file system on next page
-	} // outer for loop closed
+		filesystem.ipAddress = ipAddress
+		treeqVolume["ID"] = strconv.FormatInt(treeqData.FilesystemID, 10)
+		treeqVolume["TREEQID"] = strconv.FormatInt(treeqData.ID, 10)
+		treeqVolume["ipAddress"] = filesystem.ipAddress
+		treeqVolume["volumePath"] = path.Join(filesystem.exportpath, treeqData.Path)
+	}
 	return
 }
 
-func (filesystem *FilesystemService) getExpectedFileSystemID(maxFileSystemSize int64) (filesys *api.FileSystem, err error) {
+func (filesystem *FilesystemService) getExpectedFileSystemID(ctx context.Context, maxFileSystemSize int64) (filesys *api.FileSystem, err error) {
 	if filesystem.capacity > maxFileSystemSize {
 		klog.Errorf("Can't allowed to create treeq of size %d", filesystem.capacity)
 		klog.Errorf("Max allowed filesytem size %d", maxFileSystemSize)
 		err = errors.New("Request treeq size is greater than allowed max_filesystem_size")
 		return
 	}
-	page := 1
-	for {
-		fsMetaData, poolErr := filesystem.cs.api.GetFileSystemsByPoolID(filesystem.poolID, page)
-		if poolErr != nil {
-			klog.Errorf("failed to get filesystems from poolID %d and page no %d error %v", filesystem.poolID, page, err)
-			err = errors.New("failed to get filesystems from poolName " + filesystem.configmap["pool_name"])
-			return
-		}
-		if fsMetaData != nil && len(fsMetaData.FileSystemArry) == 0 {
-			klog.V(4).Infof("NO filesystem found.filesystem array is empty")
-			return
-		}
-		for _, fs := range fsMetaData.FileSystemArry {
-			if fs.Size+filesystem.capacity < maxFileSystemSize {
-				treeqCnt, treeqCnterr := filesystem.cs.api.GetFilesytemTreeqCount(fs.ID)
-				if treeqCnterr != nil {
-					klog.Errorf("failed to get treeq count of filesystemID %d error %v", fs.ID, err)
-					err = errors.New("failed to get treeq count of filesystemID " + strconv.FormatInt(fs.ID, 10))
-					return
-				}
-				if treeqCnt < filesystem.getAllowedCount(MAXTREEQSPERFILESYSTEM) {
-					filesystem.treeqCnt = treeqCnt
-					klog.V(4).Infof("filesystem found to create treeQ,filesystemID %d", fs.ID)
-					exportErr := filesystem.getExportPath(fs.ID) // fetch export path and set to filesystem exportPath
-					if exportErr != nil {
-						err = exportErr
-					}
-					filesys = &fs
-					return
+	fileSystems, poolErr := filesystem.cs.api.GetFileSystemsByPoolID(ctx, filesystem.poolID)
+	if poolErr != nil {
+		klog.Errorf("failed to get filesystems from poolID %d error %v", filesystem.poolID, poolErr)
+		err = errors.New("failed to get filesystems from poolName " + filesystem.configmap["pool_name"])
+		return
+	}
+	if len(fileSystems) == 0 {
+		klog.V(4).Infof("NO filesystem found.filesystem array is empty")
+		return
+	}
+	for _, fs := range fileSystems {
+		if fs.Size+filesystem.capacity < maxFileSystemSize {
+			treeqCnt, treeqCnterr := filesystem.cs.api.GetFilesytemTreeqCount(ctx, fs.ID)
+			if treeqCnterr != nil {
+				klog.Errorf("failed to get treeq count of filesystemID %d error %v", fs.ID, treeqCnterr)
+				err = errors.New("failed to get treeq count of filesystemID " + strconv.FormatInt(fs.ID, 10))
+				return
+			}
+			if treeqCnt < filesystem.getAllowedCount(MAXTREEQSPERFILESYSTEM) {
+				filesystem.treeqCnt = treeqCnt
+				klog.V(4).Infof("filesystem found to create treeQ,filesystemID %d", fs.ID)
+				exportErr := filesystem.getExportPath(ctx, fs.ID) // fetch export path and set to filesystem exportPath
+				if exportErr != nil {
+					err = exportErr
 				}
+				filesys = &fs
+				return
 			}
-		} // inner for loop closed
-		if fsMetaData.Filemetadata.PagesTotal == fsMetaData.Filemetadata.Page {
-			break
 		}
-		page++ // check the file system on next page
-	} // outer for loop closed
+	}
 	klog.V(4).Infof("NO filesystem found to create treeQ")
 	return
 }
@@ -225,7 +219,7 @@ func (filesystem *FilesystemService) setParameter(config map[string]string, capa
 }
 
 // CreateTreeqVolume create volume method
-func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string, capacity int64, pvName string) (treeqVolume map[string]string, err error) {
+func (filesystem *FilesystemService) CreateTreeqVolume(ctx context.Context, config map[string]string, capacity int64, pvName string) (treeqVolume map[string]string, err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while creating treeq method " + fmt.Sprint(res))
@@ -238,15 +232,16 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 	treeqVolume["unix_permissions"] = config["unix_permissions"]
 	filesystem.setParameter(config, capacity, pvName)
 
-	ipAddress, err := filesystem.cs.getNetworkSpaceIP(strings.Trim(config["network_space"], " "))
+	networkSpace, ipAddress, err := filesystem.cs.selectNetworkSpaceIP(ctx, config)
 	if err != nil {
 		klog.Errorf("failed to get networkspace ipaddress %v", err)
 		return
 	}
 	filesystem.ipAddress = ipAddress
+	treeqVolume["network_space"] = networkSpace
 
 	var poolID int64
-	poolID, err = filesystem.cs.api.GetStoragePoolIDByName(filesystem.configmap["pool_name"])
+	poolID, err = filesystem.cs.api.GetStoragePoolIDByName(ctx, filesystem.configmap["pool_name"])
 	if err != nil {
 		klog.Errorf("failed to get poolID from poolName %s", filesystem.configmap["pool_name"])
 		return
@@ -263,19 +258,19 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 	helper.GetMutex().Mutex.Lock()
 	defer helper.GetMutex().Mutex.Unlock()
 
-	filesys, err = filesystem.getExpectedFileSystemID(maxFileSystemSize)
+	filesys, err = filesystem.getExpectedFileSystemID(ctx, maxFileSystemSize)
 	if err != nil {
 		klog.Errorf("failed to getExpectedFileSystemID  %v", err)
 		return
 	}
 	var filesystemID int64
 	if filesys == nil { // if pool is empty or no file system found to createTreeq
-		err = filesystem.createFileSystem()
+		err = filesystem.createFileSystem(ctx)
 		if err != nil {
 			klog.Errorf("failed to create fileSystem %v", err)
 			return
 		}
-		err = filesystem.createExportPathAndAddMetadata()
+		err = filesystem.createExportPathAndAddMetadata(ctx)
 		if err != nil {
 			klog.Errorf("failed to create export and metadata %v", err)
 			return
@@ -286,11 +281,11 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 	}
 
 	// create treeq
-	treeqResponse, createTreeqerr := filesystem.cs.api.CreateTreeq(filesystemID, filesystem.getTreeParameters())
+	treeqResponse, createTreeqerr := filesystem.cs.api.CreateTreeq(ctx, filesystemID, filesystem.getTreeParameters())
 	if createTreeqerr != nil {
 		klog.Errorf("failed to create treeq  %s error %v", filesystem.pVName, err)
 		if filesys == nil { // if the file system created at the time of creating first treeq ,then delete the complete filesystem with export and metata
-			deleteFilesystemErr := filesystem.cs.api.DeleteFileSystemComplete(filesystemID)
+			deleteFilesystemErr := filesystem.cs.api.DeleteFileSystemComplete(ctx, filesystemID)
 			if deleteFilesystemErr != nil {
 				klog.Errorf("failed to delete filesystem ,filesystemID = %d", filesystemID)
 			}
@@ -310,7 +305,7 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 		}
 		if err != nil && filesystem.fileSystemID != 0 {
 			klog.V(2).Infof("Seemes to be some problem reverting treeq: %s", filesystem.pVName)
-			_, errDelTreeq := filesystem.cs.api.DeleteTreeq(filesystem.fileSystemID, treeqResponse.ID)
+			_, errDelTreeq := filesystem.cs.api.DeleteTreeq(ctx, filesystem.fileSystemID, treeqResponse.ID)
 			if errDelTreeq != nil {
 				klog.Errorf("failed to delete treeq: %s", filesystem.pVName)
 			}
@@ -318,7 +313,7 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 	}()
 
 	treeqCount := filesystem.treeqCnt + 1
-	_, updateTreeqErr := filesystem.UpdateTreeqCnt(filesystemID, NONE, treeqCount)
+	_, updateTreeqErr := filesystem.UpdateTreeqCnt(ctx, filesystemID, NONE, treeqCount)
 	if updateTreeqErr != nil {
 		err = errors.New("failed to increment treeq count as metadata")
 		return
@@ -331,7 +326,7 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 		}
 		if err != nil && filesystemID != 0 {
 			klog.V(2).Infof("Seemes to be some problem reverting treeqcount")
-			_, errUpdTreeq := filesystem.UpdateTreeqCnt(filesystemID, DecrementTreeqCount, 0)
+			_, errUpdTreeq := filesystem.UpdateTreeqCnt(ctx, filesystemID, DecrementTreeqCount, 0)
 			if errUpdTreeq != nil {
 				klog.Errorf("failed to update count for treeq: %s", filesystem.pVName)
 			}
@@ -342,30 +337,51 @@ func (filesystem *FilesystemService) CreateTreeqVolume(config map[string]string,
 	if filesys != nil {
 		var updateFileSys api.FileSystem
 		updateFileSys.Size = filesys.Size + filesystem.capacity
-		_, updateFileSizeErr := filesystem.cs.api.UpdateFilesystem(filesystemID, updateFileSys)
+		_, updateFileSizeErr := filesystem.cs.api.UpdateFilesystem(ctx, filesystemID, updateFileSys)
 		if updateFileSizeErr != nil {
 			klog.Errorf("failed to update File Size %v", err)
 			err = errors.New("failed to update files size")
 			return
 		}
 	}
+
+	filesystem.attachTreeqOwner(ctx, filesystemID, treeqResponse.ID)
 	return
 }
 
-func (filesystem *FilesystemService) createExportPathAndAddMetadata() (err error) {
+// treeqOwnerKey returns the hosting filesystem metadata key of a treeq
+func treeqOwnerKey(treeqID int64) string {
+	return TREEQOWNER + strconv.FormatInt(treeqID, 10)
+}
+
+// attachTreeqOwner records the PV, PVC and StorageClass of a treeq on its hosting filesystem,
+// a failure is logged only as the treeq is usable without it
+func (filesystem *FilesystemService) attachTreeqOwner(ctx context.Context, filesystemID, treeqID int64) {
+	owner, err := json.Marshal(filesystem.cs.getVolumeMetadata(filesystem.pVName, NFSTREEQ, filesystem.configmap))
+	if err != nil {
+		klog.Errorf("failed to encode owner of treeq %s, %v", filesystem.pVName, err)
+		return
+	}
+	metadata := map[string]interface{}{treeqOwnerKey(treeqID): string(owner)}
+	if _, err = filesystem.cs.api.AttachMetadataToObject(ctx, filesystemID, metadata); err != nil {
+		klog.Errorf("failed to attach owner of treeq %s to filesystem %d, %v", filesystem.pVName, filesystemID, err)
+	}
+}
+
+func (filesystem *FilesystemService) createExportPathAndAddMetadata(ctx context.Context) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while export directory" + fmt.Sprint(res))
 		}
 		if err != nil && filesystem.fileSystemID != 0 {
 			klog.V(2).Infof("Seemes to be some problem reverting filesystem: %s", filesystem.pVName)
-			if _, errDelFS := filesystem.cs.api.DeleteFileSystem(filesystem.fileSystemID); errDelFS != nil {
+			if _, errDelFS := filesystem.cs.api.DeleteFileSystem(ctx, filesystem.fileSystemID); errDelFS != nil {
 				klog.Errorf("failed to delete filesystem: %s", filesystem.pVName)
 			}
 		}
 	}()
 
-	err = filesystem.createExportPath()
+	err = filesystem.createExportPath(ctx)
 	if err != nil {
 		klog.Errorf("failed to export path %v", err)
 		return
@@ -378,16 +394,14 @@ func (filesystem *FilesystemService) createExportPathAndAddMetadata() (err error
 		}
 		if err != nil && filesystem.exportID != 0 {
 			klog.V(2).Info("Seemes to be some problem reverting created export id:", filesystem.exportID)
-			if _, errDelExport := filesystem.cs.api.DeleteExportPath(filesystem.exportID); errDelExport != nil {
+			if _, errDelExport := filesystem.cs.api.DeleteExportPath(ctx, filesystem.exportID); errDelExport != nil {
 				klog.Errorf("failed to delete export path: %s", filesystem.pVName)
 			}
 		}
 	}()
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = filesystem.pVName
-	metadata["host.created_by"] = filesystem.cs.GetCreatedBy()
+	metadata := filesystem.cs.getVolumeMetadata(filesystem.pVName, NFSTREEQ, filesystem.configmap)
 
-	_, err = filesystem.cs.api.AttachMetadataToObject(filesystem.fileSystemID, metadata)
+	_, err = filesystem.cs.api.AttachMetadataToObject(ctx, filesystem.fileSystemID, metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for fileSystem : %s", filesystem.pVName)
 		klog.Errorf("error to attach metadata %v", err)
@@ -397,8 +411,8 @@ func (filesystem *FilesystemService) createExportPathAndAddMetadata() (err error
 	return
 }
 
-func (filesystem *FilesystemService) createFileSystem() (err error) {
-	fileSystemCnt, err := filesystem.cs.api.GetFileSystemCountByPoolID(filesystem.poolID)
+func (filesystem *FilesystemService) createFileSystem(ctx context.Context) (err error) {
+	fileSystemCnt, err := filesystem.cs.api.GetFileSystemCountByPoolID(ctx, filesystem.poolID)
 	if err != nil {
 		klog.Errorf("failed to get the filesystem count from Ibox %v", err)
 		return
@@ -426,12 +440,23 @@ func (filesystem *FilesystemService) createFileSystem() (err error) {
 	if prefix, ok := filesystem.configmap[FSPREFIX]; ok {
 		treeqFileSystemName = prefix + pvSplit[1]
 	}
+	// the optional volume_name_template names the filesystem after the PV it is created for, instead of fs_prefix
+	if filesystem.configmap[VOLUMENAMETEMPLATE] != "" {
+		treeqFileSystemName, err = getVolumeName(filesystem.configmap, filesystem.pVName)
+		if err != nil {
+			klog.Errorf("failed to get name of filesystem for treeq %s, %v", filesystem.pVName, err)
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
 	filesystem.exportpath = "/" + treeqFileSystemName
 	mapRequest["name"] = treeqFileSystemName
 	mapRequest["ssd_enabled"] = ssd
 	mapRequest["provtype"] = strings.ToUpper(filesystem.configmap["provision_type"])
 	mapRequest["size"] = filesystem.capacity
-	fileSystem, err := filesystem.cs.api.CreateFilesystem(mapRequest)
+	if compressionEnabled, _ := getCompressionEnabled(filesystem.configmap); compressionEnabled != nil { // already validated
+		mapRequest[COMPRESSIONENABLED] = *compressionEnabled
+	}
+	fileSystem, err := filesystem.cs.api.CreateFilesystem(ctx, mapRequest)
 	if err != nil {
 		klog.Errorf("failed to create filesystem %s", filesystem.pVName)
 		return
@@ -441,7 +466,7 @@ func (filesystem *FilesystemService) createFileSystem() (err error) {
 	return
 }
 
-func (filesystem *FilesystemService) createExportPath() (err error) {
+func (filesystem *FilesystemService) createExportPath(ctx context.Context) (err error) {
 	permissionsMapArray, err := getPermissionMaps(filesystem.configmap["nfs_export_permissions"])
 	if err != nil {
 		return
@@ -453,7 +478,7 @@ func (filesystem *FilesystemService) createExportPath() (err error) {
 	exportFileSystem.Privileged_port = false
 	exportFileSystem.Export_path = filesystem.exportpath
 	exportFileSystem.Permissionsput = append(exportFileSystem.Permissionsput, permissionsMapArray...)
-	exportResp, err := filesystem.cs.api.ExportFileSystem(exportFileSystem)
+	exportResp, err := filesystem.cs.api.ExportFileSystem(ctx, exportFileSystem)
 	if err != nil {
 		klog.Errorf("failed to create export path of filesystem %s", filesystem.pVName)
 		return
@@ -536,8 +561,8 @@ func (filesystem *FilesystemService) getTreeParameters() map[string]interface{}
 // 	return values[UNIXPERMISSION]
 // }
 
-func (filesystem *FilesystemService) getExportPath(filesystemID int64) error {
-	exportResponse, exportErr := filesystem.cs.api.GetExportByFileSystem(filesystemID)
+func (filesystem *FilesystemService) getExportPath(ctx context.Context, filesystemID int64) error {
+	exportResponse, exportErr := filesystem.cs.api.GetExportByFileSystem(ctx, filesystemID)
 	if exportErr != nil {
 		klog.Errorf("failed to create export path of filesystem %d", filesystemID)
 		return exportErr
@@ -556,7 +581,7 @@ func isTreeQEmpty(treeq api.Treeq) bool {
 var deleteMutex sync.Mutex
 
 // DeleteNFSVolume delete volume method
-func (filesystem *FilesystemService) DeleteTreeqVolume(filesystemID, treeqID int64) (err error) {
+func (filesystem *FilesystemService) DeleteTreeqVolume(ctx context.Context, filesystemID, treeqID int64) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while deleting treeq " + fmt.Sprint(res))
@@ -565,9 +590,9 @@ func (filesystem *FilesystemService) DeleteTreeqVolume(filesystemID, treeqID int
 	}()
 	// 1.treeq exist or not checked
 	var treeq *api.Treeq
-	treeq, err = filesystem.cs.api.GetTreeq(filesystemID, treeqID)
+	treeq, err = filesystem.cs.api.GetTreeq(ctx, filesystemID, treeqID)
 	if err != nil {
-		if strings.Contains(err.Error(), "TREEQ_ID_DOES_NOT_EXIST") {
+		if api.HasErrorCode(err, api.ErrTreeqIDDoesNotExist) {
 			err = errors.New("treeq does not exist on infinibox")
 			return nil
 		}
@@ -587,16 +612,16 @@ func (filesystem *FilesystemService) DeleteTreeqVolume(filesystemID, treeqID int
 	deleteMutex.Lock()
 	defer deleteMutex.Unlock()
 
-	treeqCnt, err := filesystem.UpdateTreeqCnt(filesystemID, DecrementTreeqCount, 0)
+	treeqCnt, err := filesystem.UpdateTreeqCnt(ctx, filesystemID, DecrementTreeqCount, 0)
 	if err != nil {
 		klog.Errorf("failed to update treeq count, filesystem: %s", filesystem.pVName)
 		return
 	}
 	// 4.delete the treeq
-	_, err = filesystem.cs.api.DeleteTreeq(filesystemID, treeqID)
+	_, err = filesystem.cs.api.DeleteTreeq(ctx, filesystemID, treeqID)
 	if err != nil {
 		klog.Errorf("failed to delete treeq")
-		if _, errUpdTreeq := filesystem.UpdateTreeqCnt(filesystemID, IncrementTreeqCount, 0); errUpdTreeq != nil {
+		if _, errUpdTreeq := filesystem.UpdateTreeqCnt(ctx, filesystemID, IncrementTreeqCount, 0); errUpdTreeq != nil {
 			klog.Errorf("failed to update treeq count, filesystem: %s", filesystem.pVName)
 		}
 		return
@@ -604,20 +629,22 @@ func (filesystem *FilesystemService) DeleteTreeqVolume(filesystemID, treeqID int
 
 	// 5.Delete file system if all treeq are delete
 	if treeqCnt == 0 { // measn all tree are delete. then delete the complete filesystem with exportPath ,metadata..etc
-		err = filesystem.cs.api.DeleteFileSystemComplete(filesystemID)
+		err = filesystem.cs.api.DeleteFileSystemComplete(ctx, filesystemID)
 		if err != nil {
 			klog.Errorf("failed to delete filesystem filesystemID %d error %v", filesystemID, err)
 			return
 		}
+	} else if errDetach := filesystem.cs.api.DetachMetadataKeyFromObject(ctx, filesystemID, treeqOwnerKey(treeqID)); errDetach != nil {
+		klog.Errorf("failed to detach owner of treeq %d from filesystem %d, %v", treeqID, filesystemID, errDetach)
 	}
 	klog.V(4).Infof("Treeq deleted successfully")
 	return
 }
 
 // UpdateTreeqCnt method
-func (filesystem *FilesystemService) UpdateTreeqCnt(fileSystemID int64, action ACTION, treeqCnt int) (treeqCount int, err error) {
+func (filesystem *FilesystemService) UpdateTreeqCnt(ctx context.Context, fileSystemID int64, action ACTION, treeqCnt int) (treeqCount int, err error) {
 	if treeqCnt == 0 {
-		treeqCnt, err = filesystem.cs.api.GetFilesytemTreeqCount(fileSystemID)
+		treeqCnt, err = filesystem.cs.api.GetFilesytemTreeqCount(ctx, fileSystemID)
 		if err != nil {
 			return
 		}
@@ -632,7 +659,7 @@ func (filesystem *FilesystemService) UpdateTreeqCnt(fileSystemID int64, action A
 	}
 	metadataParamter := make(map[string]interface{})
 	metadataParamter[TREEQCOUNT] = treeqCnt
-	_, err = filesystem.cs.api.AttachMetadataToObject(fileSystemID, metadataParamter)
+	_, err = filesystem.cs.api.AttachMetadataToObject(ctx, fileSystemID, metadataParamter)
 	if err != nil {
 		klog.Errorf("failed to update treeq count for filesystemID : %d error %v", fileSystemID, err)
 		return
@@ -644,7 +671,7 @@ func (filesystem *FilesystemService) UpdateTreeqCnt(fileSystemID int64, action A
 }
 
 // UpdateTreeqVolume Upadate volume size method
-func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, capacity int64, maxSize string) (err error) {
+func (filesystem *FilesystemService) UpdateTreeqVolume(ctx context.Context, filesystemID, treeqID, capacity int64, maxSize string) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("failed to update treeq " + fmt.Sprint(res))
@@ -653,16 +680,16 @@ func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, ca
 	}()
 
 	// Get Filesystem
-	fileSystemResponse, err := filesystem.cs.api.GetFileSystemByID(filesystemID)
+	fileSystemResponse, err := filesystem.cs.api.GetFileSystemByID(ctx, filesystemID)
 	if err != nil {
 		klog.Errorf("failed to get file system %v", err)
 		return
 	}
 
 	// Get a treeq
-	treeq, err := filesystem.cs.api.GetTreeq(filesystemID, treeqID)
+	treeq, err := filesystem.cs.api.GetTreeq(ctx, filesystemID, treeqID)
 	if err != nil {
-		if strings.Contains(err.Error(), "TREEQ_ID_DOES_NOT_EXIST") {
+		if api.HasErrorCode(err, api.ErrTreeqIDDoesNotExist) {
 			err = errors.New("treeq not found")
 			return nil
 		}
@@ -671,7 +698,7 @@ func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, ca
 	}
 
 	// Get sum of all the treeq size of filesystem
-	totalTreeqSize, err := filesystem.cs.api.GetTreeqSizeByFileSystemID(filesystemID)
+	totalTreeqSize, err := filesystem.cs.api.GetTreeqSizeByFileSystemID(ctx, filesystemID)
 	if err != nil {
 		klog.Errorf("failed to get sum of all the treeq sizes in a filesystem")
 		return
@@ -698,7 +725,7 @@ func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, ca
 		}
 
 		// Expand file system size
-		_, err = filesystem.cs.api.UpdateFilesystem(filesystemID, fileSys)
+		_, err = filesystem.cs.api.UpdateFilesystem(ctx, filesystemID, fileSys)
 		if err != nil {
 			klog.Errorf("failed to update file system %v", err)
 			return err
@@ -707,7 +734,7 @@ func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, ca
 
 	// Expand Treeq size
 	body := map[string]interface{}{"hard_capacity": capacity}
-	_, err = filesystem.cs.api.UpdateTreeq(filesystemID, treeqID, body)
+	_, err = filesystem.cs.api.UpdateTreeq(ctx, filesystemID, treeqID, body)
 	if err != nil {
 		klog.Errorf("failed to update treeq size %v", err)
 		return
@@ -716,3 +743,61 @@ func (filesystem *FilesystemService) UpdateTreeqVolume(filesystemID, treeqID, ca
 	klog.V(2).Info("Treeq size updated successfully")
 	return
 }
+
+// GetPoolCapacity returns the free capacity of the pool named in the StorageClass parameters
+func (filesystem *FilesystemService) GetPoolCapacity(ctx context.Context, params map[string]string) (capacity int64, err error) {
+	return filesystem.cs.getPoolCapacity(ctx, params)
+}
+
+// GetTreeqVolume returns the size, export clients and condition of a treeq
+func (filesystem *FilesystemService) GetTreeqVolume(ctx context.Context, filesystemID, treeqID int64) (*csi.ControllerGetVolumeResponse, error) {
+	resp := &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{VolumeId: strconv.FormatInt(filesystemID, 10) + "#" + strconv.FormatInt(treeqID, 10)},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{},
+	}
+	treeq, err := filesystem.cs.api.GetTreeq(ctx, filesystemID, treeqID)
+	if err != nil {
+		if api.HasErrorCode(err, api.ErrTreeqIDDoesNotExist, api.ErrFileSystemNotFound) {
+			resp.Status.VolumeCondition = abnormalCondition("treeq %d of filesystem %d not found on the array", treeqID, filesystemID)
+			return resp, nil
+		}
+		klog.Errorf("Error occured while getting treeq: %s", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get treeq %d of filesystem %d: %v", treeqID, filesystemID, err)
+	}
+	resp.Volume.CapacityBytes = treeq.HardCapacity
+	resp.Status.PublishedNodeIds, err = filesystem.cs.getExportClients(ctx, filesystemID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Status.VolumeCondition = &csi.VolumeCondition{Abnormal: false, Message: "treeq is healthy"}
+	return resp, nil
+}
+
+// ListTreeqVolumes returns the treeqs of every filesystem carrying the treeq count metadata
+func (filesystem *FilesystemService) ListTreeqVolumes(ctx context.Context) (treeqs []api.Treeq, err error) {
+	fileSystems, err := filesystem.cs.api.GetMetadataByKey(ctx, TREEQCOUNT)
+	if err != nil {
+		klog.Errorf("failed to get treeq filesystems: %v", err)
+		return nil, err
+	}
+	for _, md := range fileSystems {
+		fileSystemID := int64(md.ObjectId)
+		treeqList, err := filesystem.cs.api.GetTreeqsByFileSystemID(ctx, fileSystemID)
+		if err != nil {
+			if api.HasErrorCode(err, api.ErrFileSystemNotFound) {
+				klog.V(4).Infof("filesystem %d was deleted while listing treeqs", fileSystemID)
+				continue
+			}
+			klog.Errorf("failed to get treeqs of filesystem %d: %v", fileSystemID, err)
+			return nil, err
+		}
+		for _, t := range treeqList {
+			if t.FilesystemID == 0 {
+				t.FilesystemID = fileSystemID
+			}
+			treeqs = append(treeqs, t)
+		}
+	}
+	klog.V(4).Infof("listed %d treeqs", len(treeqs))
+	return treeqs, nil
+}
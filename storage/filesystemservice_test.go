@@ -11,9 +11,11 @@ limitations under the License.*/
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"infinibox-csi-driver/api"
+	"strings"
 	"testing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -42,7 +44,7 @@ func (suite *FileSystemServiceSuite) Test_getExpectedFileSystemID_maxfilesystem(
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(0, expectedErr)
 	service := getFilesystemService(NFSTREEQ, *suite.cs)
 	service.capacity = 209951162777600
-	_, err := service.getExpectedFileSystemID(1000)
+	_, err := service.getExpectedFileSystemID(context.Background(), 1000)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -54,7 +56,7 @@ func (suite *FileSystemServiceSuite) Test_getExpectedFileSystemID_getMaxSize_err
 	configmap[MAXFILESYSTEMSIZE] = "4mib"
 	service.configmap = configmap
 	service.capacity = 209951162777600
-	_, err := service.getExpectedFileSystemID(10)
+	_, err := service.getExpectedFileSystemID(context.Background(), 10)
 	fmt.Println(err)
 	assert.NotNil(suite.T(), err, "empty object")
 }
@@ -63,9 +65,9 @@ func (suite *FileSystemServiceSuite) Test_getExpectedFileSystemID_FileSystemByPo
 	expectedErr := errors.New("some error")
 	var poolID int64 = 10
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", mock.Anything, 1).Return(nil, expectedErr)
+	suite.api.On("GetFileSystemsByPoolID", mock.Anything).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.getExpectedFileSystemID(1000)
+	_, err := service.getExpectedFileSystemID(context.Background(), 1000)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -75,10 +77,10 @@ func (suite *FileSystemServiceSuite) Test_getExpectedFileSystemID_FilesytemTreeq
 	var poolID int64 = 10
 	// var fsID int64 = 11
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", mock.Anything, 1).Return(*fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", mock.Anything).Return(fsMetada, nil)
 	suite.api.On("GetFilesytemTreeqCount", mock.Anything).Return(0, expectedErr)
 	service := FilesystemService{cs: *suite.cs, capacity: 100}
-	_, err := service.getExpectedFileSystemID(9999990)
+	_, err := service.getExpectedFileSystemID(context.Background(), 9999990)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -87,19 +89,17 @@ func (suite *FileSystemServiceSuite) Test_getExpectedFileSystemID_Success() {
 	var poolID int64 = 10
 	var fsID int64 = 10
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", mock.Anything, mock.Anything).Return(*fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", mock.Anything).Return(fsMetada, nil)
 	suite.api.On("GetFilesytemTreeqCount", mock.Anything).Return(1, nil)
 
 	exportResp := getExportResponse()
 	suite.api.On("GetExportByFileSystem", fsID).Return(exportResp, nil)
-	fsMetada2 := getfsMetadata2()
-	suite.api.On("GetFileSystemsByPoolID", poolID, 2).Return(*fsMetada2, nil)
 	service := FilesystemService{cs: *suite.cs}
 
 	service.capacity = 1000
 	service.exportpath = "/exportPath"
 
-	fs, err := service.getExpectedFileSystemID(9999999999999)
+	fs, err := service.getExpectedFileSystemID(context.Background(), 9999999999999)
 	assert.Nil(suite.T(), err, "empty object")
 	assert.Equal(suite.T(), fs.ID, fsID, "file system ID equal")
 }
@@ -119,7 +119,7 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_Success() {
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(*fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(1, nil)
 
 	exportResp := getExportResponse()
@@ -129,6 +129,10 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_Success() {
 	suite.api.On("CreateTreeq", fsID, mock.Anything).Return(*treeqResp, nil)
 
 	metadataResp := getMetadaResponse()
+	suite.api.On("AttachMetadataToObject", fsID, mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		owner, ok := metadata[treeqOwnerKey(treeqResp.ID)].(string)
+		return ok && strings.Contains(owner, "csi-TestTreeq")
+	})).Return(*metadataResp, nil)
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(*metadataResp, nil)
 
 	suite.api.On("UpdateFilesystem", fsID, mock.Anything).Return(nil, nil)
@@ -139,19 +143,20 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_Success() {
 	pVName := "csi-TestTreeq"
 	configMap := getCreateTreeqVolumeParameter()
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.Nil(suite.T(), err, "empty object")
+	suite.api.AssertExpectations(suite.T())
 }
 
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_FileSystemCount_Error() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 	//	var fsID int64 = 11
 	expectedErr := errors.New("some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(0, expectedErr)
 
 	service := FilesystemService{cs: *suite.cs}
@@ -160,17 +165,17 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_FileSystemCount_Erro
 	pVName := "csi-TestTreeq"
 	configMap := make(map[string]string)
 	configMap["network_space"] = "networkspace"
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_FileSystemCount_notAllowed() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(20000, nil)
 
 	service := FilesystemService{cs: *suite.cs}
@@ -179,18 +184,18 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_FileSystemCount_notA
 	pVName := "csi-TestTreeq"
 	configMap := getCreateTreeqVolumeParameter()
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_CreateFilesystem_Error() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 	expectedErr := errors.New("some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(200, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(nil, expectedErr)
 
@@ -201,18 +206,18 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_CreateFilesystem_Err
 	configMap := getCreateTreeqVolumeParameter()
 	configMap["fs_prefix"] = "csit_"
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_ExportFileSystem_Error() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 	expectedErr := errors.New("some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(200, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(getFileSystem, nil)
 	suite.api.On("ExportFileSystem", mock.Anything).Return(nil, expectedErr)
@@ -224,18 +229,18 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_ExportFileSystem_Err
 	configMap := getCreateTreeqVolumeParameter()
 	configMap["fs_prefix"] = "csit_"
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_metadata_Error() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 	expectedErr := errors.New("some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(200, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(getFileSystem, nil)
 	suite.api.On("ExportFileSystem", mock.Anything).Return(getExportResponse(), nil)
@@ -248,18 +253,48 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_metadata_Error() {
 	configMap := getCreateTreeqVolumeParameter()
 	configMap["fs_prefix"] = "csit_"
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
+func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_volumeNameTemplate() {
+	for _, tc := range []struct {
+		params map[string]string
+		name   string
+	}{
+		{map[string]string{FSPREFIX: "csit_"}, "csit_TestTreeq"},
+		{map[string]string{FSPREFIX: "csit_", VOLUMENAMETEMPLATE: "k8s-{{.PVCNamespace}}-{{.PVCName}}", pvcNamespaceKey: "default", pvcNameKey: "data"}, "k8s-default-data"},
+	} {
+		suite.SetupTest()
+		var poolID int64 = 10
+		suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
+		suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
+		suite.api.On("GetFileSystemsByPoolID", poolID).Return([]api.FileSystem{}, nil)
+		suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(200, nil)
+		suite.api.On("CreateFilesystem", mock.Anything).Return(getFileSystem, nil)
+		suite.api.On("ExportFileSystem", mock.Anything).Return(nil, errors.New("some error"))
+
+		service := FilesystemService{cs: *suite.cs}
+		configMap := getCreateTreeqVolumeParameter()
+		for key, value := range tc.params {
+			configMap[key] = value
+		}
+		_, err := service.CreateTreeqVolume(context.Background(), configMap, 1000, "csi-TestTreeq")
+		assert.NotNil(suite.T(), err, "expected to fail: export of the filesystem")
+		suite.api.AssertCalled(suite.T(), "CreateFilesystem", mock.MatchedBy(func(request map[string]interface{}) bool {
+			return request["name"] == tc.name
+		}))
+	}
+}
+
 func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_CreateTreeq_Error() {
-	var fsMetada api.FSMetadata
+	var fsMetada []api.FileSystem
 	var poolID int64 = 10
 	expectedErr := errors.New("some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getnetworkspace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetFileSystemCountByPoolID", mock.Anything).Return(200, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(getFileSystem, nil)
 	suite.api.On("ExportFileSystem", mock.Anything).Return(getExportResponse(), nil)
@@ -274,7 +309,7 @@ func (suite *FileSystemServiceSuite) Test_CreateTreeqVolume_CreateTreeq_Error()
 	configMap := getCreateTreeqVolumeParameter()
 	configMap["fs_prefix"] = "csit_"
 
-	_, err := service.CreateTreeqVolume(configMap, capacity, pVName)
+	_, err := service.CreateTreeqVolume(context.Background(), configMap, capacity, pVName)
 	assert.NotNil(suite.T(), err, "failed to get filecount")
 }
 
@@ -286,7 +321,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqCnt_Success1() {
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(currentTreeqCnt, nil)
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(*metadataResp, nil)
 	service := FilesystemService{cs: *suite.cs}
-	cnt, err := service.UpdateTreeqCnt(fsID, IncrementTreeqCount, 0)
+	cnt, err := service.UpdateTreeqCnt(context.Background(), fsID, IncrementTreeqCount, 0)
 	assert.Nil(suite.T(), err, "empty object")
 	assert.Equal(suite.T(), expectedCnt, cnt, "treeq count shoude be same")
 }
@@ -298,7 +333,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqCnt_Success2() {
 	metadataResp := getMetadaResponse()
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(*metadataResp, nil)
 	service := FilesystemService{cs: *suite.cs}
-	cnt, err := service.UpdateTreeqCnt(fsID, IncrementTreeqCount, currentTreeqCnt)
+	cnt, err := service.UpdateTreeqCnt(context.Background(), fsID, IncrementTreeqCount, currentTreeqCnt)
 	assert.Nil(suite.T(), err, "empty object")
 	assert.Equal(suite.T(), expectedCnt, cnt, "treeq count shoude be same")
 }
@@ -310,7 +345,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqCnt_Error1() {
 	expectedErr := errors.New("some error")
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.UpdateTreeqCnt(fsID, IncrementTreeqCount, currentTreeqCnt)
+	_, err := service.UpdateTreeqCnt(context.Background(), fsID, IncrementTreeqCount, currentTreeqCnt)
 	assert.NotNil(suite.T(), err, "err should not be nil")
 }
 
@@ -321,17 +356,17 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqCnt_Error2() {
 	expectedErr := errors.New("some error")
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.UpdateTreeqCnt(fsID, IncrementTreeqCount, 0)
+	_, err := service.UpdateTreeqCnt(context.Background(), fsID, IncrementTreeqCount, 0)
 	assert.NotNil(suite.T(), err, "err should not be nil")
 }
 
 func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_GetTreeq_error() {
 	var fsID int64 = 11
 	var treeqID int64 = 10
-	expectedErr := errors.New("TREEQ_ID_DOES_NOT_EXIST")
+	expectedErr := &api.APIError{Code: api.ErrTreeqIDDoesNotExist}
 	suite.api.On("GetTreeq", fsID, treeqID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.Nil(suite.T(), err, "empty object")
 }
 
@@ -341,7 +376,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_GetTreeq_error2() {
 	expectedErr := errors.New("some other error")
 	suite.api.On("GetTreeq", fsID, treeqID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -351,7 +386,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_GetTreeq_treeqNotEmp
 	expectedResponse := getTreeQResponse(fsID)
 	suite.api.On("GetTreeq", fsID, treeqID).Return(*expectedResponse, nil)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 	assert.Equal(suite.T(), err.Error(), "can't delete NFS-treeq PV with data", "unexpected error message")
 }
@@ -365,7 +400,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_TreeqCount_fail() {
 	suite.api.On("GetTreeq", fsID, treeqID).Return(*expectedResponse, nil)
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(0, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -379,7 +414,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_TreeqCount_fail2() {
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(10, nil)
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -392,9 +427,11 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_DeleteTreeq_success(
 	suite.api.On("GetFilesytemTreeqCount", fsID).Return(10, nil)
 	suite.api.On("AttachMetadataToObject", fsID, mock.Anything).Return(nil, nil)
 	suite.api.On("DeleteTreeq", fsID, treeqID).Return(nil, nil)
+	suite.api.On("DetachMetadataKeyFromObject", fsID, treeqOwnerKey(treeqID)).Return(nil)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.Nil(suite.T(), err, "empty object")
+	suite.api.AssertCalled(suite.T(), "DetachMetadataKeyFromObject", fsID, treeqOwnerKey(treeqID))
 }
 
 func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_DeleteTreeq_Error() {
@@ -410,7 +447,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_DeleteTreeq_Error()
 	suite.api.On("GetFilesytemTreeqCount", mock.Anything).Return(nil, expectedErr)
 
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -427,7 +464,7 @@ func (suite *FileSystemServiceSuite) Test_DeleteTreeqVolume_DeleteTreeq_errorToD
 	suite.api.On("DeleteTreeq", fsID, treeqID).Return(nil, nil)
 	suite.api.On("DeleteFileSystemComplete", fsID, treeqID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.DeleteTreeqVolume(fsID, treeqID)
+	err := service.DeleteTreeqVolume(context.Background(), fsID, treeqID)
 	assert.NotNil(suite.T(), err, "empty object")
 }
 
@@ -437,7 +474,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_GetFileSystemByID_er
 	expectedErr := errors.New("FILESYSTEM_ID_DOES_NOT_EXIST")
 	suite.api.On("GetFileSystemByID", filesytemID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	// assert.Nil(suite.T(), err, "empty object")
 	assert.Equal(suite.T(), expectedErr, err, "Unexpected error")
 }
@@ -453,7 +490,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_GetTreeqSizeByFileSy
 	suite.api.On("GetTreeq", filesytemID, treeqID).Return(*expectedResponse, nil)
 	suite.api.On("GetTreeqSizeByFileSystemID", filesytemID).Return(0, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	assert.Equal(suite.T(), expectedErr, err, "Unexpected error")
 }
 
@@ -463,11 +500,11 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_GetTreeq_Not_found_e
 	expectedFileSystemResponse := api.FileSystem{}
 	expectedResponse := getTreeQResponse(filesytemID)
 	expectedResponse.UsedCapacity = 0
-	expectedErr := errors.New("TREEQ_ID_DOES_NOT_EXIST")
+	expectedErr := &api.APIError{Code: api.ErrTreeqIDDoesNotExist}
 	suite.api.On("GetFileSystemByID", filesytemID).Return(expectedFileSystemResponse, nil)
 	suite.api.On("GetTreeq", filesytemID, treeqID).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	assert.Nil(suite.T(), err, "Response not returned as expected")
 }
 
@@ -483,7 +520,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_UpdateFilesystem_err
 	suite.api.On("GetTreeqSizeByFileSystemID", filesytemID).Return(treeqSize, nil)
 	suite.api.On("UpdateFilesystem", filesytemID, mock.Anything).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	assert.Equal(suite.T(), expectedErr, err, "Response not returned as expected")
 }
 
@@ -501,7 +538,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_UpdateTreeq_error()
 	suite.api.On("UpdateFilesystem", filesytemID, mock.Anything).Return(expectedFileSystemResponse, nil)
 	suite.api.On("UpdateTreeq", filesytemID, treeqID, body).Return(nil, expectedErr)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	assert.Equal(suite.T(), expectedErr, err, "Response not returned as expected")
 }
 
@@ -518,7 +555,7 @@ func (suite *FileSystemServiceSuite) Test_UpdateTreeqVolume_Success() {
 	suite.api.On("UpdateFilesystem", filesytemID, mock.Anything).Return(expectedFileSystemResponse, nil)
 	suite.api.On("UpdateTreeq", filesytemID, treeqID, body).Return(expectedResponse, nil)
 	service := FilesystemService{cs: *suite.cs}
-	err := service.UpdateTreeqVolume(filesytemID, treeqID, capacity, maxSize)
+	err := service.UpdateTreeqVolume(context.Background(), filesytemID, treeqID, capacity, maxSize)
 	assert.Nil(suite.T(), err, "empty object")
 }
 
@@ -532,7 +569,7 @@ func (suite *FileSystemServiceSuite) Test_IsTreeqAlreadyExist_Error() {
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, expectedErr)
 
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.IsTreeqAlreadyExist("pool_name", "network_space", "pVName")
+	_, err := service.IsTreeqAlreadyExist(context.Background(), "pool_name", "network_space", "pVName")
 	assert.NotNil(suite.T(), err, "err should not be nil")
 }
 
@@ -546,7 +583,7 @@ func (suite *FileSystemServiceSuite) Test_IsTreeqAlreadyExist_StoragePoolIDByNam
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(0, expectedErr)
 
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.IsTreeqAlreadyExist("pool_name", "network_space", "pVName")
+	_, err := service.IsTreeqAlreadyExist(context.Background(), "pool_name", "network_space", "pVName")
 	assert.NotNil(suite.T(), err, "err should not be nil")
 }
 
@@ -557,10 +594,10 @@ func (suite *FileSystemServiceSuite) Test_IsTreeqAlreadyExist_FileSystemsByPoolI
 	expectedErr := errors.New("some error")
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(nil, expectedErr)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(nil, expectedErr)
 
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.IsTreeqAlreadyExist("pool_name", "network_space", "pVName")
+	_, err := service.IsTreeqAlreadyExist(context.Background(), "pool_name", "network_space", "pVName")
 	assert.NotNil(suite.T(), err, "err should not be nil")
 }
 
@@ -572,7 +609,7 @@ func (suite *FileSystemServiceSuite) Test_IsTreeqAlreadyExist_GetExportByFileSys
 	// expectedErr := errors.New("some error")
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
 
-	suite.api.On("GetFileSystemsByPoolID", poolID, 1).Return(*fsMetada, nil)
+	suite.api.On("GetFileSystemsByPoolID", poolID).Return(fsMetada, nil)
 	suite.api.On("GetTreeqByName", mock.Anything, mock.Anything).Return(getTreeQResponse(fsID), nil)
 
 	exportResp := getExportResponse()
@@ -580,12 +617,60 @@ func (suite *FileSystemServiceSuite) Test_IsTreeqAlreadyExist_GetExportByFileSys
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
 
 	service := FilesystemService{cs: *suite.cs}
-	_, err := service.IsTreeqAlreadyExist("pool_name", "network_space", "pVName")
+	_, err := service.IsTreeqAlreadyExist(context.Background(), "pool_name", "network_space", "pVName")
 	assert.Nil(suite.T(), err, "err should not be nil")
 }
 
 //*****Test case Data Generation
 
+func (suite *FileSystemServiceSuite) Test_ListTreeqVolumes_Success() {
+	treeqFileSystems := []api.Metadata{{ObjectId: 100, Key: TREEQCOUNT, Value: "2"}}
+	treeqs := []api.Treeq{{ID: 1, FilesystemID: 100}, {ID: 2, FilesystemID: 100}}
+	suite.api.On("GetMetadataByKey", TREEQCOUNT).Return(treeqFileSystems, nil)
+	suite.api.On("GetTreeqsByFileSystemID", int64(100)).Return(treeqs, nil)
+
+	service := FilesystemService{cs: *suite.cs}
+	treeqs, err := service.ListTreeqVolumes(context.Background())
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), 2, len(treeqs), "treeqs of the filesystem expected")
+}
+
+func (suite *FileSystemServiceSuite) Test_ListTreeqVolumes_Error() {
+	treeqFileSystems := []api.Metadata{{ObjectId: 100, Key: TREEQCOUNT, Value: "2"}}
+	suite.api.On("GetMetadataByKey", TREEQCOUNT).Return(treeqFileSystems, nil)
+	suite.api.On("GetTreeqsByFileSystemID", int64(100)).Return(nil, errors.New("some error"))
+
+	service := FilesystemService{cs: *suite.cs}
+	_, err := service.ListTreeqVolumes(context.Background())
+	assert.NotNil(suite.T(), err, "err should not be nil")
+}
+
+func (suite *FileSystemServiceSuite) Test_GetTreeqVolume_Success() {
+	defer stubCSINodeIDs("node1$$192.168.1.10")()
+	exports := []api.ExportResponse{{Permissions: []api.Permissions{
+		{Client: "192.168.1.10", Access: "RW"},
+		{Client: "192.168.147.190-192.168.147.199", Access: "RW"},
+	}}}
+	suite.api.On("GetTreeq", int64(100), int64(200)).Return(api.Treeq{ID: 200, HardCapacity: 1073741824}, nil)
+	suite.api.On("GetExportByFileSystem", int64(100)).Return(exports, nil)
+
+	service := FilesystemService{cs: *suite.cs}
+	resp, err := service.GetTreeqVolume(context.Background(), 100, 200)
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.Equal(suite.T(), "100#200", resp.Volume.VolumeId)
+	assert.Equal(suite.T(), []string{"node1$$192.168.1.10"}, resp.Status.PublishedNodeIds, "expected address ranges to be left out")
+	assert.False(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *FileSystemServiceSuite) Test_GetTreeqVolume_NotFound() {
+	suite.api.On("GetTreeq", int64(100), int64(200)).Return(nil, &api.APIError{Code: api.ErrTreeqIDDoesNotExist})
+
+	service := FilesystemService{cs: *suite.cs}
+	resp, err := service.GetTreeqVolume(context.Background(), 100, 200)
+	assert.Nil(suite.T(), err, "err should be nil")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
 func getExportResponse() *[]api.ExportResponse {
 	exportRespArry := []api.ExportResponse{}
 
@@ -625,42 +710,12 @@ func getCreateVolumeRequest() *csi.CreateVolumeRequest {
 	return &req
 }
 
-func getfsMetadata() *api.FSMetadata {
-	var fsMetadata api.FSMetadata
-	fs := api.FileSystem{}
-	fs.ID = 10
-	fs.Size = 1073741824
-	fsArry := []api.FileSystem{}
-	fsArry = append(fsArry, fs)
-	fsMeta := api.FileSystemMetaData{}
-	fsMeta.NumberOfObjects = 1
-	fsMeta.Page = 1
-	fsMeta.PageSize = 50
-	fsMeta.PagesTotal = 2
-	fsMeta.Ready = true
-
-	fsMetadata.Filemetadata = fsMeta
-	fsMetadata.FileSystemArry = fsArry
-	return &fsMetadata
-}
-
-func getfsMetadata2() *api.FSMetadata {
-	var fsMetadata api.FSMetadata
-	fs := api.FileSystem{}
-	fs.ID = 11
-	fs.Size = 10000
-	fsArry := []api.FileSystem{}
-	fsArry = append(fsArry, fs)
-	fsMeta := api.FileSystemMetaData{}
-	fsMeta.NumberOfObjects = 1
-	fsMeta.Page = 2
-	fsMeta.PageSize = 50
-	fsMeta.PagesTotal = 2
-	fsMeta.Ready = true
-
-	fsMetadata.Filemetadata = fsMeta
-	fsMetadata.FileSystemArry = fsArry
-	return &fsMetadata
+func getfsMetadata() []api.FileSystem {
+	return []api.FileSystem{{ID: 10, Size: 1073741824}}
+}
+
+func getfsMetadata2() []api.FileSystem {
+	return []api.FileSystem{{ID: 11, Size: 10000}}
 }
 
 func getCreateTreeqVolumeParameter() map[string]string {
@@ -24,7 +24,6 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (iscsi *iscsistorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
@@ -71,19 +70,35 @@ func (iscsi *iscsistorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 	// Volume name to be created - already verified earlier
 	name := req.GetName()
 
+	// Name of the volume on the array, rendered from the optional volume_name_template parameter
+	volName, err := getVolumeName(params, name)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Pool name - already verified earlier
 	poolName := params["pool_name"]
 
-	targetVol, err := iscsi.cs.api.GetVolumeByName(name)
+	targetVol, err := iscsi.cs.getVolumeOfPV(ctx, name, volName, "iscsi", params)
 	if err != nil {
-		if !strings.Contains(err.Error(), "volume with given name not found") {
-			return nil, status.Errorf(codes.NotFound, "CreateVolume failed: %v", err)
-		}
+		return nil, err
 	}
 	if targetVol != nil {
 		klog.V(2).Infof("volume: %s found, size: %d requested: %d", name, targetVol.Size, sizeBytes)
 		if targetVol.Size == sizeBytes {
-			existingVolumeInfo := iscsi.cs.getCSIResponse(targetVol, req)
+			if err = iscsi.setTargetParametersOf(ctx, params, int64(targetVol.ID)); err != nil {
+				return nil, err
+			}
+			// reconcile the QoS policy, the storage class may have changed since the volume was created
+			if err = iscsi.cs.setQosPolicy(ctx, params, api.QosPolicyTypeVolume, int64(targetVol.ID), targetVol.QosPolicyID); err != nil {
+				klog.Errorf("failed to set QoS policy of volume %s, %v", name, err)
+				return nil, err
+			}
+			if err = iscsi.cs.createReplica(ctx, params, int64(targetVol.ID)); err != nil {
+				klog.Errorf("failed to replicate volume %s, %v", name, err)
+				return nil, err
+			}
+			existingVolumeInfo := iscsi.cs.getCSIResponse(ctx, targetVol, req)
 			copyRequestParameters(params, existingVolumeInfo.VolumeContext)
 			return &csi.CreateVolumeResponse{
 				Volume: existingVolumeInfo,
@@ -94,36 +109,29 @@ func (iscsi *iscsistorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		return nil, err
 	}
 
-	networkSpace := params["network_space"]
-	nspace, err := iscsi.cs.api.GetNetworkSpaceByName(networkSpace)
-	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "Error getting network space %s", networkSpace)
-	}
-	portals := ""
-	for _, p := range nspace.Portals {
-		portals = portals + "," + p.IpAdress
+	if err = iscsi.setTargetParameters(ctx, params); err != nil {
+		return nil, err
 	}
-	portals = portals[1:]
-	params["iqn"] = nspace.Properties.IscsiIqn
-	params["portals"] = portals
 
 	// Volume content source support volume and snapshots
 	contentSource := req.GetVolumeContentSource()
 	if contentSource != nil {
-		return iscsi.createVolumeFromContentSource(req, name, sizeBytes, poolName)
+		return iscsi.createVolumeFromContentSource(ctx, req, volName, sizeBytes, poolName)
 	}
+	compressionEnabled, _ := getCompressionEnabled(params) // already validated
 	volumeParam := &api.VolumeParam{
-		Name:          name,
-		VolumeSize:    sizeBytes,
-		ProvisionType: volType,
-		SsdEnabled:    ssdEnabled,
+		Name:               volName,
+		VolumeSize:         sizeBytes,
+		ProvisionType:      volType,
+		SsdEnabled:         ssdEnabled,
+		CompressionEnabled: compressionEnabled,
 	}
-	volumeResp, err := iscsi.cs.api.CreateVolume(volumeParam, poolName)
+	volumeResp, err := iscsi.cs.api.CreateVolume(ctx, volumeParam, poolName)
 	if err != nil {
-		klog.Errorf("error creating volume: %s pool %s error: %s", name, poolName, err.Error())
-		return nil, status.Errorf(codes.Internal, "error when creating volume %s storagepool %s: %s", name, poolName, err.Error())
+		klog.Errorf("error creating volume: %s pool %s error: %s", volName, poolName, err.Error())
+		return nil, status.Errorf(api.GRPCCode(err), "error when creating volume %s storagepool %s: %s", name, poolName, err.Error())
 	}
-	vi := iscsi.cs.getCSIResponse(volumeResp, req)
+	vi := iscsi.cs.getCSIResponse(ctx, volumeResp, req)
 
 	// check volume id format
 	volID, err := strconv.Atoi(vi.VolumeId)
@@ -134,10 +142,10 @@ func (iscsi *iscsistorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 	// confirm volume creation
 	var vol *api.Volume
 	var counter int
-	vol, err = iscsi.cs.api.GetVolume(volID)
+	vol, err = iscsi.cs.api.GetVolume(ctx, volID)
 	for vol == nil && counter < 100 {
 		time.Sleep(3 * time.Millisecond)
-		vol, err = iscsi.cs.api.GetVolume(volID)
+		vol, err = iscsi.cs.api.GetVolume(ctx, volID)
 		counter = counter + 1
 	}
 	if vol == nil {
@@ -151,20 +159,81 @@ func (iscsi *iscsistorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 	}
 
 	// attach metadata to volume object
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = vol.Name
+	metadata := iscsi.cs.getVolumeMetadata(name, "iscsi", params)
+	metadata[NETWORKSPACE] = params["network_space"]
 	// metadata["host.filesystem_type"] = params["fstype"] // TODO: set this correctly according to what iscsinode.go does, not the fstype parameter originally captured in this function ... which is likely overwritten by the VolumeCapability
-	_, err = iscsi.cs.api.AttachMetadataToObject(int64(vol.ID), metadata)
+	_, err = iscsi.cs.api.AttachMetadataToObject(ctx, int64(vol.ID), metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for volume : %s, err: %v", name, err)
 		return nil, status.Errorf(codes.Internal, "failed to attach metadata")
 	}
+	if err = iscsi.cs.setQosPolicy(ctx, params, api.QosPolicyTypeVolume, int64(vol.ID), vol.QosPolicyID); err != nil {
+		klog.Errorf("failed to set QoS policy of volume %s, %v", name, err)
+		return nil, err
+	}
+	if err = iscsi.cs.createReplica(ctx, params, int64(vol.ID)); err != nil {
+		klog.Errorf("failed to replicate volume %s, %v", name, err)
+		return nil, err
+	}
 
 	klog.V(2).Infof("CreateVolume resp: %v", *csiResp)
 	klog.Infof("Successfully created volume with name %s and ID %d", name, volID)
 	return csiResp, err
 }
 
+// setTargetParameters adds the iqn and portals of the network space chosen among the network_space
+// parameter to the parameters, the nodes log in to them when staging the volume
+func (iscsi *iscsistorage) setTargetParameters(ctx context.Context, params map[string]string) error {
+	networkSpace, nspace, err := iscsi.cs.selectNetworkSpace(ctx, params)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return err
+		}
+		return status.Errorf(codes.InvalidArgument, "Error getting network space %s", networkSpace)
+	}
+	setTargetPortals(params, networkSpace, nspace)
+	return nil
+}
+
+// setTargetParametersOf adds the iqn and portals of the network space recorded in the metadata of an
+// existing volume. Volumes created without it, by older driver versions or adopted by name, get a network
+// space chosen and recorded
+func (iscsi *iscsistorage) setTargetParametersOf(ctx context.Context, params map[string]string, volumeID int64) error {
+	networkSpace, err := iscsi.cs.getMetadataValue(ctx, volumeID, NETWORKSPACE)
+	if err != nil {
+		return err
+	}
+	if networkSpace == "" {
+		if err = iscsi.setTargetParameters(ctx, params); err != nil {
+			return err
+		}
+		if _, err = iscsi.cs.api.AttachMetadataToObject(ctx, volumeID, map[string]interface{}{NETWORKSPACE: params["network_space"]}); err != nil {
+			klog.Errorf("failed to record network space of volume %d, %v", volumeID, err)
+			return status.Errorf(api.GRPCCode(err), "failed to record network space of volume %d: %v", volumeID, err)
+		}
+		return nil
+	}
+	nspace, err := iscsi.cs.api.GetNetworkSpaceByName(ctx, networkSpace)
+	if err != nil {
+		klog.Errorf("failed to get network space %s of volume %d, %v", networkSpace, volumeID, err)
+		return status.Errorf(api.GRPCCode(err), "failed to get network space %s of volume %d: %v", networkSpace, volumeID, err)
+	}
+	setTargetPortals(params, networkSpace, nspace)
+	return nil
+}
+
+// setTargetPortals adds the network space, its iqn and its portals to the CreateVolume parameters
+func setTargetPortals(params map[string]string, networkSpace string, nspace api.NetworkSpace) {
+	params["network_space"] = networkSpace
+	portals := ""
+	for _, p := range nspace.Portals {
+		portals = portals + "," + p.IpAdress
+	}
+	portals = portals[1:]
+	params["iqn"] = nspace.Properties.IscsiIqn
+	params["portals"] = portals
+}
+
 func (iscsi *iscsistorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (csiResp *csi.DeleteVolumeResponse, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -177,19 +246,19 @@ func (iscsi *iscsistorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 		return nil, status.Errorf(codes.InvalidArgument, "error parsing volume id: %s", err.Error())
 	}
 	klog.V(4).Infof("Deleting volume with ID %d", id)
-	err = iscsi.ValidateDeleteVolume(id)
+	err = iscsi.ValidateDeleteVolume(ctx, id)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return &csi.DeleteVolumeResponse{}, nil
 		} else {
-			return nil, status.Errorf(codes.Internal, "failed to delete volume: %s", err.Error())
+			return nil, status.Errorf(api.GRPCCode(err), "failed to delete volume: %s", err.Error())
 		}
 	}
 	klog.V(4).Infof("Successfully deleted volume with ID %d", id)
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
-func (iscsi *iscsistorage) createVolumeFromContentSource(req *csi.CreateVolumeRequest, name string, sizeInKbytes int64, storagePool string) (*csi.CreateVolumeResponse, error) {
+func (iscsi *iscsistorage) createVolumeFromContentSource(ctx context.Context, req *csi.CreateVolumeRequest, name string, sizeInKbytes int64, storagePool string) (*csi.CreateVolumeResponse, error) {
 	var err error
 	var msg string
 	defer func() {
@@ -223,32 +292,28 @@ func (iscsi *iscsistorage) createVolumeFromContentSource(req *csi.CreateVolumeRe
 		klog.Errorf("Cannot convert %s ID %s from string to int", restoreType, volproto.VolumeID)
 		return nil, status.Errorf(codes.InvalidArgument, restoreType+" invalid: %s", volumeContentID)
 	}
-	srcVol, err := iscsi.cs.api.GetVolume(ID)
+	srcVol, err := iscsi.cs.api.GetVolume(ctx, ID)
 	if err != nil {
 		klog.Errorf("Cannot find volume with ID %d", ID)
 		return nil, status.Errorf(codes.NotFound, restoreType+" not found: %d", ID)
 	}
 
-	// Validate the size is the same.
-	if int64(srcVol.Size) != sizeInKbytes {
-		return nil, status.Errorf(codes.InvalidArgument,
-			restoreType+" %s has incompatible size %d kbytes with requested %d kbytes",
-			volumeContentID, srcVol.Size, sizeInKbytes)
+	// The clone may be larger than its source, it is grown once created.
+	if err = validateCloneSize(volumeContentID, srcVol.Size, sizeInKbytes); err != nil {
+		return nil, err
 	}
 
 	params := req.GetParameters()
 
-	// Check the storagePool is the same.
-	storagePoolID, err := iscsi.cs.api.GetStoragePoolIDByName(storagePool)
+	// Check the storagePool is the same, the clone is a snapshot in the pool of its source.
+	storagePoolID, err := iscsi.cs.api.GetStoragePoolIDByName(ctx, storagePool)
 	if err != nil {
 		msg = fmt.Sprintf("error while getting storagepoolid with name %s ", storagePool)
 		klog.Errorf(msg)
 		return nil, status.Errorf(codes.Internal, msg)
 	}
-	if storagePoolID != srcVol.PoolId {
-		msg = fmt.Sprintf("volume storage pool is different than the requested storage pool %s", storagePool)
-		klog.Errorf(msg)
-		return nil, status.Errorf(codes.InvalidArgument, msg)
+	if err = validateClonePool(volumeContentID, srcVol.PoolId, storagePoolID, storagePool); err != nil {
+		return nil, err
 	}
 
 	// Parse ssd enabled flag
@@ -267,7 +332,7 @@ func (iscsi *iscsistorage) createVolumeFromContentSource(req *csi.CreateVolumeRe
 	}
 
 	// Create snapshot
-	snapResponse, err := iscsi.cs.api.CreateSnapshotVolume(snapshotParam)
+	snapResponse, err := iscsi.cs.api.CreateSnapshotVolume(ctx, snapshotParam)
 	if err != nil {
 		msg = fmt.Sprintf("Failed to create snapshot: %s", err.Error())
 		klog.Errorf(msg)
@@ -276,24 +341,36 @@ func (iscsi *iscsistorage) createVolumeFromContentSource(req *csi.CreateVolumeRe
 
 	// Retrieve created destination volume
 	volID := snapResponse.SnapShotID
-	dstVol, err := iscsi.cs.api.GetVolume(volID)
+	dstVol, err := iscsi.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		msg = fmt.Sprintf("Cannot retrieve created volume using ID %d", volID)
 		klog.Errorf(msg)
 		return nil, status.Errorf(codes.Internal, msg)
 	}
+	dstVol, err = iscsi.cs.expandVolumeClone(ctx, dstVol, sizeInKbytes)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a volume response and return it
-	csiVolume := iscsi.cs.getCSIResponse(dstVol, req)
+	csiVolume := iscsi.cs.getCSIResponse(ctx, dstVol, req)
 	copyRequestParameters(params, csiVolume.VolumeContext)
 
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = dstVol.Name
+	metadata := iscsi.cs.getVolumeMetadata(req.GetName(), "iscsi", req.GetParameters())
+	metadata[NETWORKSPACE] = params["network_space"]
 	// metadata["host.filesystem_type"] = params["fstype"] // TODO: set this correctly according to what iscsinode.go does, not the fstype parameter originally captured in this function ... which is likely overwritten by the VolumeCapability
-	_, err = iscsi.cs.api.AttachMetadataToObject(int64(dstVol.ID), metadata)
+	_, err = iscsi.cs.api.AttachMetadataToObject(ctx, int64(dstVol.ID), metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for volume : %s, err: %v", dstVol.Name, err)
-		return nil, status.Errorf(codes.Internal, "failed to attach metadata to volume: %s, err: %v", dstVol.Name, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to attach metadata to volume: %s, err: %v", dstVol.Name, err)
+	}
+	if err = iscsi.cs.setQosPolicy(ctx, req.GetParameters(), api.QosPolicyTypeVolume, int64(dstVol.ID), dstVol.QosPolicyID); err != nil {
+		klog.Errorf("failed to set QoS policy of volume %s, %v", dstVol.Name, err)
+		return nil, err
+	}
+	if err = iscsi.cs.createReplica(ctx, req.GetParameters(), int64(dstVol.ID)); err != nil {
+		klog.Errorf("failed to replicate volume %s, %v", dstVol.Name, err)
+		return nil, err
 	}
 
 	klog.V(2).Infof("From source %s with ID %d, created volume %s with ID %s in storage pool %s",
@@ -319,7 +396,7 @@ func (iscsi *iscsistorage) ControllerPublishVolume(ctx context.Context, req *csi
 	volID, _ := strconv.Atoi(volproto.VolumeID)
 
 	klog.V(4).Infof("volID: %d", volID)
-	v, err := iscsi.cs.api.GetVolume(volID)
+	v, err := iscsi.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		klog.Errorf("Failed to find volume by volume ID '%s': %v", req.GetVolumeId(), err)
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("Volume ID: %d not found, err:%s", volID, err))
@@ -332,7 +409,7 @@ func (iscsi *iscsistorage) ControllerPublishVolume(ctx context.Context, req *csi
 	// TODO: revisit this as part of CSIC-343
 	_, err = iscsi.cs.accessModesHelper.IsValidAccessMode(v, req)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
 	nodeID := req.GetNodeId()
@@ -345,7 +422,7 @@ func (iscsi *iscsistorage) ControllerPublishVolume(ctx context.Context, req *csi
 	}
 	hostName := nodeNameIP[0]
 
-	host, err := iscsi.cs.validateHost(hostName)
+	host, err := iscsi.cs.validateHost(ctx, hostName)
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +440,7 @@ func (iscsi *iscsistorage) ControllerPublishVolume(ctx context.Context, req *csi
 		ports = ports[1:]
 	}
 
-	lunList, err := iscsi.cs.api.GetAllLunByHost(host.ID)
+	lunList, err := iscsi.cs.api.GetAllLunByHost(ctx, host.ID)
 	if err != nil {
 		klog.Errorf("failed to GetAllLunByHost() for host: %s, error: %v", hostName, err)
 		return nil, err
@@ -399,10 +476,10 @@ func (iscsi *iscsistorage) ControllerPublishVolume(ctx context.Context, req *csi
 
 	// map volume to host
 	klog.V(4).Infof("Mapping volume %d to host %s", volID, host.Name)
-	luninfo, err := iscsi.cs.mapVolumeTohost(volID, host.ID)
+	luninfo, err := iscsi.cs.mapVolumeTohost(ctx, volID, host.ID)
 	if err != nil {
 		klog.Errorf("Failed to map volume to host with error %v", err)
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
 	publishVolCtxt := make(map[string]string)
@@ -434,35 +511,35 @@ func (iscsi *iscsistorage) ControllerUnpublishVolume(ctx context.Context, req *c
 		return nil, status.Error(codes.NotFound, msg)
 	}
 	hostName := nodeNameIP[0]
-	host, err := iscsi.cs.api.GetHostByName(hostName)
+	host, err := iscsi.cs.api.GetHostByName(ctx, hostName)
 	if err != nil {
-		if strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+		if api.HasErrorCode(err, api.ErrHostNotFound) {
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 		msg = fmt.Sprintf("Failed to get host: %s, err: %v", hostName, err)
 		klog.Errorf(msg)
-		return nil, status.Error(codes.NotFound, msg)
+		return nil, status.Error(api.GRPCCode(err), msg)
 	}
 	klog.V(4).Infof("Unmapping host's luns: Host id: %d, Name: %s, LUNs: %v", host.ID, host.Name, host.Luns)
 	if len(host.Luns) > 0 {
 		volID, _ := strconv.Atoi(volproto.VolumeID)
 		klog.V(4).Infof("Unmap volume %d from host %d", volID, host.ID)
-		err = iscsi.cs.unmapVolumeFromHost(host.ID, volID)
+		err = iscsi.cs.unmapVolumeFromHost(ctx, host.ID, volID)
 		if err != nil {
 			klog.Errorf("Failed to unmap volume with ID %d from host with ID %d. Error: %v", volID, host.ID, err)
-			return nil, status.Error(codes.Internal, err.Error())
+			return nil, status.Error(api.GRPCCode(err), err.Error())
 		}
 	}
 	if len(host.Luns) < 2 {
-		luns, err := iscsi.cs.api.GetAllLunByHost(host.ID)
+		luns, err := iscsi.cs.api.GetAllLunByHost(ctx, host.ID)
 		if err != nil {
 			klog.Errorf("Failed to get LUNs for host with ID %d. Error: %v", host.ID, err)
 		}
 		if len(luns) == 0 {
-			err = iscsi.cs.api.DeleteHost(host.ID)
-			if err != nil && !strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+			err = iscsi.cs.api.DeleteHost(ctx, host.ID)
+			if err != nil && !api.HasErrorCode(err, api.ErrHostNotFound) {
 				klog.Errorf("Failed to delete host with ID %d. Error: %v", host.ID, err)
-				return nil, status.Error(codes.Internal, err.Error())
+				return nil, status.Error(api.GRPCCode(err), err.Error())
 			}
 		}
 	}
@@ -481,7 +558,7 @@ func (iscsi *iscsistorage) ValidateVolumeCapabilities(ctx context.Context, req *
 	volID, _ := strconv.Atoi(volproto.VolumeID)
 
 	klog.V(4).Infof("volID: %d", volID)
-	v, err := iscsi.cs.api.GetVolume(volID)
+	v, err := iscsi.cs.api.GetVolume(ctx, volID)
 	if err != nil {
 		klog.Errorf("Failed to find volume with ID %d. Error: %v", volID, err)
 		err = status.Errorf(codes.NotFound, "ValidateVolumeCapabilities failed to find volume ID: %d, %v", volID, err)
@@ -502,16 +579,38 @@ func (iscsi *iscsistorage) ValidateVolumeCapabilities(ctx context.Context, req *
 	return
 }
 
+// ListVolumes returns all iscsi volumes, paging across protocols is done by the caller
 func (iscsi *iscsistorage) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (resp *csi.ListVolumesResponse, err error) {
-	return &csi.ListVolumesResponse{}, nil
+	entries, err := iscsi.cs.listVolumeEntries(ctx, "iscsi")
+	if err != nil {
+		klog.Errorf("failed to list iscsi volumes: %v", err)
+		return nil, err
+	}
+	return &csi.ListVolumesResponse{Entries: entries}, nil
 }
 
+// ListSnapshots returns the iscsi snapshots matching the request filters, paging across protocols is done by the caller
 func (iscsi *iscsistorage) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (resp *csi.ListSnapshotsResponse, err error) {
-	return &csi.ListSnapshotsResponse{}, nil
+	entries, err := iscsi.cs.listSnapshotEntries(ctx, "iscsi", req)
+	if err != nil {
+		klog.Errorf("failed to list iscsi snapshots: %v", err)
+		return nil, err
+	}
+	return &csi.ListSnapshotsResponse{Entries: entries}, nil
 }
 
+// GetCapacity returns the free capacity of the StorageClass pool
 func (iscsi *iscsistorage) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (resp *csi.GetCapacityResponse, err error) {
-	return &csi.GetCapacityResponse{}, nil
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from iSCSI GetCapacity  " + fmt.Sprint(res))
+		}
+	}()
+	capacity, err := iscsi.cs.getPoolCapacity(ctx, req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetCapacityResponse{AvailableCapacity: capacity}, nil
 }
 
 func (iscsi *iscsistorage) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (resp *csi.ControllerGetCapabilitiesResponse, err error) {
@@ -534,7 +633,7 @@ func (iscsi *iscsistorage) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 	}
 
 	sourceVolumeID, _ := strconv.Atoi(volproto.VolumeID)
-	volumeSnapshot, err := iscsi.cs.api.GetVolumeByName(snapshotName)
+	volumeSnapshot, err := iscsi.cs.api.GetVolumeByName(ctx, snapshotName)
 	if err != nil {
 		klog.V(4).Infof("Snapshot with name %s not found", snapshotName)
 	} else if volumeSnapshot.ParentId == sourceVolumeID {
@@ -544,7 +643,7 @@ func (iscsi *iscsistorage) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 				SizeBytes:      volumeSnapshot.Size,
 				SnapshotId:     snapshotID,
 				SourceVolumeId: req.GetSourceVolumeId(),
-				CreationTime:   timestamppb.Now(),
+				CreationTime:   getTimestamp(volumeSnapshot.CreatedAt),
 				ReadyToUse:     true,
 			},
 		}, nil
@@ -554,24 +653,36 @@ func (iscsi *iscsistorage) CreateSnapshot(ctx context.Context, req *csi.CreateSn
 		return nil, status.Error(codes.AlreadyExists, "snapshot with already existing name and different source volume ID")
 	}
 
+	lockExpiresAt, err := iscsi.cs.getSnapshotLockExpiry(ctx, int64(sourceVolumeID))
+	if err != nil {
+		klog.Errorf("failed to get snapshot lock of volume %d, %v", sourceVolumeID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshot lock of volume %d: %v", sourceVolumeID, err)
+	}
 	snapshotParam := &api.VolumeSnapshot{
 		ParentID:       sourceVolumeID,
 		SnapshotName:   snapshotName,
 		WriteProtected: true,
+		LockExpiresAt:  lockExpiresAt,
 	}
 
-	snapshot, err := iscsi.cs.api.CreateSnapshotVolume(snapshotParam)
+	snapshot, err := iscsi.cs.api.CreateSnapshotVolume(ctx, snapshotParam)
 	if err != nil {
 		klog.Errorf("Failed to create snapshot %s error %v", snapshotName, err)
 		return
 	}
 
+	// map the snapshot to its VolumeSnapshot, a failure leaves the snapshot usable
+	metadata := iscsi.cs.getSnapshotMetadata(volproto.StorageType, req.GetParameters())
+	if _, metadataErr := iscsi.cs.api.AttachMetadataToObject(ctx, int64(snapshot.SnapShotID), metadata); metadataErr != nil {
+		klog.Errorf("failed to attach metadata to snapshot %s, %v", snapshotName, metadataErr)
+	}
+
 	snapshotID = strconv.Itoa(snapshot.SnapShotID) + "$$" + volproto.StorageType
 	csiSnapshot := &csi.Snapshot{
 		SnapshotId:     snapshotID,
 		SourceVolumeId: req.GetSourceVolumeId(),
 		ReadyToUse:     true,
-		CreationTime:   timestamppb.Now(),
+		CreationTime:   getTimestamp(snapshot.CreatedAt),
 		SizeBytes:      snapshot.Size,
 	}
 	klog.V(4).Infof("CreateFileSystemSnapshot resp: %v", csiSnapshot)
@@ -590,21 +701,21 @@ func (iscsi *iscsistorage) DeleteSnapshot(ctx context.Context, req *csi.DeleteSn
 
 	snapshotID, _ := strconv.Atoi(req.GetSnapshotId())
 	klog.V(4).Infof("Called DeleteSnapshot to delete snapshot with ID %d", snapshotID)
-	err = iscsi.ValidateDeleteVolume(snapshotID)
+	err = iscsi.ValidateDeleteVolume(ctx, snapshotID)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			klog.V(4).Infof("Snapshot with ID %d not found", snapshotID)
 			return &csi.DeleteSnapshotResponse{}, nil
 		} else {
 			klog.V(4).Infof("Failed to delete snapshot with ID %d", snapshotID)
-			return nil, status.Errorf(codes.Internal, "failed to delete snapshot: %s", err.Error())
+			return nil, status.Errorf(api.GRPCCode(err), "failed to delete snapshot: %s", err.Error())
 		}
 	}
 	klog.V(4).Infof("DeleteSnapshot successfully deleted snapshot with ID %d", snapshotID)
 	return &csi.DeleteSnapshotResponse{}, nil
 }
 
-func (iscsi *iscsistorage) ValidateDeleteVolume(volumeID int) (err error) {
+func (iscsi *iscsistorage) ValidateDeleteVolume(ctx context.Context, volumeID int) (err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
 			err = errors.New("Recovered from ISCSI DeleteSnapshot  " + fmt.Sprint(res))
@@ -613,9 +724,9 @@ func (iscsi *iscsistorage) ValidateDeleteVolume(volumeID int) (err error) {
 
 	klog.V(4).Infof("----- ValidateDeleteVolume called (also deletes volume) with ID %d", volumeID)
 
-	vol, err := iscsi.cs.api.GetVolume(volumeID)
+	vol, err := iscsi.cs.api.GetVolume(ctx, volumeID)
 	if err != nil {
-		if strings.Contains(err.Error(), "VOLUME_NOT_FOUND") {
+		if api.HasErrorCode(err, api.ErrVolumeNotFound) {
 			klog.V(4).Infof("volume: %d is already deleted", volumeID)
 			return status.Errorf(codes.NotFound, "volume not found")
 		}
@@ -623,11 +734,11 @@ func (iscsi *iscsistorage) ValidateDeleteVolume(volumeID int) (err error) {
 		klog.Errorf(msg)
 		return status.Errorf(codes.Internal, msg)
 	}
-	childVolumes, err := iscsi.cs.api.GetVolumeSnapshotByParentID(vol.ID)
+	childVolumes, err := iscsi.cs.api.GetVolumeSnapshotByParentID(ctx, vol.ID)
 	if len(*childVolumes) > 0 {
 		metadata := make(map[string]interface{})
 		metadata[TOBEDELETED] = true
-		_, err = iscsi.cs.api.AttachMetadataToObject(int64(vol.ID), metadata)
+		_, err = iscsi.cs.api.AttachMetadataToObject(ctx, int64(vol.ID), metadata)
 		if err != nil {
 			klog.Errorf("failed to update host.k8s.to_be_deleted for volume %s error: %v", vol.Name, err)
 			err = errors.New("error while Set metadata host.k8s.to_be_deleted")
@@ -635,8 +746,15 @@ func (iscsi *iscsistorage) ValidateDeleteVolume(volumeID int) (err error) {
 		klog.V(4).Infof("ValidateDeleteVolume found volume with ID %d has children volumes. Set metadata TOBEDELETED to 'true'. Deferring deletion.", volumeID)
 		return
 	}
+	if vol.RmrSource {
+		if err = iscsi.cs.deleteReplica(ctx, int64(vol.ID)); err != nil {
+			msg := fmt.Sprintf("Error deleting replica of volume named %s with ID %d: %s", vol.Name, vol.ID, err.Error())
+			klog.Errorf(msg)
+			return status.Errorf(api.GRPCCode(err), msg)
+		}
+	}
 	klog.V(2).Infof("Deleting volume named %s with ID %d", vol.Name, vol.ID)
-	if err = iscsi.cs.api.DeleteVolume(vol.ID); err != nil {
+	if err = iscsi.cs.api.DeleteVolume(ctx, vol.ID); err != nil {
 		msg := fmt.Sprintf("Error deleting volume named %s with ID %d: %s", vol.Name, vol.ID, err.Error())
 		klog.Errorf(msg)
 		return status.Errorf(codes.Internal, msg)
@@ -645,11 +763,11 @@ func (iscsi *iscsistorage) ValidateDeleteVolume(volumeID int) (err error) {
 
 	if vol.ParentId != 0 {
 		klog.V(2).Infof("Checking if parent volume with ID %d of volume named %s, with ID %d, can be deleted", vol.ParentId, vol.Name, vol.ID)
-		tobedel := iscsi.cs.api.GetMetadataStatus(int64(vol.ParentId))
+		tobedel := iscsi.cs.api.GetMetadataStatus(ctx, int64(vol.ParentId))
 		if tobedel {
 			klog.V(4).Infof("ValidateDeleteVolume recursively called for parent. Volume ID: %d. Parent volume ID: %d", vol.ID, vol.ParentId)
 			// Recursion
-			err = iscsi.ValidateDeleteVolume(vol.ParentId)
+			err = iscsi.ValidateDeleteVolume(ctx, vol.ParentId)
 			if err != nil {
 				return
 			}
@@ -680,14 +798,15 @@ func (iscsi *iscsistorage) ControllerExpandVolume(ctx context.Context, req *csi.
 	// Expand volume size
 	var volume api.Volume
 	volume.Size = capacity
-	_, err = iscsi.cs.api.UpdateVolume(volumeID, volume)
+	_, err = iscsi.cs.api.UpdateVolume(ctx, volumeID, volume)
 	if err != nil {
 		klog.Errorf("Failed to update file system %v", err)
 		return
 	}
 	klog.V(2).Infof("Volume with ID %d size updated successfully", volumeID)
+	// mounted filesystems are grown by NodeExpandVolume, raw block consumers see the new LUN size
 	return &csi.ControllerExpandVolumeResponse{
 		CapacityBytes:         capacity,
-		NodeExpansionRequired: false,
+		NodeExpansionRequired: req.GetVolumeCapability().GetBlock() == nil,
 	}, nil
 }
@@ -6,12 +6,20 @@ import (
 	"infinibox-csi-driver/api"
 	"infinibox-csi-driver/helper"
 	tests "infinibox-csi-driver/test_helper"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (suite *ISCSIControllerSuite) SetupTest() {
@@ -56,9 +64,9 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_GetName_fail() {
 	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), expectedErr)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(getVolume(), expectedErr)
 	_, err := service.CreateVolume(context.Background(), createVolReq)
-	assert.NotNil(suite.T(), err, "expected to fail: iscsi CreateVolume GetVolumeByName")
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi CreateVolume GetVolumeByPVName")
 }
 
 func (suite *ISCSIControllerSuite) Test_CreateVolume_fail() {
@@ -67,7 +75,7 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_fail() {
 	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(nil, expectedErr)
@@ -81,7 +89,7 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_success() {
 	parameterMap := getISCSICreateVolumeParameters()
 	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
@@ -93,13 +101,310 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_success() {
 	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume")
 }
 
+func (suite *ISCSIControllerSuite) Test_CreateVolume_volumeNameTemplate() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[VOLUMENAMETEMPLATE] = "k8s-{{.PVCNamespace}}-{{.PVCName}}"
+	parameterMap[pvcNamespaceKey] = "default"
+	parameterMap[pvcNameKey] = "data/db"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.MatchedBy(func(volume *api.VolumeParam) bool {
+		return volume.Name == "k8s-default-data_db"
+	}), mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		return metadata[PVNAME] == "pvname"
+	})).Return(nil, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with volume_name_template")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_kubernetesMetadata() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[pvcNamespaceKey] = "default"
+	parameterMap[pvcNameKey] = "data"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		_, created := metadata[CREATEDAT]
+		return metadata[PVNAME] == "pvname" && metadata[PVCNAME] == "data" &&
+			metadata[PVCNAMESPACE] == "default" && metadata[CREATEDBY] == suite.cs.GetCreatedBy() && created
+	})).Return(nil, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with PVC metadata")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_volumeNameTemplate_noPVCMetadata() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[VOLUMENAMETEMPLATE] = "k8s-{{.PVCName}}"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail without --extra-create-metadata")
+}
+
+func (suite *ISCSIControllerSuite) Test_getVolumeName() {
+	name, err := getVolumeName(map[string]string{}, "pvc-1")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "pvc-1", name, "PV name expected without template")
+
+	name, err = getVolumeName(map[string]string{VOLUMENAMETEMPLATE: "ibox-{{.PVName}}"}, "pvc-1")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "ibox-pvc-1", name)
+
+	_, err = getVolumeName(map[string]string{VOLUMENAMETEMPLATE: "{{.PVName"}, "pvc-1")
+	assert.NotNil(suite.T(), err, "invalid template should fail")
+
+	_, err = getVolumeName(map[string]string{VOLUMENAMETEMPLATE: strings.Repeat("x", maxObjectNameLength) + "{{.PVName}}"}, "pvc-1")
+	assert.NotNil(suite.T(), err, "too long name should fail")
+}
+
+func (suite *ISCSIControllerSuite) Test_createVolumeFromContentSource_otherPool() {
+	service := iscsistorage{cs: *suite.cs}
+	req := &csi.CreateVolumeRequest{
+		Name: "PVName",
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Volume{Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: "100$$iscsi"}},
+		},
+	}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetStoragePoolIDByName", "pool_name2").Return(getVolume().PoolId+1, nil)
+
+	_, err := service.createVolumeFromContentSource(context.Background(), req, "PVName", getVolume().Size, "pool_name2")
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: clone in another pool")
+	assert.Contains(suite.T(), err.Error(), "another pool is not supported")
+	suite.api.AssertNotCalled(suite.T(), "CreateSnapshotVolume", mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_qosPolicy() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[QOSPOLICY] = "gold"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+	suite.api.On("GetQosPolicyByName", "gold").Return(api.QosPolicy{ID: 20, Name: "gold", Type: api.QosPolicyTypeVolume}, nil)
+	suite.api.On("AssignQosPolicy", int64(20), int64(100)).Return(nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with qos_policy")
+	suite.api.AssertCalled(suite.T(), "AssignQosPolicy", int64(20), int64(100))
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_qosPolicyWrongType() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[QOSPOLICY] = "gold"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+	suite.api.On("GetQosPolicyByName", "gold").Return(api.QosPolicy{ID: 20, Name: "gold", Type: api.QosPolicyTypeFilesystem}, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: filesystem QoS policy")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_inlineQos() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[MAXIOPS] = "1000"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+	policyName := "csi-volume-iops1000-bps0"
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+	suite.api.On("GetQosPolicyByName", policyName).Return(nil, &api.APIError{Code: api.ErrQosPolicyNotFound})
+	suite.api.On("CreateQosPolicy", api.QosPolicy{Name: policyName, Type: api.QosPolicyTypeVolume, MaxOps: 1000}).
+		Return(api.QosPolicy{ID: 21, Name: policyName, Type: api.QosPolicyTypeVolume, MaxOps: 1000}, nil)
+	suite.api.On("AssignQosPolicy", int64(21), int64(100)).Return(nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with max_iops")
+	suite.api.AssertCalled(suite.T(), "AssignQosPolicy", int64(21), int64(100))
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_existing_reconcileQos() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[QOSPOLICY] = "gold"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+	volume := getVolume()
+	volume.QosPolicyID = 5
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(volume, nil)
+	suite.api.On("GetQosPolicyByName", "gold").Return(api.QosPolicy{ID: 20, Name: "gold", Type: api.QosPolicyTypeVolume}, nil)
+	suite.api.On("UnassignQosPolicy", int64(5), int64(100)).Return(nil)
+	suite.api.On("AssignQosPolicy", int64(20), int64(100)).Return(nil)
+	suite.api.On("GetMetadataValue", int64(100), NETWORKSPACE).Return("network_space1", nil)
+	suite.api.On("GetNetworkSpaceByName", "network_space1").Return(getNetworkspace(), nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume of existing volume")
+	suite.api.AssertCalled(suite.T(), "UnassignQosPolicy", int64(5), int64(100))
+	suite.api.AssertCalled(suite.T(), "AssignQosPolicy", int64(20), int64(100))
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_compressionEnabled() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[COMPRESSIONENABLED] = "false"
+	parameterMap[SNAPSHOTLOCKDURATION] = "30d"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.MatchedBy(func(volume *api.VolumeParam) bool {
+		return volume.CompressionEnabled != nil && !*volume.CompressionEnabled
+	}), mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		return metadata[SNAPSHOTLOCK] == "30d"
+	})).Return(nil, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with compression_enabled")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_invalidDatasetOptions() {
+	service := iscsistorage{cs: *suite.cs}
+	for param, value := range map[string]string{COMPRESSIONENABLED: "maybe", SNAPSHOTLOCKDURATION: "-1h"} {
+		parameterMap := getISCSICreateVolumeParameters()
+		parameterMap[param] = value
+		_, err := service.CreateVolume(context.Background(), tests.GetCreateVolumeRequest("pvname", parameterMap, ""))
+		assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: invalid "+param)
+	}
+}
+
+func (suite *ISCSIControllerSuite) Test_parseSnapshotLockDuration() {
+	duration, err := parseSnapshotLockDuration("30d")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 30*24*time.Hour, duration)
+
+	duration, err = parseSnapshotLockDuration("12h")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 12*time.Hour, duration)
+
+	_, err = parseSnapshotLockDuration("0d")
+	assert.NotNil(suite.T(), err, "zero duration should fail")
+}
+
+func (suite *ISCSIControllerSuite) Test_getQosPolicy() {
+	policy, err := getQosPolicy(map[string]string{}, api.QosPolicyTypeVolume)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), policy, "no policy expected without QoS parameters")
+
+	policy, err = getQosPolicy(map[string]string{MAXIOPS: "500", MAXBPS: "1048576"}, api.QosPolicyTypeFilesystem)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "csi-filesystem-iops500-bps1048576", policy.Name)
+
+	_, err = getQosPolicy(map[string]string{QOSPOLICY: "gold", MAXIOPS: "500"}, api.QosPolicyTypeVolume)
+	assert.NotNil(suite.T(), err, "qos_policy with max_iops should fail")
+
+	_, err = getQosPolicy(map[string]string{MAXBPS: "-1"}, api.QosPolicyTypeVolume)
+	assert.NotNil(suite.T(), err, "negative max_bps should fail")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_replication() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[REPLICATIONTARGET] = "ibox-dr"
+	parameterMap[REPLICATIONREMOTEPOOLID] = "40"
+	parameterMap[REPLICATIONRPO] = "5m"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(nil, &api.APIError{Code: api.ErrReplicaNotFound})
+	suite.api.On("GetLinkByRemoteSystem", "ibox-dr").Return(api.Link{ID: 5, RemoteSystemName: "ibox-dr"}, nil)
+	expected := api.Replica{
+		EntityType:      api.ReplicaEntityTypeVolume,
+		LocalEntityID:   100,
+		LinkID:          5,
+		RemotePoolID:    40,
+		ReplicationType: api.ReplicationTypeAsync,
+		RpoValue:        300000,
+		SyncInterval:    150000,
+	}
+	suite.api.On("CreateReplica", expected).Return(api.Replica{ID: 60}, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume with replication_target")
+	suite.api.AssertCalled(suite.T(), "CreateReplica", expected)
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_replicationNoLink() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap[REPLICATIONTARGET] = "ibox-dr"
+	parameterMap[REPLICATIONREMOTEPOOLID] = "40"
+	parameterMap[REPLICATIONRPO] = "5m"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
+	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(nil, &api.APIError{Code: api.ErrReplicaNotFound})
+	suite.api.On("GetLinkByRemoteSystem", "ibox-dr").Return(nil, &api.APIError{Code: api.ErrLinkNotFound})
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: no replication link")
+}
+
+func (suite *ISCSIControllerSuite) Test_getReplica() {
+	replica, _, err := getReplica(map[string]string{})
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), replica, "no replica expected without replication parameters")
+
+	replica, target, err := getReplica(map[string]string{REPLICATIONTARGET: "ibox-dr", REPLICATIONREMOTEPOOLID: "40", REPLICATIONRPO: "1m", REPLICATIONSYNCINTERVAL: "10s"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "ibox-dr", target)
+	assert.Equal(suite.T(), int64(60000), replica.RpoValue)
+	assert.Equal(suite.T(), int64(10000), replica.SyncInterval)
+
+	_, _, err = getReplica(map[string]string{REPLICATIONRPO: "1m"})
+	assert.NotNil(suite.T(), err, "replication_rpo without replication_target should fail")
+
+	_, _, err = getReplica(map[string]string{REPLICATIONTARGET: "ibox-dr", REPLICATIONREMOTEPOOLID: "40"})
+	assert.NotNil(suite.T(), err, "missing replication_rpo should fail")
+
+	_, _, err = getReplica(map[string]string{REPLICATIONTARGET: "ibox-dr", REPLICATIONREMOTEPOOLID: "40", REPLICATIONRPO: "1m", REPLICATIONSYNCINTERVAL: "2m"})
+	assert.NotNil(suite.T(), err, "sync interval longer than the rpo should fail")
+}
+
 func (suite *ISCSIControllerSuite) Test_CreateVolume_metadataError() {
 	service := iscsistorage{cs: *suite.cs}
 	parameterMap := getISCSICreateVolumeParameters()
 	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
 	expectedErr := errors.New("some Error")
 
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("CreateVolume", mock.Anything, mock.Anything).Return(getVolume(), nil)
@@ -111,6 +416,62 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_metadataError() {
 	assert.NotNil(suite.T(), err, "expected to fail: iscsi CreateVolume attach metadata")
 }
 
+func (suite *ISCSIControllerSuite) Test_CreateVolume_retryAfterMetadataError() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(nil, &api.APIError{Code: api.ErrVolumeNotFound})
+	suite.api.On("GetVolumeByName", "pvname").Return(getVolume(), nil)
+	suite.api.On("GetMetadataValue", int64(100), PVNAME).Return("", nil)
+	suite.api.On("GetMetadataValue", int64(100), NETWORKSPACE).Return("", nil)
+	suite.api.On("GetNetworkSpaceByName", "network_space1").Return(getNetworkspace(), nil)
+	suite.api.On("AttachMetadataToObject", int64(100), mock.Anything).Return(nil, nil)
+
+	resp, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi CreateVolume retried after attaching metadata failed")
+	assert.Equal(suite.T(), "10.20.30.40", resp.GetVolume().GetVolumeContext()["portals"], "expected the portals of the network space")
+	suite.api.AssertCalled(suite.T(), "AttachMetadataToObject", int64(100), mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		return metadata[NETWORKSPACE] == "network_space1"
+	}))
+	suite.api.AssertNotCalled(suite.T(), "CreateVolume", mock.Anything, mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_existing_recordedNetworkSpace() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	parameterMap["network_space"] = "network_space1,network_space2"
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(getVolume(), nil)
+	suite.api.On("GetMetadataValue", int64(100), NETWORKSPACE).Return("network_space2", nil)
+	suite.api.On("GetNetworkSpaceByName", "network_space2").Return(getNetworkspace(), nil)
+
+	resp, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume of existing volume")
+	volumeContext := resp.GetVolume().GetVolumeContext()
+	assert.Equal(suite.T(), "network_space2", volumeContext["network_space"], "expected the recorded network space")
+	assert.Equal(suite.T(), "iqn.1991-05.com.infinidate:example", volumeContext["iqn"])
+	assert.Equal(suite.T(), "10.20.30.40", volumeContext["portals"])
+	suite.api.AssertNotCalled(suite.T(), "GetNetworkSpaceByName", "network_space1")
+	suite.api.AssertNotCalled(suite.T(), "AttachMetadataToObject", mock.Anything, mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateVolume_nameOfOtherPV() {
+	service := iscsistorage{cs: *suite.cs}
+	parameterMap := getISCSICreateVolumeParameters()
+	createVolReq := tests.GetCreateVolumeRequest("pvname", parameterMap, "")
+
+	suite.api.On("GetVolumeByPVName", "pvname").Return(nil, &api.APIError{Code: api.ErrVolumeNotFound})
+	suite.api.On("GetVolumeByName", "pvname").Return(getVolume(), nil)
+	suite.api.On("GetMetadataValue", int64(100), PVNAME).Return("otherpv", nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Equal(suite.T(), codes.AlreadyExists, status.Code(err), "expected to fail: volume name of another PV")
+	suite.api.AssertNotCalled(suite.T(), "AttachMetadataToObject", mock.Anything, mock.Anything)
+	suite.api.AssertNotCalled(suite.T(), "CreateVolume", mock.Anything, mock.Anything)
+}
+
 func (suite *ISCSIControllerSuite) Test_DeleteVolume_InvalidVolumeID() {
 	service := iscsistorage{cs: *suite.cs}
 	createVolReq := getISCSIDeleteRequest()
@@ -174,18 +535,85 @@ func (suite *ISCSIControllerSuite) Test_DeleteVolume_success() {
 func (suite *ISCSIControllerSuite) Test_DeleteVolume_AlreadyDelete() {
 	service := iscsistorage{cs: *suite.cs}
 	createVolReq := getISCSIDeleteRequest()
-	expectedErr := errors.New("VOLUME_NOT_FOUND")
+	expectedErr := &api.APIError{Code: api.ErrVolumeNotFound}
 	suite.api.On("GetVolume", mock.Anything).Return(nil, expectedErr)
 
 	_, err := service.DeleteVolume(context.Background(), createVolReq)
 	assert.Nil(suite.T(), err, "expected to succeed: iscsi DeleteVolume when already deleted")
 }
 
+func (suite *ISCSIControllerSuite) Test_DeleteVolume_replicated() {
+	service := iscsistorage{cs: *suite.cs}
+	vol := getVolume()
+	vol.RmrSource = true
+	suite.api.On("GetVolume", mock.Anything).Return(vol, nil)
+	suite.api.On("GetVolumeSnapshotByParentID", mock.Anything).Return([]api.Volume{}, nil)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(api.Replica{ID: 60, LocalEntityID: 100}, nil)
+	suite.api.On("DeleteReplica", int64(60)).Return(nil)
+	suite.api.On("DeleteVolume", mock.Anything).Return(nil)
+	suite.api.On("GetMetadataStatus", mock.Anything).Return(false)
+
+	_, err := service.DeleteVolume(context.Background(), getISCSIDeleteRequest())
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi DeleteVolume of a replicated volume")
+	suite.api.AssertCalled(suite.T(), "DeleteReplica", int64(60))
+}
+
+func (suite *ISCSIControllerSuite) Test_PromoteVolume_activeReplica() {
+	service := iscsistorage{cs: *suite.cs}
+	vol := getVolume()
+	vol.RmrTarget = true
+	suite.api.On("GetVolume", 100).Return(vol, nil)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(api.Replica{ID: 60, Role: api.ReplicaRoleTarget, State: api.ReplicaStateActive}, nil)
+	suite.api.On("SuspendReplica", int64(60)).Return(nil)
+	suite.api.On("ChangeReplicaRole", int64(60)).Return(api.Replica{ID: 60, Role: api.ReplicaRoleSource}, nil)
+
+	err := service.PromoteVolume(context.Background(), "100")
+	assert.Nil(suite.T(), err, "expected to succeed: PromoteVolume")
+	suite.api.AssertCalled(suite.T(), "SuspendReplica", int64(60))
+	suite.api.AssertCalled(suite.T(), "ChangeReplicaRole", int64(60))
+}
+
+func (suite *ISCSIControllerSuite) Test_PromoteVolume_notTarget() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+
+	err := service.PromoteVolume(context.Background(), "100")
+	assert.Nil(suite.T(), err, "expected to succeed: PromoteVolume of a volume that is no replication target")
+	suite.api.AssertNotCalled(suite.T(), "ChangeReplicaRole", mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_ImportVolume() {
+	service := iscsistorage{cs: *suite.cs}
+	vol := getVolume()
+	vol.RmrTarget = true
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("GetVolume", 100).Return(vol, nil)
+	suite.api.On("GetVolumeByPVName", "pv-dr").Return(nil, &api.APIError{Code: api.ErrVolumeNotFound})
+	suite.api.On("AttachMetadataToObject", int64(100), mock.Anything).Return(nil, nil)
+
+	volume, err := service.ImportVolume(context.Background(), "pv-dr", "100", getISCSICreateVolumeParameters())
+	assert.Nil(suite.T(), err, "expected to succeed: ImportVolume")
+	assert.Equal(suite.T(), "100", volume.VolumeId)
+	assert.NotEmpty(suite.T(), volume.VolumeContext["portals"])
+}
+
+func (suite *ISCSIControllerSuite) Test_ImportVolume_pvInUse() {
+	service := iscsistorage{cs: *suite.cs}
+	other := getVolume()
+	other.ID = 101
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetVolumeByPVName", "pv-dr").Return(other, nil)
+
+	_, err := service.ImportVolume(context.Background(), "pv-dr", "100", getISCSICreateVolumeParameters())
+	assert.Equal(suite.T(), codes.AlreadyExists, status.Code(err), "expected to fail: PV backed by another volume")
+}
+
 func (suite *ISCSIControllerSuite) Test_CreateVolume_content_success() {
 	service := iscsistorage{cs: *suite.cs}
 	parameterMap := getISCSICreateVolumeParameters()
 	createVolReq := tests.GetCreateVolumeRequest("volumeName", parameterMap, "1$$iscsi")
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
 	var poolID int64 = 10
@@ -203,7 +631,7 @@ func (suite *ISCSIControllerSuite) Test_CreateVolume_content_AttachMetadataToObj
 	parameterMap := getISCSICreateVolumeParameters()
 	createVolReq := tests.GetCreateVolumeRequest("volumeName", parameterMap, "1$$iscsi")
 	expectedErr := errors.New("some Error")
-	suite.api.On("GetVolumeByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetVolumeByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
 	suite.api.On("GetVolume", mock.Anything).Return(getVolume(), nil)
 	var poolID int64 = 10
@@ -318,12 +746,37 @@ func (suite *ISCSIControllerSuite) Test_CreateSnapshot() {
 	//	var parameterMap map[string]string
 	ctrUnPublishValReq := getISCSICreateSnapshotRequest()
 	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), expectedErr)
+	suite.api.On("GetMetadataValue", int64(1), SNAPSHOTLOCK).Return("", nil)
 	suite.api.On("CreateSnapshotVolume", mock.Anything).Return(getSnapshotResp(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, expectedErr)
 
 	_, err := service.CreateSnapshot(context.Background(), ctrUnPublishValReq)
 	assert.Nil(suite.T(), err, "expected to fail: iscsi CreateSnapshot GetVolumeByName")
 }
 
+func (suite *ISCSIControllerSuite) Test_CreateSnapshot_locked() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), errors.New("some Error"))
+	suite.api.On("GetMetadataValue", int64(1), SNAPSHOTLOCK).Return("30d", nil)
+	suite.api.On("CreateSnapshotVolume", mock.MatchedBy(func(snapshot *api.VolumeSnapshot) bool {
+		return time.Until(time.Unix(0, snapshot.LockExpiresAt*int64(time.Millisecond))) > 29*24*time.Hour
+	})).Return(getSnapshotResp(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+
+	_, err := service.CreateSnapshot(context.Background(), getISCSICreateSnapshotRequest())
+	assert.Nil(suite.T(), err, "expected to succeed: CreateSnapshot with snapshot_lock_duration")
+}
+
+func (suite *ISCSIControllerSuite) Test_CreateSnapshot_lockError() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolumeByName", mock.Anything).Return(getVolume(), errors.New("some Error"))
+	suite.api.On("GetMetadataValue", int64(1), SNAPSHOTLOCK).Return("", errors.New("some Error"))
+
+	_, err := service.CreateSnapshot(context.Background(), getISCSICreateSnapshotRequest())
+	assert.NotNil(suite.T(), err, "expected to fail: CreateSnapshot without the snapshot lock")
+	suite.api.AssertNotCalled(suite.T(), "CreateSnapshotVolume", mock.Anything)
+}
+
 func (suite *ISCSIControllerSuite) Test_CreateSnapshot_already_Created() {
 	service := iscsistorage{cs: *suite.cs}
 	//	var parameterMap map[string]string
@@ -354,8 +807,25 @@ func (suite *ISCSIControllerSuite) Test_ControllerExpandVolume() {
 	//	var parameterMap map[string]string
 	ctrExpandValReq := getISCSIExpandVolumeRequest()
 	suite.api.On("UpdateVolume", mock.Anything, mock.Anything).Return(nil, nil)
-	_, err := service.ControllerExpandVolume(context.Background(), ctrExpandValReq)
+	resp, err := service.ControllerExpandVolume(context.Background(), ctrExpandValReq)
 	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerExpandVolume")
+	assert.True(suite.T(), resp.NodeExpansionRequired, "expected filesystem volumes to need node expansion")
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerExpandVolume_block() {
+	service := iscsistorage{cs: *suite.cs}
+	ctrExpandValReq := getISCSIExpandVolumeRequest()
+	ctrExpandValReq.VolumeCapability = &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}}
+	suite.api.On("UpdateVolume", mock.Anything, mock.Anything).Return(nil, nil)
+	resp, err := service.ControllerExpandVolume(context.Background(), ctrExpandValReq)
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerExpandVolume")
+	assert.False(suite.T(), resp.NodeExpansionRequired, "expected raw block volumes to need no node expansion")
+}
+
+func (suite *ISCSIControllerSuite) Test_NodeExpandVolume_noPath() {
+	service := iscsistorage{cs: *suite.cs}
+	_, err := service.NodeExpandVolume(context.Background(), &csi.NodeExpandVolumeRequest{VolumeId: "100"})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi NodeExpandVolume without volume path")
 }
 
 func (suite *ISCSIControllerSuite) Test_ValidateVolumeCapabilities() {
@@ -370,20 +840,281 @@ func (suite *ISCSIControllerSuite) Test_ValidateVolumeCapabilities() {
 
 func (suite *ISCSIControllerSuite) Test_ListVolumes() {
 	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByIDs", []int{100}).Return([]api.Volume{getVolume()}, nil)
+	resp, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListVolumes")
+	assert.Equal(suite.T(), 1, len(resp.Entries), "expected only the iscsi volume not marked to be deleted")
+	assert.Equal(suite.T(), "100", resp.Entries[0].Volume.VolumeId)
+	suite.api.AssertNotCalled(suite.T(), "GetVolume", mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListVolumes_sharedScan() {
+	iscsiService := iscsistorage{cs: *suite.cs}
+	fcService := fcstorage{cs: *suite.cs}
+	fcVolume := getVolume()
+	fcVolume.ID = 101
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByIDs", []int{100}).Return([]api.Volume{getVolume()}, nil)
+	suite.api.On("GetVolumesByIDs", []int{101}).Return([]api.Volume{fcVolume}, nil)
+
+	ctx := WithPVObjectScan(context.Background())
+	iscsiResp, err := iscsiService.ListVolumes(ctx, &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListVolumes")
+	fcResp, err := fcService.ListVolumes(ctx, &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: fc ListVolumes")
+	assert.Equal(suite.T(), "100", iscsiResp.Entries[0].Volume.VolumeId)
+	assert.Equal(suite.T(), "101", fcResp.Entries[0].Volume.VolumeId)
+	suite.api.AssertNumberOfCalls(suite.T(), "GetMetadataByKey", 3)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListVolumes_GetMetadataErr() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(nil, errors.New("some error"))
 	_, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ListVolumes metadata error")
+}
+
+func (suite *ISCSIControllerSuite) Test_ListVolumes_VolumeDeleted() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByIDs", []int{100}).Return([]api.Volume{}, nil)
+	resp, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
 	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListVolumes")
+	assert.Equal(suite.T(), 0, len(resp.Entries), "expected deleted volume to be skipped")
+}
+
+func (suite *ISCSIControllerSuite) Test_ListVolumes_GetVolumesErr() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByIDs", []int{100}).Return(nil, errors.New("some error"))
+	_, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ListVolumes volumes error")
 }
 
 func (suite *ISCSIControllerSuite) Test_ListSnapshots() {
 	service := iscsistorage{cs: *suite.cs}
-	_, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	suite.api.On("GetVolumesByParentIDs", []int{100, 102}).Return(getVolumeChildren(100), nil)
+	suite.api.On("GetVolumesByParentIDs", []int{200}).Return([]api.Volume{}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
 	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots")
+	assert.Equal(suite.T(), 1, len(resp.Entries), "expected the writable clone to be skipped")
+	assert.Equal(suite.T(), "200$$iscsi", resp.Entries[0].Snapshot.SnapshotId)
+	assert.Equal(suite.T(), "100$$iscsi", resp.Entries[0].Snapshot.SourceVolumeId)
+	assert.Equal(suite.T(), int64(1609459200), resp.Entries[0].Snapshot.CreationTime.Seconds)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_parentToBeDeleted() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(getPVNameMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(getStorageProtocolMetadataList(), nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return(getToBeDeletedMetadataList(), nil)
+	snapshot := getVolumeChildren(102)[0]
+	suite.api.On("GetVolumesByParentIDs", []int{100, 102}).Return([]api.Volume{snapshot}, nil)
+	suite.api.On("GetVolumesByParentIDs", []int{200}).Return([]api.Volume{}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots")
+	assert.Equal(suite.T(), 1, len(resp.Entries), "expected the snapshot of the volume marked to be deleted")
+	assert.Equal(suite.T(), "102$$iscsi", resp.Entries[0].Snapshot.SourceVolumeId)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_bySnapshotID() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 200).Return(getVolumeChildren(100)[0], nil)
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "200$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots by snapshot ID")
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "100$$iscsi", resp.Entries[0].Snapshot.SourceVolumeId)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_bySnapshotID_nested() {
+	service := iscsistorage{cs: *suite.cs}
+	nested := api.Volume{ID: 300, ParentId: 200, WriteProtected: true}
+	suite.api.On("GetVolume", 300).Return(nested, nil)
+	suite.api.On("GetVolume", 200).Return(getVolumeChildren(100)[0], nil)
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "300$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots by snapshot ID")
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "100$$iscsi", resp.Entries[0].Snapshot.SourceVolumeId, "expected the volume at the top of the snapshot chain")
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_bySnapshotID_clone() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 201).Return(getVolumeChildren(100)[1], nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "201$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots by clone ID")
+	assert.Equal(suite.T(), 0, len(resp.Entries), "expected a writable clone not to be listed as snapshot")
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_bySourceVolumeID_nested() {
+	service := iscsistorage{cs: *suite.cs}
+	nested := api.Volume{ID: 300, ParentId: 200, WriteProtected: true}
+	suite.api.On("GetVolumesByParentIDs", []int{100}).Return(getVolumeChildren(100), nil)
+	suite.api.On("GetVolumesByParentIDs", []int{200}).Return([]api.Volume{nested}, nil)
+	suite.api.On("GetVolumesByParentIDs", []int{300}).Return([]api.Volume{}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SourceVolumeId: "100$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots by source volume ID")
+	assert.Equal(suite.T(), 2, len(resp.Entries), "expected the snapshot of the snapshot to be listed")
+	assert.Equal(suite.T(), "300$$iscsi", resp.Entries[1].Snapshot.SnapshotId)
+	assert.Equal(suite.T(), "100$$iscsi", resp.Entries[1].Snapshot.SourceVolumeId)
+	suite.api.AssertNumberOfCalls(suite.T(), "GetVolumesByParentIDs", 3)
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_snapshotNotFound() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 200).Return(nil, &api.APIError{Code: api.ErrVolumeNotFound})
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "200$$iscsi"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ListSnapshots snapshot not found")
+	assert.Equal(suite.T(), 0, len(resp.Entries))
+}
+
+func (suite *ISCSIControllerSuite) Test_ListSnapshots_bySourceVolumeID_error() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolumesByParentIDs", []int{100}).Return(nil, errors.New("some error"))
+	_, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SourceVolumeId: "100$$iscsi"})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ListSnapshots by source volume ID")
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume() {
+	service := iscsistorage{cs: *suite.cs}
+	defer stubCSINodeIDs("host1$$192.168.1.10", "host3$$192.168.1.12")()
+	hosts := []api.Host{{ID: 10, Name: "host1"}, {ID: 11, Name: "host2"}, {ID: 12, Name: "host3"}}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{{HostID: 10, VolumeID: 100, Lun: 1}, {HostID: 11, VolumeID: 100, Lun: 1}}, nil)
+	suite.api.On("GetAllHosts").Return(hosts, nil)
+	suite.api.On("GetMetadataStatus", int64(100)).Return(false)
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerGetVolume")
+	assert.Equal(suite.T(), []string{"host1$$192.168.1.10"}, resp.Status.PublishedNodeIds, "expected hosts which are not nodes to be left out")
+	assert.False(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_replicaSuspended() {
+	service := iscsistorage{cs: *suite.cs}
+	volume := getVolume()
+	volume.RmrSource = true
+	suite.api.On("GetVolume", 100).Return(volume, nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{}, nil)
+	suite.api.On("GetMetadataStatus", int64(100)).Return(false)
+	suite.api.On("GetReplicaByEntity", int64(100)).Return(api.Replica{ID: 60, Role: "SOURCE", State: "SUSPENDED"}, nil)
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerGetVolume")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+	assert.Contains(suite.T(), resp.Status.VolumeCondition.Message, "SUSPENDED")
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_toBeDeleted() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{}, nil)
+	suite.api.On("GetMetadataStatus", int64(100)).Return(true)
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerGetVolume")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_writeProtected() {
+	service := iscsistorage{cs: *suite.cs}
+	volume := getVolume()
+	volume.WriteProtected = true
+	suite.api.On("GetVolume", 100).Return(volume, nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{}, nil)
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerGetVolume")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_notFound() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(nil, &api.APIError{Code: api.ErrVolumeNotFound})
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi ControllerGetVolume")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_hostsError() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{{HostID: 10, VolumeID: 100, Lun: 1}}, nil)
+	suite.api.On("GetAllHosts").Return(nil, errors.New("some error"))
+	_, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ControllerGetVolume")
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_lunsError() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetLunsByVolume", 100).Return(nil, errors.New("some error"))
+	_, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ControllerGetVolume")
+}
+
+func (suite *ISCSIControllerSuite) Test_ControllerGetVolume_nodeIDsError() {
+	defer func(nodeIDs func() ([]string, error)) { getCSINodeIDs = nodeIDs }(getCSINodeIDs)
+	getCSINodeIDs = func() ([]string, error) { return nil, errors.New("some error") }
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetVolume", 100).Return(getVolume(), nil)
+	suite.api.On("GetLunsByVolume", 100).Return([]api.LunInfo{{HostID: 10, VolumeID: 100, Lun: 1}}, nil)
+	suite.api.On("GetAllHosts").Return([]api.Host{{ID: 10, Name: "host1"}}, nil)
+	_, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi ControllerGetVolume")
+}
+
+// stubCSINodeIDs makes getCSINodeIDs return nodeIDs, until the returned func restores it
+func stubCSINodeIDs(nodeIDs ...string) func() {
+	saved := getCSINodeIDs
+	getCSINodeIDs = func() ([]string, error) { return nodeIDs, nil }
+	return func() { getCSINodeIDs = saved }
 }
 
 func (suite *ISCSIControllerSuite) Test_GetCapacity() {
 	service := iscsistorage{cs: *suite.cs}
-	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{})
+	suite.api.On("GetStoragePoolByName", "pool_name1").Return(getStoragePool(), nil)
+	resp, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: map[string]string{"pool_name": "pool_name1"}})
 	assert.Nil(suite.T(), err, "expected to succeed: iscsi GetCapacity")
+	assert.Equal(suite.T(), int64(4000), resp.AvailableCapacity, "expected virtual capacity by default")
+}
+
+func (suite *ISCSIControllerSuite) Test_GetCapacity_physical() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetStoragePoolByName", "pool_name1").Return(getStoragePool(), nil)
+	params := map[string]string{"pool_name": "pool_name1", CAPACITYTYPE: CAPACITYPHYSICAL}
+	resp, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: params})
+	assert.Nil(suite.T(), err, "expected to succeed: iscsi GetCapacity physical")
+	assert.Equal(suite.T(), int64(1500), resp.AvailableCapacity, "expected free physical space plus max_extend headroom")
+}
+
+func (suite *ISCSIControllerSuite) Test_GetCapacity_noPoolName() {
+	service := iscsistorage{cs: *suite.cs}
+	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi GetCapacity without pool_name")
+}
+
+func (suite *ISCSIControllerSuite) Test_GetCapacity_invalidCapacityType() {
+	service := iscsistorage{cs: *suite.cs}
+	params := map[string]string{"pool_name": "pool_name1", CAPACITYTYPE: "total"}
+	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: params})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi GetCapacity invalid capacity_type")
+}
+
+func (suite *ISCSIControllerSuite) Test_GetCapacity_noSuchPool() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetStoragePoolByName", "pool_name1").Return(nil, &api.APIError{Code: api.ErrPoolNotFound, Message: "No such pool: pool_name1"})
+	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: map[string]string{"pool_name": "pool_name1"}})
+	assert.NotNil(suite.T(), err, "expected to fail: iscsi GetCapacity no such pool")
 }
 
 // TEST data=============================================
@@ -490,6 +1221,45 @@ func getVolume() api.Volume {
 	return vol
 }
 
+func getPVNameMetadataList() []api.Metadata {
+	return []api.Metadata{
+		{ObjectId: 100, Key: PVNAME, Value: "pvc-iscsi"},
+		{ObjectId: 101, Key: PVNAME, Value: "pvc-fc"},
+		{ObjectId: 102, Key: PVNAME, Value: "pvc-deleted"},
+	}
+}
+
+func getStorageProtocolMetadataList() []api.Metadata {
+	return []api.Metadata{
+		{ObjectId: 100, Key: STORAGEPROTOCOL, Value: "iscsi"},
+		{ObjectId: 101, Key: STORAGEPROTOCOL, Value: "fc"},
+		{ObjectId: 102, Key: STORAGEPROTOCOL, Value: "iscsi"},
+	}
+}
+
+func getToBeDeletedMetadataList() []api.Metadata {
+	return []api.Metadata{{ObjectId: 102, Key: TOBEDELETED, Value: "true"}}
+}
+
+func getStoragePool() api.StoragePool {
+	return api.StoragePool{
+		ID:                10,
+		Name:              "pool_name1",
+		PhysicalCapacity:  2000,
+		FreePhysicalSpace: 1000,
+		MaxExtend:         2500,
+		VirtualCapacity:   8000,
+		FreeVirtualSpace:  4000,
+	}
+}
+
+func getVolumeChildren(parentID int) []api.Volume {
+	return []api.Volume{
+		{ID: 200, ParentId: parentID, Size: 1073741824, WriteProtected: true, CreatedAt: 1609459200000},
+		{ID: 201, ParentId: parentID, Size: 1073741824},
+	}
+}
+
 func getNetworkspace() api.NetworkSpace {
 	var nspace api.NetworkSpace
 	var p api.Portal
@@ -540,3 +1310,131 @@ func getISCSICreateVolumeParameters() map[string]string {
 		"useCHAP":           "none",
 	}
 }
+
+func (suite *ISCSIControllerSuite) Test_GetAccessibleTopology() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetNetworkSpaceByName", "iscsi1").Return(getNetworkspace(), nil)
+	requirements := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{
+			{Segments: map[string]string{TopologyKeyNetwork: "10.20.30.0-24"}},
+			{Segments: map[string]string{TopologyKeyNetwork: "10.20.40.0-24"}},
+			{Segments: map[string]string{TopologyKeyNetwork: TopologyNone}},
+		},
+	}
+
+	topology, err := service.GetAccessibleTopology(context.Background(), "iscsi", map[string]string{"network_space": "iscsi1"}, requirements)
+	assert.Nil(suite.T(), err, "expected to succeed: GetAccessibleTopology")
+	assert.Equal(suite.T(), 1, len(topology), "expected only the network holding the portal")
+	assert.Equal(suite.T(), "10.20.30.0-24", topology[0].GetSegments()[TopologyKeyNetwork])
+}
+
+func (suite *ISCSIControllerSuite) Test_GetAccessibleTopology_fc() {
+	service := fcstorage{cs: *suite.cs}
+	fcNodes := []api.FCNode{{Ports: []api.FCPort{{Enabled: true, SwitchWWNn: "10:00:00:05:1E:0B:6D:01"}}}}
+	suite.api.On("GetFCPorts").Return(fcNodes, nil)
+	requirements := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{
+			{Segments: map[string]string{TopologyKeyFCFabric: "100000051e0b6d01", TopologyKeyNetwork: "10.20.30.0-24"}},
+			{Segments: map[string]string{TopologyKeyFCFabric: TopologyNone, TopologyKeyNetwork: "10.20.30.0-24"}},
+		},
+	}
+
+	topology, err := service.GetAccessibleTopology(context.Background(), "fc", nil, requirements)
+	assert.Nil(suite.T(), err, "expected to succeed: GetAccessibleTopology over fc")
+	assert.Equal(suite.T(), []*csi.Topology{{Segments: map[string]string{TopologyKeyFCFabric: "100000051e0b6d01"}}}, topology)
+}
+
+func (suite *ISCSIControllerSuite) Test_GetAccessibleTopology_notRequisite() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetNetworkSpaceByName", "iscsi1").Return(getNetworkspace(), nil)
+	requirements := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{{Segments: map[string]string{TopologyKeyNetwork: "10.20.40.0-24"}}},
+	}
+
+	_, err := service.GetAccessibleTopology(context.Background(), "iscsi", map[string]string{"network_space": "iscsi1"}, requirements)
+	assert.NotNil(suite.T(), err, "expected to fail: array out of the requisite topology")
+	assert.Equal(suite.T(), codes.ResourceExhausted, status.Code(err))
+}
+
+func (suite *ISCSIControllerSuite) Test_GetAccessibleTopology_noRequirements() {
+	service := iscsistorage{cs: *suite.cs}
+
+	topology, err := service.GetAccessibleTopology(context.Background(), "iscsi", map[string]string{"network_space": "iscsi1"}, nil)
+	assert.Nil(suite.T(), err, "expected to succeed: GetAccessibleTopology without requirements")
+	assert.Nil(suite.T(), topology, "expected no topology without requirements")
+	suite.api.AssertNotCalled(suite.T(), "GetNetworkSpaceByName", mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_NodeTopology() {
+	hostsDir, err := ioutil.TempDir("", "fc_host")
+	assert.Nil(suite.T(), err)
+	defer os.RemoveAll(hostsDir)
+	hosts := map[string][]string{
+		"host1": {"Online", "0x100000051e0b6d02"},
+		"host2": {"Online", "0x100000051e0b6d01"},
+		"host3": {"Linkdown", "0x0"},
+	}
+	for host, attrs := range hosts {
+		assert.Nil(suite.T(), os.MkdirAll(filepath.Join(hostsDir, host), 0755))
+		assert.Nil(suite.T(), ioutil.WriteFile(filepath.Join(hostsDir, host, "port_state"), []byte(attrs[0]+"\n"), 0644))
+		assert.Nil(suite.T(), ioutil.WriteFile(filepath.Join(hostsDir, host, "fabric_name"), []byte(attrs[1]+"\n"), 0644))
+	}
+	defer func(glob string) { fcHostsGlob = glob }(fcHostsGlob)
+	fcHostsGlob = filepath.Join(hostsDir, "host*")
+	defer func(addrs func() ([]net.Addr, error)) { interfaceAddrs = addrs }(interfaceAddrs)
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("10.20.30.50"), Mask: net.CIDRMask(24, 32)}}, nil
+	}
+
+	segments := NodeTopology("10.20.30.50")
+	assert.Equal(suite.T(), "100000051e0b6d01", segments[TopologyKeyFCFabric])
+	assert.Equal(suite.T(), "10.20.30.0-24", segments[TopologyKeyNetwork])
+
+	segments = NodeTopology("10.20.40.50")
+	assert.Equal(suite.T(), TopologyNone, segments[TopologyKeyNetwork])
+}
+
+func (suite *ISCSIControllerSuite) Test_setTargetParameters_roundRobin() {
+	service := iscsistorage{cs: *suite.cs}
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkspace(), nil)
+	selected := []string{}
+	for i := 0; i < 3; i++ {
+		params := map[string]string{"network_space": "rr-ns1, rr-ns2", NETWORKSPACESELECTION: SelectionRoundRobin}
+		err := service.setTargetParameters(context.Background(), params)
+		assert.Nil(suite.T(), err, "expected to succeed: setTargetParameters")
+		selected = append(selected, params["network_space"])
+	}
+	assert.Equal(suite.T(), []string{"rr-ns1", "rr-ns2", "rr-ns1"}, selected)
+}
+
+func (suite *ISCSIControllerSuite) Test_setTargetParameters_invalidSelection() {
+	service := iscsistorage{cs: *suite.cs}
+	params := map[string]string{"network_space": "ns1", NETWORKSPACESELECTION: "nearest"}
+
+	err := service.setTargetParameters(context.Background(), params)
+	assert.NotNil(suite.T(), err, "expected to fail: invalid network_space_selection")
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err))
+	suite.api.AssertNotCalled(suite.T(), "GetNetworkSpaceByName", mock.Anything)
+}
+
+func (suite *ISCSIControllerSuite) Test_getNetworkSpaceSelection_subnets() {
+	params := map[string]string{
+		NETWORKSPACESELECTION: SelectionSubnet,
+		NETWORKSPACESUBNETS:   "10.0.1.0/24=ns-rack1, 10.0.2.0/24=ns-rack2",
+	}
+	policy, subnets, err := getNetworkSpaceSelection(params)
+	assert.Nil(suite.T(), err, "expected to succeed: getNetworkSpaceSelection")
+	assert.Equal(suite.T(), SelectionSubnet, policy)
+	assert.Equal(suite.T(), "ns-rack2", subnetNetworkSpace(subnets, "10.0.2.17"))
+	assert.Equal(suite.T(), "", subnetNetworkSpace(subnets, "10.0.3.17"))
+
+	params[NETWORKSPACESUBNETS] = "10.0.1.0/33=ns-rack1"
+	_, _, err = getNetworkSpaceSelection(params)
+	assert.NotNil(suite.T(), err, "expected to fail: invalid subnet")
+}
+
+func (suite *ISCSIControllerSuite) Test_leastUsedIndex() {
+	usage := map[string]int{"10.20.30.40": 3, "10.20.30.41": 1}
+	assert.Equal(suite.T(), 1, leastUsedIndex([]string{"10.20.30.40", "10.20.30.41"}, usage))
+	assert.Equal(suite.T(), 2, leastUsedIndex([]string{"10.20.30.40", "10.20.30.41", "10.20.30.42"}, usage))
+}
@@ -141,7 +141,7 @@ func (iscsi *iscsistorage) NodeStageVolume(ctx context.Context, req *csi.NodeSta
 	}
 	if !strings.Contains(ports, initiatorName) {
 		klog.V(4).Infof("Host port is not created, creating one")
-		err = iscsi.cs.AddPortForHost(hostID, "ISCSI", initiatorName)
+		err = iscsi.cs.AddPortForHost(ctx, hostID, "ISCSI", initiatorName)
 		if err != nil {
 			klog.Errorf("Error creating host port %v", err)
 			return nil, status.Error(codes.Internal, err.Error())
@@ -176,7 +176,7 @@ func (iscsi *iscsistorage) NodeStageVolume(ctx context.Context, req *csi.NodeSta
 			}
 			if len(chapCreds) > 1 {
 				klog.V(4).Infof("Create chap authentication for host %d", hostID)
-				err := iscsi.cs.AddChapSecurityForHost(hostID, chapCreds)
+				err := iscsi.cs.AddChapSecurityForHost(ctx, hostID, chapCreds)
 				if err != nil {
 					return nil, status.Error(codes.Internal, err.Error())
 				}
@@ -184,7 +184,7 @@ func (iscsi *iscsistorage) NodeStageVolume(ctx context.Context, req *csi.NodeSta
 		} else if hostSecurity != "NONE" {
 			klog.V(4).Infof("Remove chap authentication for host %d", hostID)
 			chapCreds["security_method"] = "NONE"
-			err := iscsi.cs.AddChapSecurityForHost(hostID, chapCreds)
+			err := iscsi.cs.AddChapSecurityForHost(ctx, hostID, chapCreds)
 			if err != nil {
 				return nil, status.Error(codes.Internal, err.Error())
 			}
@@ -414,11 +414,11 @@ func (iscsi *iscsistorage) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfo
 }
 
 func (iscsi *iscsistorage) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+	return iscsi.cs.getVolumeStats(req.GetVolumePath())
 }
 
 func (iscsi *iscsistorage) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, time.Now().String())
+	return iscsi.cs.expandMpathVolume(req)
 }
 
 func (iscsi *iscsistorage) rescanDeviceMap(volumeId string, lun string) error {
@@ -770,6 +770,7 @@ func (iscsi *iscsistorage) AttachDisk(b iscsiDiskMounter) (mntPath string, err e
 			}
 		} else {
 			klog.V(4).Infof("FormatAndMount err is nil")
+			resizeMountedFilesystem(devicePath, mountPoint)
 		}
 	}
 	klog.V(4).Infof("Mounted volume with device path %s successfully at '%s'", devicePath, mntPath)
@@ -0,0 +1,186 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"infinibox-csi-driver/api"
+	"infinibox-csi-driver/api/clientgo"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+// selectedNodeAnnotation is set on a PVC by the scheduler for volumes of a WaitForFirstConsumer
+// StorageClass, naming the node the pod consuming the volume runs on
+const selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+
+// roundRobin holds the next index into each list of network spaces or IPs rotated through by the
+// round_robin policy, for the lifetime of the controller
+var roundRobin = struct {
+	sync.Mutex
+	next map[string]int
+}{next: map[string]int{}}
+
+// nextRoundRobinIndex returns the next index of the count items of the list identified by key
+func nextRoundRobinIndex(key string, count int) int {
+	roundRobin.Lock()
+	defer roundRobin.Unlock()
+	index := roundRobin.next[key] % count
+	roundRobin.next[key] = index + 1
+	return index
+}
+
+// leastUsedIndex returns the index of the item used by the fewest volumes, the first one on a tie
+func leastUsedIndex(items []string, usage map[string]int) int {
+	index := 0
+	for i, item := range items {
+		if usage[item] < usage[items[index]] {
+			index = i
+		}
+	}
+	return index
+}
+
+// subnetNetworkSpace returns the network space mapped to the subnet of nodeIP, empty when none is
+func subnetNetworkSpace(subnets []networkSpaceSubnet, nodeIP string) string {
+	ip := net.ParseIP(nodeIP)
+	if ip == nil {
+		return ""
+	}
+	for _, s := range subnets {
+		if s.subnet.Contains(ip) {
+			return s.networkSpace
+		}
+	}
+	return ""
+}
+
+// getSelectedNodeIP returns the internal IP of the node selected by the scheduler for the PVC of the
+// CreateVolume parameters, empty when the StorageClass binds volumes immediately
+func getSelectedNodeIP(params map[string]string) string {
+	pvcName, pvcNamespace := params[pvcNameKey], params[pvcNamespaceKey]
+	if pvcName == "" || pvcNamespace == "" {
+		klog.Warningf("no PVC name in the parameters, run the csi provisioner with --extra-create-metadata")
+		return ""
+	}
+	cl, err := clientgo.BuildClient()
+	if err != nil {
+		return ""
+	}
+	pvc, err := cl.GetPersistentVolumeClaim(pvcName, pvcNamespace)
+	if err != nil {
+		return ""
+	}
+	nodeName := pvc.Annotations[selectedNodeAnnotation]
+	if nodeName == "" {
+		klog.V(4).Infof("no node selected for PVC %s/%s", pvcNamespace, pvcName)
+		return ""
+	}
+	nodeIP, err := cl.GetNodeIdByNodeName(nodeName)
+	if err != nil {
+		klog.Errorf("failed to get IP of node %s: %v", nodeName, err)
+		return ""
+	}
+	return nodeIP
+}
+
+// getVolumeContextUsage counts the PVs of the driver by their VolumeContext value of key, empty when
+// the PVs cannot be listed
+func getVolumeContextUsage(key string) map[string]int {
+	usage := map[string]int{}
+	cl, err := clientgo.BuildClient()
+	if err != nil {
+		return usage
+	}
+	pvs, err := cl.GetPersistentVolumes()
+	if err != nil {
+		return usage
+	}
+	for _, pv := range pvs {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == Name && pv.Spec.CSI.VolumeAttributes[key] != "" {
+			usage[pv.Spec.CSI.VolumeAttributes[key]]++
+		}
+	}
+	return usage
+}
+
+// selectNetworkSpace returns the name and details of the network space chosen among the comma separated
+// network_space parameter by the network_space_selection policy. The subnet policy may choose a network
+// space mapped to the subnet of the node out of the list, and falls back to round_robin otherwise
+func (cs *commonservice) selectNetworkSpace(ctx context.Context, params map[string]string) (string, api.NetworkSpace, error) {
+	policy, subnets, err := getNetworkSpaceSelection(params)
+	if err != nil {
+		return "", api.NetworkSpace{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	names := []string{}
+	for _, name := range strings.Split(params["network_space"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", api.NetworkSpace{}, status.Error(codes.InvalidArgument, "no network_space provided")
+	}
+	name := ""
+	switch policy {
+	case SelectionSubnet:
+		name = subnetNetworkSpace(subnets, getSelectedNodeIP(params))
+		if name == "" {
+			name = names[nextRoundRobinIndex(strings.Join(names, ","), len(names))]
+		}
+	case SelectionRoundRobin:
+		name = names[nextRoundRobinIndex(strings.Join(names, ","), len(names))]
+	case SelectionLeastUsed:
+		name = names[leastUsedIndex(names, getVolumeContextUsage("network_space"))]
+	default:
+		name = names[getRandomIndex(len(names))]
+	}
+	nspace, err := cs.api.GetNetworkSpaceByName(ctx, name)
+	if err != nil {
+		return name, nspace, err
+	}
+	klog.V(4).Infof("Selected network space %s by %s policy", name, policy)
+	return name, nspace, nil
+}
+
+// selectNetworkSpaceIP returns the network space chosen by selectNetworkSpace and one of its IPs,
+// rotated through by the round_robin and subnet policies
+func (cs *commonservice) selectNetworkSpaceIP(ctx context.Context, params map[string]string) (string, string, error) {
+	name, nspace, err := cs.selectNetworkSpace(ctx, params)
+	if err != nil {
+		return "", "", err
+	}
+	if len(nspace.Portals) == 0 {
+		return "", "", errors.New("Ip address not found")
+	}
+	ips := []string{}
+	for _, portal := range nspace.Portals {
+		ips = append(ips, portal.IpAdress)
+	}
+	policy, _, _ := getNetworkSpaceSelection(params)
+	index := 0
+	switch policy {
+	case SelectionRoundRobin, SelectionSubnet:
+		index = nextRoundRobinIndex(name+"/ips", len(ips))
+	case SelectionLeastUsed:
+		index = leastUsedIndex(ips, getVolumeContextUsage("ipAddress"))
+	default:
+		index = getRandomIndex(len(ips))
+	}
+	klog.V(4).Infof("Selected IP %s of network space %s by %s policy", ips[index], name, policy)
+	return name, ips[index], nil
+}
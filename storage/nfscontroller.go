@@ -21,7 +21,6 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 	"k8s.io/klog"
 )
 
@@ -29,6 +28,28 @@ const (
 	// TOBEDELETED status
 	TOBEDELETED          = "host.k8s.to_be_deleted"
 	StandardMountOptions = "vers=3,tcp,rsize=262144,wsize=262144"
+
+	// PVNAME metadata key holding the name of the PV an IBox object backs
+	PVNAME = api.PVNameMetadataKey
+	// STORAGEPROTOCOL metadata key holding the protocol an IBox object was provisioned for
+	STORAGEPROTOCOL = "host.k8s.storage_protocol"
+
+	// metadata keys mapping an IBox object to its Kubernetes owner
+	PVCNAME                   = "host.k8s.pvc_name"
+	PVCNAMESPACE              = "host.k8s.pvc_namespace"
+	STORAGECLASS              = "host.k8s.storage_class"
+	VOLUMESNAPSHOTNAME        = "host.k8s.volumesnapshot_name"
+	VOLUMESNAPSHOTNAMESPACE   = "host.k8s.volumesnapshot_namespace"
+	VOLUMESNAPSHOTCONTENTNAME = "host.k8s.volumesnapshotcontent_name"
+	CLUSTERID                 = "host.k8s.cluster_id"
+	CREATEDBY                 = "host.created_by"
+	CREATEDAT                 = "host.k8s.created_at"
+	SNAPSHOTLOCK              = "host.k8s.snapshot_lock_duration"
+
+	// metadata keys holding the network space chosen for an iscsi volume or nfs filesystem, and the
+	// nfs IP, so that a repeated CreateVolume answers with them
+	NETWORKSPACE   = "host.k8s.network_space"
+	NETWORKSPACEIP = "host.k8s.network_space_ip"
 )
 
 // NFSVolumeServiceType servier type
@@ -83,8 +104,18 @@ func (nfs *nfsstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	// replication is provided for block volumes only
+	if config[REPLICATIONTARGET] != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not supported for %s", REPLICATIONTARGET, NFS)
+	}
 	// TODO: negative validation - eg useCHAP should NOT be specified for nfs
 
+	// name of the filesystem on the array, rendered from the optional volume_name_template parameter
+	fileSystemName, err := getVolumeName(config, pvName)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// TODO: roll this capacity validation into broader controller.go
 	capacity := int64(req.GetCapacityRange().GetRequiredBytes())
 	if capacity < gib { // INF90
@@ -129,32 +160,28 @@ func (nfs *nfsstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 	klog.V(2).Infof("Snapshot directory is visible: %t", snapdirVisible)
 
 	nfs.pVName = pvName
+	nfs.fileSystemName = fileSystemName
 	nfs.configmap = config
 	nfs.capacity = capacity
 	nfs.usePrivilegedPorts = usePrivilegedPorts
 	nfs.snapdirVisible = snapdirVisible
-	nfs.exportpath = "/" + pvName
-	ipAddress, err := nfs.cs.getNetworkSpaceIP(strings.Trim(config["network_space"], " "))
-	if err != nil {
-		msg := fmt.Sprintf("failed to get networkspace ipaddress, %v", err)
-		klog.Errorf(msg)
-		return nil, status.Error(codes.InvalidArgument, msg)
-	}
-	nfs.ipAddress = ipAddress
-	klog.V(4).Infof("getNetworkSpaceIP ipAddress %s", nfs.ipAddress)
+	nfs.exportpath = "/" + fileSystemName
 
-	// check if volume with given name already exists
-	volume, err := nfs.cs.api.GetFileSystemByName(pvName)
-	if err != nil && !strings.EqualFold(err.Error(), "filesystem with given name not found") {
-		klog.V(4).Infof("CreateVolume - GetFileSystemByName error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "CreateVolume failed: %v", err)
+	// check if the filesystem of the PV already exists
+	volume, err := nfs.cs.getFileSystemOfPV(ctx, pvName, fileSystemName, NFS, config)
+	if err != nil {
+		klog.V(4).Infof("CreateVolume - getFileSystemOfPV error: %v", err)
+		return nil, err
 	}
 	if volume != nil {
 		// return existing volume
 		nfs.fileSystemID = volume.ID
-		exportArray, err := nfs.cs.api.GetExportByFileSystem(nfs.fileSystemID)
+		if err = nfs.setNetworkSpaceOf(ctx, volume.ID); err != nil {
+			return nil, err
+		}
+		exportArray, err := nfs.cs.api.GetExportByFileSystem(ctx, nfs.fileSystemID)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "CreateVolume failed: %v", err)
+			return nil, status.Errorf(api.GRPCCode(err), "CreateVolume failed: %v", err)
 		}
 		if exportArray == nil {
 			return nil, status.Errorf(codes.NotFound, "CreateVolume failed: %v", err)
@@ -169,9 +196,18 @@ func (nfs *nfsstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 			nfs.exportID = export.ID
 			break
 		}
+		// reconcile the QoS policy, the storage class may have changed since the filesystem was created
+		if err = nfs.cs.setQosPolicy(ctx, config, api.QosPolicyTypeFilesystem, volume.ID, volume.QosPolicyID); err != nil {
+			klog.Errorf("failed to set QoS policy of file system %s, %v", pvName, err)
+			return nil, err
+		}
 		return nfs.getNfsCsiResponse(req), nil
 	}
 
+	if err = nfs.selectNetworkSpaceIP(ctx); err != nil {
+		return nil, err
+	}
+
 	// Volume content source support Volumes and Snapshots
 	contentSource := req.GetVolumeContentSource()
 	klog.V(4).Infof("content volume source: %v", contentSource)
@@ -179,21 +215,21 @@ func (nfs *nfsstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 	if contentSource != nil {
 		if contentSource.GetSnapshot() != nil {
 			snapshot := req.GetVolumeContentSource().GetSnapshot()
-			csiResp, err = nfs.createVolumeFrmPVCSource(req, capacity, config["pool_name"], snapshot.GetSnapshotId())
+			csiResp, err = nfs.createVolumeFrmPVCSource(ctx, req, capacity, config["pool_name"], snapshot.GetSnapshotId())
 			if err != nil {
 				klog.Errorf("failed to create volume from snapshot with error: %v", err)
 				return nil, err
 			}
 		} else if contentSource.GetVolume() != nil {
 			volume := req.GetVolumeContentSource().GetVolume()
-			csiResp, err = nfs.createVolumeFrmPVCSource(req, capacity, config["pool_name"], volume.GetVolumeId())
+			csiResp, err = nfs.createVolumeFrmPVCSource(ctx, req, capacity, config["pool_name"], volume.GetVolumeId())
 			if err != nil {
 				klog.Errorf("failed to create volume from pvc with error: %v", err)
 				return nil, err
 			}
 		}
 	} else {
-		csiResp, err = nfs.CreateNFSVolume(req)
+		csiResp, err = nfs.CreateNFSVolume(ctx, req)
 		if err != nil {
 			klog.Errorf("failed to create volume, %v", err)
 			return nil, err
@@ -202,7 +238,7 @@ func (nfs *nfsstorage) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 	return csiResp, nil
 }
 
-func (nfs *nfsstorage) createVolumeFrmPVCSource(req *csi.CreateVolumeRequest, size int64, storagePool string, srcVolumeID string) (csiResp *csi.CreateVolumeResponse, err error) {
+func (nfs *nfsstorage) createVolumeFrmPVCSource(ctx context.Context, req *csi.CreateVolumeRequest, size int64, storagePool string, srcVolumeID string) (csiResp *csi.CreateVolumeResponse, err error) {
 	klog.V(2).Infof("Called createVolumeFrmPVCSource")
 	defer func() {
 		if res := recover(); res != nil {
@@ -221,42 +257,42 @@ func (nfs *nfsstorage) createVolumeFrmPVCSource(req *csi.CreateVolumeRequest, si
 	}
 
 	// Look up the source volume
-	srcfsys, err := nfs.cs.api.GetFileSystemByID(sourceVolumeID)
+	srcfsys, err := nfs.cs.api.GetFileSystemByID(ctx, sourceVolumeID)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "volume not found: %d", sourceVolumeID)
 	}
 
-	// Check that the requested volume size matches the size of source volume
-	if srcfsys.Size != size {
-		return nil, status.Errorf(codes.InvalidArgument,
-			"volume %d has not valid size %d with requested %d ",
-			sourceVolumeID, srcfsys.Size, size)
+	// The clone may be larger than its source, it is grown once created
+	if err = validateCloneSize(srcVolumeID, srcfsys.Size, size); err != nil {
+		return nil, err
 	}
 
-	// Check that the requested storagePool matches the source
-	storagePoolID, err := nfs.cs.api.GetStoragePoolIDByName(storagePool)
+	// Check that the requested storagePool matches the source, the clone is a snapshot in its pool
+	storagePoolID, err := nfs.cs.api.GetStoragePoolIDByName(ctx, storagePool)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument,
 			"failed to get storagepool id by name: %s", storagePool)
 	}
-	if storagePoolID != srcfsys.PoolID {
-		return nil, status.Errorf(codes.InvalidArgument,
-			"source storagepool id differs from requested: %s", storagePool)
+	if err = validateClonePool(srcVolumeID, srcfsys.PoolID, storagePoolID, storagePool); err != nil {
+		return nil, err
 	}
 
-	newSnapshotName := req.GetName() // create snapshot using the original CreateVolumeRequest
+	newSnapshotName := nfs.fileSystemName // the clone is named like a new filesystem of the PV
 	newSnapshotParams := &api.FileSystemSnapshot{ParentID: sourceVolumeID, SnapshotName: newSnapshotName, WriteProtected: false}
 	klog.V(2).Infof("createVolumeFrmPVCSource creating filesystem with params: %v", newSnapshotParams)
 	// Create snapshot
-	newSnapshot, err := nfs.cs.api.CreateFileSystemSnapshot(newSnapshotParams)
+	newSnapshot, err := nfs.cs.api.CreateFileSystemSnapshot(ctx, newSnapshotParams)
 	if err != nil {
 		klog.Errorf("failed to create snapshot: %s error: %v", newSnapshotParams.SnapshotName, err.Error())
-		return nil, status.Errorf(codes.Internal, "failed to create snapshot, %v", err.Error())
+		return nil, status.Errorf(api.GRPCCode(err), "failed to create snapshot, %v", err.Error())
 	}
 	klog.V(2).Infof("createVolumeFrmPVCSource successfully created volume from clone with name: %s", newSnapshotName)
 	nfs.fileSystemID = newSnapshot.SnapshotID
+	if err = nfs.cs.expandFileSystemClone(ctx, newSnapshot.SnapshotID, srcfsys.Size, size); err != nil {
+		return nil, err
+	}
 
-	err = nfs.createExportPathAndAddMetadata()
+	err = nfs.createExportPathAndAddMetadata(ctx)
 	if err != nil {
 		klog.Errorf("failed to create export and metadata, %v", err)
 		return nil, err
@@ -265,13 +301,13 @@ func (nfs *nfsstorage) createVolumeFrmPVCSource(req *csi.CreateVolumeRequest, si
 }
 
 // CreateNFSVolume create volume method
-func (nfs *nfsstorage) CreateNFSVolume(req *csi.CreateVolumeRequest) (csiResp *csi.CreateVolumeResponse, err error) {
+func (nfs *nfsstorage) CreateNFSVolume(ctx context.Context, req *csi.CreateVolumeRequest) (csiResp *csi.CreateVolumeResponse, err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while creating CreateNFSVolume method " + fmt.Sprint(res))
 		}
 	}()
-	validnwlist, err := nfs.cs.api.OneTimeValidation(nfs.configmap["pool_name"], nfs.configmap["network_space"])
+	validnwlist, err := nfs.cs.api.OneTimeValidation(ctx, nfs.configmap["pool_name"], nfs.configmap["network_space"])
 	if err != nil {
 		klog.Errorf(err.Error())
 		return nil, err
@@ -279,12 +315,12 @@ func (nfs *nfsstorage) CreateNFSVolume(req *csi.CreateVolumeRequest) (csiResp *c
 	nfs.configmap["network_space"] = validnwlist
 	klog.V(4).Infof("networkspace validation success")
 
-	err = nfs.createFileSystem()
+	err = nfs.createFileSystem(ctx)
 	if err != nil {
 		klog.Errorf("failed to create file system, %v", err)
 		return nil, err
 	}
-	err = nfs.createExportPathAndAddMetadata()
+	err = nfs.createExportPathAndAddMetadata(ctx)
 	if err != nil {
 		klog.Errorf("failed to create export and metadata, %v", err)
 		return nil, err
@@ -292,20 +328,20 @@ func (nfs *nfsstorage) CreateNFSVolume(req *csi.CreateVolumeRequest) (csiResp *c
 	return nfs.getNfsCsiResponse(req), nil
 }
 
-func (nfs *nfsstorage) createExportPathAndAddMetadata() (err error) {
+func (nfs *nfsstorage) createExportPathAndAddMetadata(ctx context.Context) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while export directory" + fmt.Sprint(res))
 		}
 		if err != nil && nfs.fileSystemID != 0 {
 			klog.V(2).Infof("Seemes to be some problem reverting filesystem: %s", nfs.pVName)
-			if _, errDelFS := nfs.cs.api.DeleteFileSystem(nfs.fileSystemID); errDelFS != nil {
+			if _, errDelFS := nfs.cs.api.DeleteFileSystem(ctx, nfs.fileSystemID); errDelFS != nil {
 				klog.Errorf("failed to delete file system id: %d", nfs.fileSystemID)
 			}
 		}
 	}()
 
-	err = nfs.createExportPath()
+	err = nfs.createExportPath(ctx)
 	if err != nil {
 		klog.Errorf("failed to export path %v", err)
 		return
@@ -318,25 +354,31 @@ func (nfs *nfsstorage) createExportPathAndAddMetadata() (err error) {
 		}
 		if err != nil && nfs.exportID != 0 {
 			klog.V(2).Infof("Seemes to be some problem reverting created export id: %d", nfs.exportID)
-			if _, errDelExport := nfs.cs.api.DeleteExportPath(nfs.exportID); errDelExport != nil {
+			if _, errDelExport := nfs.cs.api.DeleteExportPath(ctx, nfs.exportID); errDelExport != nil {
 				klog.Errorf("failed to delete export path for file system id: %d", nfs.fileSystemID)
 			}
 		}
 	}()
-	metadata := make(map[string]interface{})
-	metadata["host.k8s.pvname"] = nfs.pVName
-	metadata["host.created_by"] = nfs.cs.GetCreatedBy()
+	metadata := nfs.cs.getVolumeMetadata(nfs.pVName, NFS, nfs.configmap)
+	metadata[NETWORKSPACE] = nfs.configmap["network_space"]
+	metadata[NETWORKSPACEIP] = nfs.ipAddress
 
-	_, err = nfs.cs.api.AttachMetadataToObject(nfs.fileSystemID, metadata)
+	_, err = nfs.cs.api.AttachMetadataToObject(ctx, nfs.fileSystemID, metadata)
 	if err != nil {
 		klog.Errorf("failed to attach metadata for file system %s, %v", nfs.pVName, err)
 		return
 	}
+	// a new filesystem or clone has no QoS policy assigned
+	err = nfs.cs.setQosPolicy(ctx, nfs.configmap, api.QosPolicyTypeFilesystem, nfs.fileSystemID, 0)
+	if err != nil {
+		klog.Errorf("failed to set QoS policy of file system %s, %v", nfs.pVName, err)
+		return
+	}
 	klog.V(4).Infof("metadata attached successfully for file system %s", nfs.pVName)
 	return
 }
 
-func (nfs *nfsstorage) createExportPath() (err error) {
+func (nfs *nfsstorage) createExportPath(ctx context.Context) (err error) {
 	permissionsMapArray, err := getPermissionMaps(nfs.configmap["nfs_export_permissions"])
 	if err != nil {
 		klog.Errorf("failed to parse permission map string %s", nfs.configmap["nfs_export_permissions"])
@@ -350,7 +392,7 @@ func (nfs *nfsstorage) createExportPath() (err error) {
 	exportFileSystem.SnapdirVisible = nfs.snapdirVisible
 	exportFileSystem.Export_path = nfs.exportpath
 	exportFileSystem.Permissionsput = append(exportFileSystem.Permissionsput, permissionsMapArray...)
-	exportResp, err := nfs.cs.api.ExportFileSystem(exportFileSystem)
+	exportResp, err := nfs.cs.api.ExportFileSystem(ctx, exportFileSystem)
 	if err != nil {
 		klog.Errorf("failed to create export path of filesystem %s", nfs.pVName)
 		return err
@@ -361,9 +403,9 @@ func (nfs *nfsstorage) createExportPath() (err error) {
 	return err
 }
 
-func (nfs *nfsstorage) createFileSystem() (err error) {
+func (nfs *nfsstorage) createFileSystem(ctx context.Context) (err error) {
 	namepool := nfs.configmap["pool_name"]
-	poolID, err := nfs.cs.api.GetStoragePoolIDByName(namepool)
+	poolID, err := nfs.cs.api.GetStoragePoolIDByName(ctx, namepool)
 	if err != nil {
 		klog.Errorf("failed to get GetPoolID by pool_name %s", namepool)
 		return err
@@ -375,11 +417,14 @@ func (nfs *nfsstorage) createFileSystem() (err error) {
 	ssd, _ := strconv.ParseBool(ssdEnabled)
 	mapRequest := make(map[string]interface{})
 	mapRequest["pool_id"] = poolID
-	mapRequest["name"] = nfs.pVName
+	mapRequest["name"] = nfs.fileSystemName
 	mapRequest["ssd_enabled"] = ssd
 	mapRequest["provtype"] = strings.ToUpper(nfs.configmap["provision_type"])
 	mapRequest["size"] = nfs.capacity
-	fileSystem, err := nfs.cs.api.CreateFilesystem(mapRequest)
+	if compressionEnabled, _ := getCompressionEnabled(nfs.configmap); compressionEnabled != nil { // already validated
+		mapRequest[COMPRESSIONENABLED] = *compressionEnabled
+	}
+	fileSystem, err := nfs.cs.api.CreateFilesystem(ctx, mapRequest)
 	if err != nil {
 		klog.Errorf("failed to create filesystem %s", nfs.pVName)
 		return err
@@ -389,6 +434,48 @@ func (nfs *nfsstorage) createFileSystem() (err error) {
 	return err
 }
 
+// selectNetworkSpaceIP chooses the network space of a new filesystem among the network_space parameter,
+// with the IP nodes mount it from, both kept in the volume context
+func (nfs *nfsstorage) selectNetworkSpaceIP(ctx context.Context) error {
+	networkSpace, ipAddress, err := nfs.cs.selectNetworkSpaceIP(ctx, nfs.configmap)
+	if err != nil {
+		msg := fmt.Sprintf("failed to get networkspace ipaddress, %v", err)
+		klog.Errorf(msg)
+		return status.Error(codes.InvalidArgument, msg)
+	}
+	nfs.configmap["network_space"] = networkSpace
+	nfs.ipAddress = ipAddress
+	klog.V(4).Infof("getNetworkSpaceIP ipAddress %s", nfs.ipAddress)
+	return nil
+}
+
+// setNetworkSpaceOf takes the network space and IP of an existing filesystem from its metadata. Filesystems
+// created without them, by older driver versions or adopted by name, get a network space chosen and recorded
+func (nfs *nfsstorage) setNetworkSpaceOf(ctx context.Context, fileSystemID int64) error {
+	networkSpace, err := nfs.cs.getMetadataValue(ctx, fileSystemID, NETWORKSPACE)
+	if err != nil {
+		return err
+	}
+	ipAddress, err := nfs.cs.getMetadataValue(ctx, fileSystemID, NETWORKSPACEIP)
+	if err != nil {
+		return err
+	}
+	if networkSpace != "" && ipAddress != "" {
+		nfs.configmap["network_space"] = networkSpace
+		nfs.ipAddress = ipAddress
+		return nil
+	}
+	if err = nfs.selectNetworkSpaceIP(ctx); err != nil {
+		return err
+	}
+	metadata := map[string]interface{}{NETWORKSPACE: nfs.configmap["network_space"], NETWORKSPACEIP: nfs.ipAddress}
+	if _, err = nfs.cs.api.AttachMetadataToObject(ctx, fileSystemID, metadata); err != nil {
+		klog.Errorf("failed to record network space of file system %d, %v", fileSystemID, err)
+		return status.Errorf(api.GRPCCode(err), "failed to record network space of file system %d: %v", fileSystemID, err)
+	}
+	return nil
+}
+
 func (nfs *nfsstorage) getNfsCsiResponse(req *csi.CreateVolumeRequest) *csi.CreateVolumeResponse {
 	infinidatVol := &infinidatVolume{
 		VolID:        fmt.Sprint(nfs.fileSystemID),
@@ -427,9 +514,9 @@ func (nfs *nfsstorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRe
 	}
 
 	nfs.uniqueID = volID
-	nfsDeleteErr := nfs.DeleteNFSVolume()
+	nfsDeleteErr := nfs.DeleteNFSVolume(ctx)
 	if nfsDeleteErr != nil {
-		if strings.Contains(nfsDeleteErr.Error(), "FILESYSTEM_NOT_FOUND") {
+		if api.HasErrorCode(nfsDeleteErr, api.ErrFileSystemNotFound) {
 			klog.Errorf("file system already delete from infinibox")
 			return &csi.DeleteVolumeResponse{}, nil
 		}
@@ -441,7 +528,7 @@ func (nfs *nfsstorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRe
 }
 
 // DeleteNFSVolume delete volume method
-func (nfs *nfsstorage) DeleteNFSVolume() (err error) {
+func (nfs *nfsstorage) DeleteNFSVolume(ctx context.Context) (err error) {
 	defer func() {
 		if res := recover(); res != nil {
 			err = errors.New("error while deleting filesystem " + fmt.Sprint(res))
@@ -449,17 +536,17 @@ func (nfs *nfsstorage) DeleteNFSVolume() (err error) {
 		}
 	}()
 
-	_, fileSystemErr := nfs.cs.api.GetFileSystemByID(nfs.uniqueID)
+	_, fileSystemErr := nfs.cs.api.GetFileSystemByID(ctx, nfs.uniqueID)
 	if fileSystemErr != nil {
 		klog.Errorf("failed to get file system by ID %d", nfs.uniqueID)
 		err = fileSystemErr
 		return
 	}
-	hasChild := nfs.cs.api.FileSystemHasChild(nfs.uniqueID)
+	hasChild := nfs.cs.api.FileSystemHasChild(ctx, nfs.uniqueID)
 	if hasChild {
 		metadata := make(map[string]interface{})
 		metadata[TOBEDELETED] = true
-		_, err = nfs.cs.api.AttachMetadataToObject(nfs.uniqueID, metadata)
+		_, err = nfs.cs.api.AttachMetadataToObject(ctx, nfs.uniqueID, metadata)
 		if err != nil {
 			klog.Errorf("failed to update host.k8s.to_be_deleted for filesystem %s error: %v", nfs.pVName, err)
 			err = errors.New("error while Set metadata host.k8s.to_be_deleted")
@@ -467,14 +554,14 @@ func (nfs *nfsstorage) DeleteNFSVolume() (err error) {
 		return
 	}
 
-	parentID := nfs.cs.api.GetParentID(nfs.uniqueID)
-	err = nfs.cs.api.DeleteFileSystemComplete(nfs.uniqueID)
+	parentID := nfs.cs.api.GetParentID(ctx, nfs.uniqueID)
+	err = nfs.cs.api.DeleteFileSystemComplete(ctx, nfs.uniqueID)
 	if err != nil {
 		klog.Errorf("failed to delete filesystem %s error: %v", nfs.pVName, err)
 		err = errors.New("error while delete file system")
 	}
 	if parentID != 0 {
-		err = nfs.cs.api.DeleteParentFileSystem(parentID)
+		err = nfs.cs.api.DeleteParentFileSystem(ctx, parentID)
 		if err != nil {
 			klog.Errorf("failed to delete filesystem's %s parent filesystems error: %v", nfs.pVName, err)
 		}
@@ -499,13 +586,13 @@ func (nfs *nfsstorage) ControllerPublishVolume(ctx context.Context, req *csi.Con
 	// TODO: revisit this as part of CSIC-343
 	_, err = nfs.cs.accessModesHelper.IsValidAccessModeNfs(req)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 
 	exportPermissionMapArray, err := getPermissionMaps(req.GetVolumeContext()["nfs_export_permissions"])
 	if err != nil {
 		klog.Errorf("failed to retrieve permission maps, %v", err)
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(api.GRPCCode(err), err.Error())
 	}
 	klog.V(4).Infof("NFS export permissions for volume ID %s and export ID %s: %v", volumeID, exportID, exportPermissionMapArray)
 
@@ -521,10 +608,10 @@ func (nfs *nfsstorage) ControllerPublishVolume(ctx context.Context, req *csi.Con
 	}
 	nodeIP := nodeNameIP[1]
 	exportid, _ := strconv.Atoi(exportID)
-	_, err = nfs.cs.api.AddNodeInExport(exportid, access, noRootSquash, nodeIP)
+	_, err = nfs.cs.api.AddNodeInExport(ctx, exportid, access, noRootSquash, nodeIP)
 	if err != nil {
 		klog.Errorf("failed to add export rule, %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to add export rule  %s", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to add export rule  %s", err)
 	}
 	return &csi.ControllerPublishVolumeResponse{}, nil
 }
@@ -533,10 +620,10 @@ func (nfs *nfsstorage) ControllerUnpublishVolume(ctx context.Context, req *csi.C
 	voltype := req.GetVolumeId()
 	volproto := strings.Split(voltype, "$$")
 	fileID, _ := strconv.ParseInt(volproto[0], 10, 64)
-	err := nfs.cs.api.DeleteExportRule(fileID, req.GetNodeId())
+	err := nfs.cs.api.DeleteExportRule(ctx, fileID, req.GetNodeId())
 	if err != nil {
 		klog.Errorf("failed to delete Export Rule fileystemID %d error %v", fileID, err)
-		return nil, status.Errorf(codes.Internal, "failed to delete Export Rule  %v", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to delete Export Rule  %v", err)
 	}
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
@@ -555,7 +642,7 @@ func (nfs *nfsstorage) ValidateVolumeCapabilities(ctx context.Context, req *csi.
 	}
 
 	klog.V(4).Infof("volID: %d", volID)
-	fs, err := nfs.cs.api.GetFileSystemByID(volID)
+	fs, err := nfs.cs.api.GetFileSystemByID(ctx, volID)
 	if err != nil {
 		klog.Errorf("Failed to find volume ID: %d, %v", volID, err)
 		err = status.Errorf(codes.NotFound, "ValidateVolumeCapabilities failed to find volume ID: %d, %v", volID, err)
@@ -576,16 +663,176 @@ func (nfs *nfsstorage) ValidateVolumeCapabilities(ctx context.Context, req *csi.
 	return
 }
 
+// ListVolumes returns all nfs filesystems, read in bulk, paging across protocols is done by the caller
 func (nfs *nfsstorage) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	return &csi.ListVolumesResponse{}, nil
+	pvObjects, err := nfs.cs.getPVObjects(ctx, NFS)
+	if err != nil {
+		klog.Errorf("failed to list nfs filesystems: %v", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to list nfs filesystems: %v", err)
+	}
+	entries := []*csi.ListVolumesResponse_Entry{}
+	if len(pvObjects) == 0 {
+		return &csi.ListVolumesResponse{Entries: entries}, nil
+	}
+	fileSystemIDs := make([]int64, 0, len(pvObjects))
+	for _, md := range pvObjects {
+		fileSystemIDs = append(fileSystemIDs, int64(md.ObjectId))
+	}
+	// filesystems deleted while listing are left out of the result
+	fileSystems, err := nfs.cs.api.GetFileSystemsByIDs(ctx, fileSystemIDs)
+	if err != nil {
+		klog.Errorf("failed to get nfs filesystems: %v", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get nfs filesystems: %v", err)
+	}
+	for _, fileSystem := range fileSystems {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      strconv.FormatInt(fileSystem.ID, 10),
+				CapacityBytes: fileSystem.Size,
+			},
+		})
+	}
+	klog.V(4).Infof("listed %d nfs filesystems", len(entries))
+	return &csi.ListVolumesResponse{Entries: entries}, nil
 }
 
+// ListSnapshots returns the nfs snapshots matching the request filters, paging across protocols is done by the caller.
+// A snapshot of a snapshot is listed with the filesystem at the top of its snapshot chain as source
 func (nfs *nfsstorage) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return &csi.ListSnapshotsResponse{}, nil
+	snapshots := []api.FileSystem{}
+	sourceIDs := make(map[int64]int64)
+	if req.GetSnapshotId() != "" {
+		volproto, err := validateVolumeID(req.GetSnapshotId())
+		if err != nil {
+			klog.V(4).Infof("snapshot ID %s is not valid, no snapshots listed: %v", req.GetSnapshotId(), err)
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		snapshotID, err := strconv.ParseInt(volproto.VolumeID, 10, 64)
+		if err != nil {
+			klog.V(4).Infof("snapshot ID %s is not valid, no snapshots listed", req.GetSnapshotId())
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		snapshot, err := nfs.cs.api.GetFileSystemByID(ctx, snapshotID)
+		if err != nil {
+			if api.HasErrorCode(err, api.ErrFileSystemNotFound) {
+				return &csi.ListSnapshotsResponse{}, nil
+			}
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshot %d: %v", snapshotID, err)
+		}
+		// filesystems which are not write protected are filesystems cloned or restored from a snapshot
+		if snapshot.ParentID == 0 || !snapshot.WriteProtected {
+			return &csi.ListSnapshotsResponse{}, nil
+		}
+		sourceID, err := nfs.getSnapshotSourceID(ctx, snapshot)
+		if err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get source filesystem of snapshot %d: %v", snapshotID, err)
+		}
+		snapshots = append(snapshots, *snapshot)
+		sourceIDs[snapshot.ID] = sourceID
+	} else {
+		fileSystemIDs := []int64{}
+		if req.GetSourceVolumeId() != "" {
+			volproto, err := validateVolumeID(req.GetSourceVolumeId())
+			if err != nil {
+				klog.V(4).Infof("source volume ID %s is not valid, no snapshots listed: %v", req.GetSourceVolumeId(), err)
+				return &csi.ListSnapshotsResponse{}, nil
+			}
+			fileSystemID, err := strconv.ParseInt(volproto.VolumeID, 10, 64)
+			if err != nil {
+				klog.V(4).Infof("source volume ID %s is not valid, no snapshots listed", req.GetSourceVolumeId())
+				return &csi.ListSnapshotsResponse{}, nil
+			}
+			fileSystemIDs = append(fileSystemIDs, fileSystemID)
+		} else {
+			// filesystems deleted while they have snapshots are only marked to be deleted
+			parents, err := nfs.cs.getSnapshotParents(ctx, NFS)
+			if err != nil {
+				return nil, status.Errorf(api.GRPCCode(err), "failed to list nfs filesystems: %v", err)
+			}
+			for _, md := range parents {
+				fileSystemIDs = append(fileSystemIDs, int64(md.ObjectId))
+			}
+		}
+		var err error
+		if snapshots, sourceIDs, err = nfs.getFileSystemSnapshots(ctx, fileSystemIDs); err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshots of nfs filesystems: %v", err)
+		}
+	}
+
+	entries := []*csi.ListSnapshotsResponse_Entry{}
+	for _, snapshot := range snapshots {
+		entries = append(entries, &csi.ListSnapshotsResponse_Entry{
+			Snapshot: &csi.Snapshot{
+				SnapshotId:     strconv.FormatInt(snapshot.ID, 10) + "$$" + NFS,
+				SourceVolumeId: strconv.FormatInt(sourceIDs[snapshot.ID], 10) + "$$" + NFS,
+				SizeBytes:      snapshot.Size,
+				CreationTime:   getTimestamp(int64(snapshot.CreatedAt)),
+				ReadyToUse:     true,
+			},
+		})
+	}
+	klog.V(4).Infof("listed %d nfs snapshots", len(entries))
+	return &csi.ListSnapshotsResponse{Entries: entries}, nil
+}
+
+// getFileSystemSnapshots returns the snapshots descending from the given filesystems, snapshots of snapshots included,
+// reading the children of each generation in bulk, and the ID of the filesystem each snapshot descends from by snapshot ID
+func (nfs *nfsstorage) getFileSystemSnapshots(ctx context.Context, fileSystemIDs []int64) (snapshots []api.FileSystem, sourceIDs map[int64]int64, err error) {
+	snapshots = []api.FileSystem{}
+	sourceIDs = make(map[int64]int64)
+	for _, fileSystemID := range fileSystemIDs {
+		sourceIDs[fileSystemID] = fileSystemID
+	}
+	parentIDs := fileSystemIDs
+	for len(parentIDs) > 0 {
+		children, err := nfs.cs.api.GetFileSystemsByParentIDs(ctx, parentIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		parentIDs = []int64{}
+		for _, child := range children {
+			// children which are not write protected are filesystems cloned or restored from a snapshot
+			if !child.WriteProtected {
+				continue
+			}
+			if _, seen := sourceIDs[child.ID]; seen {
+				continue
+			}
+			sourceIDs[child.ID] = sourceIDs[child.ParentID]
+			snapshots = append(snapshots, child)
+			parentIDs = append(parentIDs, child.ID)
+		}
+	}
+	return snapshots, sourceIDs, nil
+}
+
+// getSnapshotSourceID returns the ID of the filesystem at the top of the snapshot chain of snapshot
+func (nfs *nfsstorage) getSnapshotSourceID(ctx context.Context, snapshot *api.FileSystem) (int64, error) {
+	parentID := snapshot.ParentID
+	for {
+		parent, err := nfs.cs.api.GetFileSystemByID(ctx, parentID)
+		if err != nil {
+			return 0, err
+		}
+		if parent.ParentID == 0 || !parent.WriteProtected {
+			return parent.ID, nil
+		}
+		parentID = parent.ParentID
+	}
 }
 
-func (nfs *nfsstorage) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
-	return &csi.GetCapacityResponse{}, nil
+// GetCapacity returns the free capacity of the StorageClass pool
+func (nfs *nfsstorage) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (resp *csi.GetCapacityResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from NFS GetCapacity  " + fmt.Sprint(res))
+		}
+	}()
+	capacity, err := nfs.cs.getPoolCapacity(ctx, req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetCapacityResponse{AvailableCapacity: capacity}, nil
 }
 
 func (nfs *nfsstorage) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
@@ -611,7 +858,7 @@ func (nfs *nfsstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapsh
 	}
 
 	sourceFilesystemID, _ := strconv.ParseInt(volproto.VolumeID, 10, 64)
-	snapshotArray, err := nfs.cs.api.GetSnapshotByName(snapshotName)
+	snapshotArray, err := nfs.cs.api.GetSnapshotByName(ctx, snapshotName)
 	if len(*snapshotArray) > 0 {
 		for _, snap := range *snapshotArray {
 			if snap.ParentId == sourceFilesystemID {
@@ -622,7 +869,7 @@ func (nfs *nfsstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapsh
 						SizeBytes:      snap.Size,
 						SnapshotId:     snapshotID,
 						SourceVolumeId: srcVolumeId,
-						CreationTime:   timestamppb.Now(),
+						CreationTime:   getTimestamp(snap.CreatedAt),
 						ReadyToUse:     true,
 					},
 				}, nil
@@ -634,24 +881,36 @@ func (nfs *nfsstorage) CreateSnapshot(ctx context.Context, req *csi.CreateSnapsh
 		return nil, status.Error(codes.AlreadyExists, "snapshot with already existing name and different source volume ID")
 	}
 
+	lockExpiresAt, err := nfs.cs.getSnapshotLockExpiry(ctx, sourceFilesystemID)
+	if err != nil {
+		klog.Errorf("failed to get snapshot lock of file system %d, %v", sourceFilesystemID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshot lock of file system %d: %v", sourceFilesystemID, err)
+	}
 	fileSystemSnapshot := &api.FileSystemSnapshot{
 		ParentID:       sourceFilesystemID,
 		SnapshotName:   snapshotName,
 		WriteProtected: true,
+		LockExpiresAt:  lockExpiresAt,
 	}
 
-	resp, err := nfs.cs.api.CreateFileSystemSnapshot(fileSystemSnapshot)
+	resp, err := nfs.cs.api.CreateFileSystemSnapshot(ctx, fileSystemSnapshot)
 	if err != nil {
 		klog.Errorf("failed to create snapshot %s error %v", snapshotName, err)
 		return
 	}
 
+	// map the snapshot to its VolumeSnapshot, a failure leaves the snapshot usable
+	metadata := nfs.cs.getSnapshotMetadata(NFS, req.GetParameters())
+	if _, metadataErr := nfs.cs.api.AttachMetadataToObject(ctx, resp.SnapshotID, metadata); metadataErr != nil {
+		klog.Errorf("failed to attach metadata to snapshot %s, %v", snapshotName, metadataErr)
+	}
+
 	snapshotID = strconv.FormatInt(resp.SnapshotID, 10) + "$$" + volproto.StorageType
 	snapshot := &csi.Snapshot{
 		SnapshotId:     snapshotID,
 		SourceVolumeId: srcVolumeId,
 		ReadyToUse:     true,
-		CreationTime:   timestamppb.Now(),
+		CreationTime:   getTimestamp(resp.CreatedAt),
 		SizeBytes:      resp.Size,
 	}
 	klog.V(4).Infof("CreateFileSystemSnapshot resp: %v", snapshot)
@@ -668,9 +927,9 @@ func (nfs *nfsstorage) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapsh
 
 	snapshotID, _ := strconv.ParseInt(req.GetSnapshotId(), 10, 64)
 	nfs.uniqueID = snapshotID
-	nfsSnapDeleteErr := nfs.DeleteNFSVolume()
+	nfsSnapDeleteErr := nfs.DeleteNFSVolume(ctx)
 	if nfsSnapDeleteErr != nil {
-		if strings.Contains(nfsSnapDeleteErr.Error(), "FILESYSTEM_NOT_FOUND") {
+		if api.HasErrorCode(nfsSnapDeleteErr, api.ErrFileSystemNotFound) {
 			klog.Errorf("snapshot already delete from infinibox")
 			deleteSnapshot = &csi.DeleteSnapshotResponse{}
 			return
@@ -705,7 +964,7 @@ func (nfs *nfsstorage) ControllerExpandVolume(ctx context.Context, req *csi.Cont
 	// Expand file system size
 	var fileSys api.FileSystem
 	fileSys.Size = capacity
-	_, err = nfs.cs.api.UpdateFilesystem(ID, fileSys)
+	_, err = nfs.cs.api.UpdateFilesystem(ctx, ID, fileSys)
 	if err != nil {
 		klog.Errorf("failed to update file system %v", err)
 		return
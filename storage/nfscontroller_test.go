@@ -12,6 +12,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (suite *NFSControllerSuite) SetupTest() {
@@ -49,19 +51,20 @@ func (suite *NFSControllerSuite) Test_CreateVolume_NetworkSpaceIP_Error() {
 
 	networkSpaceErr := errors.New("Some error")
 
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(nil, networkSpaceErr)
 	_, err := service.CreateVolume(context.Background(), createVolReq)
 	assert.NotNil(suite.T(), err, "expected to fail: get IP address from networkspace")
 }
 
-func (suite *NFSControllerSuite) Test_CreateVolume_GetFileSystemByName_Error() {
+func (suite *NFSControllerSuite) Test_CreateVolume_GetFileSystemByPVName_Error() {
 	service := nfsstorage{cs: *suite.cs}
 	parameterMap := getCreateVolumeParameter()
 	createVolReq := getNFSCreateVolumeRequest("PVName", parameterMap)
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, filesystemErr)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, filesystemErr)
 	suite.api.On("GetStoragePoolIDByName", parameterMap["pool_name"]).Return(100, nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return(nil, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(getFileSystem(), nil)
@@ -77,7 +80,9 @@ func (suite *NFSControllerSuite) Test_CreateVolume_FileNameExist_exportError() {
 	expectedError := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACE).Return("network_space1", nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACEIP).Return("10.20.20.50", nil)
 	suite.api.On("GetExportByFileSystem", mock.Anything).Return(nil, expectedError)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
@@ -92,7 +97,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_FileNameExist_exportArryEmpty
 	exportResp := &[]api.ExportResponse{}
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(getFileSystem(), nil)
 	suite.api.On("GetExportByFileSystem", mock.Anything).Return(exportResp, nil)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
@@ -105,13 +110,56 @@ func (suite *NFSControllerSuite) Test_CreateVolume_FileNameExist_sucess() {
 	parameterMap := getCreateVolumeParameter()
 	createVolReq := getNFSCreateVolumeRequest("PVName", parameterMap)
 
-	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACE).Return("network_space1", nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACEIP).Return("10.20.20.51", nil)
 	suite.api.On("GetExportByFileSystem", mock.Anything).Return(getExportPath(), nil)
 
 	resp, err := service.CreateVolume(context.Background(), createVolReq)
 	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume when file system exists")
 	assert.NotNil(suite.T(), resp, "CreateVolume ok response should be non-empty")
+	assert.Equal(suite.T(), "10.20.20.51", resp.GetVolume().GetVolumeContext()["ipAddress"], "expected the recorded IP")
+	suite.api.AssertNotCalled(suite.T(), "GetNetworkSpaceByName", mock.Anything)
+}
+
+func (suite *NFSControllerSuite) Test_CreateVolume_FileNameExist_noNetworkSpaceMetadata() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getNFSCreateVolumeRequest("PVName", parameterMap)
+
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACE).Return("", nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACEIP).Return("", nil)
+	suite.api.On("GetNetworkSpaceByName", "network_space1").Return(getNetworkSpace(), nil)
+	suite.api.On("AttachMetadataToObject", int64(1), mock.Anything).Return(nil, nil)
+	suite.api.On("GetExportByFileSystem", mock.Anything).Return(getExportPath(), nil)
+
+	resp, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: CreateVolume when file system exists")
+	assert.Equal(suite.T(), "10.20.20.50", resp.GetVolume().GetVolumeContext()["ipAddress"], "expected the selected IP")
+	suite.api.AssertCalled(suite.T(), "AttachMetadataToObject", int64(1), mock.MatchedBy(func(metadata map[string]interface{}) bool {
+		return metadata[NETWORKSPACE] == "network_space1" && metadata[NETWORKSPACEIP] == "10.20.20.50"
+	}))
+}
+
+func (suite *NFSControllerSuite) Test_CreateVolume_retryAfterMetadataError() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getNFSCreateVolumeRequest("PVName", parameterMap)
+
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
+	suite.api.On("GetFileSystemByPVName", "PVName").Return(nil, &api.APIError{Code: api.ErrFileSystemNotFound})
+	suite.api.On("GetFileSystemByName", "PVName").Return(getFileSystem(), nil)
+	suite.api.On("GetMetadataValue", int64(1), PVNAME).Return("", nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACE).Return("", nil)
+	suite.api.On("GetMetadataValue", int64(1), NETWORKSPACEIP).Return("", nil)
+	suite.api.On("AttachMetadataToObject", int64(1), mock.Anything).Return(nil, nil)
+	suite.api.On("GetExportByFileSystem", int64(1)).Return(getExportPath(), nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected to succeed: nfs CreateVolume retried after attaching metadata failed")
+	suite.api.AssertCalled(suite.T(), "AttachMetadataToObject", int64(1), mock.Anything)
+	suite.api.AssertNotCalled(suite.T(), "CreateFilesystem", mock.Anything)
 }
 
 func (suite *NFSControllerSuite) Test_CreateVolume_OneTimeValidation_fail() {
@@ -121,7 +169,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_OneTimeValidation_fail() {
 	expectedError := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return("", expectedError)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
@@ -135,7 +183,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_StoragePoolIDByName_Error() {
 	expectedError := errors.New("failed to create volume Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return("networkspace", nil)
 	suite.api.On("GetStoragePoolIDByName", parameterMap["pool_name"]).Return(0, expectedError)
 
@@ -151,7 +199,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_CreateFilesystem_Error() {
 	expectedError := errors.New("failed to create volume Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return("networkspace", nil)
 	suite.api.On("GetStoragePoolIDByName", parameterMap["pool_name"]).Return(100, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(0, expectedError)
@@ -168,7 +216,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_createExportPath_Error() {
 	expectedError := errors.New("failed to create volume Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return("networkspace", nil)
 	suite.api.On("GetStoragePoolIDByName", parameterMap["pool_name"]).Return(100, nil)
 	suite.api.On("CreateFilesystem", mock.Anything).Return(1, nil)
@@ -185,7 +233,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_success() {
 	createVolReq := getNFSCreateVolumeRequest("PVName", parameterMap)
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	suite.api.On("OneTimeValidation", mock.Anything, mock.Anything).Return("networkspace", nil)
 	suite.api.On("GetStoragePoolIDByName", parameterMap["pool_name"]).Return(100, nil)
@@ -208,7 +256,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_Invalid_volumeID() {
 	createVolReq.GetVolumeContentSource().GetSnapshot().SnapshotId = "a$$nfs"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
 	assert.NotNil(suite.T(), err.Error(), "failed to get filesystem name")
@@ -221,7 +269,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_Invalid_volumeID2()
 	createVolReq.GetVolumeContentSource().GetSnapshot().SnapshotId = "a$$nfs$$123"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
 	assert.NotNil(suite.T(), err.Error(), "invalid size")
@@ -235,7 +283,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_GetFileSystemByID_Er
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(nil, filesystemErr)
 
 	_, err := service.CreateVolume(context.Background(), createVolReq)
@@ -249,7 +297,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_invalidSize() {
 	createVolReq.GetVolumeContentSource().GetSnapshot().SnapshotId = "1$$nfs"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(1, nil)
 
@@ -265,7 +313,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_GetStoragePoolIDByNa
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(nil, filesystemErr)
 
@@ -280,7 +328,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_poolID_name_invalid(
 	createVolReq.GetVolumeContentSource().GetSnapshot().SnapshotId = "1$$nfs"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 101
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -297,7 +345,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_createSnapshot_faile
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -315,7 +363,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_exportPath_failed()
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -334,7 +382,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_metadatafailed() {
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -353,7 +401,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Snapshot_Success() {
 	createVolReq.GetVolumeContentSource().GetSnapshot().SnapshotId = "1$$nfs"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -372,7 +420,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Clone_Success() {
 	createVolReq.GetVolumeContentSource().GetVolume().VolumeId = "1$$nfs"
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -392,7 +440,7 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Clone_failed() {
 	filesystemErr := errors.New("Some error")
 
 	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
-	suite.api.On("GetFileSystemByName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
 	var poolID int64 = 100
 	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(poolID, nil)
@@ -404,6 +452,81 @@ func (suite *NFSControllerSuite) Test_CreateVolume_Clone_failed() {
 	assert.NotNil(suite.T(), err.Error(), "failed to clone the volume")
 }
 
+func (suite *NFSControllerSuite) Test_CreateVolume_Clone_larger() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getCreateVolumeCloneRequest("PVName", parameterMap)
+	createVolReq.GetVolumeContentSource().GetVolume().VolumeId = "1$$nfs"
+	createVolReq.CapacityRange.RequiredBytes = 200 * gib
+
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(getFileSystem().PoolID, nil)
+	suite.api.On("CreateFileSystemSnapshot", mock.Anything).Return(GetFileSystemSnapshotResponce(2), nil)
+	suite.api.On("UpdateFilesystem", int64(2), api.FileSystem{Size: 200 * gib}).Return(api.FileSystem{ID: 2, Size: 200 * gib}, nil)
+	suite.api.On("ExportFileSystem", mock.Anything).Return(getExportResponseValue(), nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Nil(suite.T(), err, "expected clone success")
+	suite.api.AssertCalled(suite.T(), "UpdateFilesystem", int64(2), api.FileSystem{Size: 200 * gib})
+}
+
+func (suite *NFSControllerSuite) Test_CreateVolume_Clone_expandFailed() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getCreateVolumeCloneRequest("PVName", parameterMap)
+	createVolReq.GetVolumeContentSource().GetVolume().VolumeId = "1$$nfs"
+	createVolReq.CapacityRange.RequiredBytes = 200 * gib
+
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(getFileSystem().PoolID, nil)
+	suite.api.On("CreateFileSystemSnapshot", mock.Anything).Return(GetFileSystemSnapshotResponce(2), nil)
+	suite.api.On("UpdateFilesystem", int64(2), api.FileSystem{Size: 200 * gib}).Return(nil, errors.New("some error"))
+	suite.api.On("DeleteFileSystemComplete", int64(2)).Return(nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.NotNil(suite.T(), err, "expected to fail: clone not expanded")
+	suite.api.AssertCalled(suite.T(), "DeleteFileSystemComplete", int64(2))
+	suite.api.AssertNotCalled(suite.T(), "ExportFileSystem", mock.Anything)
+}
+
+func (suite *NFSControllerSuite) Test_CreateVolume_Clone_otherPool() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getCreateVolumeCloneRequest("PVName", parameterMap)
+	createVolReq.GetVolumeContentSource().GetVolume().VolumeId = "1$$nfs"
+
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
+	suite.api.On("GetStoragePoolIDByName", mock.Anything).Return(getFileSystem().PoolID+1, nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: clone in another pool")
+	assert.Contains(suite.T(), err.Error(), "another pool is not supported")
+	suite.api.AssertNotCalled(suite.T(), "CreateFileSystemSnapshot", mock.Anything)
+}
+
+func (suite *NFSControllerSuite) Test_CreateVolume_Clone_smaller() {
+	service := nfsstorage{cs: *suite.cs}
+	parameterMap := getCreateVolumeParameter()
+	createVolReq := getCreateVolumeCloneRequest("PVName", parameterMap)
+	createVolReq.GetVolumeContentSource().GetVolume().VolumeId = "1$$nfs"
+	createVolReq.CapacityRange.RequiredBytes = 50 * gib
+
+	suite.api.On("GetNetworkSpaceByName", mock.Anything).Return(getNetworkSpace(), nil)
+	suite.api.On("GetFileSystemByPVName", mock.Anything).Return(nil, nil)
+	suite.api.On("GetFileSystemByID", mock.Anything).Return(getFileSystem(), nil)
+
+	_, err := service.CreateVolume(context.Background(), createVolReq)
+	assert.NotNil(suite.T(), err, "expected to fail: clone smaller than its source")
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err))
+}
+
 //===========================================================================
 func (suite *NFSControllerSuite) Test_NfsControllerExpandVolume_VolumeID_empty() {
 	service := nfsstorage{cs: *suite.cs}
@@ -516,7 +639,9 @@ func (suite *NFSControllerSuite) Test_NfsCreateSnapshot_CreateFileSystemS_succes
 	filesystem.WriteProtected = false
 
 	suite.api.On("GetSnapshotByName", mock.Anything).Return(fileSysSnapshotRespArry, nil)
+	suite.api.On("GetMetadataValue", int64(1), SNAPSHOTLOCK).Return("", nil)
 	suite.api.On("CreateFileSystemSnapshot", mock.Anything).Return(filesystem, nil)
+	suite.api.On("AttachMetadataToObject", mock.Anything, mock.Anything).Return(nil, nil)
 	service := nfsstorage{cs: *suite.cs}
 	_, err := service.CreateSnapshot(context.Background(), getNfsCreateSnapshotRequest("1$$nfs"))
 	assert.Nil(suite.T(), err, "expected to succeed: CreateSnapshot CreateFileSystemSnapshot")
@@ -552,7 +677,7 @@ func (suite *NFSControllerSuite) Test_NfsDeleteSnapshot_Error() {
 func (suite *NFSControllerSuite) Test_NfsDeleteSnapshot_file_not_found() {
 	service := nfsstorage{cs: *suite.cs, uniqueID: 100}
 	var snapshotID int64 = 100
-	expectedErr := errors.New("FILESYSTEM_NOT_FOUND")
+	expectedErr := &api.APIError{Code: api.ErrFileSystemNotFound}
 	suite.api.On("GetFileSystemByID", snapshotID).Return(nil, expectedErr)
 	_, err := service.DeleteSnapshot(context.Background(), getNfsDeleteSnapshotRequest("100"))
 	assert.Nil(suite.T(), err, "expected to fail: NfsDeleteSnapshot GetFileSystemByID fs not found")
@@ -561,9 +686,9 @@ func (suite *NFSControllerSuite) Test_NfsDeleteSnapshot_file_not_found() {
 func (suite *NFSControllerSuite) Test_NfsDeleteNFSVolume_GetFileSystemByID_error() {
 	service := nfsstorage{cs: *suite.cs, uniqueID: 100}
 	var snapshotID int64 = 100
-	expectedErr := errors.New("FILESYSTEM_NOT_FOUND")
+	expectedErr := &api.APIError{Code: api.ErrFileSystemNotFound}
 	suite.api.On("GetFileSystemByID", snapshotID).Return(nil, expectedErr)
-	err := service.DeleteNFSVolume()
+	err := service.DeleteNFSVolume(context.Background())
 	assert.NotNil(suite.T(), err, "expected to fail: DeleteNFSVolume GetFileSystemByID fs not found")
 	assert.Equal(suite.T(), expectedErr, err, "Error not returned as expected")
 }
@@ -573,7 +698,7 @@ func (suite *NFSControllerSuite) Test_NfsDeleteNFSVolume_GetFileSystemByID_Inval
 	snapshotID := 100999999999999
 	expectedErr := errors.New("Invalid_ID")
 	suite.api.On("GetFileSystemByID", snapshotID).Return(nil, expectedErr)
-	err := service.DeleteNFSVolume()
+	err := service.DeleteNFSVolume(context.Background())
 	assert.NotNil(suite.T(), err, "expected to fail: DeleteNFSVolume GetFileSystemByID invalid ID")
 }
 
@@ -589,7 +714,7 @@ func (suite *NFSControllerSuite) Test_NfsDeleteNFSVolume_Success() {
 	suite.api.On("GetParentID", snapshotID).Return(parentID)
 	suite.api.On("DeleteFileSystemComplete", snapshotID).Return(nil)
 	suite.api.On("DeleteParentFileSystem", parentID).Return(nil)
-	err := service.DeleteNFSVolume()
+	err := service.DeleteNFSVolume(context.Background())
 	assert.Nil(suite.T(), err, "expected to succeed: DeleteNFSVolume")
 }
 
@@ -608,7 +733,7 @@ func (suite *NFSControllerSuite) Test_DeleteVolume_InvalidaID() {
 func (suite *NFSControllerSuite) Test_DeleteVolume_fileNotFound_success() {
 	service := nfsstorage{cs: *suite.cs}
 	delValReq := getNFSDeletRequest()
-	expectedErr := errors.New("FILESYSTEM_NOT_FOUND")
+	expectedErr := &api.APIError{Code: api.ErrFileSystemNotFound}
 	suite.api.On("GetFileSystemByID", mock.Anything).Return(nil, expectedErr)
 	_, err := service.DeleteVolume(context.Background(), delValReq)
 	assert.Nil(suite.T(), err, "expected to succeed: DeleteVolume when fs not found")
@@ -738,6 +863,115 @@ func (suite *NFSControllerSuite) Test_ControllerUnpublishVolume_DeleteExportRule
 	assert.Nil(suite.T(), err, "expected to succeed: ControllerUnpublishVolume when DeleteExportRule succeeds")
 }
 
+func (suite *NFSControllerSuite) Test_ListVolumes_success() {
+	service := nfsstorage{cs: *suite.cs}
+	pvNames := []api.Metadata{
+		{ObjectId: 300, Key: PVNAME, Value: "pvc-nfs"},
+		{ObjectId: 301, Key: PVNAME, Value: "pvc-legacy"},
+	}
+	protocols := []api.Metadata{{ObjectId: 300, Key: STORAGEPROTOCOL, Value: NFS}}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(pvNames, nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(protocols, nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return([]api.Metadata{}, nil)
+	suite.api.On("GetFileSystemsByIDs", []int64{300}).Return([]api.FileSystem{{ID: 300, Size: 1073741824}}, nil)
+	resp, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ListVolumes")
+	// the legacy filesystem is skipped as its PV cannot be resolved outside a cluster
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "300", resp.Entries[0].Volume.VolumeId)
+}
+
+func (suite *NFSControllerSuite) Test_ListSnapshots_success() {
+	service := nfsstorage{cs: *suite.cs}
+	pvNames := []api.Metadata{{ObjectId: 300, Key: PVNAME, Value: "pvc-nfs"}}
+	protocols := []api.Metadata{{ObjectId: 300, Key: STORAGEPROTOCOL, Value: NFS}}
+	children := []api.FileSystem{
+		{ID: 400, ParentID: 300, Size: 1073741824, WriteProtected: true, CreatedAt: 1609459200000},
+		{ID: 401, ParentID: 300, Size: 1073741824},
+	}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(pvNames, nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(protocols, nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return([]api.Metadata{}, nil)
+	nested := api.FileSystem{ID: 500, ParentID: 400, Size: 1073741824, WriteProtected: true}
+	suite.api.On("GetFileSystemsByParentIDs", []int64{300}).Return(children, nil)
+	suite.api.On("GetFileSystemsByParentIDs", []int64{400}).Return([]api.FileSystem{nested}, nil)
+	suite.api.On("GetFileSystemsByParentIDs", []int64{500}).Return([]api.FileSystem{}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ListSnapshots")
+	assert.Equal(suite.T(), 2, len(resp.Entries), "expected the writable clone to be skipped")
+	assert.Equal(suite.T(), "400$$nfs", resp.Entries[0].Snapshot.SnapshotId)
+	assert.Equal(suite.T(), "300$$nfs", resp.Entries[0].Snapshot.SourceVolumeId)
+	assert.Equal(suite.T(), "500$$nfs", resp.Entries[1].Snapshot.SnapshotId)
+	assert.Equal(suite.T(), "300$$nfs", resp.Entries[1].Snapshot.SourceVolumeId, "expected the filesystem at the top of the snapshot chain")
+}
+
+func (suite *NFSControllerSuite) Test_ListSnapshots_bySnapshotID_nested() {
+	service := nfsstorage{cs: *suite.cs}
+	suite.api.On("GetFileSystemByID", int64(500)).Return(api.FileSystem{ID: 500, ParentID: 400, WriteProtected: true}, nil)
+	suite.api.On("GetFileSystemByID", int64(400)).Return(api.FileSystem{ID: 400, ParentID: 300, WriteProtected: true}, nil)
+	suite.api.On("GetFileSystemByID", int64(300)).Return(api.FileSystem{ID: 300}, nil)
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "500$$nfs"})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ListSnapshots by snapshot ID")
+	assert.Equal(suite.T(), 1, len(resp.Entries))
+	assert.Equal(suite.T(), "300$$nfs", resp.Entries[0].Snapshot.SourceVolumeId)
+}
+
+func (suite *NFSControllerSuite) Test_ListSnapshots_error() {
+	service := nfsstorage{cs: *suite.cs}
+	suite.api.On("GetFileSystemsByParentIDs", []int64{300}).Return(nil, errors.New("some error"))
+	_, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SourceVolumeId: "300$$nfs"})
+	assert.NotNil(suite.T(), err, "expected to fail: nfs ListSnapshots by source volume ID")
+}
+
+func (suite *NFSControllerSuite) Test_ListSnapshots_bySnapshotID_notFound() {
+	service := nfsstorage{cs: *suite.cs}
+	suite.api.On("GetFileSystemByID", int64(400)).Return(nil, &api.APIError{Code: api.ErrFileSystemNotFound})
+	resp, err := service.ListSnapshots(context.Background(), &csi.ListSnapshotsRequest{SnapshotId: "400$$nfs"})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ListSnapshots snapshot not found")
+	assert.Equal(suite.T(), 0, len(resp.Entries))
+}
+
+func (suite *NFSControllerSuite) Test_ControllerGetVolume() {
+	defer stubCSINodeIDs("node1$$192.168.1.10")()
+	service := nfsstorage{cs: *suite.cs}
+	exports := []api.ExportResponse{{Permissions: []api.Permissions{{Client: "192.168.1.10", Access: "RW"}, {Client: "192.168.1.11", Access: "RW"}}}}
+	suite.api.On("GetFileSystemByID", int64(300)).Return(api.FileSystem{ID: 300, Size: 1073741824}, nil)
+	suite.api.On("GetExportByFileSystem", int64(300)).Return(exports, nil)
+	suite.api.On("GetMetadataStatus", int64(300)).Return(false)
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "300"})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ControllerGetVolume")
+	assert.Equal(suite.T(), int64(1073741824), resp.Volume.CapacityBytes)
+	assert.Equal(suite.T(), []string{"node1$$192.168.1.10"}, resp.Status.PublishedNodeIds, "expected clients which are not nodes to be left out")
+	assert.False(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *NFSControllerSuite) Test_ControllerGetVolume_notFound() {
+	service := nfsstorage{cs: *suite.cs}
+	suite.api.On("GetFileSystemByID", int64(300)).Return(nil, &api.APIError{Code: api.ErrFileSystemNotFound})
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "300"})
+	assert.Nil(suite.T(), err, "expected to succeed: nfs ControllerGetVolume")
+	assert.True(suite.T(), resp.Status.VolumeCondition.Abnormal)
+}
+
+func (suite *NFSControllerSuite) Test_GetCapacity_error() {
+	service := nfsstorage{cs: *suite.cs}
+	suite.api.On("GetStoragePoolByName", "pool_name1").Return(nil, errors.New("some error"))
+	_, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: map[string]string{"pool_name": "pool_name1"}})
+	assert.NotNil(suite.T(), err, "expected to fail: nfs GetCapacity")
+}
+
+func (suite *NFSControllerSuite) Test_ListVolumes_GetFileSystemsByIDs_error() {
+	service := nfsstorage{cs: *suite.cs}
+	protocols := []api.Metadata{{ObjectId: 300, Key: STORAGEPROTOCOL, Value: NFS}}
+	pvNames := []api.Metadata{{ObjectId: 300, Key: PVNAME, Value: "pvc-nfs"}}
+	suite.api.On("GetMetadataByKey", PVNAME).Return(pvNames, nil)
+	suite.api.On("GetMetadataByKey", STORAGEPROTOCOL).Return(protocols, nil)
+	suite.api.On("GetMetadataByKey", TOBEDELETED).Return([]api.Metadata{}, nil)
+	suite.api.On("GetFileSystemsByIDs", []int64{300}).Return(nil, errors.New("some error"))
+	_, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.NotNil(suite.T(), err, "expected to fail: nfs ListVolumes GetFileSystemsByIDs")
+}
+
 //============================================================
 
 func getNFSControllerUnpublishVolume() *csi.ControllerUnpublishVolumeRequest {
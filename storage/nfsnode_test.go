@@ -25,6 +25,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/utils/mount"
 )
 
@@ -77,6 +79,26 @@ func (suite *NodeSuite) Test_NodePublishVolume_success() {
 	assert.Nil(suite.T(), err, " error should be nil")
 }
 
+func (suite *NodeSuite) Test_NodeGetVolumeStats_success() {
+	service := nfsstorage{}
+	resp, err := service.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{VolumeId: "100$$nfs", VolumePath: suite.T().TempDir()})
+	assert.Nil(suite.T(), err, "empty err")
+	assert.Equal(suite.T(), 2, len(resp.Usage), "expected bytes and inodes usage")
+	assert.False(suite.T(), resp.VolumeCondition.Abnormal)
+}
+
+func (suite *NodeSuite) Test_NodeGetVolumeStats_notFound() {
+	service := nfsstorage{}
+	_, err := service.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{VolumeId: "100$$nfs", VolumePath: "/tmp/" + RandomString(10)})
+	assert.Equal(suite.T(), codes.NotFound, status.Code(err), "expected to fail: nfs NodeGetVolumeStats missing path")
+}
+
+func (suite *NodeSuite) Test_NodeGetVolumeStats_noPath() {
+	service := nfsstorage{}
+	_, err := service.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{VolumeId: "100$$nfs"})
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: nfs NodeGetVolumeStats without path")
+}
+
 func (suite *NodeSuite) Test_NodePublishVolume_mount_fail() {
 	randomDir := RandomString(10)
 	targetPath := randomDir
@@ -0,0 +1,143 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinibox-csi-driver/api"
+	"strconv"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+// Replicator is implemented by the block protocols, which fail replicated volumes over to the
+// array of a second cluster
+type Replicator interface {
+	// PromoteVolume makes a replication target volume writable as the source of its replica
+	PromoteVolume(ctx context.Context, volumeID string) error
+	// ImportVolume maps an existing volume, typically a replication target, to the pre-provisioned
+	// PV pvName and returns the volume to set in the PV
+	ImportVolume(ctx context.Context, pvName, volumeID string, params map[string]string) (*csi.Volume, error)
+}
+
+func (iscsi *iscsistorage) PromoteVolume(ctx context.Context, volumeID string) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from ISCSI PromoteVolume  " + fmt.Sprint(res))
+		}
+	}()
+	return iscsi.cs.promoteVolume(ctx, volumeID)
+}
+
+func (iscsi *iscsistorage) ImportVolume(ctx context.Context, pvName, volumeID string, params map[string]string) (volume *csi.Volume, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from ISCSI ImportVolume  " + fmt.Sprint(res))
+		}
+	}()
+	if err = iscsi.setTargetParameters(ctx, params); err != nil {
+		return nil, err
+	}
+	return iscsi.cs.importVolume(ctx, "iscsi", pvName, volumeID, params)
+}
+
+func (fc *fcstorage) PromoteVolume(ctx context.Context, volumeID string) (err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from FC PromoteVolume  " + fmt.Sprint(res))
+		}
+	}()
+	return fc.cs.promoteVolume(ctx, volumeID)
+}
+
+func (fc *fcstorage) ImportVolume(ctx context.Context, pvName, volumeID string, params map[string]string) (volume *csi.Volume, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from FC ImportVolume  " + fmt.Sprint(res))
+		}
+	}()
+	return fc.cs.importVolume(ctx, "fc", pvName, volumeID, params)
+}
+
+// promoteVolume switches the role of the replica of a target volume, suspending the replica first as
+// the source array may be unreachable. A volume that is no replication target is left as is
+func (cs *commonservice) promoteVolume(ctx context.Context, volumeID string) error {
+	volID, err := strconv.Atoi(volumeID)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid volume ID %s", volumeID)
+	}
+	volume, err := cs.api.GetVolume(ctx, volID)
+	if err != nil {
+		klog.Errorf("failed to get volume %d, %v", volID, err)
+		return status.Errorf(api.GRPCCode(err), "failed to get volume %d: %v", volID, err)
+	}
+	if !volume.RmrTarget {
+		klog.V(4).Infof("volume %d is no replication target, nothing to promote", volID)
+		return nil
+	}
+	replica, err := cs.api.GetReplicaByEntity(ctx, int64(volID))
+	if err != nil {
+		klog.Errorf("failed to get replica of volume %d, %v", volID, err)
+		return status.Errorf(api.GRPCCode(err), "failed to get replica of volume %d: %v", volID, err)
+	}
+	if replica.Role == api.ReplicaRoleSource {
+		return nil
+	}
+	if replica.State == api.ReplicaStateActive {
+		klog.V(2).Infof("Suspending replica %d of volume %d", replica.ID, volID)
+		if err = cs.api.SuspendReplica(ctx, replica.ID); err != nil {
+			klog.Errorf("failed to suspend replica %d, %v", replica.ID, err)
+			return status.Errorf(api.GRPCCode(err), "failed to suspend replica %d: %v", replica.ID, err)
+		}
+	}
+	if _, err = cs.api.ChangeReplicaRole(ctx, replica.ID); err != nil {
+		klog.Errorf("failed to change role of replica %d, %v", replica.ID, err)
+		return status.Errorf(api.GRPCCode(err), "failed to change role of replica %d: %v", replica.ID, err)
+	}
+	klog.V(2).Infof("volume %d promoted to the source of replica %d", volID, replica.ID)
+	return nil
+}
+
+// importVolume attaches the metadata of a new volume to an existing volume, so that it is handled as
+// the volume of PV pvName
+func (cs *commonservice) importVolume(ctx context.Context, protocol, pvName, volumeID string, params map[string]string) (*csi.Volume, error) {
+	volID, err := strconv.Atoi(volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid volume ID %s", volumeID)
+	}
+	volume, err := cs.api.GetVolume(ctx, volID)
+	if err != nil {
+		klog.Errorf("failed to get volume %d, %v", volID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get volume %d: %v", volID, err)
+	}
+	existing, err := cs.api.GetVolumeByPVName(ctx, pvName)
+	if err != nil && !api.IsNotFound(err) {
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get volume of PV %s: %v", pvName, err)
+	}
+	if existing != nil && existing.ID != volID {
+		return nil, status.Errorf(codes.AlreadyExists, "PV %s is already backed by volume %d", pvName, existing.ID)
+	}
+
+	metadata := cs.getVolumeMetadata(pvName, protocol, params)
+	if _, err = cs.api.AttachMetadataToObject(ctx, int64(volID), metadata); err != nil {
+		klog.Errorf("failed to attach metadata to volume %d, %v", volID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to attach metadata to volume %d: %v", volID, err)
+	}
+	csiVolume := cs.getCSIResponse(ctx, volume, &csi.CreateVolumeRequest{Name: pvName, Parameters: params})
+	copyRequestParameters(params, csiVolume.VolumeContext)
+	klog.V(2).Infof("volume %d imported as PV %s", volID, pvName)
+	return csiVolume, nil
+}
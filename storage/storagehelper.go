@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"infinibox-csi-driver/api"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -23,6 +24,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -44,8 +47,111 @@ const (
 	kiBytesofGiB = 1024 * 1024
 
 	bytesofGiB = kiBytesofGiB * bytesofKiB
+
+	// VOLUMENAMETEMPLATE storage class parameter naming the volumes and filesystems created on the array
+	VOLUMENAMETEMPLATE = "volume_name_template"
+
+	// PVC and PV names passed by the external-provisioner when run with --extra-create-metadata
+	pvcNameKey      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+	pvNameKey       = "csi.storage.k8s.io/pv/name"
+
+	// VolumeSnapshot names passed by the external-snapshotter when run with --extra-create-metadata
+	volumeSnapshotNameKey        = "csi.storage.k8s.io/volumesnapshot/name"
+	volumeSnapshotNamespaceKey   = "csi.storage.k8s.io/volumesnapshot/namespace"
+	volumeSnapshotContentNameKey = "csi.storage.k8s.io/volumesnapshotcontent/name"
+
+	// maxObjectNameLength is the longest name accepted by the array for a volume or filesystem
+	maxObjectNameLength = 255
+
+	// QOSPOLICY storage class parameter naming an existing QoS policy
+	QOSPOLICY = "qos_policy"
+	// MAXIOPS and MAXBPS storage class parameters limit each volume or filesystem through a QoS policy managed by the driver
+	MAXIOPS = "max_iops"
+	MAXBPS  = "max_bps"
+
+	// COMPRESSIONENABLED storage class parameter, the pool default applies when unset
+	COMPRESSIONENABLED = "compression_enabled"
+	// SNAPSHOTLOCKDURATION storage class parameter locking the snapshots of a PV for the given time after creation
+	SNAPSHOTLOCKDURATION = "snapshot_lock_duration"
+
+	// REPLICATIONTARGET storage class parameter naming the remote system volumes are replicated to, over its
+	// replication link, with the given RPO into the remote pool of REPLICATIONREMOTEPOOLID
+	REPLICATIONTARGET       = "replication_target"
+	REPLICATIONREMOTEPOOLID = "replication_remote_pool_id"
+	REPLICATIONRPO          = "replication_rpo"
+	// REPLICATIONSYNCINTERVAL storage class parameter, half the RPO when unset
+	REPLICATIONSYNCINTERVAL = "replication_sync_interval"
+
+	// NETWORKSPACESELECTION storage class parameter, the policy choosing among the comma separated
+	// network_space names and their IPs, one of the selection constants below, random when unset
+	NETWORKSPACESELECTION = "network_space_selection"
+	// NETWORKSPACESUBNETS storage class parameter mapping node subnets to network spaces for the subnet
+	// policy, e.g. "10.0.1.0/24=nas-rack1,10.0.2.0/24=nas-rack2"
+	NETWORKSPACESUBNETS = "network_space_subnets"
+
+	SelectionRandom     = "random"
+	SelectionRoundRobin = "round_robin"
+	SelectionLeastUsed  = "least_used"
+	SelectionSubnet     = "subnet"
 )
 
+// invalidObjectNameChars matches the characters not allowed in array object names
+var invalidObjectNameChars = regexp.MustCompile(`[^A-Za-z0-9_.:-]`)
+
+// volumeNameData holds the fields available to a volume_name_template
+type volumeNameData struct {
+	PVCNamespace string
+	PVCName      string
+	PVName       string
+}
+
+// parseVolumeNameTemplate parses a volume_name_template storage class parameter
+func parseVolumeNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(VOLUMENAMETEMPLATE).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s': %v", VOLUMENAMETEMPLATE, text, err)
+	}
+	return tmpl, nil
+}
+
+// getVolumeName returns the name of the array object backing the PV pvName, rendered from the
+// volume_name_template parameter of the CreateVolume request, e.g. "k8s-{{.PVCNamespace}}-{{.PVCName}}",
+// or pvName when no template is set. Characters not allowed by the array are replaced by '_'
+func getVolumeName(params map[string]string, pvName string) (string, error) {
+	text := params[VOLUMENAMETEMPLATE]
+	if text == "" {
+		return pvName, nil
+	}
+	tmpl, err := parseVolumeNameTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	data := volumeNameData{
+		PVCNamespace: params[pvcNamespaceKey],
+		PVCName:      params[pvcNameKey],
+		PVName:       params[pvNameKey],
+	}
+	if data.PVName == "" {
+		data.PVName = pvName
+	}
+	if (strings.Contains(text, ".PVCName") && data.PVCName == "") || (strings.Contains(text, ".PVCNamespace") && data.PVCNamespace == "") {
+		return "", fmt.Errorf("%s '%s' requires the PVC name and namespace, run the csi provisioner with --extra-create-metadata", VOLUMENAMETEMPLATE, text)
+	}
+	var name strings.Builder
+	if err = tmpl.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("failed to render %s '%s': %v", VOLUMENAMETEMPLATE, text, err)
+	}
+	volName := invalidObjectNameChars.ReplaceAllString(name.String(), "_")
+	if volName == "" {
+		return "", fmt.Errorf("%s '%s' rendered an empty name", VOLUMENAMETEMPLATE, text)
+	}
+	if len(volName) > maxObjectNameLength {
+		return "", fmt.Errorf("name '%s' rendered from %s is longer than %d characters", volName, VOLUMENAMETEMPLATE, maxObjectNameLength)
+	}
+	return volName, nil
+}
+
 func isMountedByListMethod(targetHostPath string) (bool, error) {
 	// Use List() to search for mount matching targetHostPath
 	// Each mount in the list has this example form:
@@ -256,6 +362,40 @@ func validateStorageClassParameters(requiredStorageClassParams map[string]string
 		return fmt.Errorf("Invalid StorageClass parameters provided: %s", badParamsMap)
 	}
 
+	if _, err := getCompressionEnabled(providedStorageClassParams); err != nil {
+		klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+		return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+	}
+
+	if value, ok := providedStorageClassParams[SNAPSHOTLOCKDURATION]; ok {
+		if _, err := parseSnapshotLockDuration(value); err != nil {
+			klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+			return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+		}
+	}
+
+	if _, _, err := getReplica(providedStorageClassParams); err != nil {
+		klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+		return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+	}
+
+	if _, _, err := getNetworkSpaceSelection(providedStorageClassParams); err != nil {
+		klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+		return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+	}
+
+	if _, err := getQosPolicy(providedStorageClassParams, ""); err != nil {
+		klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+		return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+	}
+
+	if text, ok := providedStorageClassParams[VOLUMENAMETEMPLATE]; ok {
+		if _, err := parseVolumeNameTemplate(text); err != nil {
+			klog.Errorf("Invalid StorageClass parameters provided: %v", err)
+			return fmt.Errorf("Invalid StorageClass parameters provided: %v", err)
+		}
+	}
+
 	// TODO validate uid, guid, unix_permissions globally since it pertains to nfs/treeq/fc
 	// uid should be integer >= -1, if set to -1, then it means don't change
 	// gid should be integer >= -1, if set to -1, then it means don't change
@@ -515,3 +655,139 @@ func logPermissions(note, hostTargetPath string) {
 	}
 	klog.V(4).Infof("%s \nmount point permissions on %s ... %s", note, hostTargetPath, string(output))
 }
+
+// getQosPolicy returns the QoS policy requested by the storage class parameters for entities of the
+// given policy type, nil when none is requested. Inline limits name a policy shared by every entity
+// with the same limits, as a policy limits each of its entities on its own
+func getQosPolicy(params map[string]string, policyType string) (*api.QosPolicy, error) {
+	name, maxIOPS, maxBPS := params[QOSPOLICY], params[MAXIOPS], params[MAXBPS]
+	if name == "" && maxIOPS == "" && maxBPS == "" {
+		return nil, nil
+	}
+	if name != "" {
+		if maxIOPS != "" || maxBPS != "" {
+			return nil, fmt.Errorf("%s can not be combined with %s or %s", QOSPOLICY, MAXIOPS, MAXBPS)
+		}
+		return &api.QosPolicy{Name: name, Type: policyType}, nil
+	}
+	policy := &api.QosPolicy{Type: policyType}
+	for param, limit := range map[string]*int64{MAXIOPS: &policy.MaxOps, MAXBPS: &policy.MaxBps} {
+		value := params[param]
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer: %s", param, value)
+		}
+		*limit = parsed
+	}
+	policy.Name = fmt.Sprintf("csi-%s-iops%d-bps%d", strings.ToLower(policyType), policy.MaxOps, policy.MaxBps)
+	return policy, nil
+}
+
+// getCompressionEnabled returns the compression_enabled parameter, nil when unset to keep the pool default
+func getCompressionEnabled(params map[string]string) (*bool, error) {
+	value, ok := params[COMPRESSIONENABLED]
+	if !ok {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false: %s", COMPRESSIONENABLED, value)
+	}
+	return &enabled, nil
+}
+
+// parseSnapshotLockDuration parses a snapshot_lock_duration, either a duration such as "720h" or a number of days such as "30d"
+func parseSnapshotLockDuration(value string) (duration time.Duration, err error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		var n int64
+		if n, err = strconv.ParseInt(days, 10, 64); err == nil {
+			duration = time.Duration(n) * 24 * time.Hour
+		}
+	} else {
+		duration, err = time.ParseDuration(value)
+	}
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 720h or 30d: %s", SNAPSHOTLOCKDURATION, value)
+	}
+	return duration, nil
+}
+
+// getReplica returns the replica requested by the storage class parameters, without its link and
+// local volume, and the remote system to replicate to. It returns nil when no replication is requested
+func getReplica(params map[string]string) (*api.Replica, string, error) {
+	target := params[REPLICATIONTARGET]
+	if target == "" {
+		for _, param := range []string{REPLICATIONREMOTEPOOLID, REPLICATIONRPO, REPLICATIONSYNCINTERVAL} {
+			if _, ok := params[param]; ok {
+				return nil, "", fmt.Errorf("%s requires %s", param, REPLICATIONTARGET)
+			}
+		}
+		return nil, "", nil
+	}
+	remotePoolID, err := strconv.ParseInt(params[REPLICATIONREMOTEPOOLID], 10, 64)
+	if err != nil || remotePoolID <= 0 {
+		return nil, "", fmt.Errorf("%s must be the ID of a pool on %s: %s", REPLICATIONREMOTEPOOLID, target, params[REPLICATIONREMOTEPOOLID])
+	}
+	rpo, err := time.ParseDuration(params[REPLICATIONRPO])
+	if err != nil || rpo <= 0 {
+		return nil, "", fmt.Errorf("%s must be a positive duration such as 5m: %s", REPLICATIONRPO, params[REPLICATIONRPO])
+	}
+	syncInterval := rpo / 2
+	if value, ok := params[REPLICATIONSYNCINTERVAL]; ok {
+		syncInterval, err = time.ParseDuration(value)
+		if err != nil || syncInterval <= 0 || syncInterval > rpo {
+			return nil, "", fmt.Errorf("%s must be a positive duration no longer than %s: %s", REPLICATIONSYNCINTERVAL, REPLICATIONRPO, value)
+		}
+	}
+	replica := &api.Replica{
+		EntityType:      api.ReplicaEntityTypeVolume,
+		RemotePoolID:    remotePoolID,
+		ReplicationType: api.ReplicationTypeAsync,
+		RpoValue:        int64(rpo / time.Millisecond),
+		SyncInterval:    int64(syncInterval / time.Millisecond),
+	}
+	return replica, target, nil
+}
+
+// networkSpaceSubnet maps the nodes of a subnet to the network space nearest to them
+type networkSpaceSubnet struct {
+	subnet       *net.IPNet
+	networkSpace string
+}
+
+// getNetworkSpaceSelection returns the network_space_selection policy of the storage class parameters,
+// with the node subnets of network_space_subnets the subnet policy requires
+func getNetworkSpaceSelection(params map[string]string) (string, []networkSpaceSubnet, error) {
+	policy := strings.TrimSpace(params[NETWORKSPACESELECTION])
+	switch policy {
+	case "":
+		policy = SelectionRandom
+	case SelectionRandom, SelectionRoundRobin, SelectionLeastUsed:
+	case SelectionSubnet:
+		if strings.TrimSpace(params[NETWORKSPACESUBNETS]) == "" {
+			return "", nil, fmt.Errorf("%s '%s' requires %s", NETWORKSPACESELECTION, policy, NETWORKSPACESUBNETS)
+		}
+	default:
+		return "", nil, fmt.Errorf("invalid %s '%s', expected one of %s, %s, %s or %s", NETWORKSPACESELECTION, policy,
+			SelectionRandom, SelectionRoundRobin, SelectionLeastUsed, SelectionSubnet)
+	}
+	subnets := []networkSpaceSubnet{}
+	for _, entry := range strings.Split(params[NETWORKSPACESUBNETS], ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		fields := strings.SplitN(entry, "=", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[1]) == "" {
+			return "", nil, fmt.Errorf("invalid %s entry '%s', expected <subnet>=<network space>", NETWORKSPACESUBNETS, entry)
+		}
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s entry '%s': %v", NETWORKSPACESUBNETS, entry, err)
+		}
+		subnets = append(subnets, networkSpaceSubnet{subnet: subnet, networkSpace: strings.TrimSpace(fields[1])})
+	}
+	return policy, subnets, nil
+}
@@ -19,19 +19,24 @@ import (
 	"infinibox-csi-driver/helper"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"k8s.io/klog"
+	mountutils "k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
 	"k8s.io/utils/mount"
 )
 
@@ -49,6 +54,13 @@ const (
 	// tib100 int64 = tib * 100
 )
 
+const (
+	// StorageClass parameter selecting which pool capacity GetCapacity reports
+	CAPACITYTYPE     = "capacity_type"
+	CAPACITYPHYSICAL = "physical"
+	CAPACITYVIRTUAL  = "virtual"
+)
+
 type Storageoperations interface {
 	csi.ControllerServer
 	csi.NodeServer
@@ -85,6 +97,7 @@ type nfsstorage struct {
 	capacity  int64
 
 	////
+	fileSystemName     string
 	fileSystemID       int64
 	exportpath         string
 	usePrivilegedPorts bool
@@ -186,11 +199,11 @@ func (cs *commonservice) verifyApiClient() error {
 // 	return ""
 // }
 
-func (cs *commonservice) mapVolumeTohost(volumeID int, hostID int) (luninfo api.LunInfo, err error) {
-	luninfo, err = cs.api.MapVolumeToHost(hostID, volumeID, -1)
+func (cs *commonservice) mapVolumeTohost(ctx context.Context, volumeID int, hostID int) (luninfo api.LunInfo, err error) {
+	luninfo, err = cs.api.MapVolumeToHost(ctx, hostID, volumeID, -1)
 	if err != nil {
-		if strings.Contains(err.Error(), "MAPPING_ALREADY_EXISTS") {
-			luninfo, err = cs.api.GetLunByHostVolume(hostID, volumeID)
+		if api.HasErrorCode(err, api.ErrMappingAlreadyExists) {
+			luninfo, err = cs.api.GetLunByHostVolume(ctx, hostID, volumeID)
 		}
 		if err != nil {
 			return luninfo, err
@@ -199,18 +212,18 @@ func (cs *commonservice) mapVolumeTohost(volumeID int, hostID int) (luninfo api.
 	return luninfo, nil
 }
 
-func (cs *commonservice) unmapVolumeFromHost(hostID, volumeID int) (err error) {
-	err = cs.api.UnMapVolumeFromHost(hostID, volumeID)
+func (cs *commonservice) unmapVolumeFromHost(ctx context.Context, hostID, volumeID int) (err error) {
+	err = cs.api.UnMapVolumeFromHost(ctx, hostID, volumeID)
 	if err != nil {
 		// Ignore the following errors
 		successMsg := fmt.Sprintf("Success: No need to unmap volume with ID %d from host with ID %d", volumeID, hostID)
-		if strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+		if api.HasErrorCode(err, api.ErrHostNotFound) {
 			klog.V(4).Infof("%s, host not found", successMsg)
 			return nil
-		} else if strings.Contains(err.Error(), "LUN_NOT_FOUND") {
+		} else if api.HasErrorCode(err, api.ErrLunNotFound) {
 			klog.V(4).Infof("%s, lun not found", successMsg)
 			return nil
-		} else if strings.Contains(err.Error(), "VOLUME_NOT_FOUND") {
+		} else if api.HasErrorCode(err, api.ErrVolumeNotFound) {
 			klog.V(4).Infof("%s, volume not found", successMsg)
 			return nil
 		}
@@ -219,17 +232,17 @@ func (cs *commonservice) unmapVolumeFromHost(hostID, volumeID int) (err error) {
 	return nil
 }
 
-func (cs *commonservice) AddPortForHost(hostID int, portType, portName string) error {
-	_, err := cs.api.AddHostPort(portType, portName, hostID)
-	if err != nil && !strings.Contains(err.Error(), "PORT_ALREADY_BELONGS_TO_HOST") {
+func (cs *commonservice) AddPortForHost(ctx context.Context, hostID int, portType, portName string) error {
+	_, err := cs.api.AddHostPort(ctx, portType, portName, hostID)
+	if err != nil && !api.HasErrorCode(err, api.ErrPortAlreadyBelongsToHost) {
 		klog.Errorf("failed to add host port with error %v", err)
 		return err
 	}
 	return nil
 }
 
-func (cs *commonservice) AddChapSecurityForHost(hostID int, credentials map[string]string) error {
-	_, err := cs.api.AddHostSecurity(credentials, hostID)
+func (cs *commonservice) AddChapSecurityForHost(ctx context.Context, hostID int, credentials map[string]string) error {
+	_, err := cs.api.AddHostSecurity(ctx, credentials, hostID)
 	if err != nil {
 		klog.Errorf("failed to add authentication for host %d with error %v", hostID, err)
 		return err
@@ -237,16 +250,16 @@ func (cs *commonservice) AddChapSecurityForHost(hostID int, credentials map[stri
 	return nil
 }
 
-func (cs *commonservice) validateHost(hostName string) (*api.Host, error) {
+func (cs *commonservice) validateHost(ctx context.Context, hostName string) (*api.Host, error) {
 	klog.V(2).Infof("Check if host available, create if not available")
-	host, err := cs.api.GetHostByName(hostName)
-	if err != nil && !strings.Contains(err.Error(), "HOST_NOT_FOUND") {
+	host, err := cs.api.GetHostByName(ctx, hostName)
+	if err != nil && !api.HasErrorCode(err, api.ErrHostNotFound) {
 		klog.Errorf("failed to get host with error %v", err)
-		return nil, status.Errorf(codes.NotFound, "host not found: %s", hostName)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get host %s: %v", hostName, err)
 	}
 	if host.ID == 0 {
 		klog.V(2).Infof("Creating host with name: %s", hostName)
-		host, err = cs.api.CreateHost(hostName)
+		host, err = cs.api.CreateHost(ctx, hostName)
 		if err != nil {
 			klog.Errorf("failed to create host with error %v", err)
 			return nil, status.Errorf(codes.Internal, "failed to create host: %s", hostName)
@@ -255,11 +268,11 @@ func (cs *commonservice) validateHost(hostName string) (*api.Host, error) {
 	return &host, nil
 }
 
-func (cs *commonservice) getCSIResponse(vol *api.Volume, req *csi.CreateVolumeRequest) *csi.Volume {
+func (cs *commonservice) getCSIResponse(ctx context.Context, vol *api.Volume, req *csi.CreateVolumeRequest) *csi.Volume {
 	klog.V(2).Infof("getCSIResponse called with volume %+v", vol)
 	storagePoolName := vol.PoolName
 	if storagePoolName == "" {
-		storagePoolName = cs.getStoragePoolNameFromID(vol.PoolId)
+		storagePoolName = cs.getStoragePoolNameFromID(ctx, vol.PoolId)
 	}
 	// Make the additional volume attributes
 	attributes := map[string]string{
@@ -279,11 +292,11 @@ func (cs *commonservice) getCSIResponse(vol *api.Volume, req *csi.CreateVolumeRe
 	return vi
 }
 
-func (cs *commonservice) getStoragePoolNameFromID(id int64) string {
+func (cs *commonservice) getStoragePoolNameFromID(ctx context.Context, id int64) string {
 	klog.V(2).Infof("getStoragePoolNameFromID called with storagepoolid %d", id)
 	storagePoolName := cs.storagePoolIdName[id]
 	if storagePoolName == "" {
-		pool, err := cs.api.FindStoragePool(id, "")
+		pool, err := cs.api.FindStoragePool(ctx, id, "")
 		if err == nil {
 			storagePoolName = pool.Name
 			cs.storagePoolIdName[id] = pool.Name
@@ -294,16 +307,10 @@ func (cs *commonservice) getStoragePoolNameFromID(id int64) string {
 	return storagePoolName
 }
 
-func (cs *commonservice) getNetworkSpaceIP(networkSpace string) (string, error) {
-	nspace, err := cs.api.GetNetworkSpaceByName(networkSpace)
-	if err != nil {
-		return "", err
-	}
-	if len(nspace.Portals) == 0 {
-		return "", errors.New("Ip address not found")
-	}
-	index := getRandomIndex(len(nspace.Portals))
-	return nspace.Portals[index].IpAdress, nil
+// getNetworkSpaceIP returns a random IP of a random network space of the comma separated networkSpace
+func (cs *commonservice) getNetworkSpaceIP(ctx context.Context, networkSpace string) (string, error) {
+	_, ipAddress, err := cs.selectNetworkSpaceIP(ctx, map[string]string{"network_space": networkSpace})
+	return ipAddress, err
 }
 
 func getRandomIndex(max int) int {
@@ -332,6 +339,604 @@ func getClusterVersion() string {
 	return version
 }
 
+// clusterID holds the UID of the kube-system namespace, which identifies the cluster, once resolved
+var clusterID struct {
+	sync.Once
+	value string
+}
+
+// LoadClusterID resolves the ID of the cluster the controller runs in, recorded in the metadata of
+// the IBox objects it creates. It is resolved once, empty outside a cluster
+func LoadClusterID() string {
+	clusterID.Do(func() {
+		cl, err := clientgo.BuildClient()
+		if err != nil {
+			klog.Warningf("cluster ID is not recorded in IBox object metadata, failed to build kubernetes client: %v", err)
+			return
+		}
+		clusterID.value, err = cl.GetClusterID()
+		if err != nil {
+			klog.Warningf("cluster ID is not recorded in IBox object metadata, failed to get it: %v", err)
+		}
+	})
+	return clusterID.value
+}
+
+// getStorageClassOfPVC returns the StorageClass name of a PVC, empty when it cannot be read
+func getStorageClassOfPVC(cl clientgo.KubeClient, name, nameSpace string) string {
+	pvc, err := cl.GetPersistentVolumeClaim(name, nameSpace)
+	if err != nil || pvc.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *pvc.Spec.StorageClassName
+}
+
+// getCreationMetadata returns the metadata recording the cluster, driver and time an IBox object was created
+func (cs *commonservice) getCreationMetadata() map[string]interface{} {
+	metadata := make(map[string]interface{})
+	metadata[CREATEDBY] = cs.GetCreatedBy()
+	metadata[CREATEDAT] = time.Now().UTC().Format(time.RFC3339)
+	if clusterID := LoadClusterID(); clusterID != "" {
+		metadata[CLUSTERID] = clusterID
+	}
+	return metadata
+}
+
+// getVolumeMetadata returns the metadata attached to the IBox volume or filesystem of a PV, mapping it to
+// its PVC and StorageClass when the CreateVolume parameters carry the provisioner's --extra-create-metadata
+func (cs *commonservice) getVolumeMetadata(pvName, protocol string, params map[string]string) map[string]interface{} {
+	metadata := cs.getCreationMetadata()
+	metadata[PVNAME] = pvName
+	metadata[STORAGEPROTOCOL] = protocol
+	pvcName, pvcNamespace := params[pvcNameKey], params[pvcNamespaceKey]
+	if pvcName != "" && pvcNamespace != "" {
+		metadata[PVCNAME] = pvcName
+		metadata[PVCNAMESPACE] = pvcNamespace
+		if cl, err := clientgo.BuildClient(); err == nil {
+			if storageClass := getStorageClassOfPVC(cl, pvcName, pvcNamespace); storageClass != "" {
+				metadata[STORAGECLASS] = storageClass
+			}
+		}
+	}
+	// CreateSnapshot has no storage class parameters, the lock of its snapshots is kept with the PV
+	if lockDuration := params[SNAPSHOTLOCKDURATION]; lockDuration != "" {
+		metadata[SNAPSHOTLOCK] = lockDuration
+	}
+	return metadata
+}
+
+// getVolumeOfPV returns the volume of a PV, found by its pv name metadata or, when an earlier CreateVolume
+// created the volume but failed to attach that metadata, by its array name volName. Nil when the PV has no volume
+func (cs *commonservice) getVolumeOfPV(ctx context.Context, pvName, volName, protocol string, params map[string]string) (*api.Volume, error) {
+	volume, err := cs.api.GetVolumeByPVName(ctx, pvName)
+	if err == nil || !api.IsNotFound(err) {
+		if err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "CreateVolume failed: %v", err)
+		}
+		return volume, nil
+	}
+	volume, err = cs.api.GetVolumeByName(ctx, volName)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, status.Errorf(api.GRPCCode(err), "CreateVolume failed: %v", err)
+	}
+	if volume == nil {
+		return nil, nil
+	}
+	if err = cs.adoptByName(ctx, int64(volume.ID), "volume "+volName, pvName, protocol, params); err != nil {
+		return nil, err
+	}
+	return volume, nil
+}
+
+// getFileSystemOfPV returns the filesystem of a PV, found by its pv name metadata or, when an earlier
+// CreateVolume created the filesystem but failed to attach that metadata, by its array name fileSystemName.
+// Nil when the PV has no filesystem
+func (cs *commonservice) getFileSystemOfPV(ctx context.Context, pvName, fileSystemName, protocol string, params map[string]string) (*api.FileSystem, error) {
+	fileSystem, err := cs.api.GetFileSystemByPVName(ctx, pvName)
+	if err == nil || !api.IsNotFound(err) {
+		if err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "CreateVolume failed: %v", err)
+		}
+		return fileSystem, nil
+	}
+	fileSystem, err = cs.api.GetFileSystemByName(ctx, fileSystemName)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, status.Errorf(api.GRPCCode(err), "CreateVolume failed: %v", err)
+	}
+	if fileSystem == nil {
+		return nil, nil
+	}
+	if err = cs.adoptByName(ctx, fileSystem.ID, "filesystem "+fileSystemName, pvName, protocol, params); err != nil {
+		return nil, err
+	}
+	return fileSystem, nil
+}
+
+// adoptByName attaches the metadata of a PV to the IBox object found by the array name the PV renders to.
+// Objects of another PV, holding its pv name metadata, are not adopted
+func (cs *commonservice) adoptByName(ctx context.Context, objectID int64, object, pvName, protocol string, params map[string]string) error {
+	owner, err := cs.api.GetMetadataValue(ctx, objectID, PVNAME)
+	if err != nil {
+		klog.Errorf("failed to get %s of %s: %v", PVNAME, object, err)
+		return status.Errorf(api.GRPCCode(err), "failed to get %s of %s: %v", PVNAME, object, err)
+	}
+	if owner != "" && owner != pvName {
+		klog.Errorf("%s already exists for PV %s", object, owner)
+		return status.Errorf(codes.AlreadyExists, "%s already exists for PV %s", object, owner)
+	}
+	klog.V(2).Infof("Adopting %s, created for PV %s without its metadata", object, pvName)
+	if _, err = cs.api.AttachMetadataToObject(ctx, objectID, cs.getVolumeMetadata(pvName, protocol, params)); err != nil {
+		klog.Errorf("failed to attach metadata to %s: %v", object, err)
+		return status.Errorf(api.GRPCCode(err), "failed to attach metadata to %s: %v", object, err)
+	}
+	return nil
+}
+
+// getMetadataValue returns the metadata value of key of an IBox object, empty when it has none
+func (cs *commonservice) getMetadataValue(ctx context.Context, objectID int64, key string) (string, error) {
+	value, err := cs.api.GetMetadataValue(ctx, objectID, key)
+	if err != nil {
+		klog.Errorf("failed to get %s of IBox object %d: %v", key, objectID, err)
+		return "", status.Errorf(api.GRPCCode(err), "failed to get %s of IBox object %d: %v", key, objectID, err)
+	}
+	return value, nil
+}
+
+// getSnapshotLockExpiry returns the lock expiry, in milliseconds since the epoch, of a new snapshot of a
+// volume or filesystem, zero when its storage class set no snapshot_lock_duration
+func (cs *commonservice) getSnapshotLockExpiry(ctx context.Context, objectID int64) (int64, error) {
+	value, err := cs.api.GetMetadataValue(ctx, objectID, SNAPSHOTLOCK)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	duration, err := parseSnapshotLockDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Now().Add(duration).UnixNano() / int64(time.Millisecond), nil
+}
+
+// getSnapshotMetadata returns the metadata attached to the IBox snapshot of a VolumeSnapshot, mapping it to
+// the VolumeSnapshot when the CreateSnapshot parameters carry the snapshotter's --extra-create-metadata
+func (cs *commonservice) getSnapshotMetadata(protocol string, params map[string]string) map[string]interface{} {
+	metadata := cs.getCreationMetadata()
+	metadata[STORAGEPROTOCOL] = protocol
+	if name := params[volumeSnapshotNameKey]; name != "" {
+		metadata[VOLUMESNAPSHOTNAME] = name
+		metadata[VOLUMESNAPSHOTNAMESPACE] = params[volumeSnapshotNamespaceKey]
+	}
+	if contentName := params[volumeSnapshotContentNameKey]; contentName != "" {
+		metadata[VOLUMESNAPSHOTCONTENTNAME] = contentName
+	}
+	return metadata
+}
+
+// setQosPolicy assigns the QoS policy requested by the storage class parameters to a volume or filesystem,
+// replacing the policy currently assigned. The policy of inline limits is created on first use
+func (cs *commonservice) setQosPolicy(ctx context.Context, params map[string]string, policyType string, entityID, currentPolicyID int64) error {
+	requested, err := getQosPolicy(params, policyType)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if requested == nil {
+		return nil
+	}
+	policy, err := cs.api.GetQosPolicyByName(ctx, requested.Name)
+	if err != nil && api.IsNotFound(err) && params[QOSPOLICY] == "" {
+		policy, err = cs.api.CreateQosPolicy(ctx, *requested)
+	}
+	if err != nil {
+		if api.IsNotFound(err) {
+			return status.Errorf(codes.InvalidArgument, "QoS policy %s not found", requested.Name)
+		}
+		return status.Errorf(api.GRPCCode(err), "failed to get QoS policy %s: %v", requested.Name, err)
+	}
+	if policy.Type != policyType {
+		return status.Errorf(codes.InvalidArgument, "QoS policy %s is of type %s, %s expected", policy.Name, policy.Type, policyType)
+	}
+	if policy.ID == currentPolicyID {
+		return nil
+	}
+	if currentPolicyID != 0 {
+		if err = cs.api.UnassignQosPolicy(ctx, currentPolicyID, entityID); err != nil {
+			return status.Errorf(api.GRPCCode(err), "failed to unassign QoS policy %d: %v", currentPolicyID, err)
+		}
+	}
+	if err = cs.api.AssignQosPolicy(ctx, policy.ID, entityID); err != nil {
+		return status.Errorf(api.GRPCCode(err), "failed to assign QoS policy %s: %v", policy.Name, err)
+	}
+	klog.V(2).Infof("QoS policy %s assigned to %d", policy.Name, entityID)
+	return nil
+}
+
+// createReplica replicates a volume as requested by the storage class parameters, a volume that already
+// has a replica is left as is
+func (cs *commonservice) createReplica(ctx context.Context, params map[string]string, volumeID int64) error {
+	requested, target, err := getReplica(params)
+	if err != nil || requested == nil {
+		return err
+	}
+	if _, err = cs.api.GetReplicaByEntity(ctx, volumeID); err == nil {
+		return nil
+	} else if !api.IsNotFound(err) {
+		return status.Errorf(api.GRPCCode(err), "failed to get replica of volume %d: %v", volumeID, err)
+	}
+	link, err := cs.api.GetLinkByRemoteSystem(ctx, target)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return status.Errorf(codes.InvalidArgument, "no replication link to %s %s", REPLICATIONTARGET, target)
+		}
+		return status.Errorf(api.GRPCCode(err), "failed to get replication link to %s: %v", target, err)
+	}
+	requested.LinkID = link.ID
+	requested.LocalEntityID = volumeID
+	replica, err := cs.api.CreateReplica(ctx, *requested)
+	if err != nil {
+		return status.Errorf(api.GRPCCode(err), "failed to replicate volume %d to %s: %v", volumeID, target, err)
+	}
+	klog.V(2).Infof("volume %d replicated to %s by replica %d", volumeID, target, replica.ID)
+	return nil
+}
+
+// deleteReplica stops the replication of a volume, the replica volume on the remote system is kept
+func (cs *commonservice) deleteReplica(ctx context.Context, volumeID int64) error {
+	replica, err := cs.api.GetReplicaByEntity(ctx, volumeID)
+	if err != nil {
+		if api.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	klog.V(2).Infof("Deleting replica %d of volume %d", replica.ID, volumeID)
+	if err = cs.api.DeleteReplica(ctx, replica.ID); err != nil && !api.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// getTimestamp converts an IBox created_at value, in milliseconds since the epoch, to a protobuf timestamp
+func getTimestamp(createdAt int64) *timestamppb.Timestamp {
+	return timestamppb.New(time.Unix(0, createdAt*int64(time.Millisecond)))
+}
+
+// pvObjectScanKey is the context key of the pvObjectScan shared by the protocols of one list request
+type pvObjectScanKey struct{}
+
+// pvObjectScan holds the IBox objects provisioned for PVs, read once per list request
+type pvObjectScan struct {
+	once    sync.Once
+	objects *pvObjects
+	err     error
+}
+
+// pvObjects holds the host.k8s.pvname metadata of the IBox objects provisioned for PVs, by protocol,
+// and the objects among them marked to be deleted
+type pvObjects struct {
+	byProtocol  map[string][]api.Metadata
+	toBeDeleted map[int]bool
+}
+
+// WithPVObjectScan returns a context under which the controllers of all protocols share a single
+// scan of the array metadata, so that listing every protocol reads the metadata once
+func WithPVObjectScan(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pvObjectScanKey{}, &pvObjectScan{})
+}
+
+// getPVObjects returns the host.k8s.pvname metadata of every IBox object provisioned
+// for the given protocol, skipping objects already marked to be deleted
+func (cs *commonservice) getPVObjects(ctx context.Context, protocol string) ([]api.Metadata, error) {
+	objects, err := cs.loadPVObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := []api.Metadata{}
+	for _, md := range objects.byProtocol[protocol] {
+		if !objects.toBeDeleted[md.ObjectId] {
+			live = append(live, md)
+		}
+	}
+	return live, nil
+}
+
+// getSnapshotParents returns the host.k8s.pvname metadata of every IBox object provisioned for the
+// given protocol, including objects marked to be deleted, which are kept while they have snapshots
+func (cs *commonservice) getSnapshotParents(ctx context.Context, protocol string) ([]api.Metadata, error) {
+	objects, err := cs.loadPVObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return objects.byProtocol[protocol], nil
+}
+
+// loadPVObjects returns the scan of the request context, scanning the metadata on first use,
+// or a new scan when the context carries none
+func (cs *commonservice) loadPVObjects(ctx context.Context) (*pvObjects, error) {
+	if scan, ok := ctx.Value(pvObjectScanKey{}).(*pvObjectScan); ok {
+		scan.once.Do(func() {
+			scan.objects, scan.err = cs.scanPVObjects(ctx)
+		})
+		return scan.objects, scan.err
+	}
+	return cs.scanPVObjects(ctx)
+}
+
+// scanPVObjects returns the host.k8s.pvname metadata of every IBox object provisioned for a PV,
+// partitioned by protocol
+func (cs *commonservice) scanPVObjects(ctx context.Context) (*pvObjects, error) {
+	pvNames, err := cs.api.GetMetadataByKey(ctx, PVNAME)
+	if err != nil {
+		return nil, err
+	}
+	protocols, err := cs.api.GetMetadataByKey(ctx, STORAGEPROTOCOL)
+	if err != nil {
+		return nil, err
+	}
+	toBeDeleted, err := cs.api.GetMetadataByKey(ctx, TOBEDELETED)
+	if err != nil {
+		return nil, err
+	}
+
+	protocolByObject := make(map[int]string)
+	for _, md := range protocols {
+		protocolByObject[md.ObjectId] = md.Value
+	}
+	objects := &pvObjects{
+		byProtocol:  make(map[string][]api.Metadata),
+		toBeDeleted: make(map[int]bool),
+	}
+	for _, md := range toBeDeleted {
+		if deleted, _ := strconv.ParseBool(md.Value); deleted {
+			objects.toBeDeleted[md.ObjectId] = true
+		}
+	}
+
+	legacyObjects := []api.Metadata{}
+	for _, md := range pvNames {
+		objectProtocol, ok := protocolByObject[md.ObjectId]
+		if !ok {
+			// objects provisioned by older driver versions carry no protocol metadata
+			legacyObjects = append(legacyObjects, md)
+			continue
+		}
+		objects.byProtocol[objectProtocol] = append(objects.byProtocol[objectProtocol], md)
+	}
+	if len(legacyObjects) == 0 {
+		return objects, nil
+	}
+
+	cl, err := clientgo.BuildClient()
+	if err != nil {
+		klog.Warningf("skipping %d IBox objects without protocol metadata, failed to build kubernetes client: %v", len(legacyObjects), err)
+		return objects, nil
+	}
+	for _, md := range legacyObjects {
+		objectProtocol, err := getProtocolFromPV(cl, md.Value)
+		if err != nil {
+			klog.Warningf("skipping IBox object %d, could not determine protocol of PV %s: %v", md.ObjectId, md.Value, err)
+			continue
+		}
+		objects.byProtocol[objectProtocol] = append(objects.byProtocol[objectProtocol], md)
+	}
+	return objects, nil
+}
+
+// getProtocolFromPV returns the protocol part of the CSI volume handle of the named PV
+func getProtocolFromPV(cl clientgo.KubeClient, pvName string) (string, error) {
+	pv, err := cl.GetPersistantVolumeByName(pvName)
+	if err != nil {
+		return "", err
+	}
+	if pv.Spec.CSI == nil {
+		return "", fmt.Errorf("PV %s is not a CSI volume", pvName)
+	}
+	volproto := strings.Split(pv.Spec.CSI.VolumeHandle, "$$")
+	if len(volproto) != 2 {
+		return "", fmt.Errorf("volume handle %s of PV %s does not follow '<id>$$<proto>' pattern", pv.Spec.CSI.VolumeHandle, pvName)
+	}
+	return volproto[1], nil
+}
+
+// listVolumeEntries returns a ListVolumes entry for every IBox volume provisioned for the given block protocol,
+// fetching the volumes in bulk by the IDs carrying host.k8s.pvname metadata
+func (cs *commonservice) listVolumeEntries(ctx context.Context, protocol string) (entries []*csi.ListVolumesResponse_Entry, err error) {
+	pvObjects, err := cs.getPVObjects(ctx, protocol)
+	if err != nil {
+		return nil, status.Errorf(api.GRPCCode(err), "failed to list %s volumes: %v", protocol, err)
+	}
+	if len(pvObjects) == 0 {
+		return entries, nil
+	}
+	volumeIDs := make([]int, 0, len(pvObjects))
+	for _, md := range pvObjects {
+		volumeIDs = append(volumeIDs, md.ObjectId)
+	}
+	// volumes deleted while listing are left out of the result
+	volumes, err := cs.api.GetVolumesByIDs(ctx, volumeIDs)
+	if err != nil {
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get %s volumes: %v", protocol, err)
+	}
+	for _, vol := range volumes {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      strconv.Itoa(vol.ID),
+				CapacityBytes: vol.Size,
+			},
+		})
+	}
+	klog.V(4).Infof("listed %d %s volumes", len(entries), protocol)
+	return entries, nil
+}
+
+// listSnapshotEntries returns a ListSnapshots entry for every snapshot of the given block protocol matching
+// the snapshot_id or source_volume_id filter of the request, or for all snapshots without a filter.
+// A snapshot of a snapshot is listed with the volume at the top of its snapshot chain as source
+func (cs *commonservice) listSnapshotEntries(ctx context.Context, protocol string, req *csi.ListSnapshotsRequest) (entries []*csi.ListSnapshotsResponse_Entry, err error) {
+	snapshots := []api.Volume{}
+	sourceIDs := make(map[int]int)
+	if req.GetSnapshotId() != "" {
+		volproto, err := validateVolumeID(req.GetSnapshotId())
+		if err != nil {
+			klog.V(4).Infof("snapshot ID %s is not valid, no snapshots listed: %v", req.GetSnapshotId(), err)
+			return entries, nil
+		}
+		snapshotID, err := strconv.Atoi(volproto.VolumeID)
+		if err != nil {
+			klog.V(4).Infof("snapshot ID %s is not valid, no snapshots listed", req.GetSnapshotId())
+			return entries, nil
+		}
+		snapshot, err := cs.api.GetVolume(ctx, snapshotID)
+		if err != nil {
+			if api.HasErrorCode(err, api.ErrVolumeNotFound) {
+				return entries, nil
+			}
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshot %d: %v", snapshotID, err)
+		}
+		// volumes which are not write protected are volumes cloned or restored from a snapshot
+		if snapshot.ParentId == 0 || !snapshot.WriteProtected {
+			return entries, nil
+		}
+		sourceID, err := cs.getSnapshotSourceID(ctx, snapshot)
+		if err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get source volume of snapshot %d: %v", snapshotID, err)
+		}
+		snapshots = append(snapshots, *snapshot)
+		sourceIDs[snapshot.ID] = sourceID
+	} else {
+		volumeIDs := []int{}
+		if req.GetSourceVolumeId() != "" {
+			volproto, err := validateVolumeID(req.GetSourceVolumeId())
+			if err != nil {
+				klog.V(4).Infof("source volume ID %s is not valid, no snapshots listed: %v", req.GetSourceVolumeId(), err)
+				return entries, nil
+			}
+			volumeID, err := strconv.Atoi(volproto.VolumeID)
+			if err != nil {
+				klog.V(4).Infof("source volume ID %s is not valid, no snapshots listed", req.GetSourceVolumeId())
+				return entries, nil
+			}
+			volumeIDs = append(volumeIDs, volumeID)
+		} else {
+			// volumes deleted while they have snapshots are only marked to be deleted
+			parents, err := cs.getSnapshotParents(ctx, protocol)
+			if err != nil {
+				return nil, status.Errorf(api.GRPCCode(err), "failed to list %s volumes: %v", protocol, err)
+			}
+			for _, md := range parents {
+				volumeIDs = append(volumeIDs, md.ObjectId)
+			}
+		}
+		if snapshots, sourceIDs, err = cs.getVolumeSnapshots(ctx, volumeIDs); err != nil {
+			return nil, status.Errorf(api.GRPCCode(err), "failed to get snapshots of %s volumes: %v", protocol, err)
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		entries = append(entries, &csi.ListSnapshotsResponse_Entry{
+			Snapshot: &csi.Snapshot{
+				SnapshotId:     strconv.Itoa(snapshot.ID) + "$$" + protocol,
+				SourceVolumeId: strconv.Itoa(sourceIDs[snapshot.ID]) + "$$" + protocol,
+				SizeBytes:      snapshot.Size,
+				CreationTime:   getTimestamp(snapshot.CreatedAt),
+				ReadyToUse:     true,
+			},
+		})
+	}
+	klog.V(4).Infof("listed %d %s snapshots", len(entries), protocol)
+	return entries, nil
+}
+
+// getVolumeSnapshots returns the snapshots descending from the given volumes, snapshots of snapshots included,
+// reading the children of each generation in bulk, and the ID of the volume each snapshot descends from by snapshot ID
+func (cs *commonservice) getVolumeSnapshots(ctx context.Context, volumeIDs []int) (snapshots []api.Volume, sourceIDs map[int]int, err error) {
+	snapshots = []api.Volume{}
+	sourceIDs = make(map[int]int)
+	for _, volumeID := range volumeIDs {
+		sourceIDs[volumeID] = volumeID
+	}
+	parentIDs := volumeIDs
+	for len(parentIDs) > 0 {
+		children, err := cs.api.GetVolumesByParentIDs(ctx, parentIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		parentIDs = []int{}
+		for _, child := range children {
+			// children which are not write protected are volumes cloned or restored from a snapshot
+			if !child.WriteProtected {
+				continue
+			}
+			if _, seen := sourceIDs[child.ID]; seen {
+				continue
+			}
+			sourceIDs[child.ID] = sourceIDs[child.ParentId]
+			snapshots = append(snapshots, child)
+			parentIDs = append(parentIDs, child.ID)
+		}
+	}
+	return snapshots, sourceIDs, nil
+}
+
+// getSnapshotSourceID returns the ID of the volume at the top of the snapshot chain of snapshot
+func (cs *commonservice) getSnapshotSourceID(ctx context.Context, snapshot *api.Volume) (int, error) {
+	parentID := snapshot.ParentId
+	for {
+		parent, err := cs.api.GetVolume(ctx, parentID)
+		if err != nil {
+			return 0, err
+		}
+		if parent.ParentId == 0 || !parent.WriteProtected {
+			return parent.ID, nil
+		}
+		parentID = parent.ParentId
+	}
+}
+
+// getPoolCapacity returns the free capacity of the pool named by the pool_name parameter,
+// virtual capacity unless the capacity_type parameter asks for physical capacity
+func (cs *commonservice) getPoolCapacity(ctx context.Context, params map[string]string) (capacity int64, err error) {
+	poolName := params["pool_name"]
+	if poolName == "" {
+		return 0, status.Error(codes.InvalidArgument, "no 'pool_name' provided to GetCapacity")
+	}
+	capacityType := params[CAPACITYTYPE]
+	if capacityType == "" {
+		capacityType = CAPACITYVIRTUAL
+	}
+	if capacityType != CAPACITYVIRTUAL && capacityType != CAPACITYPHYSICAL {
+		return 0, status.Errorf(codes.InvalidArgument, "%s '%s' must be either %s or %s", CAPACITYTYPE, capacityType, CAPACITYPHYSICAL, CAPACITYVIRTUAL)
+	}
+
+	pool, err := cs.api.GetStoragePoolByName(ctx, poolName)
+	if err != nil {
+		klog.Errorf("failed to get storage pool %s: %v", poolName, err)
+		if api.IsNotFound(err) {
+			return 0, status.Errorf(codes.NotFound, "storage pool %s not found", poolName)
+		}
+		return 0, status.Errorf(api.GRPCCode(err), "failed to get storage pool %s: %v", poolName, err)
+	}
+
+	if capacityType == CAPACITYPHYSICAL {
+		capacity = int64(pool.FreePhysicalSpace)
+		// a pool with max_extend set grows its physical capacity on demand up to that size
+		if pool.MaxExtend > pool.PhysicalCapacity {
+			capacity += int64(pool.MaxExtend - pool.PhysicalCapacity)
+		}
+	} else {
+		capacity = int64(pool.FreeVirtualSpace)
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	klog.V(4).Infof("pool %s has %d bytes of free %s capacity", poolName, capacity, capacityType)
+	return capacity, nil
+}
+
 /*
 func GetUnixPermission(unixPermission, defaultPermission string) (os.FileMode, error) {
 	var mode os.FileMode
@@ -704,6 +1309,147 @@ func (cs *commonservice) pathExists(path string) (bool, error) {
 	}
 }
 
+// resizeMountedFilesystem grows the filesystem just mounted from devicePath to the size of the device, for
+// volumes cloned larger than their source. Filesystems already filling the device are left as is
+func resizeMountedFilesystem(devicePath, mountPath string) {
+	resizer := mountutils.NewResizeFs(utilexec.New())
+	needResize, err := resizer.NeedResize(devicePath, mountPath)
+	if err != nil {
+		klog.Warningf("failed to check size of filesystem on device %s mounted at %s: %v", devicePath, mountPath, err)
+		return
+	}
+	if !needResize {
+		return
+	}
+	klog.V(2).Infof("Resizing filesystem on device %s mounted at %s to the device size", devicePath, mountPath)
+	if _, err := resizer.Resize(devicePath, mountPath); err != nil {
+		klog.Warningf("failed to resize filesystem on device %s mounted at %s: %v", devicePath, mountPath, err)
+	}
+}
+
+// expandMpathVolume grows the filesystem mounted at the volume path after ControllerExpandVolume grew
+// the LUN: the SCSI paths are rescanned, the multipath map is resized and then the filesystem is resized
+func (cs *commonservice) expandMpathVolume(req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	volumePath := req.GetVolumePath()
+	if volumePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume path missing in request")
+	}
+	if req.GetVolumeCapability().GetBlock() != nil {
+		klog.V(4).Infof("volume %s is a raw block volume, no filesystem to expand", req.GetVolumeId())
+		return &csi.NodeExpandVolumeResponse{}, nil
+	}
+
+	devicePath, _, err := mount.GetDeviceNameFromMount(mount.New(""), volumePath)
+	if err != nil || devicePath == "" {
+		klog.Errorf("failed to find device mounted at %s: %v", volumePath, err)
+		return nil, status.Errorf(codes.NotFound, "failed to find device mounted at %s", volumePath)
+	}
+	disk, err := findDeviceForPath(devicePath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve device %s: %v", devicePath, err)
+	}
+	klog.V(4).Infof("volume %s is mounted at %s from device %s", req.GetVolumeId(), volumePath, disk)
+
+	devices := []string{"/dev/" + disk}
+	isMultipath := strings.HasPrefix(disk, "dm-")
+	if isMultipath {
+		if devices, err = findSlaveDevicesOnMultipath("/dev/" + disk); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to find paths of multipath device %s: %v", disk, err)
+		}
+	}
+	for _, device := range devices {
+		rescanPath := fmt.Sprintf("/sys/block/%s/device/rescan", strings.TrimPrefix(device, "/dev/"))
+		if _, err := execScsi.Command("echo", fmt.Sprintf("1 > %s", rescanPath)); err != nil {
+			klog.Errorf("rescan of device %s failed: %v", device, err)
+			return nil, status.Errorf(codes.Internal, "rescan of device %s failed: %v", device, err)
+		}
+	}
+	if isMultipath {
+		mpath, err := findMpathFromDevice("/dev/" + disk)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to find multipath map of device %s: %v", disk, err)
+		}
+		if _, err := execScsi.Command("multipathd", fmt.Sprintf("resize map %s", mpath)); err != nil {
+			klog.Errorf("resize of multipath map %s failed: %v", mpath, err)
+			return nil, status.Errorf(codes.Internal, "resize of multipath map %s failed: %v", mpath, err)
+		}
+	}
+
+	if _, err := mountutils.NewResizeFs(utilexec.New()).Resize("/dev/"+disk, volumePath); err != nil {
+		klog.Errorf("failed to resize filesystem on device %s mounted at %s: %v", disk, volumePath, err)
+		return nil, status.Errorf(codes.Internal, "failed to resize filesystem on device %s: %v", disk, err)
+	}
+	klog.V(2).Infof("volume %s expanded, filesystem on device %s resized", req.GetVolumeId(), disk)
+	return &csi.NodeExpandVolumeResponse{CapacityBytes: req.GetCapacityRange().GetRequiredBytes()}, nil
+}
+
+// getVolumeStats returns the bytes and inodes of the filesystem mounted at volumePath,
+// or the size of the multipath device when volumePath is a published raw block volume
+func (cs *commonservice) getVolumeStats(volumePath string) (*csi.NodeGetVolumeStatsResponse, error) {
+	if volumePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume path missing in request")
+	}
+	info, err := os.Stat(volumePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, status.Errorf(codes.NotFound, "volume path %s not found", volumePath)
+		}
+		if cs.isCorruptedMnt(err) {
+			klog.Warningf("volume path %s is corrupted: %v", volumePath, err)
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("mount at %s is corrupted: %v", volumePath, err)},
+			}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to stat volume path %s: %v", volumePath, err)
+	}
+
+	if info.Mode()&os.ModeDevice != 0 {
+		out, err := cs.ExecuteWithTimeout(10000, "blockdev", []string{"--getsize64", volumePath})
+		if err != nil {
+			klog.Errorf("failed to get size of block device %s: %v", volumePath, err)
+			return nil, status.Errorf(codes.Internal, "failed to get size of block device %s: %v", volumePath, err)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to parse size of block device %s: %v", volumePath, err)
+		}
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage:           []*csi.VolumeUsage{{Unit: csi.VolumeUsage_BYTES, Total: size}},
+			VolumeCondition: &csi.VolumeCondition{Abnormal: false, Message: "block device is healthy"},
+		}, nil
+	}
+
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(volumePath, &statfs); err != nil {
+		if cs.isCorruptedMnt(&os.SyscallError{Syscall: "statfs", Err: err}) {
+			klog.Warningf("mount at %s is corrupted: %v", volumePath, err)
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf("mount at %s is corrupted: %v", volumePath, err)},
+			}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to statfs volume path %s: %v", volumePath, err)
+	}
+	blockSize := int64(statfs.Bsize)
+	klog.V(4).Infof("volume path %s has %d of %d blocks of %d bytes free", volumePath, statfs.Bavail, statfs.Blocks, blockSize)
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Unit:      csi.VolumeUsage_BYTES,
+				Total:     int64(statfs.Blocks) * blockSize,
+				Available: int64(statfs.Bavail) * blockSize,
+				Used:      int64(statfs.Blocks-statfs.Bfree) * blockSize,
+			},
+			{
+				Unit:      csi.VolumeUsage_INODES,
+				Total:     int64(statfs.Files),
+				Available: int64(statfs.Ffree),
+				Used:      int64(statfs.Files - statfs.Ffree),
+			},
+		},
+		VolumeCondition: &csi.VolumeCondition{Abnormal: false, Message: "mount is healthy"},
+	}, nil
+}
+
 func (cs *commonservice) isCorruptedMnt(err error) bool {
 	if err == nil {
 		return false
@@ -724,22 +1470,197 @@ func (cs *commonservice) isCorruptedMnt(err error) bool {
 }
 
 func (st *fcstorage) ControllerGetVolume(
-	_ context.Context, _ *csi.ControllerGetVolumeRequest,
+	ctx context.Context, req *csi.ControllerGetVolumeRequest,
 ) (*csi.ControllerGetVolumeResponse, error) {
-	// Infinidat does not support ControllerGetVolume
-	return nil, status.Error(codes.Unimplemented, "")
+	return st.cs.getBlockVolume(ctx, req.GetVolumeId())
 }
 
 func (st *iscsistorage) ControllerGetVolume(
-	_ context.Context, _ *csi.ControllerGetVolumeRequest,
+	ctx context.Context, req *csi.ControllerGetVolumeRequest,
 ) (*csi.ControllerGetVolumeResponse, error) {
-	// Infinidat does not support ControllerGetVolume
-	return nil, status.Error(codes.Unimplemented, "")
+	return st.cs.getBlockVolume(ctx, req.GetVolumeId())
 }
 
 func (st *nfsstorage) ControllerGetVolume(
-	_ context.Context, _ *csi.ControllerGetVolumeRequest,
+	ctx context.Context, req *csi.ControllerGetVolumeRequest,
 ) (*csi.ControllerGetVolumeResponse, error) {
-	// Infinidat does not support ControllerGetVolume
-	return nil, status.Error(codes.Unimplemented, "")
+	fileSystemID, err := strconv.ParseInt(req.GetVolumeId(), 10, 64)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "volume ID %s is not a filesystem ID", req.GetVolumeId())
+	}
+	resp := &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{VolumeId: req.GetVolumeId()},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{},
+	}
+	fileSystem, err := st.cs.api.GetFileSystemByID(ctx, fileSystemID)
+	if err != nil {
+		if api.HasErrorCode(err, api.ErrFileSystemNotFound) {
+			resp.Status.VolumeCondition = abnormalCondition("filesystem %d not found on the array", fileSystemID)
+			return resp, nil
+		}
+		klog.Errorf("failed to get filesystem %d: %v", fileSystemID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get filesystem %d: %v", fileSystemID, err)
+	}
+	resp.Volume.CapacityBytes = fileSystem.Size
+	resp.Status.PublishedNodeIds, err = st.cs.getExportClients(ctx, fileSystemID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Status.VolumeCondition = st.cs.getObjectCondition(ctx, fileSystemID, "filesystem", fileSystem.WriteProtected)
+	return resp, nil
+}
+
+// getBlockVolume returns the size, mapped hosts and condition of an iscsi or fc volume
+func (cs *commonservice) getBlockVolume(ctx context.Context, volumeID string) (*csi.ControllerGetVolumeResponse, error) {
+	volID, err := strconv.Atoi(volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "volume ID %s is not a volume ID", volumeID)
+	}
+	resp := &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{VolumeId: volumeID},
+		Status: &csi.ControllerGetVolumeResponse_VolumeStatus{},
+	}
+	volume, err := cs.api.GetVolume(ctx, volID)
+	if err != nil {
+		if api.HasErrorCode(err, api.ErrVolumeNotFound) {
+			resp.Status.VolumeCondition = abnormalCondition("volume %d not found on the array", volID)
+			return resp, nil
+		}
+		klog.Errorf("failed to get volume %d: %v", volID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get volume %d: %v", volID, err)
+	}
+	resp.Volume.CapacityBytes = volume.Size
+	resp.Status.PublishedNodeIds, err = cs.getVolumeHosts(ctx, volID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Status.VolumeCondition = cs.getObjectCondition(ctx, int64(volID), "volume", volume.WriteProtected)
+	if !resp.Status.VolumeCondition.Abnormal && (volume.RmrSource || volume.RmrTarget) {
+		resp.Status.VolumeCondition = cs.getReplicaCondition(ctx, int64(volID))
+	}
+	return resp, nil
+}
+
+// getCSINodeIDs returns the node IDs the nodes of the cluster registered for the driver
+var getCSINodeIDs = func() ([]string, error) {
+	cl, err := clientgo.BuildClient()
+	if err != nil {
+		return nil, err
+	}
+	return cl.GetCSINodeIDs(Name)
+}
+
+// getNodeIDsByPart returns the node IDs, '<fqdn>$$<node IP>', keyed by their host name part for
+// part 0 or by their node IP part for part 1
+func getNodeIDsByPart(part int) (map[string]string, error) {
+	nodeIDs, err := getCSINodeIDs()
+	if err != nil {
+		klog.Errorf("failed to get the node IDs of the driver: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "failed to get the node IDs of the driver: %v", err)
+	}
+	byPart := map[string]string{}
+	for _, nodeID := range nodeIDs {
+		if parts := strings.Split(nodeID, "$$"); len(parts) == 2 {
+			byPart[parts[part]] = nodeID
+		}
+	}
+	return byPart, nil
+}
+
+// getVolumeHosts returns the IDs of the nodes a volume is mapped to, hosts which are not nodes
+// of the driver are left out
+func (cs *commonservice) getVolumeHosts(ctx context.Context, volumeID int) (nodeIDs []string, err error) {
+	luns, err := cs.api.GetLunsByVolume(ctx, volumeID)
+	if err != nil {
+		klog.Errorf("failed to get luns of volume %d: %v", volumeID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get luns of volume %d: %v", volumeID, err)
+	}
+	if len(luns) == 0 {
+		return nil, nil
+	}
+	hosts, err := cs.api.GetAllHosts(ctx)
+	if err != nil {
+		klog.Errorf("failed to get hosts: %v", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get hosts: %v", err)
+	}
+	hostNames := map[int]string{}
+	for _, host := range hosts {
+		hostNames[host.ID] = host.Name
+	}
+	nodeIDsByName, err := getNodeIDsByPart(0)
+	if err != nil {
+		return nil, err
+	}
+	for _, lun := range luns {
+		if nodeID, ok := nodeIDsByName[hostNames[lun.HostID]]; ok {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	klog.V(4).Infof("volume %d is mapped to nodes %v", volumeID, nodeIDs)
+	return nodeIDs, nil
+}
+
+// getExportClients returns the IDs of the nodes a filesystem is exported to, clients which are
+// not node IPs of the driver, e.g. address ranges of the StorageClass nfs_export_permissions, are left out
+func (cs *commonservice) getExportClients(ctx context.Context, fileSystemID int64) (nodeIDs []string, err error) {
+	exports, err := cs.api.GetExportByFileSystem(ctx, fileSystemID)
+	if err != nil {
+		klog.Errorf("failed to get exports of filesystem %d: %v", fileSystemID, err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to get exports of filesystem %d: %v", fileSystemID, err)
+	}
+	clients := []string{}
+	for _, export := range *exports {
+		for _, permission := range export.Permissions {
+			if net.ParseIP(permission.Client) != nil {
+				clients = append(clients, permission.Client)
+			}
+		}
+	}
+	if len(clients) == 0 {
+		return nil, nil
+	}
+	nodeIDsByIP, err := getNodeIDsByPart(1)
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		if nodeID, ok := nodeIDsByIP[client]; ok {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	klog.V(4).Infof("filesystem %d is exported to nodes %v", fileSystemID, nodeIDs)
+	return nodeIDs, nil
+}
+
+// getObjectCondition reports a volume or filesystem as abnormal when it is write protected,
+// the driver never creates them so, or when it is waiting for its snapshots to be deleted
+func (cs *commonservice) getObjectCondition(ctx context.Context, objectID int64, objectType string, writeProtected bool) *csi.VolumeCondition {
+	if writeProtected {
+		return abnormalCondition("%s %d is write protected", objectType, objectID)
+	}
+	if cs.api.GetMetadataStatus(ctx, objectID) {
+		return abnormalCondition("%s %d is marked %s", objectType, objectID, TOBEDELETED)
+	}
+	return &csi.VolumeCondition{Abnormal: false, Message: objectType + " is healthy"}
+}
+
+// getReplicaCondition reports a replicated volume as abnormal when its replica is not replicating
+func (cs *commonservice) getReplicaCondition(ctx context.Context, volumeID int64) *csi.VolumeCondition {
+	replica, err := cs.api.GetReplicaByEntity(ctx, volumeID)
+	if err != nil {
+		klog.Errorf("failed to get replica of volume %d: %v", volumeID, err)
+		return abnormalCondition("failed to get replica of volume %d: %v", volumeID, err)
+	}
+	if replica.State != api.ReplicaStateActive {
+		return abnormalCondition("replica %d of volume %d is %s", replica.ID, volumeID, replica.State)
+	}
+	message := fmt.Sprintf("volume is healthy, %s replica %d is %s", strings.ToLower(replica.Role), replica.ID, replica.SyncState)
+	if replica.LastSynchronized > 0 {
+		message += ", last synchronized " + time.Unix(0, replica.LastSynchronized*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+	}
+	return &csi.VolumeCondition{Abnormal: false, Message: message}
+}
+
+func abnormalCondition(format string, args ...interface{}) *csi.VolumeCondition {
+	return &csi.VolumeCondition{Abnormal: true, Message: fmt.Sprintf(format, args...)}
 }
@@ -0,0 +1,293 @@
+/*Copyright 2022 Infinidat
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+const (
+	// TopologyKeyFCFabric is the topology segment key of the FC fabric a node is attached to. Its value
+	// is the fabric name of the node's FC HBAs, e.g. 100000051e0b6d01
+	TopologyKeyFCFabric = Name + "/fc-fabric"
+	// TopologyKeyNetwork is the topology segment key of the IP network of a node. Its value is the
+	// network of the node IP, e.g. 10.20.30.0-24
+	TopologyKeyNetwork = Name + "/network"
+	// TopologyNone is the segment value of a node without FC HBAs, or without a known network
+	TopologyNone = "none"
+)
+
+// TopologyProvider is implemented by the storage protocols, which place a volume on the array of the
+// StorageClass secret and report the nodes able to reach it
+type TopologyProvider interface {
+	// GetAccessibleTopology returns the topology a new volume is accessible from, failing when the
+	// array is out of the requisite topology of the request
+	GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error)
+}
+
+// fcHostsGlob matches the FC HBAs of a node
+var fcHostsGlob = "/sys/class/fc_host/host*"
+
+// interfaceAddrs returns the addresses of the network interfaces of a node
+var interfaceAddrs = net.InterfaceAddrs
+
+// NodeTopology returns the topology segments of a node: the same keys on every node, valued with the
+// FC fabric of its HBAs and the network of its IP, or TopologyNone
+func NodeTopology(nodeIP string) map[string]string {
+	segments := map[string]string{
+		TopologyKeyFCFabric: TopologyNone,
+		TopologyKeyNetwork:  TopologyNone,
+	}
+	if fabrics := getNodeFabrics(); len(fabrics) > 0 {
+		// a node attached to two fabrics for multipathing reports the first, an array reachable
+		// over both is attached to it as well
+		segments[TopologyKeyFCFabric] = fabrics[0]
+	}
+	if network := getNodeNetwork(nodeIP); network != "" {
+		segments[TopologyKeyNetwork] = network
+	}
+	klog.V(4).Infof("Node topology segments: %v", segments)
+	return segments
+}
+
+// getNodeFabrics returns the sorted fabric names of the online FC HBAs of the node
+func getNodeFabrics() []string {
+	hosts, err := filepath.Glob(fcHostsGlob)
+	if err != nil {
+		return nil
+	}
+	fabrics := []string{}
+	for _, host := range hosts {
+		state, err := ioutil.ReadFile(filepath.Join(host, "port_state"))
+		if err != nil || strings.TrimSpace(string(state)) != "Online" {
+			continue
+		}
+		fabric, err := ioutil.ReadFile(filepath.Join(host, "fabric_name"))
+		if err != nil {
+			continue
+		}
+		// HBAs not logged into a fabric report a zero fabric name
+		if name := normalizeWWN(string(fabric)); strings.Trim(name, "0f") != "" {
+			fabrics = append(fabrics, name)
+		}
+	}
+	sort.Strings(fabrics)
+	return fabrics
+}
+
+// getNodeNetwork returns the topology value of the network of the interface holding nodeIP,
+// empty when no interface holds it
+func getNodeNetwork(nodeIP string) string {
+	ip := net.ParseIP(nodeIP)
+	if ip == nil {
+		return ""
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		klog.Errorf("failed to get interface addresses: %v", err)
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return networkSegment(ipNet)
+		}
+	}
+	return ""
+}
+
+// normalizeWWN returns a WWN as lower case hex digits, without separators or 0x prefix
+func normalizeWWN(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	wwn = strings.TrimPrefix(wwn, "0x")
+	return strings.Replace(wwn, ":", "", -1)
+}
+
+// networkSegment returns the network of ipNet as a topology value, which cannot hold '/' or ':',
+// e.g. 10.20.30.0-24 or fd00__-64
+func networkSegment(ipNet *net.IPNet) string {
+	ones, _ := ipNet.Mask.Size()
+	network := ipNet.IP.Mask(ipNet.Mask).String()
+	return strings.Replace(network, ":", "_", -1) + "-" + strconv.Itoa(ones)
+}
+
+// parseNetworkSegment returns the network of a topology value made by networkSegment
+func parseNetworkSegment(segment string) (*net.IPNet, error) {
+	i := strings.LastIndex(segment, "-")
+	if i < 0 {
+		return nil, fmt.Errorf("network segment %s does not follow '<network>-<prefix length>' pattern", segment)
+	}
+	_, ipNet, err := net.ParseCIDR(strings.Replace(segment[:i], "_", ":", -1) + "/" + segment[i+1:])
+	return ipNet, err
+}
+
+func (iscsi *iscsistorage) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) (topology []*csi.Topology, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from ISCSI GetAccessibleTopology  " + fmt.Sprint(res))
+		}
+	}()
+	return iscsi.cs.getAccessibleTopology(ctx, protocol, params, requirements)
+}
+
+func (fc *fcstorage) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) (topology []*csi.Topology, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from FC GetAccessibleTopology  " + fmt.Sprint(res))
+		}
+	}()
+	return fc.cs.getAccessibleTopology(ctx, protocol, params, requirements)
+}
+
+func (nfs *nfsstorage) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) (topology []*csi.Topology, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from NFS GetAccessibleTopology  " + fmt.Sprint(res))
+		}
+	}()
+	return nfs.cs.getAccessibleTopology(ctx, protocol, params, requirements)
+}
+
+func (treeq *treeqstorage) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) (topology []*csi.Topology, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from treeq GetAccessibleTopology  " + fmt.Sprint(res))
+		}
+	}()
+	return treeq.filesysService.GetAccessibleTopology(ctx, protocol, params, requirements)
+}
+
+// GetAccessibleTopology returns the topology of the array holding the treeq filesystems
+func (filesystem *FilesystemService) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error) {
+	return filesystem.cs.getAccessibleTopology(ctx, protocol, params, requirements)
+}
+
+// arrayReach holds the FC fabrics the array is attached to, or the IPs of the network spaces
+// it serves a protocol from
+type arrayReach struct {
+	protocol string
+	fabrics  map[string]bool
+	ips      []net.IP
+}
+
+// getArrayReach returns the fabrics of the array FC ports for fc, or the IPs of the network spaces
+// of the network_space parameter for the other protocols
+func (cs *commonservice) getArrayReach(ctx context.Context, protocol string, params map[string]string) (*arrayReach, error) {
+	reach := &arrayReach{protocol: protocol, fabrics: map[string]bool{}}
+	if protocol == "fc" {
+		fcNodes, err := cs.api.GetFCPorts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, fcNode := range fcNodes {
+			for _, port := range fcNode.Ports {
+				if port.Enabled && port.SwitchWWNn != "" {
+					reach.fabrics[normalizeWWN(port.SwitchWWNn)] = true
+				}
+			}
+		}
+		return reach, nil
+	}
+	for _, name := range strings.Split(params["network_space"], ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		nspace, err := cs.api.GetNetworkSpaceByName(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, portal := range nspace.Portals {
+			if ip := net.ParseIP(portal.IpAdress); ip != nil && portal.Enabled {
+				reach.ips = append(reach.ips, ip)
+			}
+		}
+	}
+	return reach, nil
+}
+
+// key returns the topology segment key the reach of the protocol is matched against
+func (reach *arrayReach) key() string {
+	if reach.protocol == "fc" {
+		return TopologyKeyFCFabric
+	}
+	return TopologyKeyNetwork
+}
+
+// accessibleFrom reports whether the nodes of the topology segments reach the array: over FC when
+// their fabric is one the array is attached to, otherwise when their network holds an IP of one of
+// the network spaces
+func (reach *arrayReach) accessibleFrom(segments map[string]string) bool {
+	value := segments[reach.key()]
+	if value == "" || value == TopologyNone {
+		return false
+	}
+	if reach.protocol == "fc" {
+		return reach.fabrics[value]
+	}
+	network, err := parseNetworkSegment(value)
+	if err != nil {
+		klog.Warningf("ignoring topology segment %s: %v", value, err)
+		return false
+	}
+	for _, ip := range reach.ips {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAccessibleTopology returns the segments of the requisite topology, or of the preferred one without
+// requisite topology, whose nodes reach the array of the client over protocol. Without topology
+// requirements, i.e. with the Topology feature of the provisioner off, no topology is returned
+func (cs *commonservice) getAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error) {
+	candidates := requirements.GetRequisite()
+	if len(candidates) == 0 {
+		candidates = requirements.GetPreferred()
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	reach, err := cs.getArrayReach(ctx, protocol, params)
+	if err != nil {
+		klog.Errorf("failed to get the %s ports of the array: %v", protocol, err)
+		return nil, status.Errorf(codes.Unavailable, "failed to get the %s ports of the array: %v", protocol, err)
+	}
+	key := reach.key()
+	accessible := []*csi.Topology{}
+	seen := map[string]bool{}
+	for _, topology := range candidates {
+		value := topology.GetSegments()[key]
+		if seen[value] || !reach.accessibleFrom(topology.GetSegments()) {
+			continue
+		}
+		seen[value] = true
+		accessible = append(accessible, &csi.Topology{Segments: map[string]string{key: value}})
+	}
+	if len(accessible) == 0 {
+		klog.Errorf("array is not accessible over %s from the requisite topology", protocol)
+		return nil, status.Errorf(codes.ResourceExhausted, "array is not accessible over %s from the requisite topology", protocol)
+	}
+	klog.V(4).Infof("Volume is accessible from topology %v", accessible)
+	return accessible, nil
+}
@@ -14,6 +14,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"infinibox-csi-driver/api"
 	"strconv"
 	"strings"
 
@@ -39,6 +40,17 @@ func (treeq *treeqstorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	// treeqs share the QoS of their filesystem, which hosts the treeqs of many PVs
+	if policy, _ := getQosPolicy(config, api.QosPolicyTypeFilesystem); policy != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s, %s and %s are not supported for %s", QOSPOLICY, MAXIOPS, MAXBPS, NFSTREEQ)
+	}
+	if config[REPLICATIONTARGET] != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not supported for %s", REPLICATIONTARGET, NFSTREEQ)
+	}
+	// volume_name_template names a new filesystem hosting the treeq, render it before creating anything
+	if _, err = getVolumeName(config, pvName); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	// TODO: negative validation - eg useCHAP should NOT be specified for nfs
 
 	// TODO: move this capacity validation into controller.go
@@ -58,9 +70,9 @@ func (treeq *treeqstorage) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		}
 	}
 
-	treeqVolumeMap, err = treeq.filesysService.IsTreeqAlreadyExist(config["pool_name"], strings.Trim(config["network_space"], ""), pvName)
+	treeqVolumeMap, err = treeq.filesysService.IsTreeqAlreadyExist(ctx, config["pool_name"], strings.Trim(config["network_space"], ""), pvName)
 	if len(treeqVolumeMap) == 0 && err == nil {
-		treeqVolumeMap, err = treeq.filesysService.CreateTreeqVolume(config, capacity, pvName)
+		treeqVolumeMap, err = treeq.filesysService.CreateTreeqVolume(ctx, config, capacity, pvName)
 	}
 	if err != nil {
 		klog.Errorf("failed to create volume %v", err)
@@ -111,9 +123,9 @@ func (treeq *treeqstorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 		klog.Errorf("Invalid Volume ID %v", err)
 		return nil, status.Error(codes.InvalidArgument, "Invalid volume ID")
 	}
-	nfsDeleteErr := treeq.filesysService.DeleteTreeqVolume(filesystemID, treeqID)
+	nfsDeleteErr := treeq.filesysService.DeleteTreeqVolume(ctx, filesystemID, treeqID)
 	if nfsDeleteErr != nil {
-		if strings.Contains(nfsDeleteErr.Error(), "FILESYSTEM_NOT_FOUND") {
+		if api.HasErrorCode(nfsDeleteErr, api.ErrFileSystemNotFound) {
 			klog.Error("treeq already delete from infinibox")
 			return &csi.DeleteVolumeResponse{}, nil
 		}
@@ -123,6 +135,25 @@ func (treeq *treeqstorage) DeleteVolume(ctx context.Context, req *csi.DeleteVolu
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// ListVolumes returns all treeqs, paging across protocols is done by the caller
+func (treeq *treeqstorage) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	treeqs, err := treeq.filesysService.ListTreeqVolumes(ctx)
+	if err != nil {
+		klog.Errorf("failed to list treeqs: %v", err)
+		return nil, status.Errorf(api.GRPCCode(err), "failed to list treeqs: %v", err)
+	}
+	entries := []*csi.ListVolumesResponse_Entry{}
+	for _, t := range treeqs {
+		entries = append(entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      strconv.FormatInt(t.FilesystemID, 10) + "#" + strconv.FormatInt(t.ID, 10),
+				CapacityBytes: t.HardCapacity,
+			},
+		})
+	}
+	return &csi.ListVolumesResponse{Entries: entries}, nil
+}
+
 func (treeq *treeqstorage) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
 	return &csi.ControllerPublishVolumeResponse{}, nil
 }
@@ -139,6 +170,35 @@ func (treeq *treeqstorage) DeleteSnapshot(ctx context.Context, req *csi.DeleteSn
 	return nil, status.Error(codes.Unimplemented, "Unsupported operation for treeq")
 }
 
+// ListSnapshots returns no entries, snapshots are not supported for treeq
+func (treeq *treeqstorage) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	return &csi.ListSnapshotsResponse{}, nil
+}
+
+// ControllerGetVolume returns the size, export clients and condition of a treeq
+func (treeq *treeqstorage) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	filesystemID, treeqID, _, err := getVolumeIDs(req.GetVolumeId())
+	if err != nil {
+		klog.Errorf("Invalid Volume ID %v", err)
+		return nil, status.Error(codes.NotFound, "Invalid volume ID")
+	}
+	return treeq.filesysService.GetTreeqVolume(ctx, filesystemID, treeqID)
+}
+
+// GetCapacity returns the free capacity of the StorageClass pool holding the treeq filesystems
+func (treeq *treeqstorage) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (resp *csi.GetCapacityResponse, err error) {
+	defer func() {
+		if res := recover(); res != nil && err == nil {
+			err = errors.New("Recovered from treeq GetCapacity  " + fmt.Sprint(res))
+		}
+	}()
+	capacity, err := treeq.filesysService.GetPoolCapacity(ctx, req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+	return &csi.GetCapacityResponse{AvailableCapacity: capacity}, nil
+}
+
 func (treeq *treeqstorage) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (expandVolume *csi.ControllerExpandVolumeResponse, err error) {
 	defer func() {
 		if res := recover(); res != nil && err == nil {
@@ -159,7 +219,7 @@ func (treeq *treeqstorage) ControllerExpandVolume(ctx context.Context, req *csi.
 	}
 
 	klog.V(4).Infof("filesystemID %d treeqID %d capacity %d maxSize %s\n", filesystemID, treeqID, capacity, maxSize)
-	err = treeq.filesysService.UpdateTreeqVolume(filesystemID, treeqID, capacity, maxSize)
+	err = treeq.filesysService.UpdateTreeqVolume(ctx, filesystemID, treeqID, capacity, maxSize)
 	if err != nil {
 		return
 	}
@@ -14,6 +14,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"infinibox-csi-driver/api"
 	"infinibox-csi-driver/helper"
 	tests "infinibox-csi-driver/test_helper"
 	"testing"
@@ -22,6 +23,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (suite *TreeqControllerSuite) SetupTest() {
@@ -57,6 +60,24 @@ func (suite *TreeqControllerSuite) Test_CreateVolume_Error() {
 	assert.NotNil(suite.T(), err, "empty error")
 }
 
+func (suite *TreeqControllerSuite) Test_CreateVolume_qosNotSupported() {
+	req := getCreateVolumeRequest()
+	req.Parameters[MAXIOPS] = "1000"
+	service := treeqstorage{filesysService: suite.filesystem}
+	_, err := service.CreateVolume(context.Background(), req)
+	assert.NotNil(suite.T(), err, "expected to fail: QoS of treeq")
+	suite.filesystem.AssertNotCalled(suite.T(), "CreateTreeqVolume", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *TreeqControllerSuite) Test_CreateVolume_volumeNameTemplateWithoutPVC() {
+	req := getCreateVolumeRequest()
+	req.Parameters[VOLUMENAMETEMPLATE] = "k8s-{{.PVCNamespace}}-{{.PVCName}}"
+	service := treeqstorage{filesysService: suite.filesystem}
+	_, err := service.CreateVolume(context.Background(), req)
+	assert.Equal(suite.T(), codes.InvalidArgument, status.Code(err), "expected to fail: volume_name_template without PVC metadata")
+	suite.filesystem.AssertNotCalled(suite.T(), "CreateTreeqVolume", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func (suite *TreeqControllerSuite) Test_CreateVolume_Success() {
 	volumeResponse := getCreateVolumeResponse()
 	volumeRespoance := make(map[string]string)
@@ -103,7 +124,7 @@ func (suite *TreeqControllerSuite) Test_DeleteVolume_Error() {
 func (suite *TreeqControllerSuite) Test_DeleteVolume_Error_filenotfound() {
 	service := treeqstorage{filesysService: suite.filesystem}
 	volumeID := "100#200$$"
-	expectedErr := errors.New("FILESYSTEM_NOT_FOUND error")
+	expectedErr := &api.APIError{Code: api.ErrFileSystemNotFound}
 	var filesytemID, treeqID int64 = 100, 200
 	suite.filesystem.On("DeleteTreeqVolume", filesytemID, treeqID).Return(expectedErr)
 	_, err := service.DeleteVolume(context.Background(), getDeleteVolumeRequest(volumeID))
@@ -177,6 +198,46 @@ func getExpandVolumeRequest(vID string) *csi.ControllerExpandVolumeRequest {
 	}
 }
 
+func (suite *TreeqControllerSuite) Test_ListVolumes_Success() {
+	treeqs := []api.Treeq{{ID: 200, FilesystemID: 100, HardCapacity: 1073741824}}
+	suite.filesystem.On("ListTreeqVolumes").Return(treeqs, nil)
+	service := treeqstorage{filesysService: suite.filesystem}
+	resp, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.Nil(suite.T(), err, "empty error")
+	assert.Equal(suite.T(), "100#200", resp.Entries[0].Volume.VolumeId)
+}
+
+func (suite *TreeqControllerSuite) Test_ListVolumes_Error() {
+	suite.filesystem.On("ListTreeqVolumes").Return(nil, errors.New("some error"))
+	service := treeqstorage{filesysService: suite.filesystem}
+	_, err := service.ListVolumes(context.Background(), &csi.ListVolumesRequest{})
+	assert.NotNil(suite.T(), err, "expected to fail: treeq ListVolumes")
+}
+
+func (suite *TreeqControllerSuite) Test_GetCapacity() {
+	params := map[string]string{"pool_name": "pool1"}
+	suite.filesystem.On("GetPoolCapacity", params).Return(int64(1073741824), nil)
+	service := treeqstorage{filesysService: suite.filesystem}
+	resp, err := service.GetCapacity(context.Background(), &csi.GetCapacityRequest{Parameters: params})
+	assert.Nil(suite.T(), err, "empty error")
+	assert.Equal(suite.T(), int64(1073741824), resp.AvailableCapacity)
+}
+
+func (suite *TreeqControllerSuite) Test_ControllerGetVolume() {
+	expected := &csi.ControllerGetVolumeResponse{Volume: &csi.Volume{VolumeId: "100#200"}}
+	suite.filesystem.On("GetTreeqVolume", int64(100), int64(200)).Return(expected, nil)
+	service := treeqstorage{filesysService: suite.filesystem}
+	resp, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100#200"})
+	assert.Nil(suite.T(), err, "empty error")
+	assert.Equal(suite.T(), expected, resp)
+}
+
+func (suite *TreeqControllerSuite) Test_ControllerGetVolume_InvalidID() {
+	service := treeqstorage{filesysService: suite.filesystem}
+	_, err := service.ControllerGetVolume(context.Background(), &csi.ControllerGetVolumeRequest{VolumeId: "100"})
+	assert.NotNil(suite.T(), err, "expected to fail: treeq ControllerGetVolume invalid ID")
+}
+
 func getCreateVolumeResponse() map[string]string {
 	result := make(map[string]string)
 	result["ID"] = "100"
@@ -195,26 +256,54 @@ type FileSystemInterfaceMock struct {
 	mock.Mock
 }
 
-func (m *FileSystemInterfaceMock) CreateTreeqVolume(config map[string]string, capacity int64, pvName string) (map[string]string, error) {
+func (m *FileSystemInterfaceMock) CreateTreeqVolume(ctx context.Context, config map[string]string, capacity int64, pvName string) (map[string]string, error) {
 	status := m.Called(config, capacity, pvName)
 	st, _ := status.Get(0).(map[string]string)
 	err, _ := status.Get(1).(error)
 	return st, err
 }
 
-func (m *FileSystemInterfaceMock) DeleteTreeqVolume(filesystemID, treeqID int64) error {
+func (m *FileSystemInterfaceMock) DeleteTreeqVolume(ctx context.Context, filesystemID, treeqID int64) error {
 	status := m.Called(filesystemID, treeqID)
 	st, _ := status.Get(0).(error)
 	return st
 }
 
-func (m *FileSystemInterfaceMock) UpdateTreeqVolume(filesystemID, treeqID, capacity int64, maxSize string) error {
+func (m *FileSystemInterfaceMock) UpdateTreeqVolume(ctx context.Context, filesystemID, treeqID, capacity int64, maxSize string) error {
 	status := m.Called(filesystemID, treeqID, capacity, maxSize)
 	err, _ := status.Get(0).(error)
 	return err
 }
 
-func (m *FileSystemInterfaceMock) IsTreeqAlreadyExist(pool_name, network_space, pVName string) (map[string]string, error) {
+func (m *FileSystemInterfaceMock) ListTreeqVolumes(ctx context.Context) ([]api.Treeq, error) {
+	status := m.Called()
+	st, _ := status.Get(0).([]api.Treeq)
+	err, _ := status.Get(1).(error)
+	return st, err
+}
+
+func (m *FileSystemInterfaceMock) GetPoolCapacity(ctx context.Context, params map[string]string) (int64, error) {
+	status := m.Called(params)
+	st, _ := status.Get(0).(int64)
+	err, _ := status.Get(1).(error)
+	return st, err
+}
+
+func (m *FileSystemInterfaceMock) GetAccessibleTopology(ctx context.Context, protocol string, params map[string]string, requirements *csi.TopologyRequirement) ([]*csi.Topology, error) {
+	status := m.Called(protocol, requirements)
+	st, _ := status.Get(0).([]*csi.Topology)
+	err, _ := status.Get(1).(error)
+	return st, err
+}
+
+func (m *FileSystemInterfaceMock) GetTreeqVolume(ctx context.Context, filesystemID, treeqID int64) (*csi.ControllerGetVolumeResponse, error) {
+	status := m.Called(filesystemID, treeqID)
+	st, _ := status.Get(0).(*csi.ControllerGetVolumeResponse)
+	err, _ := status.Get(1).(error)
+	return st, err
+}
+
+func (m *FileSystemInterfaceMock) IsTreeqAlreadyExist(ctx context.Context, pool_name, network_space, pVName string) (map[string]string, error) {
 	status := m.Called(pool_name, network_space, pVName)
 	st, _ := status.Get(0).(map[string]string)
 	err, _ := status.Get(1).(error)
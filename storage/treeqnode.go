@@ -108,3 +108,9 @@ func (treeq *treeqstorage) NodeStageVolume(ctx context.Context, req *csi.NodeSta
 func (treeq *treeqstorage) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
+
+func (treeq *treeqstorage) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	// volume stats are read from the node, no array access is needed
+	cs := commonservice{}
+	return cs.getVolumeStats(req.GetVolumePath())
+}
@@ -198,6 +198,19 @@ func (suite *TreeqNodeSuite) Test_NodeUnstageVolume() {
 	assert.Nil(suite.T(), err, "empty err")
 }
 
+func (suite *TreeqNodeSuite) Test_NodeGetVolumeStats() {
+	service := treeqstorage{mounter: suite.nfsMountMock, osHelper: suite.osHelperMock}
+	targetPath := suite.T().TempDir()
+
+	resp, err := service.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{VolumeId: "100#200$$nfs_treeq", VolumePath: targetPath})
+	assert.Nil(suite.T(), err, "empty err")
+	assert.Equal(suite.T(), 2, len(resp.Usage), "expected bytes and inodes usage")
+	assert.Equal(suite.T(), csi.VolumeUsage_BYTES, resp.Usage[0].Unit)
+	assert.True(suite.T(), resp.Usage[0].Total > 0)
+	assert.Equal(suite.T(), csi.VolumeUsage_INODES, resp.Usage[1].Unit)
+	assert.False(suite.T(), resp.VolumeCondition.Abnormal)
+}
+
 func RandomString(n int) string {
 	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 